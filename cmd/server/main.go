@@ -2,13 +2,68 @@ package main
 
 import (
 	"log"
+	"terminal-history-analyzer/internal/baseline"
 	"terminal-history-analyzer/internal/handlers"
+	"terminal-history-analyzer/internal/intel"
+	"terminal-history-analyzer/internal/intent"
+	"terminal-history-analyzer/internal/netintel"
+	"terminal-history-analyzer/internal/risk"
+	"terminal-history-analyzer/internal/semantic"
+	"terminal-history-analyzer/internal/suppress"
+	"terminal-history-analyzer/pkg/config"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	appConfig := config.Load()
+
+	// Cargar reglas de amenazas (predeterminadas + reglas de la organización)
+	rules, err := semantic.ConfigureRules(appConfig.RulesDir)
+	if err != nil {
+		log.Fatal("Error al cargar reglas de amenazas:", err)
+	}
+	log.Printf("Reglas de amenazas cargadas: %d y %d secuencias (directorio: %s)", rules.Len(), len(rules.Sequences()), appConfig.RulesDir)
+
+	// Redes propias de la organización para la clasificación de direcciones
+	if err := netintel.SetOrganizationNetworks(appConfig.OrgCIDRs); err != nil {
+		log.Fatal("Error en ORG_CIDRS:", err)
+	}
+
+	// Cargar fuentes de inteligencia de amenazas (fuente embebida + fuentes del directorio)
+	feeds, err := intel.Configure(appConfig.IntelDir)
+	if err != nil {
+		log.Fatal("Error al cargar fuentes de inteligencia:", err)
+	}
+	log.Printf("Fuentes de inteligencia cargadas: %d con %d indicadores (directorio: %s)", len(feeds.Feeds), feeds.Matcher.Len(), appConfig.IntelDir)
+
+	// Pesos de la puntuación de riesgo (predeterminados si no existe el archivo)
+	if _, err := risk.Configure(appConfig.RiskWeights); err != nil {
+		log.Fatal("Error al cargar los pesos de riesgo:", err)
+	}
+
+	// Taxonomía de intenciones para el perfil de la sesión
+	taxonomy, err := intent.Configure(appConfig.IntentTaxonomy)
+	if err != nil {
+		log.Fatal("Error al cargar la taxonomía de intenciones:", err)
+	}
+	log.Printf("Taxonomía de intenciones cargada: %d categorías y %d reglas", len(taxonomy.Categories), len(taxonomy.Rules))
+
+	// Supresiones de detecciones (se crean y modifican mediante /api/suppressions)
+	suppressions, err := suppress.Configure(appConfig.Suppressions)
+	if err != nil {
+		log.Fatal("Error al cargar las supresiones:", err)
+	}
+	log.Printf("Supresiones cargadas: %d (archivo: %s)", len(suppressions.List()), appConfig.Suppressions)
+
+	// Líneas base por usuario@equipo (se aprenden de los análisis con user)
+	baselines, err := baseline.Configure(appConfig.Baselines)
+	if err != nil {
+		log.Fatal("Error al cargar las líneas base:", err)
+	}
+	log.Printf("Líneas base cargadas: %d (archivo: %s)", len(baselines.List()), appConfig.Baselines)
+
 	// Configurar Gin
 	r := gin.Default()
 
@@ -46,6 +101,26 @@ func main() {
 		api.POST("/validate-realtime", handlers.ValidateRealTime)
 		api.GET("/spelling-suggestions/:command", handlers.GetSpellingSuggestions)
 		api.GET("/command-help/:command", handlers.GetCommandHelp)
+		api.GET("/rules", handlers.GetRules)
+		api.GET("/intel/feeds", handlers.GetIntelFeeds)
+		api.POST("/intel/reload", handlers.ReloadIntelFeeds)
+		api.GET("/risk/weights", handlers.GetRiskWeights)
+		api.GET("/intent/taxonomy", handlers.GetIntentTaxonomy)
+		api.GET("/lint/rules", handlers.GetLintRules)
+		api.GET("/dialects", handlers.GetDialects)
+		api.GET("/analyses", handlers.ListAnalyses)
+		api.GET("/suppressions", handlers.ListSuppressions)
+		api.POST("/suppressions", handlers.CreateSuppression)
+		api.GET("/suppressions/:id", handlers.GetSuppression)
+		api.PUT("/suppressions/:id", handlers.UpdateSuppression)
+		api.DELETE("/suppressions/:id", handlers.DeleteSuppression)
+		api.GET("/baselines", handlers.ListBaselines)
+		api.GET("/baselines/export", handlers.ExportBaselines)
+		api.GET("/baselines/:key", handlers.GetBaseline)
+		api.DELETE("/baselines/:key", handlers.DeleteBaseline)
+		api.GET("/analysis/:id/attack-layer", handlers.GetAttackLayer)
+		api.GET("/analysis/:id/iocs", handlers.GetIOCs)
+		api.GET("/analysis/:id/lateral-movement", handlers.GetLateralMovement)
 	}
 
 	// Servir archivos estáticos del frontend (en producción)
@@ -65,6 +140,26 @@ func main() {
 	log.Println("  GET  /api/v1/demo")
 	log.Println("  POST /api/analyze-enhanced")
 	log.Println("  POST /api/validate-realtime")
+	log.Println("  GET  /api/rules")
+	log.Println("  GET  /api/intel/feeds")
+	log.Println("  POST /api/intel/reload")
+	log.Println("  GET  /api/risk/weights")
+	log.Println("  GET  /api/intent/taxonomy")
+	log.Println("  GET  /api/lint/rules")
+	log.Println("  GET  /api/dialects")
+	log.Println("  GET  /api/analyses?sort=risk_score|threats|created_at&order=desc|asc")
+	log.Println("  GET  /api/suppressions")
+	log.Println("  POST /api/suppressions")
+	log.Println("  GET  /api/suppressions/:id")
+	log.Println("  PUT  /api/suppressions/:id")
+	log.Println("  DELETE /api/suppressions/:id")
+	log.Println("  GET  /api/baselines")
+	log.Println("  GET  /api/baselines/export")
+	log.Println("  GET  /api/baselines/:key")
+	log.Println("  DELETE /api/baselines/:key")
+	log.Println("  GET  /api/analysis/:id/attack-layer")
+	log.Println("  GET  /api/analysis/:id/iocs?format=json|csv|stix")
+	log.Println("  GET  /api/analysis/:id/lateral-movement?format=json|dot")
 
 	if err := r.Run(":8080"); err != nil {
 		log.Fatal("Error al iniciar el servidor:", err)
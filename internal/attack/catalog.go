@@ -0,0 +1,257 @@
+package attack
+
+import (
+	"strings"
+
+	"terminal-history-analyzer/internal/models"
+)
+
+// Tactic representa una táctica de MITRE ATT&CK Enterprise
+type Tactic struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	ShortName string `json:"short_name"` // Nombre usado por ATT&CK Navigator (privilege-escalation)
+}
+
+// Technique representa una técnica o subtécnica con su táctica principal
+type Technique struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	TacticID string `json:"tactic_id"`
+}
+
+// tactics contiene las tácticas de la matriz Enterprise
+var tactics = map[string]Tactic{
+	"TA0043": {"TA0043", "Reconnaissance", "reconnaissance"},
+	"TA0042": {"TA0042", "Resource Development", "resource-development"},
+	"TA0001": {"TA0001", "Initial Access", "initial-access"},
+	"TA0002": {"TA0002", "Execution", "execution"},
+	"TA0003": {"TA0003", "Persistence", "persistence"},
+	"TA0004": {"TA0004", "Privilege Escalation", "privilege-escalation"},
+	"TA0005": {"TA0005", "Defense Evasion", "defense-evasion"},
+	"TA0006": {"TA0006", "Credential Access", "credential-access"},
+	"TA0007": {"TA0007", "Discovery", "discovery"},
+	"TA0008": {"TA0008", "Lateral Movement", "lateral-movement"},
+	"TA0009": {"TA0009", "Collection", "collection"},
+	"TA0011": {"TA0011", "Command and Control", "command-and-control"},
+	"TA0010": {"TA0010", "Exfiltration", "exfiltration"},
+	"TA0040": {"TA0040", "Impact", "impact"},
+}
+
+// techniques contiene las técnicas que pueden producir las detecciones de la terminal.
+// Cada técnica se asocia a la táctica más habitual en un historial de comandos.
+var techniques = map[string]Technique{
+	// Ejecución
+	"T1059":     {"T1059", "Command and Scripting Interpreter", "TA0002"},
+	"T1059.001": {"T1059.001", "PowerShell", "TA0002"},
+	"T1059.004": {"T1059.004", "Unix Shell", "TA0002"},
+	"T1059.006": {"T1059.006", "Python", "TA0002"},
+	"T1059.007": {"T1059.007", "JavaScript", "TA0002"},
+	"T1204.002": {"T1204.002", "Malicious File", "TA0002"},
+	"T1609":     {"T1609", "Container Administration Command", "TA0002"},
+	"T1610":     {"T1610", "Deploy Container", "TA0002"},
+
+	// Persistencia
+	"T1098":     {"T1098", "Account Manipulation", "TA0003"},
+	"T1098.001": {"T1098.001", "Additional Cloud Credentials", "TA0003"},
+	"T1098.003": {"T1098.003", "Additional Cloud Roles", "TA0003"},
+	"T1098.004": {"T1098.004", "SSH Authorized Keys", "TA0003"},
+	"T1136.001": {"T1136.001", "Local Account", "TA0003"},
+	"T1053.002": {"T1053.002", "At", "TA0003"},
+	"T1053.003": {"T1053.003", "Cron", "TA0003"},
+	"T1053.005": {"T1053.005", "Scheduled Task", "TA0003"},
+	"T1543.002": {"T1543.002", "Systemd Service", "TA0003"},
+	"T1546.004": {"T1546.004", "Unix Shell Configuration Modification", "TA0003"},
+	"T1547.001": {"T1547.001", "Registry Run Keys / Startup Folder", "TA0003"},
+	"T1037.004": {"T1037.004", "RC Scripts", "TA0003"},
+	"T1574.006": {"T1574.006", "Dynamic Linker Hijacking", "TA0003"},
+	"T1505.003": {"T1505.003", "Web Shell", "TA0003"},
+
+	// Escalación de privilegios
+	"T1548.001": {"T1548.001", "Setuid and Setgid", "TA0004"},
+	"T1548.003": {"T1548.003", "Sudo and Sudo Caching", "TA0004"},
+	"T1068":     {"T1068", "Exploitation for Privilege Escalation", "TA0004"},
+	"T1078.003": {"T1078.003", "Local Accounts", "TA0004"},
+	"T1611":     {"T1611", "Escape to Host", "TA0004"},
+
+	// Evasión de defensas
+	"T1027":     {"T1027", "Obfuscated Files or Information", "TA0005"},
+	"T1036":     {"T1036", "Masquerading", "TA0005"},
+	"T1070.001": {"T1070.001", "Clear Windows Event Logs", "TA0005"},
+	"T1070.002": {"T1070.002", "Clear Linux or Mac System Logs", "TA0005"},
+	"T1070.003": {"T1070.003", "Clear Command History", "TA0005"},
+	"T1070.004": {"T1070.004", "File Deletion", "TA0005"},
+	"T1070.006": {"T1070.006", "Timestomp", "TA0005"},
+	"T1140":     {"T1140", "Deobfuscate/Decode Files or Information", "TA0005"},
+	"T1222.002": {"T1222.002", "Linux and Mac File and Directory Permissions Modification", "TA0005"},
+	"T1562.001": {"T1562.001", "Disable or Modify Tools", "TA0005"},
+	"T1562.003": {"T1562.003", "Impair Command History Logging", "TA0005"},
+	"T1562.007": {"T1562.007", "Disable or Modify Cloud Firewall", "TA0005"},
+	"T1562.008": {"T1562.008", "Disable or Modify Cloud Logs", "TA0005"},
+	"T1564.001": {"T1564.001", "Hidden Files and Directories", "TA0005"},
+	"T1564.003": {"T1564.003", "Hidden Window", "TA0005"},
+	"T1197":     {"T1197", "BITS Jobs", "TA0005"},
+	"T1578":     {"T1578", "Modify Cloud Compute Infrastructure", "TA0005"},
+	"T1578.003": {"T1578.003", "Delete Cloud Instance", "TA0005"},
+	"T1014":     {"T1014", "Rootkit", "TA0005"},
+
+	// Acceso a credenciales
+	"T1003.001": {"T1003.001", "LSASS Memory", "TA0006"},
+	"T1003.008": {"T1003.008", "/etc/passwd and /etc/shadow", "TA0006"},
+	"T1110":     {"T1110", "Brute Force", "TA0006"},
+	"T1552.001": {"T1552.001", "Credentials In Files", "TA0006"},
+	"T1552.003": {"T1552.003", "Bash History", "TA0006"},
+	"T1552.004": {"T1552.004", "Private Keys", "TA0006"},
+	"T1552.005": {"T1552.005", "Cloud Instance Metadata API", "TA0006"},
+	"T1552.007": {"T1552.007", "Container API", "TA0006"},
+	"T1555.006": {"T1555.006", "Cloud Secrets Management Stores", "TA0006"},
+
+	// Descubrimiento
+	"T1016":     {"T1016", "System Network Configuration Discovery", "TA0007"},
+	"T1018":     {"T1018", "Remote System Discovery", "TA0007"},
+	"T1033":     {"T1033", "System Owner/User Discovery", "TA0007"},
+	"T1046":     {"T1046", "Network Service Discovery", "TA0007"},
+	"T1049":     {"T1049", "System Network Connections Discovery", "TA0007"},
+	"T1057":     {"T1057", "Process Discovery", "TA0007"},
+	"T1082":     {"T1082", "System Information Discovery", "TA0007"},
+	"T1083":     {"T1083", "File and Directory Discovery", "TA0007"},
+	"T1087.001": {"T1087.001", "Local Account", "TA0007"},
+	"T1580":     {"T1580", "Cloud Infrastructure Discovery", "TA0007"},
+	"T1613":     {"T1613", "Container and Resource Discovery", "TA0007"},
+
+	// Movimiento lateral
+	"T1021":     {"T1021", "Remote Services", "TA0008"},
+	"T1021.004": {"T1021.004", "SSH", "TA0008"},
+	"T1563.001": {"T1563.001", "SSH Hijacking", "TA0008"},
+	"T1570":     {"T1570", "Lateral Tool Transfer", "TA0008"},
+
+	// Recolección
+	"T1005":     {"T1005", "Data from Local System", "TA0009"},
+	"T1074.001": {"T1074.001", "Local Data Staging", "TA0009"},
+	"T1530":     {"T1530", "Data from Cloud Storage", "TA0009"},
+	"T1560.001": {"T1560.001", "Archive via Utility", "TA0009"},
+
+	// Comando y control
+	"T1071.001": {"T1071.001", "Web Protocols", "TA0011"},
+	"T1090":     {"T1090", "Proxy", "TA0011"},
+	"T1095":     {"T1095", "Non-Application Layer Protocol", "TA0011"},
+	"T1105":     {"T1105", "Ingress Tool Transfer", "TA0011"},
+	"T1219":     {"T1219", "Remote Access Software", "TA0011"},
+	"T1572":     {"T1572", "Protocol Tunneling", "TA0011"},
+
+	// Exfiltración
+	"T1041": {"T1041", "Exfiltration Over C2 Channel", "TA0010"},
+	"T1048": {"T1048", "Exfiltration Over Alternative Protocol", "TA0010"},
+	"T1537": {"T1537", "Transfer Data to Cloud Account", "TA0010"},
+
+	// Impacto
+	"T1485":     {"T1485", "Data Destruction", "TA0040"},
+	"T1489":     {"T1489", "Service Stop", "TA0040"},
+	"T1490":     {"T1490", "Inhibit System Recovery", "TA0040"},
+	"T1496":     {"T1496", "Resource Hijacking", "TA0040"},
+	"T1529":     {"T1529", "System Shutdown/Reboot", "TA0040"},
+	"T1561.001": {"T1561.001", "Disk Content Wipe", "TA0040"},
+	"T1561.002": {"T1561.002", "Disk Structure Wipe", "TA0040"},
+}
+
+// typeTechniques asocia los tipos de detección que no provienen de reglas
+// (patrones, anomalías y reglas sin técnica explícita) con su técnica
+var typeTechniques = map[string]string{
+	// Amenazas
+	"critical_command":        "T1485",
+	"dangerous_deletion":      "T1485",
+	"unsafe_expansion":        "T1485",
+	"code_injection":          "T1059.004",
+	"disk_manipulation":       "T1561.001",
+	"privilege_escalation":    "T1548.003",
+	"sudo_dangerous":          "T1548.003",
+	"user_switch":             "T1078.003",
+	"suspicious_network":      "T1105",
+	"suspicious_download":     "T1105",
+	"dangerous_file_download": "T1105",
+	"suspicious_filename":     "T1105",
+	"threat_intel_match":      "T1105",
+	"root_ssh":                "T1078.003",
+	"private_network_ssh":     "T1021.004",
+	"ssh_agent_forwarding":    "T1563.001",
+	"ssh_tunnel":              "T1572",
+	"sensitive_file_access":   "T1005",
+	"reverse_shell":           "T1059.004",
+	"bind_shell":              "T1059.004",
+	"pipe_to_interpreter":     "T1059.004",
+	"download_execute":        "T1204.002",
+	"credential_exposure":     "T1552.003",
+	"anti_forensics":          "T1070.003",
+	"persistence":             "T1543.002",
+	"container_escape":        "T1611",
+	"kubernetes_misuse":       "T1609",
+	"cloud_destructive":       "T1485",
+	"cloud_exposure":          "T1530",
+	"cloud_credentials":       "T1098.001",
+	"cloud_logging_disabled":  "T1562.008",
+	"obfuscated_command":      "T1027",
+
+	// Patrones
+	"excessive_sudo":   "T1548.003",
+	"multiple_network": "T1105",
+
+	// Anomalías
+	"download_execute_sequence": "T1204.002",
+	"sudo_delete_sequence":      "T1485",
+}
+
+// Lookup devuelve la técnica y su táctica principal; las técnicas desconocidas conservan solo el ID
+func Lookup(techniqueID string) *models.AttackTechnique {
+	return LookupWithTactic(techniqueID, "")
+}
+
+// LookupWithTactic devuelve la técnica usando la táctica indicada en lugar de la principal.
+// Útil para técnicas que pertenecen a varias tácticas (T1078 en acceso inicial o escalación).
+func LookupWithTactic(techniqueID, tacticID string) *models.AttackTechnique {
+	techniqueID = strings.ToUpper(strings.TrimSpace(techniqueID))
+	if techniqueID == "" {
+		return nil
+	}
+
+	result := &models.AttackTechnique{TechniqueID: techniqueID}
+	if technique, ok := techniques[techniqueID]; ok {
+		result.Technique = technique.Name
+		if tacticID == "" {
+			tacticID = technique.TacticID
+		}
+	}
+
+	if tactic, ok := tactics[strings.ToUpper(tacticID)]; ok {
+		result.TacticID = tactic.ID
+		result.Tactic = tactic.Name
+	}
+
+	return result
+}
+
+// ForType devuelve la técnica asociada a un tipo de detección, o nil si no tiene equivalente
+// (por ejemplo los errores del sistema de archivos, que son operativos y no adversariales)
+func ForType(detectionType string) *models.AttackTechnique {
+	techniqueID, ok := typeTechniques[detectionType]
+	if !ok {
+		return nil
+	}
+	return Lookup(techniqueID)
+}
+
+// IsKnownTechnique indica si la técnica está en el catálogo
+func IsKnownTechnique(techniqueID string) bool {
+	_, ok := techniques[strings.ToUpper(techniqueID)]
+	return ok
+}
+
+// IsKnownTactic indica si la táctica está en el catálogo
+func IsKnownTactic(tacticID string) bool {
+	_, ok := tactics[strings.ToUpper(tacticID)]
+	return ok
+}
+
+// TacticShortName devuelve el nombre corto de la táctica usado por ATT&CK Navigator
+func TacticShortName(tacticID string) string {
+	return tactics[strings.ToUpper(tacticID)].ShortName
+}
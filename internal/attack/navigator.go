@@ -0,0 +1,175 @@
+package attack
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"terminal-history-analyzer/internal/models"
+)
+
+// Versiones del formato de capa de ATT&CK Navigator que se generan
+const (
+	navigatorAttackVersion = "15"
+	navigatorVersion       = "5.0.1"
+	navigatorLayerVersion  = "4.5"
+)
+
+// levelScores convierte la severidad de una detección en la puntuación de la capa
+var levelScores = map[models.ThreatLevel]int{
+	models.SAFE:     0,
+	models.LOW:      25,
+	models.MEDIUM:   50,
+	models.HIGH:     75,
+	models.CRITICAL: 100,
+}
+
+// Patrones y anomalías no tienen severidad propia; se puntúan como MEDIUM
+const defaultScore = 50
+
+// NavigatorLayer es una capa importable en ATT&CK Navigator
+type NavigatorLayer struct {
+	Name         string               `json:"name"`
+	Versions     NavigatorVersions    `json:"versions"`
+	Domain       string               `json:"domain"`
+	Description  string               `json:"description"`
+	Sorting      int                  `json:"sorting"`
+	HideDisabled bool                 `json:"hideDisabled"`
+	Techniques   []NavigatorTechnique `json:"techniques"`
+	Gradient     NavigatorGradient    `json:"gradient"`
+	LegendItems  []NavigatorLegend    `json:"legendItems"`
+	Metadata     []NavigatorMetadata  `json:"metadata"`
+}
+
+// NavigatorVersions indica las versiones de ATT&CK, Navigator y del formato de capa
+type NavigatorVersions struct {
+	Attack    string `json:"attack"`
+	Navigator string `json:"navigator"`
+	Layer     string `json:"layer"`
+}
+
+// NavigatorTechnique es una técnica puntuada dentro de la capa
+type NavigatorTechnique struct {
+	TechniqueID       string              `json:"techniqueID"`
+	Tactic            string              `json:"tactic,omitempty"`
+	Score             int                 `json:"score"`
+	Comment           string              `json:"comment"`
+	Enabled           bool                `json:"enabled"`
+	ShowSubtechniques bool                `json:"showSubtechniques"`
+	Metadata          []NavigatorMetadata `json:"metadata,omitempty"`
+}
+
+// NavigatorGradient define la escala de colores de las puntuaciones
+type NavigatorGradient struct {
+	Colors   []string `json:"colors"`
+	MinValue int      `json:"minValue"`
+	MaxValue int      `json:"maxValue"`
+}
+
+// NavigatorLegend es una entrada de la leyenda de la capa
+type NavigatorLegend struct {
+	Label string `json:"label"`
+	Color string `json:"color"`
+}
+
+// NavigatorMetadata es un par nombre/valor mostrado en Navigator
+type NavigatorMetadata struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// layerEntry acumula las detecciones de una misma técnica y táctica
+type layerEntry struct {
+	technique *models.AttackTechnique
+	score     int
+	lines     []string
+	details   []string
+}
+
+// BuildLayer genera la capa de ATT&CK Navigator de un análisis.
+// Cada técnica recibe la puntuación de su detección más severa.
+func BuildLayer(result *models.AnalysisResult) *NavigatorLayer {
+	entries := make(map[string]*layerEntry)
+	var order []string
+
+	add := func(technique *models.AttackTechnique, score int, line int, detail string) {
+		if technique == nil {
+			return
+		}
+		key := technique.TechniqueID + "|" + technique.TacticID
+		entry, exists := entries[key]
+		if !exists {
+			entry = &layerEntry{technique: technique}
+			entries[key] = entry
+			order = append(order, key)
+		}
+		if score > entry.score {
+			entry.score = score
+		}
+		if line > 0 {
+			entry.lines = append(entry.lines, fmt.Sprintf("%d", line))
+		}
+		entry.details = append(entry.details, detail)
+	}
+
+	for _, threat := range result.SemanticAnalysis.Threats {
+		add(threat.Attack, levelScores[threat.Level], threat.Line,
+			fmt.Sprintf("[%s] %s", threat.Level, threat.Description))
+	}
+	for _, pattern := range result.SemanticAnalysis.Patterns {
+		add(pattern.Attack, defaultScore, 0,
+			fmt.Sprintf("[patrón] %s (%d ocurrencias)", pattern.Description, pattern.Occurrences))
+	}
+	for _, anomaly := range result.SemanticAnalysis.Anomalies {
+		add(anomaly.Attack, defaultScore, anomaly.Line,
+			fmt.Sprintf("[anomalía] %s", anomaly.Description))
+	}
+
+	sort.Strings(order)
+
+	layer := &NavigatorLayer{
+		Name: "Análisis de historial " + result.ID,
+		Versions: NavigatorVersions{
+			Attack:    navigatorAttackVersion,
+			Navigator: navigatorVersion,
+			Layer:     navigatorLayerVersion,
+		},
+		Domain:      "enterprise-attack",
+		Description: fmt.Sprintf("Técnicas observadas en %d comandos analizados", result.Summary.TotalCommands),
+		Sorting:     3, // Ordenar por puntuación descendente
+		Techniques:  make([]NavigatorTechnique, 0, len(order)),
+		Gradient: NavigatorGradient{
+			Colors:   []string{"#ffe766", "#ff9933", "#ff3333"},
+			MinValue: 0,
+			MaxValue: 100,
+		},
+		LegendItems: []NavigatorLegend{
+			{Label: "LOW", Color: "#ffe766"},
+			{Label: "MEDIUM / HIGH", Color: "#ff9933"},
+			{Label: "CRITICAL", Color: "#ff3333"},
+		},
+		Metadata: []NavigatorMetadata{
+			{Name: "analysis_id", Value: result.ID},
+			{Name: "created_at", Value: result.CreatedAt.Format("2006-01-02T15:04:05Z07:00")},
+		},
+	}
+
+	for _, key := range order {
+		entry := entries[key]
+		technique := NavigatorTechnique{
+			TechniqueID: entry.technique.TechniqueID,
+			Tactic:      TacticShortName(entry.technique.TacticID),
+			Score:       entry.score,
+			Comment:     strings.Join(entry.details, "\n"),
+			Enabled:     true,
+		}
+		if len(entry.lines) > 0 {
+			technique.Metadata = []NavigatorMetadata{
+				{Name: "líneas", Value: strings.Join(entry.lines, ", ")},
+			}
+		}
+		layer.Techniques = append(layer.Techniques, technique)
+	}
+
+	return layer
+}
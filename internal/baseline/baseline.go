@@ -0,0 +1,291 @@
+package baseline
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// Tipos de rasgo que se registran en la línea base
+const (
+	KindCommand  = "command"  // Comando efectivo (sudo nc -> nc)
+	KindFlag     = "flag"     // Flag de un comando (base64 -d)
+	KindHost     = "host"     // Host de destino (ssh, scp, curl, nc, ...)
+	KindSequence = "sequence" // N-grama de comandos encadenados en una línea (curl → bash)
+)
+
+const (
+	// MinCommands es el historial mínimo para comparar una sesión con la línea base; con menos
+	// comandos todo sería "raro" y la sesión solo se aprende
+	MinCommands = 50
+
+	// RarityThreshold es la rareza a partir de la que un rasgo se reporta como anomalía
+	RarityThreshold = 0.8
+
+	// maxDigests limita las huellas de sesiones aprendidas que se conservan por línea base
+	maxDigests = 1000
+
+	// minNGram y maxNGram son las longitudes de las secuencias de comandos que se registran; solo
+	// se encadenan los comandos de una misma línea (pipelines, &&, ; y sustituciones)
+	minNGram = 2
+	maxNGram = 3
+)
+
+// Baseline es el perfil estadístico de un usuario en un equipo: frecuencias acumuladas de los
+// rasgos de todas las sesiones analizadas
+type Baseline struct {
+	User       string         `json:"user"`
+	Host       string         `json:"host,omitempty"`
+	Analyses   int            `json:"analyses"` // Sesiones aprendidas
+	Commands   int            `json:"commands"` // Comandos observados en total
+	Vocabulary map[string]int `json:"vocabulary"`
+	Flags      map[string]int `json:"flags"`
+	Hosts      map[string]int `json:"hosts"`
+	NGrams     map[string]int `json:"ngrams"`
+	Digests    []string       `json:"digests,omitempty"` // SHA-256 del contenido de las sesiones aprendidas
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+}
+
+// Observation es un comando de la sesión reducido a los rasgos de la línea base
+type Observation struct {
+	Line    int
+	Command string   // Comando efectivo
+	Raw     string   // Línea original, para el reporte
+	Flags   []string // "base64 -d", "tar --create"
+	Hosts   []string
+}
+
+// Session acumula las observaciones de una sesión en orden
+type Session struct {
+	Observations []Observation
+}
+
+// Feature es la primera aparición de un rasgo en la sesión
+type Feature struct {
+	Kind  string
+	Value string
+	Line  int
+	Raw   string
+}
+
+// Finding es un rasgo de la sesión poco habitual para el usuario
+type Finding struct {
+	Feature
+	Rarity float64 // 0 (habitual) a 1 (nunca visto)
+	Seen   int     // Veces que aparece en la línea base
+	Total  int     // Observaciones del mismo tipo en la línea base
+}
+
+// New crea una línea base vacía
+func New(user, host string) *Baseline {
+	return &Baseline{
+		User:       user,
+		Host:       host,
+		Vocabulary: make(map[string]int),
+		Flags:      make(map[string]int),
+		Hosts:      make(map[string]int),
+		NGrams:     make(map[string]int),
+	}
+}
+
+// Key devuelve la clave de la línea base: usuario@equipo o solo el usuario
+func Key(user, host string) string {
+	if host == "" {
+		return user
+	}
+	return user + "@" + host
+}
+
+// ParseKey separa una clave usuario@equipo
+func ParseKey(key string) (string, string) {
+	if at := strings.LastIndex(key, "@"); at >= 0 {
+		return key[:at], key[at+1:]
+	}
+	return key, ""
+}
+
+// Observe añade un comando a la sesión
+func (s *Session) Observe(observation Observation) {
+	s.Observations = append(s.Observations, observation)
+}
+
+// Features devuelve la primera aparición de cada rasgo de la sesión y el número de apariciones
+func (s *Session) Features() ([]Feature, map[Feature]int) {
+	var features []Feature
+	counts := make(map[Feature]int)
+	first := make(map[[2]string]Feature)
+
+	add := func(kind, value string, observation Observation) {
+		key := [2]string{kind, value}
+		feature, ok := first[key]
+		if !ok {
+			feature = Feature{Kind: kind, Value: value, Line: observation.Line, Raw: observation.Raw}
+			first[key] = feature
+			features = append(features, feature)
+		}
+		counts[feature]++
+	}
+
+	for i, observation := range s.Observations {
+		add(KindCommand, observation.Command, observation)
+		for _, flag := range observation.Flags {
+			add(KindFlag, flag, observation)
+		}
+		for _, host := range observation.Hosts {
+			add(KindHost, host, observation)
+		}
+		for n := minNGram; n <= maxNGram && i+1 >= n; n++ {
+			chain := s.Observations[i+1-n : i+1]
+			if chain[0].Line != observation.Line {
+				break
+			}
+			names := make([]string, 0, n)
+			for _, previous := range chain {
+				names = append(names, previous.Command)
+			}
+			add(KindSequence, strings.Join(names, " → "), observation)
+		}
+	}
+	return features, counts
+}
+
+// Learned indica si la sesión con esa huella ya forma parte de la línea base
+func (b *Baseline) Learned(digest string) bool {
+	for _, learned := range b.Digests {
+		if learned == digest {
+			return true
+		}
+	}
+	return false
+}
+
+// Merge suma las frecuencias de la sesión a la línea base
+func (b *Baseline) Merge(session *Session, now time.Time) {
+	_, counts := session.Features()
+	for feature, count := range counts {
+		b.table(feature.Kind)[feature.Value] += count
+	}
+	b.Analyses++
+	b.Commands += len(session.Observations)
+	if b.CreatedAt.IsZero() {
+		b.CreatedAt = now
+	}
+	b.UpdatedAt = now
+}
+
+// Ready indica si la línea base tiene historial suficiente para detectar anomalías
+func (b *Baseline) Ready() bool {
+	return b != nil && b.Commands >= MinCommands
+}
+
+// Rarity calcula la rareza de un rasgo: 1 si nunca se vio y decrece con el logaritmo de su
+// frecuencia respecto al total de observaciones de su tipo
+func (b *Baseline) Rarity(kind, value string) (float64, int, int) {
+	table := b.table(kind)
+	seen := table[value]
+	total := 0
+	for _, count := range table {
+		total += count
+	}
+	if kind == KindSequence {
+		// Bigramas y trigramas se cuentan por separado
+		total = 0
+		size := strings.Count(value, " → ")
+		for key, count := range table {
+			if strings.Count(key, " → ") == size {
+				total += count
+			}
+		}
+	}
+
+	rarity := 1 - math.Log(1+float64(seen))/math.Log(2+float64(total))
+	return math.Round(100*math.Max(rarity, 0)) / 100, seen, total
+}
+
+// Compare devuelve los rasgos de la sesión con rareza igual o superior al umbral. Las flags y las
+// secuencias de un comando raro, y los trigramas que contienen un bigrama raro, no se reportan
+// para no repetir la anomalía.
+func (b *Baseline) Compare(session *Session) []Finding {
+	if !b.Ready() {
+		return nil
+	}
+
+	features, _ := session.Features()
+	rareCommands := make(map[string]bool)
+	rareSequences := make(map[string]bool)
+	var findings []Finding
+
+	for _, feature := range features {
+		if feature.Kind == KindSequence && (rareSequenceMember(feature.Value, rareCommands) || containsRareSequence(feature.Value, rareSequences)) {
+			continue
+		}
+		if feature.Kind == KindFlag && rareCommands[strings.Fields(feature.Value)[0]] {
+			continue
+		}
+		rarity, seen, total := b.Rarity(feature.Kind, feature.Value)
+		if rarity < RarityThreshold {
+			continue
+		}
+		switch feature.Kind {
+		case KindCommand:
+			rareCommands[feature.Value] = true
+		case KindSequence:
+			rareSequences[feature.Value] = true
+		}
+		findings = append(findings, Finding{Feature: feature, Rarity: rarity, Seen: seen, Total: total})
+	}
+	return findings
+}
+
+// clone copia la línea base para usarla fuera del almacén
+func (b *Baseline) clone() *Baseline {
+	copied := *b
+	copied.Vocabulary = cloneCounts(b.Vocabulary)
+	copied.Flags = cloneCounts(b.Flags)
+	copied.Hosts = cloneCounts(b.Hosts)
+	copied.NGrams = cloneCounts(b.NGrams)
+	copied.Digests = append([]string(nil), b.Digests...)
+	return &copied
+}
+
+func (b *Baseline) table(kind string) map[string]int {
+	switch kind {
+	case KindFlag:
+		return b.Flags
+	case KindHost:
+		return b.Hosts
+	case KindSequence:
+		return b.NGrams
+	default:
+		return b.Vocabulary
+	}
+}
+
+func rareSequenceMember(sequence string, rareCommands map[string]bool) bool {
+	for _, name := range strings.Split(sequence, " → ") {
+		if rareCommands[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// containsRareSequence indica si la secuencia empieza o termina con una secuencia más corta ya reportada
+func containsRareSequence(sequence string, rareSequences map[string]bool) bool {
+	names := strings.Split(sequence, " → ")
+	for n := minNGram; n < len(names); n++ {
+		if rareSequences[strings.Join(names[:n], " → ")] || rareSequences[strings.Join(names[len(names)-n:], " → ")] {
+			return true
+		}
+	}
+	return false
+}
+
+func cloneCounts(counts map[string]int) map[string]int {
+	copied := make(map[string]int, len(counts))
+	for key, count := range counts {
+		copied[key] = count
+	}
+	return copied
+}
@@ -0,0 +1,212 @@
+package baseline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound indica que no hay línea base para el usuario y equipo indicados
+	ErrNotFound = errors.New("línea base no encontrada")
+
+	// ErrAlreadyLearned indica que la sesión ya se sumó a la línea base (el mismo contenido reenviado)
+	ErrAlreadyLearned = errors.New("la sesión ya forma parte de la línea base")
+)
+
+// Store guarda las líneas base por usuario@equipo y, si tiene archivo, las persiste en JSON
+type Store struct {
+	mu        sync.RWMutex
+	path      string
+	baselines map[string]*Baseline
+}
+
+// storeFile es el formato del archivo de líneas base (también el de la exportación)
+type storeFile struct {
+	Baselines []*Baseline `json:"baselines"`
+}
+
+var (
+	activeStore   = &Store{baselines: make(map[string]*Baseline)}
+	activeStoreMu sync.RWMutex
+)
+
+// Active devuelve el almacén de líneas base activo
+func Active() *Store {
+	activeStoreMu.RLock()
+	defer activeStoreMu.RUnlock()
+	return activeStore
+}
+
+// Configure carga las líneas base del archivo indicado y lo activa; si no existe se crea al
+// aprender la primera sesión. Sin ruta las líneas base solo se guardan en memoria.
+func Configure(path string) (*Store, error) {
+	store := &Store{path: path, baselines: make(map[string]*Baseline)}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("no se pudo leer el archivo de líneas base %s: %w", path, err)
+		}
+		if err == nil {
+			var file storeFile
+			if err := json.Unmarshal(data, &file); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			for _, loaded := range file.Baselines {
+				if loaded == nil || loaded.User == "" {
+					return nil, fmt.Errorf("%s: línea base sin usuario", path)
+				}
+				baseline := New(loaded.User, loaded.Host)
+				baseline.Analyses = loaded.Analyses
+				baseline.Commands = loaded.Commands
+				baseline.CreatedAt = loaded.CreatedAt
+				baseline.UpdatedAt = loaded.UpdatedAt
+				for kind, counts := range map[string]map[string]int{
+					KindCommand: loaded.Vocabulary, KindFlag: loaded.Flags,
+					KindHost: loaded.Hosts, KindSequence: loaded.NGrams,
+				} {
+					for value, count := range counts {
+						baseline.table(kind)[value] = count
+					}
+				}
+				store.baselines[Key(baseline.User, baseline.Host)] = baseline
+			}
+		}
+	}
+
+	activeStoreMu.Lock()
+	activeStore = store
+	activeStoreMu.Unlock()
+
+	return store, nil
+}
+
+// List devuelve las líneas base ordenadas por clave
+func (s *Store) List() []*Baseline {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	baselines := make([]*Baseline, 0, len(s.baselines))
+	for _, baseline := range s.baselines {
+		baselines = append(baselines, baseline.clone())
+	}
+	sort.Slice(baselines, func(i, j int) bool {
+		return Key(baselines[i].User, baselines[i].Host) < Key(baselines[j].User, baselines[j].Host)
+	})
+	return baselines
+}
+
+// Get devuelve una copia de la línea base del usuario en el equipo
+func (s *Store) Get(user, host string) (*Baseline, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	baseline, ok := s.baselines[Key(user, host)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return baseline.clone(), nil
+}
+
+// Learn suma una sesión a la línea base del usuario en el equipo (creándola si no existe) y
+// devuelve la línea base actualizada. digest es la huella del contenido de la sesión: si ya se
+// aprendió devuelve la línea base sin cambios y ErrAlreadyLearned.
+func (s *Store) Learn(user, host, digest string, session *Session) (*Baseline, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := Key(user, host)
+	previous, existed := s.baselines[key]
+	if existed && previous.Learned(digest) {
+		return previous.clone(), ErrAlreadyLearned
+	}
+	baseline := New(user, host)
+	if existed {
+		baseline = previous.clone()
+	}
+	baseline.Merge(session, time.Now().UTC())
+	baseline.Digests = append(baseline.Digests, digest)
+	if len(baseline.Digests) > maxDigests {
+		baseline.Digests = baseline.Digests[len(baseline.Digests)-maxDigests:]
+	}
+
+	s.baselines[key] = baseline
+	if err := s.save(); err != nil {
+		if existed {
+			s.baselines[key] = previous
+		} else {
+			delete(s.baselines, key)
+		}
+		return nil, err
+	}
+	return baseline.clone(), nil
+}
+
+// Delete elimina la línea base del usuario en el equipo (vuelve a aprenderse desde cero)
+func (s *Store) Delete(user, host string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := Key(user, host)
+	previous, ok := s.baselines[key]
+	if !ok {
+		return ErrNotFound
+	}
+
+	delete(s.baselines, key)
+	if err := s.save(); err != nil {
+		s.baselines[key] = previous
+		return err
+	}
+	return nil
+}
+
+// Export devuelve las líneas base en el formato del archivo, para importarlas en otra instancia
+// con BASELINES_FILE
+func (s *Store) Export() ([]byte, error) {
+	return json.MarshalIndent(storeFile{Baselines: s.List()}, "", "  ")
+}
+
+// save escribe el archivo de líneas base de forma atómica; requiere el bloqueo de escritura
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	baselines := make([]*Baseline, 0, len(s.baselines))
+	for _, baseline := range s.baselines {
+		baselines = append(baselines, baseline)
+	}
+	sort.Slice(baselines, func(i, j int) bool {
+		return Key(baselines[i].User, baselines[i].Host) < Key(baselines[j].User, baselines[j].Host)
+	})
+
+	data, err := json.MarshalIndent(storeFile{Baselines: baselines}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".baselines-*.json")
+	if err != nil {
+		return fmt.Errorf("no se pudo guardar el archivo de líneas base: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("no se pudo guardar el archivo de líneas base: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("no se pudo guardar el archivo de líneas base: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("no se pudo guardar el archivo de líneas base: %w", err)
+	}
+	return nil
+}
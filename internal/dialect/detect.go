@@ -0,0 +1,152 @@
+package dialect
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"terminal-history-analyzer/internal/lint"
+	"terminal-history-analyzer/internal/models"
+	"terminal-history-analyzer/pkg/utils"
+)
+
+// Origen del dialecto detectado (DialectInfo.Source)
+const (
+	SourceRequest  = "request"
+	SourceFilename = "filename"
+	SourceShebang  = "shebang"
+	SourceContent  = "content"
+	SourceDefault  = "default"
+)
+
+// historyNames asocia los archivos de historial de cada shell a su dialecto
+var historyNames = map[string]string{
+	"bash_history":        Bash,
+	"zsh_history":         Zsh,
+	"fish_history":        Fish,
+	"ConsoleHost_history": PowerShell,
+}
+
+// extensions asocia las extensiones de scripts a su dialecto; .sh lo decide el shebang
+var extensions = map[string]string{
+	".bash": Bash, ".zsh": Zsh, ".fish": Fish, ".ps1": PowerShell, ".psm1": PowerShell, ".psd1": PowerShell,
+}
+
+// shebangShells asocia el intérprete del shebang a su dialecto
+var shebangShells = map[string]string{
+	"bash": Bash, "zsh": Zsh, "fish": Fish, "sh": POSIX, "dash": POSIX, "ash": POSIX, "posh": POSIX,
+	"pwsh": PowerShell, "powershell": PowerShell,
+}
+
+// signals son las construcciones propias de cada dialecto; se cuentan las líneas con alguna
+var signals = []struct {
+	dialect  string
+	patterns []*regexp.Regexp
+}{
+	{PowerShell, []*regexp.Regexp{
+		regexp.MustCompile(`^\s*&?\s*(Get|Set|New|Remove|Invoke|Start|Stop|Add|Import|Export|Write|Select|Where|ForEach|Test|Out|ConvertTo|ConvertFrom|Copy|Move|Clear|Enable|Disable|Install|Update|Format|Restart|Expand|Compress|Resolve)-[A-Z]\w+`),
+		regexp.MustCompile(`\$env:\w+|\[[A-Z][\w.]*\]::\w+`),
+		regexp.MustCompile(`(?i)(^|[|;(]\s*)(iex|iwr|irm)\b|\s-(EncodedCommand|ExecutionPolicy)\b`),
+	}},
+	{Fish, []*regexp.Regexp{
+		regexp.MustCompile(`^\s*(and|or|not)\s+\S`),
+		regexp.MustCompile(`^\s*set\s+-[gUlxeu]+\s+\w`),
+		regexp.MustCompile(`^\s*(funcsave|funced|abbr|fish_add_path|fish_config|set_color)\b`),
+		regexp.MustCompile(`\$status\b|;\s*(and|or)\s`),
+	}},
+	{Zsh, []*regexp.Regexp{
+		regexp.MustCompile(`^\s*(setopt|unsetopt|autoload|zstyle|bindkey|compinit|zmodload|zle)\b`),
+		regexp.MustCompile(`(^|\s)=\(`),
+		regexp.MustCompile(`\*\([.@/*%^-]*[a-zA-Z]*(\[\d+(,\d+)?\])?\)(\s|$)`),
+	}},
+}
+
+// minSignalShare es la proporción mínima de líneas con construcciones propias para elegir un dialecto
+const minSignalShare = 0.1
+
+// Resolve devuelve el dialecto pedido o, si no se indica (o es auto), el detectado
+func Resolve(requested, filename, content string) models.DialectInfo {
+	if requested != "" && requested != Auto {
+		return models.DialectInfo{Name: requested, Source: SourceRequest}
+	}
+	return Detect(content, filename)
+}
+
+// Detect determina el dialecto por el nombre del archivo (historiales de cada shell y extensiones),
+// el shebang, el formato del historial o las construcciones propias de cada dialecto; bash si no
+// hay indicios
+func Detect(content, filename string) models.DialectInfo {
+	if name := fromFilename(filename); name != "" {
+		return models.DialectInfo{Name: name, Source: SourceFilename}
+	}
+
+	if info := lint.Shebang(content); info.Shebang != "" {
+		if name, ok := shebangShells[info.Shell]; ok {
+			return models.DialectInfo{Name: name, Source: SourceShebang}
+		}
+	}
+
+	if name := fromContent(content); name != "" {
+		return models.DialectInfo{Name: name, Source: SourceContent}
+	}
+	return models.DialectInfo{Name: Bash, Source: SourceDefault}
+}
+
+// fromFilename reconoce los historiales de cada shell y las extensiones de sus scripts
+func fromFilename(filename string) string {
+	if filename == "" {
+		return ""
+	}
+	base := path.Base(strings.ReplaceAll(filename, `\`, "/"))
+	if utils.IsHistoryFile(base) {
+		for name, dialect := range historyNames {
+			if strings.Contains(base, name) {
+				return dialect
+			}
+		}
+	}
+	return extensions[strings.ToLower(path.Ext(base))]
+}
+
+// fromContent reconoce los formatos de historial de zsh y fish y, si no, el dialecto con más líneas
+// de construcciones propias
+func fromContent(content string) string {
+	lines := strings.Split(content, "\n")
+	total, extended := 0, 0
+	counts := make(map[string]int)
+
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		total++
+		if zshExtendedLine.MatchString(line) {
+			extended++
+		}
+		for _, signal := range signals {
+			for _, pattern := range signal.patterns {
+				if pattern.MatchString(line) {
+					counts[signal.dialect]++
+					break
+				}
+			}
+		}
+	}
+
+	switch {
+	case total == 0:
+		return ""
+	case extended*2 >= total:
+		return Zsh
+	case hasFishEntries(content):
+		return Fish
+	}
+
+	best := ""
+	for _, signal := range signals {
+		if count := counts[signal.dialect]; count > counts[best] && float64(count) >= minSignalShare*float64(total) {
+			best = signal.dialect
+		}
+	}
+	return best
+}
@@ -0,0 +1,184 @@
+// Package dialect selecciona el lexer y el parser de cada dialecto de shell (bash, zsh, fish, sh
+// POSIX y PowerShell). Todos producen el mismo CommandAST, así que el análisis semántico es común;
+// las reglas pueden limitarse a dialectos concretos.
+package dialect
+
+import (
+	"regexp"
+	"strings"
+
+	"terminal-history-analyzer/internal/lexer"
+	"terminal-history-analyzer/internal/models"
+	"terminal-history-analyzer/internal/parser"
+)
+
+// Dialectos admitidos; Auto detecta el dialecto por el nombre del archivo, el shebang o el contenido
+const (
+	Bash       = "bash"
+	Zsh        = "zsh"
+	Fish       = "fish"
+	POSIX      = "sh"
+	PowerShell = "powershell"
+	Auto       = "auto"
+)
+
+// Dialect describe un dialecto del catálogo
+type Dialect struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// catalog es la lista de dialectos en el orden en que se documenta
+var catalog = []Dialect{
+	{Bash, "Bash (historial ~/.bash_history y scripts); dialecto por defecto"},
+	{Zsh, "Zsh: historial extendido (: inicio:duración;comando), =(comando), calificadores de glob y setopt"},
+	{Fish, "Fish: fish_history, and/or/not, set -x VAR valor y (comando) como sustitución"},
+	{POSIX, "sh POSIX (dash, ash): la sintaxis de bash; el linter marca los bashismos"},
+	{PowerShell, "PowerShell (ConsoleHost_history.txt): cmdlets, -Parametro, $(...), script blocks y acento grave"},
+}
+
+// All devuelve el catálogo de dialectos
+func All() []Dialect {
+	return append([]Dialect(nil), catalog...)
+}
+
+// IsKnown indica si el nombre es un dialecto del catálogo
+func IsKnown(name string) bool {
+	for _, d := range catalog {
+		if d.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidRequest indica si el dialecto pedido es admitido; vacío equivale a auto
+func ValidRequest(name string) bool {
+	return name == "" || name == Auto || IsKnown(name)
+}
+
+// IsBourne indica si el dialecto es de la familia de sh, sobre la que trabaja el linter de scripts
+func IsBourne(name string) bool {
+	return name == Bash || name == POSIX || name == Zsh
+}
+
+// Frontend convierte el contenido de un dialecto en comandos
+type Frontend interface {
+	Tokenize(content string) ([]models.Token, []models.LexicalError)
+	Parse(tokens []models.Token) ([]models.CommandAST, []models.SyntaxError, []string)
+}
+
+// shellFrontend usa el lexer y el parser de bash con las opciones del dialecto
+type shellFrontend struct {
+	options parser.Options
+}
+
+func (f shellFrontend) Tokenize(content string) ([]models.Token, []models.LexicalError) {
+	return lexer.NewLexerWithOptions(content, f.options.Lexer).Tokenize()
+}
+
+func (f shellFrontend) Parse(tokens []models.Token) ([]models.CommandAST, []models.SyntaxError, []string) {
+	return parser.NewParserWithOptions(tokens, f.options).Parse()
+}
+
+// powerShellFrontend usa la gramática de PowerShell
+type powerShellFrontend struct{}
+
+func (powerShellFrontend) Tokenize(content string) ([]models.Token, []models.LexicalError) {
+	return lexer.NewPowerShellLexer(content).Tokenize()
+}
+
+func (powerShellFrontend) Parse(tokens []models.Token) ([]models.CommandAST, []models.SyntaxError, []string) {
+	return parser.NewPowerShellParser(tokens).Parse()
+}
+
+// frontends son los frontends de cada dialecto; bash y sh comparten la gramática
+var frontends = map[string]Frontend{
+	Bash:  shellFrontend{},
+	POSIX: shellFrontend{},
+	Zsh: shellFrontend{options: parser.Options{
+		Lexer: lexer.Options{EqualsSubstitution: true, GlobQualifiers: true},
+	}},
+	Fish: shellFrontend{options: parser.Options{
+		Lexer:    lexer.Options{BareSubstitution: true},
+		Keywords: []string{"and", "or", "not", "begin"},
+		Closers:  []string{"end", "switch", "case"},
+	}},
+	PowerShell: powerShellFrontend{},
+}
+
+// For devuelve el frontend del dialecto; bash si no se conoce
+func For(name string) Frontend {
+	if frontend, ok := frontends[name]; ok {
+		return frontend
+	}
+	return frontends[Bash]
+}
+
+var (
+	// zshExtendedLine reconoce el prefijo del historial extendido de zsh: ": 1700000000:0;"
+	zshExtendedLine = regexp.MustCompile(`^: *\d+:\d+;`)
+
+	// fishCommandLine reconoce la entrada de fish_history: "- cmd: comando"
+	fishCommandLine = regexp.MustCompile(`^- cmd: ?`)
+)
+
+// Normalize convierte el formato de historial del dialecto en una línea por comando conservando la
+// numeración: quita las marcas de tiempo de zsh y deja solo los comandos de fish_history (sus
+// líneas when: y paths: quedan vacías)
+func Normalize(name, content string) string {
+	switch name {
+	case Zsh:
+		lines := strings.Split(content, "\n")
+		for i, line := range lines {
+			lines[i] = zshExtendedLine.ReplaceAllString(line, "")
+		}
+		return strings.Join(lines, "\n")
+	case Fish:
+		if !hasFishEntries(content) {
+			return content
+		}
+		lines := strings.Split(content, "\n")
+		for i, line := range lines {
+			if prefix := fishCommandLine.FindString(line); prefix != "" {
+				lines[i] = unescapeFish(line[len(prefix):])
+			} else {
+				lines[i] = ""
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+	return content
+}
+
+// hasFishEntries indica si el contenido tiene el formato YAML de fish_history
+func hasFishEntries(content string) bool {
+	for _, line := range strings.SplitN(content, "\n", 20) {
+		if fishCommandLine.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// unescapeFish deshace el escapado de fish_history (\\ y \n); las líneas de un comando de varias
+// líneas se unen con ; para no alterar la numeración
+func unescapeFish(command string) string {
+	var result strings.Builder
+	for i := 0; i < len(command); i++ {
+		if command[i] == '\\' && i+1 < len(command) {
+			switch command[i+1] {
+			case 'n':
+				result.WriteString("; ")
+				i++
+				continue
+			case '\\':
+				result.WriteByte('\\')
+				i++
+				continue
+			}
+		}
+		result.WriteByte(command[i])
+	}
+	return result.String()
+}
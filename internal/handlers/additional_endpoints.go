@@ -2,7 +2,13 @@ package handlers
 
 import (
 	"net/http"
+	"terminal-history-analyzer/internal/dialect"
+	"terminal-history-analyzer/internal/intel"
+	"terminal-history-analyzer/internal/intent"
+	"terminal-history-analyzer/internal/lint"
 	"terminal-history-analyzer/internal/parser"
+	"terminal-history-analyzer/internal/risk"
+	"terminal-history-analyzer/internal/semantic"
 
 	"github.com/gin-gonic/gin"
 )
@@ -163,3 +169,88 @@ func getCommandHelpInfo(command string) map[string]interface{} {
 		},
 	}
 }
+
+// GetRules devuelve las reglas y secuencias de ataque activas (predeterminadas y de la organización)
+func GetRules(c *gin.Context) {
+	active := semantic.ActiveRules()
+	rules := active.Rules()
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":     len(rules),
+		"rules":     rules,
+		"sequences": active.Sequences(),
+	})
+}
+
+// GetIntelFeeds devuelve las fuentes de inteligencia de amenazas cargadas
+func GetIntelFeeds(c *gin.Context) {
+	active := intel.Active()
+
+	c.JSON(http.StatusOK, gin.H{
+		"directory":  active.Dir,
+		"loaded_at":  active.LoadedAt,
+		"indicators": active.Matcher.Len(),
+		"feeds":      active.Feeds,
+	})
+}
+
+// ReloadIntelFeeds vuelve a cargar las fuentes de inteligencia sin reiniciar el servidor;
+// si alguna fuente es inválida se mantienen las anteriores
+func ReloadIntelFeeds(c *gin.Context) {
+	reloaded, err := intel.Reload()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Error al recargar las fuentes de inteligencia: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Fuentes de inteligencia recargadas",
+		"loaded_at":  reloaded.LoadedAt,
+		"indicators": reloaded.Matcher.Len(),
+		"feeds":      reloaded.Feeds,
+	})
+}
+
+// GetRiskWeights devuelve los pesos activos de la puntuación de riesgo
+func GetRiskWeights(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"weights": risk.ActiveWeights(),
+	})
+}
+
+// GetIntentTaxonomy devuelve las categorías y reglas de la taxonomía de intenciones activa
+func GetIntentTaxonomy(c *gin.Context) {
+	taxonomy := intent.Active()
+
+	c.JSON(http.StatusOK, gin.H{
+		"categories": taxonomy.Categories,
+		"rules":      taxonomy.Rules,
+	})
+}
+
+// GetLintRules devuelve las reglas del linter de scripts (mode=script)
+func GetLintRules(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"modes": []string{lint.ModeHistory, lint.ModeScript},
+		"rules": lint.Rules(),
+	})
+}
+
+// GetDialects devuelve los dialectos de shell admitidos con las reglas activas propias de cada uno;
+// las reglas sin dialectos aplican a todos
+func GetDialects(c *gin.Context) {
+	specific := make(map[string][]string)
+	for _, rule := range semantic.ActiveRules().Rules() {
+		for _, name := range rule.Dialects {
+			specific[name] = append(specific[name], rule.ID)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dialects": dialect.All(),
+		"default":  dialect.Auto,
+		"rules":    specific,
+	})
+}
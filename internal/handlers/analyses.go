@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"terminal-history-analyzer/internal/attack"
+	"terminal-history-analyzer/internal/ioc"
+	"terminal-history-analyzer/internal/models"
+	"terminal-history-analyzer/internal/semantic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxStoredAnalyses limita la memoria usada por los análisis guardados
+const maxStoredAnalyses = 200
+
+// analysisStore guarda en memoria los últimos análisis para consultarlos por ID
+type analysisStore struct {
+	mu       sync.RWMutex
+	results  map[string]*models.AnalysisResult
+	order    []string // IDs en orden de llegada para descartar los más antiguos
+	capacity int
+}
+
+// Almacén global de análisis
+var globalAnalysisStore = newAnalysisStore(maxStoredAnalyses)
+
+func newAnalysisStore(capacity int) *analysisStore {
+	return &analysisStore{
+		results:  make(map[string]*models.AnalysisResult),
+		capacity: capacity,
+	}
+}
+
+// Save asigna ID y fecha al análisis y lo guarda, descartando el más antiguo si se supera la capacidad
+func (s *analysisStore) Save(result *models.AnalysisResult) {
+	result.ID = newAnalysisID()
+	result.CreatedAt = time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.results[result.ID] = result
+	s.order = append(s.order, result.ID)
+	if len(s.order) > s.capacity {
+		delete(s.results, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+// Get devuelve un análisis guardado
+func (s *analysisStore) Get(id string) (*models.AnalysisResult, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result, exists := s.results[id]
+	return result, exists
+}
+
+// analysisListing resume un análisis guardado para el listado
+type analysisListing struct {
+	ID            string             `json:"id"`
+	CreatedAt     time.Time          `json:"created_at"`
+	TotalCommands int                `json:"total_commands"`
+	ThreatCount   int                `json:"threat_count"`
+	RiskScore     int                `json:"risk_score"`
+	RiskLevel     models.ThreatLevel `json:"risk_level"`
+}
+
+// List devuelve el resumen de los análisis guardados en orden de llegada
+func (s *analysisStore) List() []analysisListing {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	listing := make([]analysisListing, 0, len(s.order))
+	for _, id := range s.order {
+		result := s.results[id]
+		listing = append(listing, analysisListing{
+			ID:            result.ID,
+			CreatedAt:     result.CreatedAt,
+			TotalCommands: result.Summary.TotalCommands,
+			ThreatCount:   len(result.SemanticAnalysis.Threats),
+			RiskScore:     result.Summary.RiskScore,
+			RiskLevel:     result.Summary.RiskLevel,
+		})
+	}
+	return listing
+}
+
+// newAnalysisID genera un identificador aleatorio de 16 caracteres hexadecimales
+func newAnalysisID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		// crypto/rand no debería fallar; se usa la hora como respaldo
+		return hex.EncodeToString([]byte(time.Now().Format("150405.000000")))[:16]
+	}
+	return hex.EncodeToString(buf)
+}
+
+// lookupAnalysis obtiene el análisis del parámetro :id o responde 404
+func lookupAnalysis(c *gin.Context) (*models.AnalysisResult, bool) {
+	result, exists := globalAnalysisStore.Get(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Análisis no encontrado",
+		})
+		return nil, false
+	}
+	return result, true
+}
+
+// GetAttackLayer devuelve la capa de ATT&CK Navigator de un análisis
+func GetAttackLayer(c *gin.Context) {
+	result, ok := lookupAnalysis(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=attack-layer-"+result.ID+".json")
+	c.JSON(http.StatusOK, attack.BuildLayer(result))
+}
+
+// GetIOCs exporta los indicadores de compromiso de un análisis en JSON, CSV o STIX 2.1
+func GetIOCs(c *gin.Context) {
+	result, ok := lookupAnalysis(c)
+	if !ok {
+		return
+	}
+
+	switch format := c.DefaultQuery("format", "json"); format {
+	case "json":
+		c.JSON(http.StatusOK, gin.H{
+			"analysis_id": result.ID,
+			"total":       len(result.IOCs),
+			"iocs":        result.IOCs,
+		})
+
+	case "csv":
+		var buf bytes.Buffer
+		if err := ioc.WriteCSV(&buf, result.IOCs); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error generando el CSV: " + err.Error(),
+			})
+			return
+		}
+		c.Header("Content-Disposition", "attachment; filename=iocs-"+result.ID+".csv")
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+
+	case "stix":
+		c.Header("Content-Disposition", "attachment; filename=iocs-"+result.ID+".stix.json")
+		c.JSON(http.StatusOK, ioc.BuildSTIXBundle(result))
+
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Formato no soportado: " + format,
+			"formats": []string{"json", "csv", "stix"},
+		})
+	}
+}
+
+// GetLateralMovement exporta el grafo de movimiento lateral de un análisis en JSON o DOT (Graphviz)
+func GetLateralMovement(c *gin.Context) {
+	result, ok := lookupAnalysis(c)
+	if !ok {
+		return
+	}
+
+	switch format := c.DefaultQuery("format", "json"); format {
+	case "json":
+		graph := result.LateralMovement
+		if graph == nil {
+			graph = &models.HostGraph{Nodes: []models.HostNode{}, Edges: []models.HostEdge{}}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"analysis_id": result.ID,
+			"graph":       graph,
+		})
+
+	case "dot":
+		c.Header("Content-Disposition", "attachment; filename=lateral-"+result.ID+".dot")
+		c.Data(http.StatusOK, "text/vnd.graphviz; charset=utf-8", []byte(semantic.LateralMovementDOT(result.LateralMovement)))
+
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Formato no soportado: " + format,
+			"formats": []string{"json", "dot"},
+		})
+	}
+}
+
+// ListAnalyses lista los análisis guardados, ordenados por risk_score (por defecto), threats o created_at
+func ListAnalyses(c *gin.Context) {
+	listing := globalAnalysisStore.List()
+
+	var less func(a, b analysisListing) bool
+	switch sortBy := c.DefaultQuery("sort", "risk_score"); sortBy {
+	case "risk_score":
+		less = func(a, b analysisListing) bool { return a.RiskScore < b.RiskScore }
+	case "threats":
+		less = func(a, b analysisListing) bool { return a.ThreatCount < b.ThreatCount }
+	case "created_at":
+		less = func(a, b analysisListing) bool { return a.CreatedAt.Before(b.CreatedAt) }
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Orden no soportado: " + sortBy,
+			"sorts": []string{"risk_score", "threats", "created_at"},
+		})
+		return
+	}
+
+	descending := true
+	switch order := c.DefaultQuery("order", "desc"); order {
+	case "desc":
+	case "asc":
+		descending = false
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Sentido no soportado: " + order,
+			"orders": []string{"desc", "asc"},
+		})
+		return
+	}
+
+	sort.SliceStable(listing, func(i, j int) bool {
+		if descending {
+			return less(listing[j], listing[i])
+		}
+		return less(listing[i], listing[j])
+	})
+
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil && limit >= 0 && limit < len(listing) {
+		listing = listing[:limit]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":    len(listing),
+		"analyses": listing,
+	})
+}
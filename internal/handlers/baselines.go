@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"terminal-history-analyzer/internal/baseline"
+
+	"github.com/gin-gonic/gin"
+)
+
+// baselineListing es una línea base sin las tablas de frecuencias
+type baselineListing struct {
+	Key        string    `json:"key"`
+	User       string    `json:"user"`
+	Host       string    `json:"host,omitempty"`
+	Analyses   int       `json:"analyses"`
+	Commands   int       `json:"commands"`
+	Vocabulary int       `json:"vocabulary"`
+	Flags      int       `json:"flags"`
+	Hosts      int       `json:"hosts"`
+	NGrams     int       `json:"ngrams"`
+	Ready      bool      `json:"ready"` // Tiene historial suficiente para detectar anomalías
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ListBaselines devuelve el resumen de las líneas base por usuario@equipo
+func ListBaselines(c *gin.Context) {
+	baselines := baseline.Active().List()
+
+	listings := make([]baselineListing, 0, len(baselines))
+	for _, b := range baselines {
+		listings = append(listings, baselineListing{
+			Key:        baseline.Key(b.User, b.Host),
+			User:       b.User,
+			Host:       b.Host,
+			Analyses:   b.Analyses,
+			Commands:   b.Commands,
+			Vocabulary: len(b.Vocabulary),
+			Flags:      len(b.Flags),
+			Hosts:      len(b.Hosts),
+			NGrams:     len(b.NGrams),
+			Ready:      b.Ready(),
+			CreatedAt:  b.CreatedAt,
+			UpdatedAt:  b.UpdatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":        len(listings),
+		"min_commands": baseline.MinCommands,
+		"baselines":    listings,
+	})
+}
+
+// GetBaseline devuelve la línea base completa de un usuario@equipo, con sus frecuencias
+func GetBaseline(c *gin.Context) {
+	user, host := baseline.ParseKey(c.Param("key"))
+	b, err := baseline.Active().Get(user, host)
+	if err != nil {
+		respondBaselineError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, b)
+}
+
+// ExportBaselines descarga todas las líneas base en el formato de BASELINES_FILE
+func ExportBaselines(c *gin.Context) {
+	data, err := baseline.Active().Export()
+	if err != nil {
+		respondBaselineError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename=baselines.json")
+	c.Data(http.StatusOK, "application/json", data)
+}
+
+// DeleteBaseline elimina la línea base de un usuario@equipo; la siguiente sesión empieza a aprender de cero
+func DeleteBaseline(c *gin.Context) {
+	user, host := baseline.ParseKey(c.Param("key"))
+	if err := baseline.Active().Delete(user, host); err != nil {
+		respondBaselineError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Línea base eliminada",
+	})
+}
+
+// respondBaselineError traduce los errores del almacén de líneas base a códigos HTTP
+func respondBaselineError(c *gin.Context, err error) {
+	if errors.Is(err, baseline.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Línea base no encontrada"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al guardar las líneas base: " + err.Error()})
+}
@@ -6,10 +6,13 @@ import (
 	"strings"
 	"time"
 
-	"terminal-history-analyzer/internal/lexer"
+	"terminal-history-analyzer/internal/dialect"
+	"terminal-history-analyzer/internal/intent"
+	"terminal-history-analyzer/internal/ioc"
+	"terminal-history-analyzer/internal/lint"
 	"terminal-history-analyzer/internal/models"
 	"terminal-history-analyzer/internal/monitor"
-	"terminal-history-analyzer/internal/parser"
+	"terminal-history-analyzer/internal/risk"
 	"terminal-history-analyzer/internal/semantic"
 
 	"github.com/gin-gonic/gin"
@@ -21,6 +24,26 @@ type EnhancedAnalysisRequest struct {
 	Filename         string `json:"filename,omitempty"`
 	EnableRealTime   bool   `json:"enable_real_time,omitempty"`
 	ValidateSpelling bool   `json:"validate_spelling,omitempty"`
+
+	// Corrección automática de comandos, subcomandos y flags; el umbral va de 0 (aplica todas) a 1
+	AutoFix          bool     `json:"autofix,omitempty"`
+	AutoFixThreshold *float64 `json:"autofix_threshold,omitempty" binding:"omitempty,gte=0,lte=1"`
+
+	// Las credenciales detectadas se enmascaran salvo que se pida explícitamente lo contrario
+	RevealSecrets bool `json:"reveal_secrets,omitempty"`
+
+	// Dueño y equipo del historial, para las supresiones con ámbito user y host
+	User string `json:"user,omitempty"`
+	Host string `json:"host,omitempty"`
+
+	// history (por defecto) o script: el contenido es un script y se aplica el linter
+	Mode string `json:"mode,omitempty"`
+
+	// bash, zsh, fish, sh o powershell; auto (por defecto) lo detecta por el nombre y el contenido
+	Dialect string `json:"dialect,omitempty"`
+
+	// false: la sesión se compara con la línea base del usuario sin sumarse a ella
+	Learn *bool `json:"learn,omitempty"`
 }
 
 // Monitor para análisis mejorado
@@ -44,12 +67,30 @@ func AnalyzeEnhanced(c *gin.Context) {
 		return
 	}
 
+	opts := analysisOptions{
+		AutoFix:          request.AutoFix,
+		AutoFixThreshold: autoFixThreshold(request.AutoFixThreshold),
+		RevealSecrets:    request.RevealSecrets,
+		User:             request.User,
+		Host:             request.Host,
+		Mode:             request.Mode,
+		Dialect:          request.Dialect,
+		Filename:         request.Filename,
+		SkipLearning:     request.Learn != nil && !*request.Learn,
+	}
+	if err := opts.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Opciones inválidas: " + err.Error(),
+		})
+		return
+	}
+
 	fmt.Printf("\n🚀 ANÁLISIS MEJORADO - %s (%d caracteres)\n", request.Filename, len(request.Content))
-	fmt.Printf("🔧 Configuraciones: Real-time=%v, Spelling=%v\n", request.EnableRealTime, request.ValidateSpelling)
+	fmt.Printf("🔧 Configuraciones: Real-time=%v, Spelling=%v, Autofix=%v\n", request.EnableRealTime, request.ValidateSpelling, request.AutoFix)
 	fmt.Println("============================")
 
 	// Realizar análisis completo CON monitoreo
-	result := analyzeContentEnhancedWithMonitoring(request.Content)
+	result := analyzeContentEnhancedWithMonitoring(request.Content, opts)
 
 	c.JSON(http.StatusOK, result)
 }
@@ -78,16 +119,23 @@ func ValidateRealTime(c *gin.Context) {
 }
 
 // analyzeContentEnhancedWithMonitoring realiza el análisis mejorado con monitoreo
-func analyzeContentEnhancedWithMonitoring(content string) *models.AnalysisResult {
+func analyzeContentEnhancedWithMonitoring(content string, opts analysisOptions) *models.AnalysisResult {
 	startTime := time.Now()
 
+	// Dialecto de shell: el indicado por el cliente o el detectado por el nombre del archivo, el
+	// shebang o el contenido; su formato de historial se normaliza a un comando por línea
+	shell := dialect.Resolve(opts.Dialect, opts.Filename, content)
+	opts.Dialect = shell.Name
+	content = dialect.Normalize(shell.Name, content)
+	frontend := dialect.For(shell.Name)
+	fmt.Printf("🐚 Dialecto: %s (%s)\n", shell.Name, shell.Source)
+
 	// === FASE 1: ANÁLISIS LÉXICO MEJORADO ===
 	fmt.Printf("🔍 Iniciando análisis léxico mejorado...\n")
 	lexerMetric := enhancedMonitor.StartPhase("LÉXICO_MEJORADO")
 
 	// Análisis léxico con más validaciones
-	lex := lexer.NewLexer(content)
-	tokens, lexErrors := lex.Tokenize()
+	tokens, lexErrors := frontend.Tokenize(content)
 
 	enhancedMonitor.EndPhase(lexerMetric)
 	fmt.Printf("✅ Análisis léxico mejorado: %d tokens, %d errores\n", len(tokens), len(lexErrors))
@@ -97,8 +145,7 @@ func analyzeContentEnhancedWithMonitoring(content string) *models.AnalysisResult
 	parserMetric := enhancedMonitor.StartPhase("SINTÁCTICO_SPELL")
 
 	// Parser con SpellChecker
-	p := parser.NewParser(tokens)
-	commands, parseErrors, warnings := p.Parse()
+	commands, parseErrors, warnings := frontend.Parse(tokens)
 
 	enhancedMonitor.EndPhase(parserMetric)
 	fmt.Printf("✅ Análisis sintáctico con spell: %d comandos, %d errores, %d advertencias\n",
@@ -109,8 +156,24 @@ func analyzeContentEnhancedWithMonitoring(content string) *models.AnalysisResult
 	semanticMetric := enhancedMonitor.StartPhase("SEMÁNTICO_FS")
 
 	// Análisis semántico CON sistema de archivos
+	// Intención de cada comando (navegación, desarrollo, administración, ...)
+	intent.Label(commands)
+
 	analyzer := semantic.NewAnalyzer()
+	analyzer.SetSource(content)
+	analyzer.SetSession(opts.User, opts.Host)
+	analyzer.SetDialect(shell.Name)
+	if opts.Mode == lint.ModeScript {
+		analyzer.SetScript(lint.Shebang(content))
+	}
 	threats, patterns, anomalies, fsAnalysis := analyzer.AnalyzeWithFileSystem(commands)
+	analyzer.AnnotateContexts(commands) // Usuario y equipo de cada comando (sudo -i, su, ssh)
+
+	// La sesión se compara con la línea base del usuario antes de sumarse a ella
+	baselineStatus, err := analyzer.LearnBaseline(!opts.SkipLearning)
+	if err != nil {
+		fmt.Printf("⚠️  No se pudo actualizar la línea base %s: %v\n", baselineStatus.Key, err)
+	}
 
 	enhancedMonitor.EndPhase(semanticMetric)
 	fmt.Printf("✅ Análisis semántico FS: %d amenazas, %d patrones, %d anomalías, %d errores FS\n",
@@ -119,26 +182,41 @@ func analyzeContentEnhancedWithMonitoring(content string) *models.AnalysisResult
 	// Generar reporte de monitoreo
 	enhancedMonitor.FinishAnalysis()
 
+	// mode=script: los hallazgos del linter se suman a los errores de sintaxis y a las amenazas,
+	// sujetos a las mismas supresiones que el resto
+	var script *models.ScriptInfo
+	if opts.Mode == lint.ModeScript && dialect.IsBourne(shell.Name) {
+		report := lint.Lint(content)
+		parseErrors = append(parseErrors, report.Errors...)
+		threats = append(threats, analyzer.SuppressFindings(commands, report.Threats)...)
+		script = &report.Script
+	}
+
 	// Estadísticas
 	commandFreq := calculateCommandFrequency(commands)
 	threatCount := calculateThreatCount(threats)
+	riskAssessment := risk.Assess(threats, strings.Count(content, "\n")+1)
 	tokenStats := calculateTokenStats(tokens)
 
 	processingTime := time.Since(startTime)
 
-	return &models.AnalysisResult{
+	result := &models.AnalysisResult{
 		Summary: struct {
 			TotalCommands    int                        `json:"total_commands"`
 			UniqueCommands   int                        `json:"unique_commands"`
 			ThreatCount      map[models.ThreatLevel]int `json:"threat_count"`
 			MostUsedCommands []models.CommandFrequency  `json:"most_used_commands"`
 			ProcessingTime   time.Duration              `json:"processing_time"`
+			RiskScore        int                        `json:"risk_score"`
+			RiskLevel        models.ThreatLevel         `json:"risk_level"`
 		}{
 			TotalCommands:    len(commands),
 			UniqueCommands:   len(getUniqueCommands(commands)),
 			ThreatCount:      threatCount,
 			MostUsedCommands: commandFreq,
 			ProcessingTime:   processingTime,
+			RiskScore:        riskAssessment.Score,
+			RiskLevel:        riskAssessment.Level,
 		},
 		LexicalAnalysis: struct {
 			Tokens     []models.Token           `json:"tokens"`
@@ -167,8 +245,24 @@ func analyzeContentEnhancedWithMonitoring(content string) *models.AnalysisResult
 			Patterns:  patterns,
 			Anomalies: anomalies,
 		},
+		SessionIntegrity:   analyzer.SessionIntegrity(),
+		CloudSummary:       analyzer.CloudSummary(),
+		IOCs:               ioc.Extract(tokens, commands, threats),
+		LateralMovement:    analyzer.LateralMovement(),
+		Contexts:           analyzer.ExecutionContexts(),
+		Suppressed:         analyzer.Suppressed(),
+		Baseline:           baselineStatus,
+		SessionProfile:     intent.Profile(commands),
+		Risk:               riskAssessment,
+		Script:             script,
+		Dialect:            &shell,
 		FileSystemAnalysis: &fsAnalysis, // Análisis adicional de filesystem
 	}
+
+	applyAnalysisOptions(result, content, tokens, analyzer.Secrets(), opts)
+	globalAnalysisStore.Save(result)
+
+	return result
 }
 
 // performQuickValidation realiza validación rápida para tiempo real
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"terminal-history-analyzer/internal/ioc"
+	"terminal-history-analyzer/internal/models"
+	"terminal-history-analyzer/internal/parser"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAnalysisRedactsSecrets(t *testing.T) {
+	content := "sshpass -p hunter2 ssh admin@10.0.0.5\n"
+	analyzers := map[string]func(string, analysisOptions) *models.AnalysisResult{
+		"basico":   analyzeContentWithMonitoring,
+		"mejorado": analyzeContentEnhancedWithMonitoring,
+	}
+
+	for name, analyze := range analyzers {
+		t.Run(name, func(t *testing.T) {
+			result := analyze(content, analysisOptions{})
+			if len(result.IOCs) == 0 {
+				t.Fatalf("no se extrajeron indicadores")
+			}
+
+			output, err := json.Marshal(result)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(output), "hunter2") {
+				t.Errorf("el resultado contiene la credencial: %s", output)
+			}
+			if !strings.Contains(string(output), "10.0.0.5") || !strings.Contains(string(output), "admin@10.0.0.5") {
+				t.Errorf("se enmascaró más que la credencial: %s", output)
+			}
+
+			var csv bytes.Buffer
+			if err := ioc.WriteCSV(&csv, result.IOCs); err != nil {
+				t.Fatal(err)
+			}
+			stix, err := json.Marshal(ioc.BuildSTIXBundle(result))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for format, export := range map[string]string{"csv": csv.String(), "stix": string(stix)} {
+				if strings.Contains(export, "hunter2") {
+					t.Errorf("la exportación %s contiene la credencial: %s", format, export)
+				}
+			}
+		})
+	}
+}
+
+func TestAutoFixThresholdBinding(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/analyze-text", AnalyzeText)
+	router.POST("/analyze-enhanced", AnalyzeEnhanced)
+
+	tests := []struct {
+		name      string
+		threshold string // Valor JSON de autofix_threshold; vacío si no se envía
+		status    int
+		want      float64 // Umbral aplicado
+	}{
+		{name: "sin umbral", status: http.StatusOK, want: parser.DefaultAutoFixThreshold},
+		{name: "cero aplica todas", threshold: "0", status: http.StatusOK, want: 0},
+		{name: "uno", threshold: "1", status: http.StatusOK, want: 1},
+		{name: "negativo", threshold: "-0.1", status: http.StatusBadRequest},
+		{name: "mayor que uno", threshold: "1.5", status: http.StatusBadRequest},
+	}
+
+	for _, path := range []string{"/analyze-text", "/analyze-enhanced"} {
+		for _, tt := range tests {
+			t.Run(path+" "+tt.name, func(t *testing.T) {
+				body := `{"content": "gti status\n", "autofix": true`
+				if tt.threshold != "" {
+					body += `, "autofix_threshold": ` + tt.threshold
+				}
+				body += "}"
+
+				recorder := httptest.NewRecorder()
+				router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+				if recorder.Code != tt.status {
+					t.Fatalf("estado = %d, se esperaba %d: %s", recorder.Code, tt.status, recorder.Body)
+				}
+				if tt.status != http.StatusOK {
+					return
+				}
+
+				var result models.AnalysisResult
+				if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+					t.Fatal(err)
+				}
+				if result.AutoFix == nil || result.AutoFix.Threshold != tt.want {
+					t.Errorf("autofix = %+v, se esperaba el umbral %v", result.AutoFix, tt.want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"terminal-history-analyzer/internal/suppress"
+
+	"github.com/gin-gonic/gin"
+)
+
+// suppressionView es una supresión con su estado en el momento de la consulta
+type suppressionView struct {
+	suppress.Rule
+	Expired bool `json:"expired"`
+}
+
+func newSuppressionView(rule suppress.Rule) suppressionView {
+	return suppressionView{Rule: rule, Expired: rule.Expired(time.Now())}
+}
+
+// ListSuppressions devuelve todas las supresiones, incluidas las expiradas
+func ListSuppressions(c *gin.Context) {
+	rules := suppress.Active().List()
+
+	views := make([]suppressionView, 0, len(rules))
+	active := 0
+	for _, rule := range rules {
+		view := newSuppressionView(rule)
+		if !view.Expired {
+			active++
+		}
+		views = append(views, view)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":        len(views),
+		"active":       active,
+		"suppressions": views,
+	})
+}
+
+// GetSuppression devuelve una supresión por ID
+func GetSuppression(c *gin.Context) {
+	rule, err := suppress.Active().Get(c.Param("id"))
+	if err != nil {
+		respondSuppressionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, newSuppressionView(rule))
+}
+
+// CreateSuppression crea una supresión; requiere ámbito, justificación y expires_at futura. La
+// supresión aparta amenazas y hallazgos del linter, no patrones ni anomalías
+func CreateSuppression(c *gin.Context) {
+	var request suppress.Rule
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Formato de datos inválido: " + err.Error(),
+		})
+		return
+	}
+
+	rule, err := suppress.Active().Create(request)
+	if err != nil {
+		respondSuppressionError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, newSuppressionView(rule))
+}
+
+// UpdateSuppression reemplaza los ámbitos, la justificación y la expiración de una supresión
+func UpdateSuppression(c *gin.Context) {
+	var request suppress.Rule
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Formato de datos inválido: " + err.Error(),
+		})
+		return
+	}
+
+	rule, err := suppress.Active().Update(c.Param("id"), request)
+	if err != nil {
+		respondSuppressionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, newSuppressionView(rule))
+}
+
+// DeleteSuppression elimina una supresión
+func DeleteSuppression(c *gin.Context) {
+	if err := suppress.Active().Delete(c.Param("id")); err != nil {
+		respondSuppressionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Supresión eliminada",
+	})
+}
+
+// respondSuppressionError traduce los errores del almacén de supresiones a códigos HTTP
+func respondSuppressionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, suppress.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Supresión no encontrada"})
+	case errors.Is(err, suppress.ErrInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al guardar las supresiones: " + err.Error()})
+	}
+}
@@ -0,0 +1,481 @@
+package intel
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Formatos de fuente admitidos
+const (
+	FormatList    = "list"    // Un indicador por línea; admite comentarios # y formato hosts (0.0.0.0 dominio)
+	FormatCSV     = "csv"     // CSV con cabecera; columna indicator/value/ioc/url/domain/ip y opcional confidence
+	FormatMISP    = "misp"    // Exportación JSON de eventos MISP
+	FormatAbuseCH = "abusech" // CSV de abuse.ch (URLhaus, Feodo Tracker, ThreatFox) con la cabecera comentada
+)
+
+// manifestFile es el archivo opcional del directorio de fuentes que fija nombre, formato y confianza
+const manifestFile = "feeds.yaml"
+
+// pasteSitesFeed es la fuente embebida con los servicios de pegado y alojamiento anónimo de archivos
+//
+//go:embed feeds/paste-sites.txt
+var pasteSitesFeed []byte
+
+// defaultConfidence es la confianza de cada formato cuando ni la fuente ni el manifiesto la indican
+var defaultConfidence = map[string]int{
+	FormatList:    50,
+	FormatCSV:     50,
+	FormatMISP:    50,
+	FormatAbuseCH: 75,
+}
+
+// indicatorColumns son los nombres de columna con el indicador, por orden de preferencia
+var indicatorColumns = []string{
+	"indicator", "value", "ioc", "ioc_value", "url", "domain", "hostname", "host",
+	"ip", "ip_address", "dst_ip", "sha256_hash", "sha1_hash", "md5_hash", "hash",
+}
+
+// confidenceColumns son los nombres de columna con la confianza (0-100)
+var confidenceColumns = []string{"confidence", "confidence_level", "score"}
+
+// mispThreatLevels convierte el threat_level_id de un evento MISP en confianza
+var mispThreatLevels = map[string]int{"1": 90, "2": 70, "3": 50}
+
+// mispTypes son los tipos de atributo MISP que se cargan; los compuestos (a|b) se cargan por partes
+var mispTypes = map[string]bool{
+	"ip-src": true, "ip-dst": true, "ip-src|port": true, "ip-dst|port": true,
+	"domain": true, "hostname": true, "domain|ip": true, "hostname|port": true,
+	"url": true, "md5": true, "sha1": true, "sha256": true, "sha512": true,
+	"filename|md5": true, "filename|sha1": true, "filename|sha256": true, "filename|sha512": true,
+}
+
+// FeedConfig describe una fuente en el manifiesto feeds.yaml
+type FeedConfig struct {
+	Name       string `yaml:"name" json:"name"`
+	File       string `yaml:"file" json:"file"`
+	Format     string `yaml:"format,omitempty" json:"format,omitempty"`
+	Confidence int    `yaml:"confidence,omitempty" json:"confidence,omitempty"`
+	Disabled   bool   `yaml:"disabled,omitempty" json:"disabled,omitempty"` // Permite desactivar la fuente embebida por nombre
+}
+
+// manifest es el formato de feeds.yaml
+type manifest struct {
+	Feeds []FeedConfig `yaml:"feeds"`
+}
+
+// Feed resume una fuente cargada
+type Feed struct {
+	Name       string `json:"name"`
+	Format     string `json:"format"`
+	Source     string `json:"source"` // Ruta del archivo o "embedded"
+	Confidence int    `json:"confidence"`
+	Indicators int    `json:"indicators"`
+	Skipped    int    `json:"skipped"` // Líneas o atributos que no son indicadores reconocibles
+}
+
+// Intel es el conjunto de fuentes cargadas con su matcher
+type Intel struct {
+	Matcher  *Matcher
+	Feeds    []Feed
+	Dir      string
+	LoadedAt time.Time
+}
+
+var (
+	active   = mustLoadEmbedded()
+	activeMu sync.RWMutex
+)
+
+// mustLoadEmbedded carga solo la fuente embebida; un error aquí es un error de programación
+func mustLoadEmbedded() *Intel {
+	intel, err := Load("")
+	if err != nil {
+		panic("fuente de inteligencia embebida inválida: " + err.Error())
+	}
+	return intel
+}
+
+// Active devuelve las fuentes activas
+func Active() *Intel {
+	activeMu.RLock()
+	defer activeMu.RUnlock()
+	return active
+}
+
+// Configure carga la fuente embebida más las fuentes del directorio y las activa
+func Configure(dir string) (*Intel, error) {
+	intel, err := Load(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	activeMu.Lock()
+	active = intel
+	activeMu.Unlock()
+
+	return intel, nil
+}
+
+// Reload vuelve a leer el directorio de las fuentes activas; si falla se conservan las anteriores
+func Reload() (*Intel, error) {
+	return Configure(Active().Dir)
+}
+
+// Load carga la fuente embebida y las fuentes del directorio indicado. Sin manifiesto se cargan todos
+// los archivos .txt, .list, .csv y .json del directorio, con el formato deducido de la extensión.
+func Load(dir string) (*Intel, error) {
+	configs := []FeedConfig{{Name: "paste-sites", Format: FormatList, Confidence: 50}}
+
+	if dir != "" {
+		dirConfigs, err := readDirectory(dir)
+		if err != nil {
+			return nil, err
+		}
+		configs = mergeConfigs(configs, dirConfigs)
+	}
+
+	intel := &Intel{Matcher: NewMatcher(), Dir: dir, LoadedAt: time.Now()}
+	for _, config := range configs {
+		if config.Disabled {
+			continue
+		}
+
+		data, source := pasteSitesFeed, "embedded"
+		if config.File != "" {
+			source = filepath.Join(dir, config.File)
+			var err error
+			if data, err = os.ReadFile(source); err != nil {
+				return nil, fmt.Errorf("no se pudo leer la fuente %s: %w", source, err)
+			}
+		}
+
+		feed, err := intel.loadFeed(config, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+		feed.Source = source
+		intel.Feeds = append(intel.Feeds, feed)
+	}
+
+	return intel, nil
+}
+
+// readDirectory lee el manifiesto del directorio o, si no existe, deduce las fuentes de los archivos
+func readDirectory(dir string) ([]FeedConfig, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo leer el directorio de fuentes %s: %w", dir, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err == nil {
+		var m manifest
+		if err := yaml.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Join(dir, manifestFile), err)
+		}
+		for i, config := range m.Feeds {
+			if config.Name == "" {
+				return nil, fmt.Errorf("%s: la fuente %d no tiene nombre", manifestFile, i+1)
+			}
+			if config.File == "" && !config.Disabled {
+				return nil, fmt.Errorf("%s: la fuente %s no indica el archivo", manifestFile, config.Name)
+			}
+			if config.Format == "" {
+				m.Feeds[i].Format = formatForFile(config.File)
+			}
+		}
+		return m.Feeds, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("no se pudo leer %s: %w", manifestFile, err)
+	}
+
+	var configs []FeedConfig
+	for _, entry := range entries {
+		format := formatForFile(entry.Name())
+		if entry.IsDir() || format == "" {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		configs = append(configs, FeedConfig{Name: name, File: entry.Name(), Format: format})
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Name < configs[j].Name })
+	return configs, nil
+}
+
+// formatForFile deduce el formato de una fuente por su extensión
+func formatForFile(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".txt", ".list":
+		return FormatList
+	case ".csv":
+		return FormatCSV
+	case ".json":
+		return FormatMISP
+	}
+	return ""
+}
+
+// mergeConfigs añade las fuentes del directorio; las que tienen el nombre de una existente la reemplazan
+func mergeConfigs(base, other []FeedConfig) []FeedConfig {
+	result := append([]FeedConfig{}, base...)
+	for _, config := range other {
+		replaced := false
+		for i := range result {
+			if result[i].Name == config.Name {
+				if config.File == "" {
+					config.File, config.Format = result[i].File, result[i].Format
+				}
+				result[i] = config
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			result = append(result, config)
+		}
+	}
+	return result
+}
+
+// loadFeed interpreta una fuente según su formato y añade sus indicadores al matcher
+func (in *Intel) loadFeed(config FeedConfig, data []byte) (Feed, error) {
+	// Los CSV de abuse.ch llevan la cabecera comentada; se reconocen por el comentario inicial
+	if config.Format == FormatCSV && bytes.HasPrefix(bytes.TrimSpace(data), []byte("#")) {
+		config.Format = FormatAbuseCH
+	}
+
+	confidence := config.Confidence
+	if confidence == 0 {
+		confidence = defaultConfidence[config.Format]
+	}
+	if confidence < 0 || confidence > 100 {
+		return Feed{}, fmt.Errorf("confianza fuera de rango (0-100): %d", confidence)
+	}
+
+	feed := Feed{Name: config.Name, Format: config.Format, Confidence: confidence}
+	add := func(indicator string, indicatorConfidence int) {
+		if indicatorConfidence == 0 {
+			indicatorConfidence = confidence
+		}
+		if in.Matcher.Add(indicator, feed.Name, indicatorConfidence) {
+			feed.Indicators++
+		} else {
+			feed.Skipped++
+		}
+	}
+
+	var err error
+	switch config.Format {
+	case FormatList:
+		err = parseList(data, add)
+	case FormatCSV:
+		err = parseCSV(data, false, add)
+	case FormatAbuseCH:
+		err = parseCSV(data, true, add)
+	case FormatMISP:
+		err = parseMISP(data, add)
+	default:
+		return Feed{}, fmt.Errorf("formato de fuente desconocido: %q", config.Format)
+	}
+	if err != nil {
+		return Feed{}, err
+	}
+	return feed, nil
+}
+
+// parseList lee una lista de indicadores, uno por línea
+func parseList(data []byte, add func(string, int)) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		// Formato hosts: 0.0.0.0 dominio
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && (fields[0] == "0.0.0.0" || fields[0] == "127.0.0.1") {
+			line = fields[1]
+		} else {
+			line = fields[0]
+		}
+		add(line, 0)
+	}
+	return scanner.Err()
+}
+
+// parseCSV lee un CSV de indicadores. Con commentedHeader la cabecera es la última línea comentada
+// con comas antes de los datos (formato de abuse.ch).
+func parseCSV(data []byte, commentedHeader bool, add func(string, int)) error {
+	var header []string
+	var body bytes.Buffer
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") {
+			if commentedHeader && body.Len() == 0 && strings.Contains(trimmed, ",") {
+				record, err := csv.NewReader(strings.NewReader(strings.TrimSpace(strings.TrimPrefix(trimmed, "#")))).Read()
+				if err == nil {
+					header = record
+				}
+			}
+			continue
+		}
+		if trimmed != "" {
+			body.WriteString(trimmed + "\n")
+		}
+	}
+
+	reader := csv.NewReader(&body)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if header == nil {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("cabecera CSV inválida: %w", err)
+		}
+		header = record
+	}
+
+	indicatorColumn := findColumn(header, indicatorColumns)
+	confidenceColumn := findColumn(header, confidenceColumns)
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("CSV inválido: %w", err)
+		}
+
+		confidence := 0
+		if confidenceColumn >= 0 && confidenceColumn < len(record) {
+			if value, err := strconv.Atoi(strings.TrimSpace(record[confidenceColumn])); err == nil && value > 0 && value <= 100 {
+				confidence = value
+			}
+		}
+
+		if indicatorColumn >= 0 {
+			if indicatorColumn < len(record) {
+				add(record[indicatorColumn], confidence)
+			}
+			continue
+		}
+
+		// Sin columna conocida se toma el primer campo que sea un indicador
+		for _, field := range record {
+			if kind, _ := Classify(field); kind != "" {
+				add(field, confidence)
+				break
+			}
+		}
+	}
+}
+
+// findColumn devuelve el índice de la primera columna de la cabecera con uno de los nombres, o -1
+func findColumn(header []string, names []string) int {
+	for _, name := range names {
+		for i, column := range header {
+			if strings.EqualFold(strings.TrimSpace(column), name) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// mispEvent es la parte de un evento MISP que se usa
+type mispEvent struct {
+	ThreatLevelID string          `json:"threat_level_id"`
+	Attribute     []mispAttribute `json:"Attribute"`
+	Object        []struct {
+		Attribute []mispAttribute `json:"Attribute"`
+	} `json:"Object"`
+}
+
+// mispAttribute es un atributo MISP; to_ids=false indica un dato de contexto, no un indicador
+type mispAttribute struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	ToIDs *bool  `json:"to_ids"`
+}
+
+// parseMISP lee una exportación de MISP: un evento ({"Event": ...}), una lista de eventos o la
+// respuesta de la API ({"response": [...]})
+func parseMISP(data []byte, add func(string, int)) error {
+	type wrapper struct {
+		Event mispEvent `json:"Event"`
+	}
+
+	var events []wrapper
+	var single wrapper
+	var response struct {
+		Response []wrapper `json:"response"`
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		if err := json.Unmarshal(trimmed, &events); err != nil {
+			return fmt.Errorf("exportación MISP inválida: %w", err)
+		}
+	case bytes.Contains(trimmed, []byte(`"response"`)):
+		if err := json.Unmarshal(trimmed, &response); err != nil {
+			return fmt.Errorf("exportación MISP inválida: %w", err)
+		}
+		events = response.Response
+	default:
+		if err := json.Unmarshal(trimmed, &single); err != nil {
+			return fmt.Errorf("exportación MISP inválida: %w", err)
+		}
+		events = []wrapper{single}
+	}
+
+	for _, event := range events {
+		confidence := mispThreatLevels[event.Event.ThreatLevelID]
+		attributes := event.Event.Attribute
+		for _, object := range event.Event.Object {
+			attributes = append(attributes, object.Attribute...)
+		}
+
+		for _, attribute := range attributes {
+			if !mispTypes[attribute.Type] || attribute.ToIDs != nil && !*attribute.ToIDs {
+				continue
+			}
+			parts := strings.Split(attribute.Value, "|")
+			switch {
+			case strings.HasPrefix(attribute.Type, "filename|"):
+				add(parts[len(parts)-1], confidence)
+			case strings.HasSuffix(attribute.Type, "|port"):
+				add(parts[0], confidence)
+			default:
+				for _, part := range parts {
+					add(part, confidence)
+				}
+			}
+		}
+	}
+	return nil
+}
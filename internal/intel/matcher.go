@@ -0,0 +1,273 @@
+// Package intel carga fuentes de inteligencia de amenazas sin conexión (listas, CSV, exportaciones
+// MISP y formatos de abuse.ch) y busca en ellas los indicadores observados en el historial
+package intel
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Tipos de indicador de las fuentes
+const (
+	KindDomain = "domain"
+	KindIP     = "ip"
+	KindURL    = "url"
+	KindHash   = "hash"
+)
+
+// Entry es un indicador de una fuente
+type Entry struct {
+	Feed       string `json:"feed"`
+	Confidence int    `json:"confidence"` // 0-100
+	Indicator  string `json:"indicator"`  // Valor tal como está en la fuente (dominio, CIDR, URL, hash)
+	Kind       string `json:"kind"`
+}
+
+// Matcher agrupa los indicadores de todas las fuentes: dominios en un trie de sufijos (un dominio
+// incluye sus subdominios), redes en una tabla CIDR por longitud de prefijo y URLs y hashes en
+// conjuntos
+type Matcher struct {
+	domains *domainNode
+	cidrs   map[int]map[string][]Entry // Longitud de prefijo → red enmascarada → entradas
+	ipv4    []int                      // Longitudes de prefijo IPv4 presentes, de mayor a menor
+	ipv6    []int
+	urls    map[string][]Entry
+	hashes  map[string][]Entry
+	size    int
+}
+
+// domainNode es un nodo del trie de dominios; las etiquetas se recorren de derecha a izquierda
+type domainNode struct {
+	children map[string]*domainNode
+	entries  []Entry
+}
+
+// NewMatcher crea un matcher vacío
+func NewMatcher() *Matcher {
+	return &Matcher{
+		domains: &domainNode{},
+		cidrs:   make(map[int]map[string][]Entry),
+		urls:    make(map[string][]Entry),
+		hashes:  make(map[string][]Entry),
+	}
+}
+
+// Len devuelve el número de indicadores cargados
+func (m *Matcher) Len() int {
+	return m.size
+}
+
+// Add clasifica un indicador y lo añade; devuelve false si no es un dominio, IP, red, URL o hash
+func (m *Matcher) Add(indicator, feed string, confidence int) bool {
+	kind, value := Classify(indicator)
+	entry := Entry{Feed: feed, Confidence: confidence, Indicator: value, Kind: kind}
+
+	switch kind {
+	case KindDomain:
+		node := m.domains
+		labels := strings.Split(value, ".")
+		for i := len(labels) - 1; i >= 0; i-- {
+			if node.children == nil {
+				node.children = make(map[string]*domainNode)
+			}
+			child, ok := node.children[labels[i]]
+			if !ok {
+				child = &domainNode{}
+				node.children[labels[i]] = child
+			}
+			node = child
+		}
+		node.entries = append(node.entries, entry)
+	case KindIP:
+		_, network, _ := net.ParseCIDR(value)
+		m.addNetwork(network, entry)
+	case KindURL:
+		m.urls[value] = append(m.urls[value], entry)
+	case KindHash:
+		m.hashes[value] = append(m.hashes[value], entry)
+	default:
+		return false
+	}
+
+	m.size++
+	return true
+}
+
+// addNetwork registra una red en la tabla CIDR
+func (m *Matcher) addNetwork(network *net.IPNet, entry Entry) {
+	ones, bits := network.Mask.Size()
+	key := prefixKey(ones, bits)
+	table, ok := m.cidrs[key]
+	if !ok {
+		table = make(map[string][]Entry)
+		m.cidrs[key] = table
+		if bits == 32 {
+			m.ipv4 = insertPrefix(m.ipv4, ones)
+		} else {
+			m.ipv6 = insertPrefix(m.ipv6, ones)
+		}
+	}
+	table[network.IP.String()] = append(table[network.IP.String()], entry)
+}
+
+// prefixKey separa en la tabla las longitudes de prefijo IPv4 (0-32) de las IPv6 (100-228)
+func prefixKey(ones, bits int) int {
+	if bits == 32 {
+		return ones
+	}
+	return 100 + ones
+}
+
+// insertPrefix añade una longitud de prefijo manteniendo el orden de mayor a menor
+func insertPrefix(prefixes []int, ones int) []int {
+	for i, existing := range prefixes {
+		if ones > existing {
+			return append(prefixes[:i], append([]int{ones}, prefixes[i:]...)...)
+		}
+	}
+	return append(prefixes, ones)
+}
+
+// MatchDomain devuelve las entradas del dominio o de cualquiera de sus dominios padre
+func (m *Matcher) MatchDomain(domain string) []Entry {
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	labels := strings.Split(domain, ".")
+
+	var matches []Entry
+	node := m.domains
+	for i := len(labels) - 1; i >= 0; i-- {
+		node = node.children[labels[i]]
+		if node == nil {
+			break
+		}
+		matches = append(matches, node.entries...)
+	}
+	return matches
+}
+
+// MatchIP devuelve las entradas de la red más específica que contiene la dirección
+func (m *Matcher) MatchIP(address string) []Entry {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return nil
+	}
+
+	bits, prefixes := 128, m.ipv6
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits, prefixes = ip4, 32, m.ipv4
+	}
+	for _, ones := range prefixes {
+		network := ip.Mask(net.CIDRMask(ones, bits))
+		if entries := m.cidrs[prefixKey(ones, bits)][network.String()]; len(entries) > 0 {
+			return entries
+		}
+	}
+	return nil
+}
+
+// MatchURL devuelve las entradas de la URL exacta (normalizada)
+func (m *Matcher) MatchURL(raw string) []Entry {
+	return m.urls[normalizeURL(raw)]
+}
+
+// MatchHash devuelve las entradas del hash
+func (m *Matcher) MatchHash(hash string) []Entry {
+	return m.hashes[strings.ToLower(hash)]
+}
+
+// Classify determina el tipo de un indicador de una fuente y lo normaliza. Acepta indicadores
+// desactivados (hxxp://, evil[.]com) y direcciones con puerto (1.2.3.4:443).
+func Classify(indicator string) (string, string) {
+	value := strings.TrimSpace(indicator)
+	value = strings.NewReplacer("[.]", ".", "(.)", ".", "[:]", ":").Replace(value)
+	if strings.HasPrefix(strings.ToLower(value), "hxxp") {
+		value = "http" + value[4:]
+	}
+	if value == "" {
+		return "", ""
+	}
+
+	if strings.Contains(value, "://") {
+		if normalized := normalizeURL(value); normalized != "" {
+			return KindURL, normalized
+		}
+		return "", ""
+	}
+
+	if _, network, err := net.ParseCIDR(value); err == nil {
+		return KindIP, network.String()
+	}
+	host := value
+	if h, _, err := net.SplitHostPort(value); err == nil {
+		host = h
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			return KindIP, ip4.String() + "/32"
+		}
+		return KindIP, ip.String() + "/128"
+	}
+
+	if isHash(value) {
+		return KindHash, strings.ToLower(value)
+	}
+
+	domain := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(host), "*."), "."), ".")
+	if isDomain(domain) {
+		return KindDomain, domain
+	}
+	return "", ""
+}
+
+// normalizeURL pone en minúsculas el esquema y el host y quita la barra final
+func normalizeURL(raw string) string {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	parsed.Host = strings.ToLower(parsed.Host)
+	parsed.Fragment = ""
+	return strings.TrimSuffix(parsed.String(), "/")
+}
+
+// isHash indica si el valor es un hash hexadecimal MD5, SHA-1, SHA-256 o SHA-512
+func isHash(value string) bool {
+	switch len(value) {
+	case 32, 40, 64, 128:
+	default:
+		return false
+	}
+	for _, r := range value {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isDomain valida la forma de un nombre de dominio (al menos dos etiquetas y TLD alfabético)
+func isDomain(value string) bool {
+	labels := strings.Split(value, ".")
+	if len(labels) < 2 || len(value) > 253 {
+		return false
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+				return false
+			}
+		}
+	}
+	tld := labels[len(labels)-1]
+	for _, r := range tld {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,208 @@
+package intent
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"terminal-history-analyzer/internal/models"
+)
+
+// sessionGroups agrupa las categorías para distinguir sesiones de desarrollo, administración y
+// reconocimiento; navegación y gestión de archivos son neutras
+var sessionGroups = map[string]string{
+	"development":           "development",
+	"vcs":                   "development",
+	"system_administration": "administration",
+	"package_management":    "administration",
+	"monitoring":            "administration",
+	"networking":            "administration",
+	"reconnaissance":        "reconnaissance",
+}
+
+// sessionTypeLabels son los encabezados del resumen por tipo de sesión
+var sessionTypeLabels = map[string]string{
+	"development":    "Sesión de desarrollo",
+	"administration": "Sesión de administración",
+	"reconnaissance": "Sesión de reconocimiento",
+	"mixed":          "Sesión mixta",
+	"general":        "Sesión de uso general",
+}
+
+// Label etiqueta cada comando (y los segmentos de sus pipelines) con su categoría de intención
+func Label(commands []models.CommandAST) {
+	taxonomy := Active()
+	for i := range commands {
+		commands[i].Intent, _ = taxonomy.Classify(commands[i])
+		for _, segment := range commands[i].Pipes {
+			segment.Intent, _ = taxonomy.Classify(*segment)
+		}
+	}
+}
+
+// Profile construye el perfil de actividad de la sesión con la taxonomía activa
+func Profile(commands []models.CommandAST) *models.SessionProfile {
+	taxonomy := Active()
+	profile := &models.SessionProfile{
+		Categories:  make([]models.IntentShare, 0),
+		Transitions: make([]models.IntentTransition, 0),
+		Tools:       make([]models.ToolUsage, 0),
+	}
+
+	counts := make(map[string]int)
+	tools := make(map[string]*models.ToolUsage)
+	transitions := make(map[[2]string]int)
+	previous := ""
+
+	for _, cmd := range commands {
+		category, tool := taxonomy.Classify(cmd)
+		if category == "" {
+			profile.Unclassified++
+			continue
+		}
+		profile.Classified++
+		counts[category]++
+
+		if tool != "" {
+			key := tool + "|" + category
+			if tools[key] == nil {
+				tools[key] = &models.ToolUsage{Tool: tool, Category: category}
+			}
+			tools[key].Count++
+		}
+		if previous != "" && previous != category {
+			transitions[[2]string{previous, category}]++
+		}
+		previous = category
+	}
+
+	for category, count := range counts {
+		profile.Categories = append(profile.Categories, models.IntentShare{
+			Category:   category,
+			Label:      taxonomy.Label(category),
+			Count:      count,
+			Percentage: math.Round(1000*float64(count)/float64(profile.Classified)) / 10,
+		})
+	}
+	sort.Slice(profile.Categories, func(i, j int) bool {
+		a, b := profile.Categories[i], profile.Categories[j]
+		return a.Count > b.Count || a.Count == b.Count && a.Category < b.Category
+	})
+
+	for pair, count := range transitions {
+		profile.Transitions = append(profile.Transitions, models.IntentTransition{From: pair[0], To: pair[1], Count: count})
+	}
+	sort.Slice(profile.Transitions, func(i, j int) bool {
+		a, b := profile.Transitions[i], profile.Transitions[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.From+a.To < b.From+b.To
+	})
+
+	for _, usage := range tools {
+		profile.Tools = append(profile.Tools, *usage)
+	}
+	sort.Slice(profile.Tools, func(i, j int) bool {
+		a, b := profile.Tools[i], profile.Tools[j]
+		return a.Count > b.Count || a.Count == b.Count && a.Tool < b.Tool
+	})
+
+	profile.Type = sessionType(counts)
+	profile.Summary = summarize(profile)
+	return profile
+}
+
+// sessionType decide el tipo de sesión por el grupo con más de la mitad de los comandos no neutros
+func sessionType(counts map[string]int) string {
+	if len(counts) == 0 {
+		return "empty"
+	}
+
+	groups := make(map[string]int)
+	total := 0
+	for category, count := range counts {
+		if group, ok := sessionGroups[category]; ok {
+			groups[group] += count
+			total += count
+		}
+	}
+	if total == 0 {
+		return "general"
+	}
+	for group, count := range groups {
+		if 2*count > total {
+			return group
+		}
+	}
+	return "mixed"
+}
+
+// summarize genera el párrafo de resumen: "Sesión de desarrollo: mayormente desarrollo y
+// compilación con Go (60%), con algo de administración del sistema con Docker (20%). ..."
+func summarize(profile *models.SessionProfile) string {
+	if profile.Classified == 0 {
+		return "No hay comandos clasificables en la sesión."
+	}
+
+	describe := func(share models.IntentShare) string {
+		text := share.Label
+		if tool := topTool(profile.Tools, share.Category); tool != "" {
+			text += " con " + tool
+		}
+		return fmt.Sprintf("%s (%.0f%%)", text, share.Percentage)
+	}
+
+	main := profile.Categories[0]
+	qualifier := "principalmente"
+	if main.Percentage >= 50 {
+		qualifier = "mayormente"
+	}
+	summary := sessionTypeLabels[profile.Type] + ": " + qualifier + " " + describe(main)
+
+	var others []string
+	for _, share := range profile.Categories[1:] {
+		if share.Percentage < 10 || len(others) == 3 {
+			break
+		}
+		amount := "algo de"
+		if share.Percentage >= 25 {
+			amount = "bastante"
+		}
+		others = append(others, amount+" "+describe(share))
+	}
+	if len(others) > 0 {
+		summary += ", con " + joinSpanish(others)
+	}
+
+	summary += fmt.Sprintf(". %d de %d comandos clasificados", profile.Classified, profile.Classified+profile.Unclassified)
+	changes := 0
+	for _, transition := range profile.Transitions {
+		changes += transition.Count
+	}
+	if changes == 1 {
+		summary += "; 1 cambio de actividad"
+	} else if changes > 1 {
+		summary += fmt.Sprintf("; %d cambios de actividad", changes)
+	}
+	return summary + "."
+}
+
+// topTool devuelve la herramienta más usada de una categoría
+func topTool(tools []models.ToolUsage, category string) string {
+	for _, usage := range tools {
+		if usage.Category == category {
+			return usage.Tool
+		}
+	}
+	return ""
+}
+
+// joinSpanish une elementos con comas y "y" antes del último
+func joinSpanish(items []string) string {
+	if len(items) == 1 {
+		return items[0]
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " y " + items[len(items)-1]
+}
@@ -0,0 +1,174 @@
+package intent
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"terminal-history-analyzer/internal/models"
+	"terminal-history-analyzer/internal/parser"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultTaxonomyYAML es la taxonomía que se distribuye con el binario
+//
+//go:embed taxonomy.yaml
+var defaultTaxonomyYAML []byte
+
+// Rule asigna una categoría a los comandos que cumplen sus condiciones
+type Rule struct {
+	Category    string   `yaml:"category" json:"category"`
+	Commands    []string `yaml:"commands" json:"commands"`
+	Subcommands []string `yaml:"subcommands,omitempty" json:"subcommands,omitempty"`
+	Arguments   []string `yaml:"arguments,omitempty" json:"arguments,omitempty"` // Regex sobre alguno de los argumentos
+	Tool        string   `yaml:"tool,omitempty" json:"tool,omitempty"`
+
+	arguments []*regexp.Regexp
+}
+
+// Taxonomy son las categorías con su etiqueta y las reglas en orden de prioridad
+type Taxonomy struct {
+	Categories map[string]string `yaml:"categories" json:"categories"`
+	Rules      []Rule            `yaml:"rules" json:"rules"`
+
+	byCommand map[string][]int // Índices de las reglas de cada comando, en orden
+}
+
+var (
+	activeTaxonomy   = mustParseDefault()
+	activeTaxonomyMu sync.RWMutex
+)
+
+// mustParseDefault interpreta la taxonomía embebida; un error aquí es un error de programación
+func mustParseDefault() *Taxonomy {
+	taxonomy, err := Parse(defaultTaxonomyYAML)
+	if err != nil {
+		panic("taxonomía por defecto inválida: " + err.Error())
+	}
+	return taxonomy
+}
+
+// Active devuelve la taxonomía activa
+func Active() *Taxonomy {
+	activeTaxonomyMu.RLock()
+	defer activeTaxonomyMu.RUnlock()
+	return activeTaxonomy
+}
+
+// Configure activa la taxonomía del archivo indicado; sin archivo (o si no existe) se usa la embebida
+func Configure(path string) (*Taxonomy, error) {
+	taxonomy := mustParseDefault()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("no se pudo leer la taxonomía %s: %w", path, err)
+		}
+		if err == nil {
+			if taxonomy, err = Parse(data); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	}
+
+	activeTaxonomyMu.Lock()
+	activeTaxonomy = taxonomy
+	activeTaxonomyMu.Unlock()
+
+	return taxonomy, nil
+}
+
+// Parse interpreta y valida una taxonomía en YAML
+func Parse(data []byte) (*Taxonomy, error) {
+	var taxonomy Taxonomy
+	if err := yaml.Unmarshal(data, &taxonomy); err != nil {
+		return nil, fmt.Errorf("formato de taxonomía inválido: %w", err)
+	}
+	if len(taxonomy.Categories) == 0 {
+		return nil, fmt.Errorf("la taxonomía no define categorías")
+	}
+
+	taxonomy.byCommand = make(map[string][]int)
+	for i := range taxonomy.Rules {
+		rule := &taxonomy.Rules[i]
+		if _, ok := taxonomy.Categories[rule.Category]; !ok {
+			return nil, fmt.Errorf("regla %d: categoría desconocida %q", i+1, rule.Category)
+		}
+		if len(rule.Commands) == 0 {
+			return nil, fmt.Errorf("regla %d (%s): sin comandos", i+1, rule.Category)
+		}
+		for _, pattern := range rule.Arguments {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("regla %d (%s): expresión inválida %q: %w", i+1, rule.Category, pattern, err)
+			}
+			rule.arguments = append(rule.arguments, re)
+		}
+		for _, command := range rule.Commands {
+			command = strings.ToLower(command)
+			taxonomy.byCommand[command] = append(taxonomy.byCommand[command], i)
+		}
+	}
+
+	return &taxonomy, nil
+}
+
+// Label devuelve la etiqueta legible de una categoría
+func (t *Taxonomy) Label(category string) string {
+	if label, ok := t.Categories[category]; ok {
+		return label
+	}
+	return category
+}
+
+// Classify devuelve la categoría y la herramienta de un comando, o "" si ninguna regla coincide.
+// Los wrappers se desenvuelven (sudo apt install -> apt install).
+func (t *Taxonomy) Classify(cmd models.CommandAST) (string, string) {
+	name := filepath.Base(cmd.Command)
+	arguments := cmd.Arguments
+	for parser.IsWrapperCommand(name) && len(arguments) > 0 {
+		name = filepath.Base(arguments[0])
+		arguments = arguments[1:]
+	}
+	name = strings.ToLower(name)
+
+	for _, index := range t.byCommand[name] {
+		rule := &t.Rules[index]
+		if len(rule.Subcommands) > 0 && (len(arguments) == 0 || !contains(rule.Subcommands, arguments[0])) {
+			continue
+		}
+		if len(rule.arguments) > 0 && !anyArgument(rule.arguments, cmd, arguments) {
+			continue
+		}
+		return rule.Category, rule.Tool
+	}
+	return "", ""
+}
+
+// anyArgument verifica si algún argumento coincide con las expresiones; además de los argumentos
+// posicionales se usan las palabras de la línea tal como se escribieron (find / -perm -4000, tail -f)
+func anyArgument(patterns []*regexp.Regexp, cmd models.CommandAST, arguments []string) bool {
+	values := append(append([]string{}, arguments...), strings.Fields(cmd.Raw)...)
+	for _, value := range values {
+		for _, re := range patterns {
+			if re.MatchString(strings.Trim(value, `"'`)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func contains(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package ioc
+
+import (
+	"crypto/sha1"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"terminal-history-analyzer/internal/models"
+)
+
+// stixNamespace es el espacio de nombres de STIX 2.1 para UUIDv5 (00abedb4-aa42-466c-9c01-fed23315a9b7):
+// el mismo análisis exportado dos veces produce los mismos identificadores
+var stixNamespace = [16]byte{0x00, 0xab, 0xed, 0xb4, 0xaa, 0x42, 0x46, 0x6c, 0x9c, 0x01, 0xfe, 0xd2, 0x33, 0x15, 0xa9, 0xb7}
+
+// stixPatterns convierte cada tipo de indicador en su patrón STIX
+var stixPatterns = map[string]string{
+	TypeIPv4:   "[ipv4-addr:value = '%s']",
+	TypeIPv6:   "[ipv6-addr:value = '%s']",
+	TypeDomain: "[domain-name:value = '%s']",
+	TypeURL:    "[url:value = '%s']",
+	TypeEmail:  "[email-addr:value = '%s']",
+	TypeMD5:    "[file:hashes.MD5 = '%s']",
+	TypeSHA1:   "[file:hashes.'SHA-1' = '%s']",
+	TypeSHA256: "[file:hashes.'SHA-256' = '%s']",
+	TypeSHA512: "[file:hashes.'SHA-512' = '%s']",
+}
+
+// STIXBundle es un bundle STIX 2.1 con un indicador por IOC
+type STIXBundle struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id"`
+	Objects []STIXIndicator `json:"objects"`
+}
+
+// STIXIndicator es un objeto indicator de STIX 2.1
+type STIXIndicator struct {
+	Type           string    `json:"type"`
+	SpecVersion    string    `json:"spec_version"`
+	ID             string    `json:"id"`
+	Created        time.Time `json:"created"`
+	Modified       time.Time `json:"modified"`
+	Name           string    `json:"name"`
+	Description    string    `json:"description"`
+	IndicatorTypes []string  `json:"indicator_types"`
+	Pattern        string    `json:"pattern"`
+	PatternType    string    `json:"pattern_type"`
+	ValidFrom      time.Time `json:"valid_from"`
+	Labels         []string  `json:"labels,omitempty"`
+}
+
+// BuildSTIXBundle genera el bundle STIX 2.1 de los indicadores de un análisis. Los indicadores que
+// aparecen en líneas con amenazas se marcan como malicious-activity; el resto como unknown.
+func BuildSTIXBundle(result *models.AnalysisResult) STIXBundle {
+	created := result.CreatedAt.UTC().Truncate(time.Millisecond)
+	threatLines := make(map[int]bool)
+	for _, threat := range result.SemanticAnalysis.Threats {
+		threatLines[threat.Line] = true
+	}
+
+	bundle := STIXBundle{
+		Type:    "bundle",
+		ID:      "bundle--" + uuid5(result.ID),
+		Objects: make([]STIXIndicator, 0, len(result.IOCs)),
+	}
+
+	for _, indicator := range result.IOCs {
+		indicatorType := "unknown"
+		for line := indicator.FirstLine; line <= indicator.LastLine; line++ {
+			if threatLines[line] {
+				indicatorType = "malicious-activity"
+				break
+			}
+		}
+
+		bundle.Objects = append(bundle.Objects, STIXIndicator{
+			Type:           "indicator",
+			SpecVersion:    "2.1",
+			ID:             "indicator--" + uuid5(result.ID+"|"+indicator.Type+"|"+indicator.Value),
+			Created:        created,
+			Modified:       created,
+			Name:           fmt.Sprintf("%s %s", indicator.Type, indicator.Value),
+			Description:    describe(indicator),
+			IndicatorTypes: []string{indicatorType},
+			Pattern:        stixPattern(indicator),
+			PatternType:    "stix",
+			ValidFrom:      created,
+			Labels:         []string{"terminal-history", indicator.Type},
+		})
+	}
+
+	return bundle
+}
+
+// WriteCSV escribe los indicadores en CSV con una fila por indicador
+func WriteCSV(w io.Writer, iocs []models.IOC) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"type", "value", "first_line", "last_line", "count", "commands"}); err != nil {
+		return err
+	}
+
+	for _, indicator := range iocs {
+		record := []string{
+			indicator.Type,
+			indicator.Value,
+			strconv.Itoa(indicator.FirstLine),
+			strconv.Itoa(indicator.LastLine),
+			strconv.Itoa(indicator.Count),
+			strings.Join(indicator.Commands, " ; "),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// stixPattern construye el patrón STIX del indicador
+func stixPattern(indicator models.IOC) string {
+	if indicator.Type == TypeFilePath {
+		dir, name := path.Split(indicator.Value)
+		if dir == "" || name == "" {
+			return fmt.Sprintf("[directory:path = '%s']", escapePattern(indicator.Value))
+		}
+		return fmt.Sprintf("[file:name = '%s' AND file:parent_directory_ref.path = '%s']",
+			escapePattern(name), escapePattern(strings.TrimSuffix(dir, "/")))
+	}
+	return fmt.Sprintf(stixPatterns[indicator.Type], escapePattern(indicator.Value))
+}
+
+// escapePattern escapa las comillas simples y barras de un valor dentro de un patrón STIX
+func escapePattern(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+}
+
+// describe resume dónde apareció el indicador
+func describe(indicator models.IOC) string {
+	lines := fmt.Sprintf("línea %d", indicator.FirstLine)
+	if indicator.LastLine != indicator.FirstLine {
+		lines = fmt.Sprintf("líneas %d-%d", indicator.FirstLine, indicator.LastLine)
+	}
+	description := fmt.Sprintf("Apariciones en el historial: %d (%s)", indicator.Count, lines)
+	if len(indicator.Commands) > 0 {
+		description += ": " + strings.Join(indicator.Commands, " ; ")
+	}
+	return description
+}
+
+// uuid5 genera un UUID versión 5 (SHA-1) a partir del nombre
+func uuid5(name string) string {
+	hash := sha1.New()
+	hash.Write(stixNamespace[:])
+	hash.Write([]byte(name))
+	sum := hash.Sum(nil)
+
+	sum[6] = (sum[6] & 0x0f) | 0x50
+	sum[8] = (sum[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
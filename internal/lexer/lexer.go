@@ -9,18 +9,28 @@ import (
 )
 
 type Lexer struct {
-	input    string
-	position int
-	line     int
-	tokens   []models.Token
-	errors   []models.LexicalError
+	input         string
+	position      int
+	line          int
+	tokens        []models.Token
+	errors        []models.LexicalError
+	options       Options
+	powershell    bool // Gramática de PowerShell (NewPowerShellLexer)
+	expectCommand bool // PowerShell: la siguiente palabra es un comando
+}
+
+// Options ajusta el lexer a la sintaxis de un dialecto de shell; el valor cero es bash
+type Options struct {
+	BareSubstitution   bool // fish: (comando) es una sustitución de comandos
+	EqualsSubstitution bool // zsh: =(comando) es una sustitución de proceso con archivo temporal
+	GlobQualifiers     bool // zsh: los calificadores de glob (*(.), **/*.log(om[1,3])) son parte de la palabra
 }
 
 // Patrones regex para identificar tokens
 var (
 	urlPattern      = regexp.MustCompile(`https?://[^\s]+`)
 	pathPattern     = regexp.MustCompile(`[~/][\w\-\./_]*`)
-	flagPattern     = regexp.MustCompile(`-{1,2}[\w\-]+`)
+	flagPattern     = regexp.MustCompile(`^-{1,2}\w`)
 	variablePattern = regexp.MustCompile(`\$\{?[\w_]+\}?`)
 	numberPattern   = regexp.MustCompile(`^\d+$`)
 )
@@ -39,17 +49,27 @@ var dangerousCommands = map[string]bool{
 }
 
 func NewLexer(input string) *Lexer {
+	return NewLexerWithOptions(input, Options{})
+}
+
+// NewLexerWithOptions crea un lexer para un dialecto de la familia de sh (zsh, fish)
+func NewLexerWithOptions(input string, options Options) *Lexer {
 	return &Lexer{
 		input:    input,
 		position: 0,
 		line:     1,
 		tokens:   make([]models.Token, 0),
 		errors:   make([]models.LexicalError, 0),
+		options:  options,
 	}
 }
 
 func (l *Lexer) Tokenize() ([]models.Token, []models.LexicalError) {
 	for l.position < len(l.input) {
+		if l.powershell {
+			l.nextPowerShellToken()
+			continue
+		}
 		l.nextToken()
 	}
 
@@ -68,8 +88,8 @@ func (l *Lexer) nextToken() {
 
 	// Nueva línea
 	if l.current() == '\n' {
-		l.addToken(models.NEWLINE, "\n")
 		l.position++
+		l.addToken(models.NEWLINE, "\n")
 		l.line++
 		return
 	}
@@ -80,10 +100,28 @@ func (l *Lexer) nextToken() {
 		return
 	}
 
-	// Pipes
+	// Operadores lógicos || y &&
+	if l.peekIs("||") || l.peekIs("&&") {
+		operator := l.input[l.position : l.position+2]
+		l.position += 2
+		l.addToken(models.OPERATOR, operator)
+		return
+	}
+
+	// Pipes (|& también envía stderr)
 	if l.current() == '|' {
-		l.addToken(models.PIPE, "|")
+		start := l.position
 		l.position++
+		if l.peekIs("&") {
+			l.position++
+		}
+		l.addToken(models.PIPE, l.input[start:l.position])
+		return
+	}
+
+	// Sustituciones de comandos y procesos: $(...), <(...), >(...), `...`
+	if l.isSubstitutionStart() {
+		l.consumeSubstitution()
 		return
 	}
 
@@ -100,7 +138,7 @@ func (l *Lexer) nextToken() {
 	}
 
 	// Tokens de palabras
-	if l.isAlphaNumeric() {
+	if l.isWordStart() {
 		l.consumeWord()
 		return
 	}
@@ -111,6 +149,13 @@ func (l *Lexer) nextToken() {
 		return
 	}
 
+	// Operadores de test y [ ] (=, ==, !=, =~) y negación (! comando)
+	if length := l.testOperatorLength(); length > 0 {
+		l.position += length
+		l.addToken(models.ARGUMENT, l.input[l.position-length:l.position])
+		return
+	}
+
 	// Carácter no reconocido
 	l.addError("Carácter no reconocido: " + string(l.current()))
 	l.position++
@@ -119,9 +164,33 @@ func (l *Lexer) nextToken() {
 func (l *Lexer) consumeWord() {
 	start := l.position
 
-	// Consumir caracteres de palabra
-	for l.position < len(l.input) && (l.isAlphaNumeric() || l.current() == '-' || l.current() == '_' || l.current() == '.') {
-		l.position++
+	// Consumir caracteres de palabra; las comillas dentro de la palabra forman parte de ella
+	// (exec:'bash -li',pty es una sola palabra para la shell)
+	for l.position < len(l.input) {
+		// Direcciones IPv6 entre corchetes (usuario@[fd00::1]:22, http://[::1]:8080/)
+		if length := l.bracketedAddressLength(); length > 0 {
+			l.position += length
+			continue
+		}
+		if length := l.variableLength(); length > 0 {
+			l.position += length
+			continue
+		}
+		if l.isWordChar() {
+			l.position++
+			continue
+		}
+		if l.current() == '"' || l.current() == '\'' {
+			if closing := strings.IndexByte(l.input[l.position+1:], byte(l.current())); closing >= 0 {
+				l.position += closing + 2
+				continue
+			}
+		}
+		if length := l.globQualifierLength(start); length > 0 {
+			l.position += length
+			continue
+		}
+		break
 	}
 
 	word := l.input[start:l.position]
@@ -136,16 +205,21 @@ func (l *Lexer) classifyWord(word string, isCommand bool) models.TokenType {
 		return models.URL
 	}
 
+	// Flags (antes que paths para reconocer --output=/tmp/x)
+	if flagPattern.MatchString(word) {
+		return models.FLAG
+	}
+
+	// Ejecutables invocados por ruta (./script.sh, /bin/sh)
+	if isCommand && pathPattern.MatchString(word) {
+		return models.COMMAND
+	}
+
 	// Paths
 	if pathPattern.MatchString(word) {
 		return models.PATH
 	}
 
-	// Flags
-	if flagPattern.MatchString(word) {
-		return models.FLAG
-	}
-
 	// Variables
 	if variablePattern.MatchString(word) {
 		return models.VARIABLE
@@ -187,6 +261,62 @@ func (l *Lexer) consumeQuotedString() {
 	l.addToken(models.STRING, value)
 }
 
+// consumeSubstitution consume una sustitución completa respetando paréntesis anidados y comillas
+func (l *Lexer) consumeSubstitution() {
+	start := l.position
+
+	if l.current() == '`' {
+		l.position++
+		for l.position < len(l.input) && l.current() != '`' {
+			if l.current() == '\\' {
+				l.position++
+			}
+			l.position++
+		}
+		if l.position >= len(l.input) {
+			l.addError("Sustitución sin cerrar")
+			return
+		}
+		l.position++
+		l.addToken(models.SUBSTITUTION, l.input[start:l.position])
+		return
+	}
+
+	// $( , <( , >( , =( de zsh o ( de fish
+	if l.current() == '(' {
+		l.position++
+	} else {
+		l.position += 2
+	}
+	depth := 1
+	for l.position < len(l.input) && depth > 0 {
+		switch c := l.current(); c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case '\'', '"':
+			closing := strings.IndexByte(l.input[l.position+1:], byte(c))
+			if closing < 0 {
+				l.position = len(l.input)
+				continue
+			}
+			l.position += closing + 1
+		case '\\':
+			l.position++
+		case '\n':
+			l.line++
+		}
+		l.position++
+	}
+
+	if depth > 0 {
+		l.addError("Sustitución sin cerrar")
+		return
+	}
+	l.addToken(models.SUBSTITUTION, l.input[start:l.position])
+}
+
 func (l *Lexer) consumeComment() {
 	start := l.position
 
@@ -209,34 +339,89 @@ func (l *Lexer) consumeWhitespace() {
 	l.addToken(models.WHITESPACE, whitespace)
 }
 
+// consumeRedirect consume una redirección con su descriptor opcional:
+// >, >>, >|, >&, <, <<, <<<, <&, <>, &>, &>> y las variantes con descriptor (2>, 0>&, 2>&)
 func (l *Lexer) consumeRedirect() {
 	start := l.position
 
-	if l.current() == '>' {
+	// Descriptor de archivo (2>) o redirección de ambas salidas (&>)
+	for l.position < len(l.input) && unicode.IsDigit(l.current()) {
+		l.position++
+	}
+	if l.current() == '&' {
 		l.position++
-		if l.position < len(l.input) && l.current() == '>' {
+	}
+
+	switch l.current() {
+	case '>':
+		l.position++
+		if l.peekIs(">") || l.peekIs("|") || l.peekIs("&") {
 			l.position++
 		}
-	} else if l.current() == '<' {
+	case '<':
 		l.position++
+		if l.peekIs("<<") {
+			l.position += 2
+		} else if l.peekIs("<") || l.peekIs("&") || l.peekIs(">") {
+			l.position++
+		}
 	}
 
 	redirect := l.input[start:l.position]
 	l.addToken(models.REDIRECT, redirect)
 }
 
+// peekIs indica si el texto en la posición actual comienza con el prefijo dado
+func (l *Lexer) peekIs(prefix string) bool {
+	return strings.HasPrefix(l.input[l.position:], prefix)
+}
+
 func (l *Lexer) consumeOperator() {
 	operator := string(l.current())
-	l.addToken(models.OPERATOR, operator)
 	l.position++
+	l.addToken(models.OPERATOR, operator)
 }
 
 func (l *Lexer) isWhitespace() bool {
 	return l.current() == ' ' || l.current() == '\t'
 }
 
+// isSubstitutionStart indica si comienza una sustitución: $(, <(, >( o `, y según el dialecto
+// =( (zsh) o ( (fish)
+func (l *Lexer) isSubstitutionStart() bool {
+	return l.peekIs("$(") || l.peekIs("<(") || l.peekIs(">(") || l.current() == '`' ||
+		(l.options.EqualsSubstitution && l.peekIs("=(")) || (l.options.BareSubstitution && l.current() == '(')
+}
+
+// globQualifierLength devuelve la longitud del calificador de glob de zsh en la posición actual
+// (el (.) de *(.)) si la palabra que empieza en start es un glob, o 0
+func (l *Lexer) globQualifierLength(start int) int {
+	if !l.options.GlobQualifiers || l.current() != '(' || !strings.ContainsAny(l.input[start:l.position], "*?") {
+		return 0
+	}
+	end := strings.IndexByte(l.input[l.position:], ')')
+	if end < 1 || strings.ContainsAny(l.input[l.position:l.position+end], " \t\n") {
+		return 0
+	}
+	return end + 1
+}
+
 func (l *Lexer) isRedirect() bool {
-	return l.current() == '>' || l.current() == '<'
+	if l.current() == '>' || l.current() == '<' {
+		return true
+	}
+
+	// &> y &>> redirigen stdout y stderr
+	if l.current() == '&' {
+		return l.position+1 < len(l.input) && l.input[l.position+1] == '>'
+	}
+
+	// Descriptor seguido de redirección: 2>, 0>&1, 3<
+	end := l.position
+	for end < len(l.input) && unicode.IsDigit(rune(l.input[end])) {
+		end++
+	}
+	return end > l.position && end < len(l.input) && (l.input[end] == '>' || l.input[end] == '<')
 }
 
 func (l *Lexer) isAlphaNumeric() bool {
@@ -244,6 +429,88 @@ func (l *Lexer) isAlphaNumeric() bool {
 	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '/' || c == '~'
 }
 
+// isWordStart indica si el carácter actual puede iniciar una palabra (comando, flag, path o argumento)
+func (l *Lexer) isWordStart() bool {
+	c := l.current()
+	return l.isAlphaNumeric() || c == '-' || c == '.' || c == '+' || c == '_' || l.bracketedAddressLength() > 0 ||
+		l.variableLength() > 0 || (l.options.GlobQualifiers && c == '*')
+}
+
+// variableLength devuelve la longitud de la expansión de variable en la posición actual ($DIR,
+// ${DIR:-x}, $1, $?), o 0 si no la hay; forma parte de la palabra ($DIR/ es una sola palabra)
+func (l *Lexer) variableLength() int {
+	if l.current() != '$' || l.position+1 >= len(l.input) {
+		return 0
+	}
+	next := l.input[l.position+1]
+
+	switch {
+	case next == '{':
+		if end := strings.IndexByte(l.input[l.position:], '}'); end > 2 && !strings.ContainsAny(l.input[l.position:l.position+end], " \n") {
+			return end + 1
+		}
+		return 0
+	case next == '_' || unicode.IsLetter(rune(next)):
+		length := 2
+		for l.position+length < len(l.input) {
+			c := rune(l.input[l.position+length])
+			if c != '_' && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+				break
+			}
+			length++
+		}
+		return length
+	case unicode.IsDigit(rune(next)) || strings.IndexByte("?#@*!$-", next) >= 0:
+		return 2
+	}
+	return 0
+}
+
+// bracketedAddressLength devuelve la longitud de una dirección IPv6 entre corchetes en la posición
+// actual ([fd00::1], [fe80::1%eth0]), o 0 si no la hay; [ -f x ] y [[ ... ]] no son direcciones
+func (l *Lexer) bracketedAddressLength() int {
+	if l.current() != '[' {
+		return 0
+	}
+	end := strings.IndexByte(l.input[l.position:], ']')
+	if end < 3 {
+		return 0
+	}
+	address := l.input[l.position+1 : l.position+end]
+	if !strings.Contains(address, ":") {
+		return 0
+	}
+	zone := false
+	for _, c := range address {
+		switch {
+		case c == '%':
+			zone = true
+		case zone && (unicode.IsLetter(c) || unicode.IsDigit(c) || c == '.' || c == '_' || c == '-'):
+		case strings.ContainsRune("0123456789abcdefABCDEF:.", c):
+		default:
+			return 0
+		}
+	}
+	return end + 1
+}
+
+// isWordChar indica si el carácter actual puede continuar una palabra
+func (l *Lexer) isWordChar() bool {
+	return l.isWordStart() || strings.ContainsRune("@:=,%*?", l.current())
+}
+
+// testOperatorLength devuelve la longitud del operador de test en la posición actual si es una
+// palabra completa (seguida de espacio o fin de línea), o 0
+func (l *Lexer) testOperatorLength() int {
+	for _, operator := range []string{"==", "!=", "=~", "=", "!"} {
+		end := l.position + len(operator)
+		if l.peekIs(operator) && (end == len(l.input) || strings.IndexByte(" \t\n", l.input[end]) >= 0) {
+			return len(operator)
+		}
+	}
+	return 0
+}
+
 func (l *Lexer) isOperator() bool {
 	operators := ";&()[]{}*?$"
 	return strings.ContainsRune(operators, l.current())
@@ -261,6 +528,10 @@ func (l *Lexer) isStartOfCommand(pos int) bool {
 		if c == '\n' || c == ';' || c == '|' {
 			return true
 		}
+		// && y & en segundo plano, pero no las redirecciones >& y <&
+		if c == '&' && (i == 0 || (l.input[i-1] != '>' && l.input[i-1] != '<')) {
+			return true
+		}
 		if c != ' ' && c != '\t' {
 			return false
 		}
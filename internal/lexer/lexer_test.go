@@ -0,0 +1,42 @@
+package lexer
+
+import (
+	"testing"
+
+	"terminal-history-analyzer/internal/models"
+)
+
+func TestTokenizeFlags(t *testing.T) {
+	tests := []struct {
+		content string
+		value   string
+		want    models.TokenType
+	}{
+		{"rm -rf build", "-rf", models.FLAG},
+		{"docker run --rm -it ubuntu", "--rm", models.FLAG},
+		{"curl --output=/tmp/x http://example.com/a", "--output=/tmp/x", models.FLAG},
+		{"curl --output=/tmp/x http://example.com/a", "http://example.com/a", models.URL},
+		{"apt install build-essential", "build-essential", models.ARGUMENT},
+		{"echo hello-world", "hello-world", models.ARGUMENT},
+		{"ls -la /tmp", "/tmp", models.PATH},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.content+" "+tt.value, func(t *testing.T) {
+			tokens, _ := NewLexer(tt.content).Tokenize()
+			var found []models.Token
+			for _, token := range tokens {
+				if token.Value == tt.value {
+					if token.Type != tt.want {
+						t.Errorf("%q es %s, se esperaba %s", tt.value, token.Type, tt.want)
+					}
+					return
+				}
+				if token.Type != models.WHITESPACE {
+					found = append(found, token)
+				}
+			}
+			t.Errorf("no hay token %q: %v", tt.value, found)
+		})
+	}
+}
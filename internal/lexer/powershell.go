@@ -0,0 +1,308 @@
+package lexer
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"terminal-history-analyzer/internal/models"
+)
+
+// Patrones de palabras de PowerShell
+var (
+	psFlagPattern = regexp.MustCompile(`^-[A-Za-z][\w]*(:.*)?$`)
+	psPathPattern = regexp.MustCompile(`^([A-Za-z]:[\\/]|\\\\|\.{1,2}[\\/]|~[\\/]|[\w.-]+\\)`)
+)
+
+// NewPowerShellLexer crea un lexer para historiales de PowerShell (ConsoleHost_history.txt)
+func NewPowerShellLexer(input string) *Lexer {
+	l := NewLexer(input)
+	l.powershell = true
+	l.expectCommand = true
+	return l
+}
+
+// nextPowerShellToken reconoce el siguiente token con la gramática de PowerShell: el acento grave
+// escapa y continúa líneas, # y <# #> son comentarios, (...), $(...), @(...) y {...} son
+// sustituciones y -Parametro es una flag
+func (l *Lexer) nextPowerShellToken() {
+	c := l.current()
+
+	switch {
+	case l.isWhitespace() || c == '\r':
+		start := l.position
+		for l.position < len(l.input) && (l.isWhitespace() || l.current() == '\r') {
+			l.position++
+		}
+		l.addToken(models.WHITESPACE, l.input[start:l.position])
+
+	case l.peekIs("`\n") || l.peekIs("`\r\n"):
+		// Continuación de línea: el comando sigue en la línea siguiente
+		l.position = strings.IndexByte(l.input[l.position:], '\n') + l.position + 1
+		l.line++
+
+	case c == '\n':
+		l.position++
+		l.addToken(models.NEWLINE, "\n")
+		l.line++
+		l.expectCommand = true
+
+	case l.peekIs("<#"):
+		l.consumeUntil("#>", models.COMMENT)
+
+	case c == '#':
+		l.consumeComment()
+
+	case l.peekIs("@\"") || l.peekIs("@'"):
+		// Here-string: termina con "@ o '@ al inicio de una línea
+		l.consumeUntil("\n"+string(l.input[l.position+1])+"@", models.STRING)
+		l.expectCommand = false
+
+	case l.peekIs("&&") || l.peekIs("||") || c == ';':
+		length := 2
+		if c == ';' {
+			length = 1
+		}
+		l.position += length
+		l.addToken(models.OPERATOR, l.input[l.position-length:l.position])
+		l.expectCommand = true
+
+	case c == '|':
+		l.position++
+		l.addToken(models.PIPE, "|")
+		l.expectCommand = true
+
+	case l.expectCommand && (c == '&' || c == '.') && l.position+1 < len(l.input) &&
+		strings.IndexByte(" \t$'\"({", l.input[l.position+1]) >= 0:
+		// Operadores de invocación: & $cmd, & 'C:\ruta\app.exe', . .\perfil.ps1
+		l.position++
+		l.addToken(models.OPERATOR, string(c))
+
+	case l.isPowerShellRedirect():
+		l.consumePowerShellRedirect()
+
+	case c == '(' || l.peekIs("$(") || l.peekIs("@(") || c == '{' || l.peekIs("@{"):
+		l.consumePowerShellGroup()
+
+	case c == '"' || c == '\'':
+		start := l.position
+		l.position = l.powerShellQuoteEnd(l.position)
+		if l.position > len(l.input) {
+			l.position = len(l.input)
+			l.addError("String sin cerrar")
+			return
+		}
+		l.addPowerShellWord(l.input[start:l.position], models.STRING)
+
+	case c == '=' && !l.expectCommand:
+		// $x = Invoke-WebRequest ...: el valor de la asignación puede ser un comando
+		l.position++
+		l.addToken(models.OPERATOR, "=")
+		l.expectCommand = true
+
+	default:
+		l.consumePowerShellWord()
+	}
+}
+
+// consumeUntil consume hasta el terminador indicado (incluido) y agrega el token
+func (l *Lexer) consumeUntil(terminator string, tokenType models.TokenType) {
+	start := l.position
+	end := strings.Index(l.input[l.position+2:], terminator)
+	if end < 0 {
+		l.position = len(l.input)
+		l.addError("Bloque sin cerrar")
+	} else {
+		l.position += 2 + end + len(terminator)
+	}
+	value := l.input[start:l.position]
+	l.addToken(tokenType, value)
+	l.line += strings.Count(value, "\n")
+}
+
+// powerShellQuoteEnd devuelve la posición siguiente a la comilla que cierra la que empieza en start;
+// ” escapa la comilla simple y el acento grave escapa en comillas dobles
+func (l *Lexer) powerShellQuoteEnd(start int) int {
+	quote := l.input[start]
+	for i := start + 1; i < len(l.input); i++ {
+		switch {
+		case l.input[i] == '`' && quote == '"':
+			i++
+		case l.input[i] == quote && i+1 < len(l.input) && l.input[i+1] == quote:
+			i++
+		case l.input[i] == quote:
+			return i + 1
+		}
+	}
+	return len(l.input) + 1
+}
+
+// consumePowerShellGroup consume (...), $(...), @(...), {...} o @{...} con su anidamiento y los
+// accesos a miembros que le siguen: (New-Object Net.WebClient).DownloadString('http://x')
+func (l *Lexer) consumePowerShellGroup() {
+	start := l.position
+	if l.current() == '$' || l.current() == '@' {
+		l.position++
+	}
+
+	for {
+		if !l.skipPowerShellBrackets() {
+			l.addError("Sustitución sin cerrar")
+			l.position = len(l.input)
+			return
+		}
+		// Miembros encadenados: .Method(...), ::Metodo(...), [indice]
+		rest := l.input[l.position:]
+		member := 0
+		switch {
+		case strings.HasPrefix(rest, "::"):
+			member = 2
+		case strings.HasPrefix(rest, "."), strings.HasPrefix(rest, "["):
+			member = 1
+		}
+		if member == 0 {
+			break
+		}
+		if rest[0] != '[' {
+			l.position += member
+			for l.position < len(l.input) && (unicode.IsLetter(l.current()) || unicode.IsDigit(l.current()) || l.current() == '_') {
+				l.position++
+			}
+		}
+		if l.current() != '(' && l.current() != '[' {
+			break
+		}
+	}
+
+	value := l.input[start:l.position]
+	l.addToken(models.SUBSTITUTION, value)
+	l.line += strings.Count(value, "\n")
+	l.expectCommand = false
+}
+
+// skipPowerShellBrackets avanza hasta después del paréntesis, llave o corchete que cierra el de la
+// posición actual; devuelve false si no se cierra
+func (l *Lexer) skipPowerShellBrackets() bool {
+	depth := 0
+	for l.position < len(l.input) {
+		switch c := l.input[l.position]; c {
+		case '(', '{', '[':
+			depth++
+		case ')', '}', ']':
+			depth--
+			if depth == 0 {
+				l.position++
+				return true
+			}
+		case '\'', '"':
+			l.position = l.powerShellQuoteEnd(l.position) - 1
+		case '`':
+			l.position++
+		}
+		l.position++
+	}
+	return false
+}
+
+// isPowerShellRedirect indica si empieza una redirección: >, >>, 2>, 2>&1, *>, *>>
+func (l *Lexer) isPowerShellRedirect() bool {
+	end := l.position
+	for end < len(l.input) && (unicode.IsDigit(rune(l.input[end])) || l.input[end] == '*') {
+		end++
+	}
+	return end < len(l.input) && l.input[end] == '>' && end-l.position <= 1
+}
+
+func (l *Lexer) consumePowerShellRedirect() {
+	start := l.position
+	for l.current() != '>' {
+		l.position++
+	}
+	l.position++
+	if l.peekIs(">") {
+		l.position++
+	} else if l.peekIs("&1") || l.peekIs("&2") {
+		l.position += 2
+	}
+	l.addToken(models.REDIRECT, l.input[start:l.position])
+}
+
+// consumePowerShellWord consume una palabra hasta un espacio u operador; las comillas, variables
+// (${env:PATH}) y grupos pegados forman parte de ella
+func (l *Lexer) consumePowerShellWord() {
+	start := l.position
+	for l.position < len(l.input) {
+		c := l.current()
+		if c == '"' || c == '\'' {
+			l.position = min(l.powerShellQuoteEnd(l.position), len(l.input))
+			continue
+		}
+		if c == '`' && l.position+1 < len(l.input) && l.input[l.position+1] != '\n' && l.input[l.position+1] != '\r' {
+			l.position += 2
+			continue
+		}
+		// Llamadas y accesos pegados a una expresión: $wc.DownloadString(...), $env:PATH[0],
+		// ${env:TEMP}, [Convert]::FromBase64String(...); en iex(...) el grupo es un argumento
+		expression := l.input[start] == '$' || l.input[start] == '['
+		if (c == '[' || c == '{' || c == '(' && expression) && l.position > start {
+			if !l.skipPowerShellBrackets() {
+				l.position = len(l.input)
+			}
+			continue
+		}
+		if unicode.IsSpace(c) || strings.ContainsRune(";|()}>", c) || c == '&' && l.peekIs("&&") ||
+			c == '=' && l.expectCommand && l.position > start && l.input[start] == '$' {
+			break
+		}
+		l.position++
+	}
+
+	if l.position == start {
+		l.addError("Carácter no reconocido: " + string(l.current()))
+		l.position++
+		return
+	}
+
+	word := l.input[start:l.position]
+	tokenType := l.classifyPowerShellWord(word)
+	l.addPowerShellWord(word, tokenType)
+	l.line += strings.Count(word, "\n")
+}
+
+// addPowerShellWord agrega una palabra: en posición de comando pasa a ser el comando; un string solo
+// es un comando tras un operador de invocación (& 'C:\Program Files\app.exe')
+func (l *Lexer) addPowerShellWord(word string, tokenType models.TokenType) {
+	invoked := tokenType != models.STRING || l.afterCallOperator()
+	if l.expectCommand && invoked && tokenType != models.VARIABLE && tokenType != models.NUMBER && tokenType != models.FLAG {
+		tokenType = models.COMMAND
+	}
+	l.addToken(tokenType, word)
+	l.expectCommand = false
+}
+
+func (l *Lexer) classifyPowerShellWord(word string) models.TokenType {
+	lower := strings.ToLower(word)
+	switch {
+	case strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://"):
+		return models.URL
+	case psFlagPattern.MatchString(word):
+		return models.FLAG
+	case strings.HasPrefix(word, "$"):
+		return models.VARIABLE
+	case numberPattern.MatchString(word):
+		return models.NUMBER
+	case psPathPattern.MatchString(word) || strings.HasPrefix(word, "/") || strings.HasPrefix(word, "~"):
+		return models.PATH
+	}
+	return models.ARGUMENT
+}
+
+// afterCallOperator indica si el último token significativo es un operador de invocación (& o .)
+func (l *Lexer) afterCallOperator() bool {
+	for i := len(l.tokens) - 1; i >= 0; i-- {
+		if l.tokens[i].Type != models.WHITESPACE {
+			return l.tokens[i].Type == models.OPERATOR && (l.tokens[i].Value == "&" || l.tokens[i].Value == ".")
+		}
+	}
+	return false
+}
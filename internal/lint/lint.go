@@ -0,0 +1,572 @@
+package lint
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"terminal-history-analyzer/internal/attack"
+	"terminal-history-analyzer/internal/models"
+)
+
+// Modos de análisis del contenido: historial de comandos (por defecto) o script
+const (
+	ModeHistory = "history"
+	ModeScript  = "script"
+)
+
+// ValidMode indica si el modo es uno de los admitidos; vacío equivale a history
+func ValidMode(mode string) bool {
+	return mode == "" || mode == ModeHistory || mode == ModeScript
+}
+
+// Report es el resultado del linter: las reglas de estilo como errores de sintaxis con su código y
+// las de seguridad como amenazas
+type Report struct {
+	Script  models.ScriptInfo
+	Errors  []models.SyntaxError
+	Threats []models.ThreatDetection
+}
+
+// posixShells son los intérpretes que solo garantizan la sintaxis de sh POSIX
+var posixShells = map[string]bool{"sh": true, "dash": true, "ash": true, "posh": true}
+
+// interpreters ejecutan el argumento de -c como código
+var interpreters = map[string]bool{"sh": true, "bash": true, "dash": true, "zsh": true, "ksh": true}
+
+// wrappers ejecutan el comando que reciben como argumento
+var wrappers = map[string]bool{"sudo": true, "doas": true, "nohup": true, "exec": true, "command": true, "builtin": true}
+
+// wrapperValueFlags son las opciones de sudo y doas que consumen el argumento siguiente
+var wrapperValueFlags = map[string]bool{"-u": true, "-g": true, "-C": true, "-h": true, "-p": true}
+
+// optionCommands interpretan como opción un operando que empiece por -, con las opciones cortas que
+// consumen el argumento siguiente
+var optionCommands = map[string]string{
+	"rm": "", "cp": "tS", "mv": "tS", "ln": "tS", "chmod": "", "chown": "", "chgrp": "",
+	"cat": "", "grep": "efm", "ls": "", "touch": "dr", "mkdir": "m", "rmdir": "",
+	"ssh": "ilpoFJ", "scp": "ilPoFJ", "rsync": "e",
+}
+
+// declarationCommands asignan las variables que reciben como argumentos
+var declarationCommands = map[string]bool{
+	"export": true, "local": true, "declare": true, "typeset": true, "readonly": true,
+}
+
+// readValueFlags son las opciones de read que consumen el argumento siguiente
+var readValueFlags = map[string]bool{"-p": true, "-d": true, "-n": true, "-N": true, "-t": true, "-u": true, "-i": true}
+
+// redirectPrefix es el operador de una redirección pegado a su destino (>$LOG, 2>>$LOG)
+var redirectPrefix = regexp.MustCompile(`^[0-9]*[<>&]+`)
+
+// caseTerminators cierran una rama de case; después viene el patrón de la siguiente
+var caseTerminators = map[string]bool{";;": true, ";&": true, ";;&": true}
+
+// linter recorre el script en orden con las opciones del shell y las variables que provienen de la
+// entrada del usuario
+type linter struct {
+	report       Report
+	errexit      bool
+	expectsLabel bool            // La siguiente sentencia empieza por un patrón de case (start|stop))
+	tainted      map[string]bool // Variables con entrada del usuario (read, $1, getopts, ...)
+}
+
+// Shebang devuelve el intérprete y las opciones que indica la primera línea del script
+func Shebang(content string) models.ScriptInfo {
+	info := models.ScriptInfo{Shell: "bash"}
+	first, _, _ := strings.Cut(content, "\n")
+	first = strings.TrimSpace(first)
+	if !strings.HasPrefix(first, "#!") {
+		return info
+	}
+	info.Shebang = first
+
+	fields := strings.Fields(first[2:])
+	if len(fields) == 0 {
+		return info
+	}
+	interpreter, options := path.Base(fields[0]), fields[1:]
+	if interpreter == "env" {
+		for len(options) > 0 && strings.HasPrefix(options[0], "-") {
+			options = options[1:] // env -S
+		}
+		if len(options) == 0 {
+			return info
+		}
+		interpreter, options = path.Base(options[0]), options[1:]
+	}
+	info.Shell = interpreter
+	info.POSIX = posixShells[interpreter]
+
+	for i, option := range options {
+		if option == "-o" && i+1 < len(options) {
+			info.Errexit = info.Errexit || options[i+1] == "errexit"
+			info.Nounset = info.Nounset || options[i+1] == "nounset"
+		} else if strings.HasPrefix(option, "-") && !strings.HasPrefix(option, "--") {
+			info.Errexit = info.Errexit || strings.Contains(option, "e")
+			info.Nounset = info.Nounset || strings.Contains(option, "u")
+		}
+	}
+	return info
+}
+
+// Lint aplica las reglas del linter al script
+func Lint(content string) Report {
+	l := &linter{tainted: make(map[string]bool)}
+	l.report.Script = Shebang(content)
+	l.report.Script.Findings = make(map[string]int)
+	l.errexit = l.report.Script.Errexit
+
+	for _, line := range logicalLines(content) {
+		for _, st := range statements(line) {
+			l.check(st)
+		}
+	}
+	return l.report
+}
+
+// check aplica las reglas a una sentencia y actualiza el estado del script
+func (l *linter) check(st statement) {
+	defer func() {
+		if caseTerminators[st.next] {
+			l.expectsLabel = true
+		}
+	}()
+	if len(st.words) == 0 {
+		return
+	}
+	if l.expectsLabel {
+		if !l.stripLabel(&st) {
+			return
+		}
+	}
+	if !st.normalize() {
+		return
+	}
+
+	command := commandIndex(st.words)
+	if command >= len(st.words) {
+		l.assign(st.words)
+		return
+	}
+	name := st.words[command]
+	args := st.words[command+1:]
+
+	switch name {
+	case "case":
+		l.expectsLabel = true
+		return
+	case "for", "select":
+		l.forLoop(args)
+		return
+	case "((":
+		return
+	case "[[":
+		if l.report.Script.POSIX {
+			l.addError(st, "SH004", "[[ ]] no existe en "+l.report.Script.Shell, command,
+				"Use [ ] con = o cambie el shebang a bash")
+		}
+		l.track(name, args)
+		return
+	}
+
+	l.unquotedVariables(st)
+	l.cdWithoutExit(st, name)
+	l.uselessCat(st, name, args)
+	l.posixEquality(st, name, command, args)
+	l.endOfOptions(st, name, command, args)
+	l.evalOnInput(st, name, args)
+	l.track(name, args)
+}
+
+// stripLabel quita el patrón de case al inicio de la sentencia; false si la sentencia es solo una
+// alternativa del patrón (start en start|restart))
+func (l *linter) stripLabel(st *statement) bool {
+	if st.words[0] == "esac" {
+		l.expectsLabel = false
+		return true
+	}
+	for i, word := range st.words {
+		if word == ")" {
+			l.expectsLabel = false
+			st.words = st.words[i+1:]
+			return len(st.words) > 0
+		}
+	}
+	return st.next != "|"
+}
+
+// normalize quita las palabras reservadas, las cabeceras de función y las agrupaciones que rodean
+// al comando; false si no queda comando (fi, done, then)
+func (st *statement) normalize() bool {
+	words := st.words
+	for stripped := true; stripped && len(words) > 0; {
+		word := words[0]
+		switch {
+		case word == "if" || word == "elif" || word == "while" || word == "until" || word == "!":
+			st.condition = true
+			words = words[1:]
+		case word == "then" || word == "else" || word == "do" || word == "{" || word == "(" || word == "time":
+			words = words[1:]
+		case word == "function" && len(words) > 1:
+			words = words[2:]
+		case strings.HasSuffix(word, "()") && len(word) > 2:
+			words = words[1:]
+		case len(words) > 2 && words[1] == "(" && words[2] == ")":
+			words = words[3:]
+		default:
+			stripped = false
+		}
+	}
+	for len(words) > 0 && words[len(words)-1] == ")" {
+		words = words[:len(words)-1]
+	}
+	if len(words) == 0 {
+		return false
+	}
+	switch words[0] {
+	case "fi", "done", "esac", "}", ")", "in":
+		return false
+	}
+	st.words = words
+	return true
+}
+
+// commandIndex devuelve la posición del comando que se ejecuta, tras las asignaciones y los
+// envoltorios (sudo -u root rm -> rm); len(words) si la sentencia solo asigna variables o no llega a
+// nombrar el comando (sudo -u)
+func commandIndex(words []string) int {
+	i := 0
+	for i < len(words) && isAssignment(words[i]) {
+		i++
+	}
+	for i < len(words) && wrappers[words[i]] {
+		i++
+		for i < len(words) && strings.HasPrefix(words[i], "-") {
+			if wrapperValueFlags[words[i]] && i+1 < len(words) {
+				i++
+			}
+			i++
+		}
+	}
+	return i
+}
+
+// unquotedVariables (SH001) detecta variables sin comillas dobles fuera de asignaciones
+func (l *linter) unquotedVariables(st statement) {
+	var names []string
+	seen := make(map[string]bool)
+	rewrite := append([]string(nil), st.words...)
+	position := -1
+
+	for i, word := range st.words {
+		if isAssignment(word) {
+			continue
+		}
+		unquoted := false
+		for _, e := range expansions(word) {
+			if e.quoted || specialParameters[e.name] {
+				continue
+			}
+			unquoted = true
+			if !seen[e.name] {
+				seen[e.name] = true
+				names = append(names, "$"+e.name)
+			}
+		}
+		if unquoted {
+			prefix := redirectPrefix.FindString(word)
+			rewrite[i] = prefix + quoteWord(word[len(prefix):])
+			if position < 0 {
+				position = i
+			}
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+
+	l.addError(st, "SH001", fmt.Sprintf("Variable sin comillas: %s", strings.Join(names, ", ")), position,
+		"Use comillas dobles: "+strings.Join(rewrite, " "))
+}
+
+// cdWithoutExit (SH002) detecta cd y pushd cuyo fallo no detiene el script
+func (l *linter) cdWithoutExit(st statement, name string) {
+	if name != "cd" && name != "pushd" {
+		return
+	}
+	if st.condition || st.next == "||" || st.next == "&&" || l.errexit {
+		return
+	}
+	text := strings.Join(st.words, " ")
+	l.addError(st, "SH002", fmt.Sprintf("%s sin comprobar el resultado", name), 0, "Use: "+text+" || exit 1")
+}
+
+// uselessCat (SH003) detecta cat de un único archivo al inicio de una tubería
+func (l *linter) uselessCat(st statement, name string, args []string) {
+	if name != "cat" || st.next != "|" || st.prev == "|" || len(args) != 1 {
+		return
+	}
+	file := args[0]
+	if strings.HasPrefix(file, "-") || strings.ContainsAny(file, "*?[<>") {
+		return
+	}
+	l.addError(st, "SH003", "cat innecesario: "+file+" se puede leer directamente", 0,
+		"Use: comando < "+file+" o pase "+file+" como argumento")
+}
+
+// posixEquality (SH004) detecta == dentro de [ ] o test cuando el intérprete es sh POSIX
+func (l *linter) posixEquality(st statement, name string, command int, args []string) {
+	if !l.report.Script.POSIX || (name != "[" && name != "test") {
+		return
+	}
+	for i, arg := range args {
+		if arg == "==" {
+			l.addError(st, "SH004", fmt.Sprintf("== en %s no es POSIX; %s solo admite =", name, l.report.Script.Shell),
+				command+1+i, "Use = en lugar de ==")
+			return
+		}
+	}
+}
+
+// endOfOptions (SH005) detecta entrada del usuario como operando de un comando sin -- previo
+func (l *linter) endOfOptions(st statement, name string, command int, args []string) {
+	valueFlags, ok := optionCommands[name]
+	if !ok {
+		return
+	}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return
+		}
+		if strings.HasPrefix(arg, "-") {
+			if len(arg) == 2 && strings.ContainsRune(valueFlags, rune(arg[1])) {
+				i++
+			}
+			continue
+		}
+		if redirectPrefix.MatchString(arg) || !l.fromInput(arg) {
+			continue
+		}
+
+		at := command + 1 + i
+		rewrite := strings.Join(st.words[:at], " ") + " -- " + strings.Join(st.words[at:], " ")
+		l.addThreat(st, "SH005", fmt.Sprintf("%s recibe %s sin -- previo: un valor que empiece por - se tomaría como opción",
+			name, arg), models.Suggestion{Explanation: "Marcar el fin de las opciones con --", Rewrite: rewrite})
+		return
+	}
+}
+
+// evalOnInput (SH006) detecta eval, sh -c y source con código que proviene de la entrada
+func (l *linter) evalOnInput(st statement, name string, args []string) {
+	var code string
+	switch {
+	case name == "eval":
+		code = strings.Join(args, " ")
+	case name == "source" || name == ".":
+		if len(args) > 0 {
+			code = args[0]
+		}
+	case interpreters[path.Base(name)]:
+		for i, arg := range args {
+			if strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && strings.Contains(arg, "c") && i+1 < len(args) {
+				code = args[i+1]
+				break
+			}
+		}
+	}
+	if code == "" || !l.fromInput(code) {
+		return
+	}
+	l.addThreat(st, "SH006", fmt.Sprintf("%s ejecuta como código la entrada del usuario (%s)", name, code),
+		models.Suggestion{Explanation: "Validar la entrada contra una lista de valores permitidos (case) y ejecutar el comando sin eval"})
+}
+
+// fromInput indica si la palabra expande una variable con entrada del usuario
+func (l *linter) fromInput(word string) bool {
+	for _, e := range expansions(word) {
+		if l.isInput(e.name) {
+			return true
+		}
+	}
+	return false
+}
+
+// isInput indica si el parámetro proviene de la entrada: posicionales, read, getopts o derivadas
+func (l *linter) isInput(name string) bool {
+	if name == "@" || name == "*" || (len(name) == 1 && name[0] >= '1' && name[0] <= '9') {
+		return true
+	}
+	return l.tainted[name]
+}
+
+// track actualiza las opciones del shell y las variables con entrada del usuario
+func (l *linter) track(name string, args []string) {
+	switch {
+	case name == "set":
+		l.setOptions(args)
+	case declarationCommands[name]:
+		l.assign(args)
+	case name == "read":
+		l.read(args)
+	case name == "getopts":
+		l.tainted["OPTARG"] = true
+		if len(args) > 1 {
+			l.tainted[args[1]] = true
+		}
+	case name == "mapfile" || name == "readarray":
+		target := "MAPFILE"
+		for _, arg := range args {
+			if !strings.HasPrefix(arg, "-") {
+				target = arg
+			}
+		}
+		l.tainted[target] = true
+	}
+}
+
+// assign marca o desmarca como entrada las variables asignadas según el valor
+func (l *linter) assign(words []string) {
+	for _, word := range words {
+		match := assignmentWord.FindStringSubmatch(word)
+		if match == nil {
+			continue
+		}
+		if l.fromInput(word[len(match[0]):]) {
+			l.tainted[match[1]] = true
+		} else {
+			delete(l.tainted, match[1])
+		}
+	}
+}
+
+// read marca como entrada las variables que lee read (REPLY si no se indica ninguna)
+func (l *linter) read(args []string) {
+	var names []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "-a" && i+1 < len(args):
+			i++
+			names = append(names, args[i])
+		case readValueFlags[args[i]]:
+			i++
+		case strings.HasPrefix(args[i], "-"):
+		default:
+			names = append(names, args[i])
+		}
+	}
+	if len(names) == 0 {
+		names = []string{"REPLY"}
+	}
+	for _, name := range names {
+		l.tainted[name] = true
+	}
+}
+
+// forLoop marca la variable del bucle si recorre la entrada (for f in "$@"; for f; do)
+func (l *linter) forLoop(args []string) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "((") {
+		return
+	}
+	variable := args[0]
+	if len(args) == 1 || args[1] != "in" {
+		l.tainted[variable] = true
+		return
+	}
+	for _, word := range args[2:] {
+		if l.fromInput(word) {
+			l.tainted[variable] = true
+			return
+		}
+	}
+	delete(l.tainted, variable)
+}
+
+// setOptions sigue set -e, set +e, set -o errexit y set -u
+func (l *linter) setOptions(args []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || len(arg) < 2 || (arg[0] != '-' && arg[0] != '+') {
+			break
+		}
+		enable := arg[0] == '-'
+		if arg[1:] == "o" {
+			if i+1 < len(args) {
+				i++
+				l.setOption(args[i], enable)
+			}
+			continue
+		}
+		for _, flag := range arg[1:] {
+			switch flag {
+			case 'e':
+				l.setOption("errexit", enable)
+			case 'u':
+				l.setOption("nounset", enable)
+			}
+		}
+	}
+}
+
+// setOption activa o desactiva una opción del shell
+func (l *linter) setOption(option string, enable bool) {
+	switch option {
+	case "errexit":
+		l.errexit = enable
+		l.report.Script.Errexit = l.report.Script.Errexit || enable
+	case "nounset":
+		l.report.Script.Nounset = l.report.Script.Nounset || enable
+	}
+}
+
+// addError registra el hallazgo de una regla de estilo como error de sintaxis
+func (l *linter) addError(st statement, code, message string, word int, suggestion string) {
+	rule := ruleByCode(code)
+	position := wordOffset(st.words, word)
+	l.report.Errors = append(l.report.Errors, models.SyntaxError{
+		Message:  code + ": " + message,
+		Line:     st.line,
+		Command:  strings.Join(st.words, " "),
+		Position: position,
+		Type:     "lint",
+		Code:     code,
+		Severity: rule.Severity,
+		Validation: models.SyntaxValidation{
+			IsValidCommand: true,
+			StructureErrors: []models.StructureError{{
+				Type:        rule.Name,
+				Description: rule.Description,
+				Position:    position,
+				Suggestion:  suggestion,
+			}},
+		},
+	})
+	l.report.Script.Findings[code]++
+}
+
+// addThreat registra el hallazgo de una regla de seguridad como amenaza
+func (l *linter) addThreat(st statement, code, description string, suggestion models.Suggestion) {
+	rule := ruleByCode(code)
+	l.report.Threats = append(l.report.Threats, models.ThreatDetection{
+		RuleID:      code,
+		Type:        rule.ThreatType,
+		Level:       rule.Level,
+		Description: description,
+		Command:     strings.Join(st.words, " "),
+		Line:        st.line,
+		Suggestions: []models.Suggestion{suggestion},
+		Attack:      attack.ForType(rule.ThreatType),
+	})
+	l.report.Script.Findings[code]++
+}
+
+// wordOffset devuelve la posición de la palabra en el texto del comando
+func wordOffset(words []string, index int) int {
+	offset := 0
+	for _, word := range words[:index] {
+		offset += len(word) + 1
+	}
+	return offset
+}
@@ -0,0 +1,56 @@
+package lint
+
+import "testing"
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		findings map[string]int
+	}{
+		{
+			name:     "sudo -u sin valor",
+			content:  "#!/bin/bash\nsudo -u\n",
+			findings: map[string]int{},
+		},
+		{
+			name:     "sudo -p sin valor",
+			content:  "#!/bin/bash\nsudo -p\n",
+			findings: map[string]int{},
+		},
+		{
+			name:     "doas -u sin valor",
+			content:  "#!/bin/sh\ndoas -u\n",
+			findings: map[string]int{},
+		},
+		{
+			name:     "variable sin comillas tras sudo -u",
+			content:  "#!/bin/bash\nsudo -u root rm -rf $DIR\n",
+			findings: map[string]int{"SH001": 1},
+		},
+		{
+			name:     "cd sin exit",
+			content:  "#!/bin/bash\ncd /opt/app\nmake\n",
+			findings: map[string]int{"SH002": 1},
+		},
+		{
+			name:     "[[ ]] en sh",
+			content:  "#!/bin/sh\nif [[ -f x ]]; then echo si; fi\n",
+			findings: map[string]int{"SH004": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Lint(tt.content)
+			for code, want := range tt.findings {
+				if got := report.Script.Findings[code]; got != want {
+					t.Errorf("Findings[%s] = %d, se esperaba %d (%v)", code, got, want, report.Script.Findings)
+				}
+			}
+			if len(tt.findings) == 0 && len(report.Script.Findings) != 0 {
+				t.Errorf("Findings = %v, no se esperaban hallazgos", report.Script.Findings)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package lint
+
+import "terminal-history-analyzer/internal/models"
+
+// Severidades de las reglas de estilo; las reglas de seguridad se reportan como amenazas
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityStyle   = "style"
+)
+
+// Rule es una regla del linter de scripts. Las reglas con ThreatType se reportan como amenazas
+// (ThreatDetection); el resto como errores de sintaxis con su código.
+type Rule struct {
+	Code        string             `json:"code"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Severity    string             `json:"severity"`
+	ThreatType  string             `json:"threat_type,omitempty"`
+	Level       models.ThreatLevel `json:"level,omitempty"`
+	ShellCheck  string             `json:"shellcheck,omitempty"` // Regla equivalente de ShellCheck
+	Suggestion  string             `json:"suggestion"`
+}
+
+// rules es el catálogo del linter en el orden en que se documenta
+var rules = []Rule{
+	{
+		Code:        "SH001",
+		Name:        "unquoted_variable",
+		Description: "Variable sin comillas: se divide en palabras y se expanden sus comodines",
+		Severity:    SeverityWarning,
+		ShellCheck:  "SC2086",
+		Suggestion:  "Encierre la expansión entre comillas dobles",
+	},
+	{
+		Code:        "SH002",
+		Name:        "cd_without_exit",
+		Description: "cd sin comprobar el resultado: si falla, los comandos siguientes se ejecutan en otro directorio",
+		Severity:    SeverityWarning,
+		ShellCheck:  "SC2164",
+		Suggestion:  "Añada || exit (o active set -e) para detener el script si cd falla",
+	},
+	{
+		Code:        "SH003",
+		Name:        "useless_cat",
+		Description: "cat de un solo archivo hacia una tubería: el comando siguiente puede leer el archivo",
+		Severity:    SeverityStyle,
+		ShellCheck:  "SC2002",
+		Suggestion:  "Pase el archivo como argumento o redirija la entrada (< archivo)",
+	},
+	{
+		Code:        "SH004",
+		Name:        "posix_bashism",
+		Description: "Sintaxis de bash en un script para sh POSIX ([ x == y ], [[ ]])",
+		Severity:    SeverityError,
+		ShellCheck:  "SC3014",
+		Suggestion:  "Use = dentro de [ ] o cambie el shebang a bash",
+	},
+	{
+		Code:        "SH005",
+		Name:        "missing_end_of_options",
+		Description: "Entrada del usuario como operando sin -- previo: un valor que empiece por - se interpreta como opción",
+		Severity:    SeverityWarning,
+		ThreatType:  "option_injection",
+		Level:       models.MEDIUM,
+		Suggestion:  "Añada -- antes de los operandos que vienen de la entrada",
+	},
+	{
+		Code:        "SH006",
+		Name:        "eval_on_input",
+		Description: "eval, sh -c o source sobre entrada del usuario: ejecuta código arbitrario",
+		Severity:    SeverityError,
+		ThreatType:  "code_injection",
+		Level:       models.HIGH,
+		Suggestion:  "Evite eval; use arrays, case o validación con una lista de valores permitidos",
+	},
+}
+
+// Rules devuelve el catálogo de reglas del linter
+func Rules() []Rule {
+	return append([]Rule(nil), rules...)
+}
+
+// ruleByCode devuelve la regla con el código indicado
+func ruleByCode(code string) Rule {
+	for _, rule := range rules {
+		if rule.Code == code {
+			return rule
+		}
+	}
+	return Rule{Code: code}
+}
@@ -0,0 +1,198 @@
+package lint
+
+import (
+	"regexp"
+	"strings"
+)
+
+// heredocPattern reconoce el inicio de un here-document (<<EOF, <<-EOF, <<'EOF') pero no <<<
+var heredocPattern = regexp.MustCompile(`(?:^|[^<])<<(-?)[ \t]*(?:'([^']+)'|"([^"]+)"|\\?([A-Za-z_][A-Za-z0-9_.-]*))`)
+
+// logicalLine es una línea del script con sus continuaciones (\ al final) unidas
+type logicalLine struct {
+	number int
+	text   string
+}
+
+// item es una palabra o un operador de control (;, &&, ||, |, &, ;;) de una línea
+type item struct {
+	value    string
+	operator bool
+}
+
+// statement es un comando simple del script con sus palabras tal como se escribieron
+type statement struct {
+	line      int
+	words     []string
+	prev      string // Operador anterior (|, &&, ...); vacío al inicio de la línea
+	next      string // Operador siguiente; vacío al final de la línea
+	condition bool   // Condición de if, elif, while o until, o negada con !
+}
+
+// heredoc es un here-document pendiente: su cuerpo no se analiza como comandos
+type heredoc struct {
+	delimiter string
+	tabs      bool // <<- permite tabuladores antes del delimitador
+}
+
+// logicalLines divide el script en líneas lógicas, sin los cuerpos de los here-documents
+func logicalLines(content string) []logicalLine {
+	lines := strings.Split(content, "\n")
+	var result []logicalLine
+	var pending []heredoc
+
+	for i := 0; i < len(lines); i++ {
+		text := strings.TrimRight(lines[i], "\r")
+		if len(pending) > 0 {
+			if pending[0].tabs {
+				text = strings.TrimLeft(text, "\t")
+			}
+			if text == pending[0].delimiter {
+				pending = pending[1:]
+			}
+			continue
+		}
+
+		number := i + 1
+		for strings.HasSuffix(text, `\`) && i+1 < len(lines) {
+			i++
+			text = text[:len(text)-1] + " " + strings.TrimRight(lines[i], "\r")
+		}
+		result = append(result, logicalLine{number: number, text: text})
+
+		for _, match := range heredocPattern.FindAllStringSubmatch(text, -1) {
+			pending = append(pending, heredoc{delimiter: match[2] + match[3] + match[4], tabs: match[1] == "-"})
+		}
+	}
+	return result
+}
+
+// tokenize separa una línea en palabras y operadores de control respetando comillas, sustituciones
+// y comentarios
+func tokenize(text string) []item {
+	var items []item
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			items = append(items, item{value: current.String()})
+			current.Reset()
+		}
+	}
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text):
+			current.WriteString(text[i : i+2])
+			i++
+		case c == '\'' || c == '"' || c == '`':
+			end := closingQuote(text, i)
+			current.WriteString(text[i : end+1])
+			i = end
+		case (c == '$' || c == '<' || c == '>') && i+1 < len(text) && text[i+1] == '(':
+			end := closingParen(text, i+1)
+			current.WriteString(text[i : end+1])
+			i = end
+		case c == '$' && i+1 < len(text) && text[i+1] == '{':
+			end := strings.IndexByte(text[i:], '}')
+			if end < 0 {
+				end = len(text) - i - 1
+			}
+			current.WriteString(text[i : i+end+1])
+			i += end
+		case c == '#' && current.Len() == 0:
+			flush()
+			return items
+		case c == ' ' || c == '\t':
+			flush()
+		case c == '&' && (strings.HasSuffix(current.String(), ">") || strings.HasSuffix(current.String(), "<") ||
+			(i+1 < len(text) && text[i+1] == '>')):
+			current.WriteByte(c) // Redirecciones 2>&1 y &>
+		case c == ';' || c == '|' || c == '&':
+			flush()
+			operator := string(c)
+			if i+1 < len(text) && (text[i+1] == c || (c == '|' && text[i+1] == '&')) {
+				operator += string(text[i+1])
+				i++
+			}
+			items = append(items, item{value: operator, operator: true})
+		case c == '(' && current.Len() == 0, c == ')' && !strings.Contains(current.String(), "("):
+			flush()
+			items = append(items, item{value: string(c)}) // Subshell, agrupación o patrón de case
+		default:
+			current.WriteByte(c)
+		}
+	}
+	flush()
+	return items
+}
+
+// statements divide la línea en comandos simples; dentro de [[ ]] && y || no separan comandos
+func statements(line logicalLine) []statement {
+	var result []statement
+	var words []string
+	prev := ""
+	inTest := false
+
+	add := func(next string) {
+		// Una línea con solo ;; cierra la rama de case aunque no tenga comando
+		if len(words) > 0 || caseTerminators[next] {
+			result = append(result, statement{line: line.number, words: words, prev: prev, next: next})
+		}
+		words = nil
+		prev = next
+	}
+
+	for _, it := range tokenize(line.text) {
+		switch {
+		case !it.operator:
+			if it.value == "[[" {
+				inTest = true
+			} else if it.value == "]]" {
+				inTest = false
+			}
+			words = append(words, it.value)
+		case inTest && (it.value == "&&" || it.value == "||"):
+			words = append(words, it.value)
+		default:
+			add(it.value)
+		}
+	}
+	add("")
+	return result
+}
+
+// closingQuote devuelve la posición de la comilla que cierra la que empieza en start
+func closingQuote(text string, start int) int {
+	quote := text[start]
+	for i := start + 1; i < len(text); i++ {
+		switch {
+		case text[i] == '\\' && quote != '\'':
+			i++
+		case text[i] == quote:
+			return i
+		}
+	}
+	return len(text) - 1
+}
+
+// closingParen devuelve la posición del paréntesis que cierra el que empieza en open
+func closingParen(text string, open int) int {
+	depth := 0
+	for i := open; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '\'', '"', '`':
+			i = closingQuote(text, i)
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(text) - 1
+}
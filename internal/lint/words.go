@@ -0,0 +1,93 @@
+package lint
+
+import (
+	"regexp"
+	"strings"
+)
+
+// assignmentWord reconoce una asignación NOMBRE=valor (también NOMBRE+=valor)
+var assignmentWord = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\+?=`)
+
+// expansion es una referencia a una variable dentro de una palabra
+type expansion struct {
+	name   string // Nombre de la variable o parámetro especial (1, @, #, ?)
+	quoted bool   // Dentro de comillas dobles
+}
+
+// specialParameters no se dividen en palabras útiles: su valor es un número o un único carácter
+var specialParameters = map[string]bool{"#": true, "?": true, "$": true, "!": true, "-": true}
+
+// expansions devuelve las variables que se expanden en la palabra, fuera de comillas simples y de
+// sustituciones de comandos
+func expansions(word string) []expansion {
+	var result []expansion
+	inSingle, inDouble := false, false
+
+	for i := 0; i < len(word); i++ {
+		c := word[i]
+		switch {
+		case c == '\\' && !inSingle:
+			i++
+		case c == '\'' && !inDouble:
+			inSingle = !inSingle
+		case c == '"' && !inSingle:
+			inDouble = !inDouble
+		case c == '$' && !inSingle && i+1 < len(word):
+			next := word[i+1]
+			switch {
+			case next == '(':
+				i = closingParen(word, i+1)
+			case next == '{':
+				end := strings.IndexByte(word[i:], '}')
+				if end < 0 {
+					return result
+				}
+				name := strings.TrimLeft(word[i+2:i+end], "#!")
+				if name = parameterName(name); name != "" {
+					result = append(result, expansion{name: name, quoted: inDouble})
+				}
+				i += end
+			default:
+				if name := parameterName(word[i+1:]); name != "" {
+					result = append(result, expansion{name: name, quoted: inDouble})
+					i += len(name)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// parameterName devuelve el nombre del parámetro al inicio del texto: un nombre de variable, un
+// dígito posicional o un parámetro especial
+func parameterName(text string) string {
+	if text == "" {
+		return ""
+	}
+	c := text[0]
+	switch {
+	case c >= '0' && c <= '9', c == '@', c == '*', specialParameters[string(c)]:
+		return text[:1]
+	case c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z':
+		end := 1
+		for end < len(text) && (text[end] == '_' || text[end] >= '0' && text[end] <= '9' ||
+			text[end] >= 'A' && text[end] <= 'Z' || text[end] >= 'a' && text[end] <= 'z') {
+			end++
+		}
+		return text[:end]
+	}
+	return ""
+}
+
+// quoteWord encierra entre comillas dobles las expansiones sin comillas de la palabra
+func quoteWord(word string) string {
+	if strings.ContainsAny(word, `'"\`) {
+		return word
+	}
+	return `"` + word + `"`
+}
+
+// isAssignment indica si la palabra es una asignación de variable
+func isAssignment(word string) bool {
+	return assignmentWord.MatchString(word)
+}
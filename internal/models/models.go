@@ -15,37 +15,50 @@ type TokenType string
 
 const (
 	// Tokens básicos
-	COMMAND    TokenType = "COMMAND"
-	ARGUMENT   TokenType = "ARGUMENT"
-	FLAG       TokenType = "FLAG"
-	PATH       TokenType = "PATH"
-	URL        TokenType = "URL"
-	PIPE       TokenType = "PIPE"
-	REDIRECT   TokenType = "REDIRECT"
-	VARIABLE   TokenType = "VARIABLE"
-	STRING     TokenType = "STRING"
-	NUMBER     TokenType = "NUMBER"
-	OPERATOR   TokenType = "OPERATOR"
-	COMMENT    TokenType = "COMMENT"
-	WHITESPACE TokenType = "WHITESPACE"
-	NEWLINE    TokenType = "NEWLINE"
-	EOF        TokenType = "EOF"
+	COMMAND      TokenType = "COMMAND"
+	ARGUMENT     TokenType = "ARGUMENT"
+	FLAG         TokenType = "FLAG"
+	PATH         TokenType = "PATH"
+	URL          TokenType = "URL"
+	PIPE         TokenType = "PIPE"
+	REDIRECT     TokenType = "REDIRECT"
+	VARIABLE     TokenType = "VARIABLE"
+	STRING       TokenType = "STRING"
+	NUMBER       TokenType = "NUMBER"
+	OPERATOR     TokenType = "OPERATOR"
+	SUBSTITUTION TokenType = "SUBSTITUTION" // $(...), <(...), >(...) y `...`
+	COMMENT      TokenType = "COMMENT"
+	WHITESPACE   TokenType = "WHITESPACE"
+	NEWLINE      TokenType = "NEWLINE"
+	EOF          TokenType = "EOF"
 )
 
 // CommandAST representa un comando parseado
 type CommandAST struct {
-	Command   string            `json:"command"`
-	Arguments []string          `json:"arguments"`
-	Flags     map[string]string `json:"flags"`
-	Pipes     []*CommandAST     `json:"pipes,omitempty"`
-	Redirects []Redirect        `json:"redirects,omitempty"`
-	Line      int               `json:"line"`
-	Raw       string            `json:"raw"`
+	Command       string            `json:"command"`
+	Arguments     []string          `json:"arguments"`
+	Flags         map[string]string `json:"flags"`
+	Pipes         []*CommandAST     `json:"pipes,omitempty"`
+	Substitutions []*CommandAST     `json:"substitutions,omitempty"` // Comandos de $(...), <(...) y `...`
+	Redirects     []Redirect        `json:"redirects,omitempty"`
+	Line          int               `json:"line"`
+	Raw           string            `json:"raw"`
+	LeadingSpace  bool              `json:"leading_space,omitempty"` // La línea empieza con espacios (oculta con HISTCONTROL=ignorespace)
+	Intent        string            `json:"intent,omitempty"`        // Categoría de intención (navigation, vcs, ...); ver el paquete intent
+	Assignments   []Assignment      `json:"assignments,omitempty"`   // Asignaciones iniciales: DIR=/opt/app o X=1 comando
+	Context       *ExecutionContext `json:"context,omitempty"`       // Usuario y equipo con los que se ejecutó (ver ExecutionContext)
+}
+
+// Assignment representa una asignación de variable (NOMBRE=valor) al inicio de un comando
+type Assignment struct {
+	Name  string `json:"name"`
+	Value string `json:"value"` // Valor sin comillas externas
 }
 
 // Redirect representa una redirección
 type Redirect struct {
-	Type   string `json:"type"` // >, >>, <, etc.
+	Type   string `json:"type"`         // >, >>, <, >&, <&, &>, <<, <<<, etc.
+	FD     string `json:"fd,omitempty"` // Descriptor explícito (2 en 2>&1)
 	Target string `json:"target"`
 }
 
@@ -62,21 +75,96 @@ const (
 
 // ThreatDetection representa una amenaza detectada
 type ThreatDetection struct {
-	Type        string      `json:"type"`
-	Level       ThreatLevel `json:"level"`
-	Description string      `json:"description"`
-	Command     string      `json:"command"`
-	Line        int         `json:"line"`
-	Suggestions []string    `json:"suggestions,omitempty"`
+	RuleID      string            `json:"rule_id,omitempty"` // Regla que generó la detección
+	Type        string            `json:"type"`
+	Level       ThreatLevel       `json:"level"`
+	Description string            `json:"description"`
+	Command     string            `json:"command"`
+	Line        int               `json:"line"`
+	Suggestions []Suggestion      `json:"suggestions,omitempty"`
+	Attack      *AttackTechnique  `json:"attack,omitempty"`  // Táctica y técnica MITRE ATT&CK
+	Remote      *RemoteEndpoint   `json:"remote,omitempty"`  // Extremo remoto (reverse/bind shells)
+	Chain       []ChainStep       `json:"chain,omitempty"`   // Pasos de la cadena descarga → ejecución
+	Secret      *SecretFinding    `json:"secret,omitempty"`  // Credencial expuesta (sin su valor)
+	Details     map[string]string `json:"details,omitempty"` // Datos específicos de la regla (vector de escape, recurso)
+
+	Deobfuscation *Deobfuscation    `json:"deobfuscation,omitempty"` // Comando ofuscado y su forma decodificada
+	ThreatIntel   *ThreatIntelMatch `json:"threat_intel,omitempty"`  // Coincidencia con una fuente de inteligencia
+	Host          string            `json:"host,omitempty"`          // Host remoto en el que se ejecutó (sesión ssh interactiva)
+	User          string            `json:"user,omitempty"`          // Usuario efectivo si la sesión cambió de usuario (sudo -i, su, ssh)
+}
+
+// Suggestion es una recomendación para una detección: una alternativa más segura del propio comando
+// (Rewrite, con una línea por paso) o solo un consejo
+type Suggestion struct {
+	Explanation string `json:"explanation"`
+	Rewrite     string `json:"rewrite,omitempty"`
+}
+
+// ThreatIntelMatch describe la coincidencia de un valor del historial con una fuente de inteligencia
+type ThreatIntelMatch struct {
+	Feed       string   `json:"feed"`       // Fuente de mayor confianza
+	Confidence int      `json:"confidence"` // 0-100
+	Indicator  string   `json:"indicator"`  // Entrada de la fuente (dominio padre, red CIDR, URL, hash)
+	Value      string   `json:"value"`      // Valor observado en el comando
+	Kind       string   `json:"kind"`       // domain, ip, url, hash
+	Feeds      []string `json:"feeds"`      // Todas las fuentes que contienen el valor
+}
+
+// SecretFinding describe una credencial expuesta en un comando; nunca incluye el valor
+type SecretFinding struct {
+	Type     string  `json:"type"`              // aws_access_key, github_token, password, jwt, ...
+	Location string  `json:"location"`          // flag -p, variable AWS_SECRET_ACCESS_KEY, cabecera Authorization, URL, ...
+	Column   int     `json:"column"`            // Posición (desde 1) dentro del comando
+	Length   int     `json:"length"`            // Longitud del secreto
+	Entropy  float64 `json:"entropy,omitempty"` // Bits por carácter (detecciones por entropía)
+	Redacted bool    `json:"redacted"`          // El valor se enmascaró en la respuesta
+}
+
+// Deobfuscation relaciona un comando ofuscado con el texto decodificado que se analizó
+type Deobfuscation struct {
+	Original   string   `json:"original"`
+	Decoded    string   `json:"decoded"`
+	Techniques []string `json:"techniques"` // base64, hex, printf, reverse, rot13, quote_splitting, ifs, eval
+}
+
+// ChainStep es un paso del flujo de un archivo descargado hasta su ejecución
+type ChainStep struct {
+	Line    int    `json:"line"`
+	Action  string `json:"action"` // download, copy, move, chmod, execute, write, enable
+	Command string `json:"command"`
+	Path    string `json:"path,omitempty"`   // Archivo afectado en este paso
+	Source  string `json:"source,omitempty"` // URL o archivo de origen
+}
+
+// RemoteEndpoint describe el extremo de red de una detección
+type RemoteEndpoint struct {
+	Host     string `json:"host,omitempty"` // Vacío en bind shells (escucha en todas las interfaces)
+	Port     int    `json:"port,omitempty"`
+	Protocol string `json:"protocol"`         // tcp, udp, tls
+	Listen   bool   `json:"listen,omitempty"` // El comando abre un puerto en escucha (bind shell)
+}
+
+// AttackTechnique identifica la táctica y la técnica MITRE ATT&CK de una detección
+type AttackTechnique struct {
+	TacticID    string `json:"tactic_id"`    // TA0002
+	Tactic      string `json:"tactic"`       // Execution
+	TechniqueID string `json:"technique_id"` // T1059.004
+	Technique   string `json:"technique"`    // Unix Shell
 }
 
 type AnalysisResult struct {
+	ID        string    `json:"id,omitempty"` // Identificador para consultas posteriores
+	CreatedAt time.Time `json:"created_at"`
+
 	Summary struct {
 		TotalCommands    int                 `json:"total_commands"`
 		UniqueCommands   int                 `json:"unique_commands"`
 		ThreatCount      map[ThreatLevel]int `json:"threat_count"`
 		MostUsedCommands []CommandFrequency  `json:"most_used_commands"`
 		ProcessingTime   time.Duration       `json:"processing_time"`
+		RiskScore        int                 `json:"risk_score"` // 0-100, ver Risk
+		RiskLevel        ThreatLevel         `json:"risk_level"`
 	} `json:"summary"`
 
 	LexicalAnalysis struct {
@@ -99,6 +187,196 @@ type AnalysisResult struct {
 
 	// AGREGAR ESTE CAMPO NUEVO:
 	FileSystemAnalysis *FileSystemAnalysis `json:"filesystem_analysis,omitempty"`
+
+	// Versión corregida del historial (solo cuando se solicita autofix)
+	AutoFix *AutoFixResult `json:"autofix,omitempty"`
+
+	// Indicios de manipulación del historial (borrado, desactivación, comandos ocultos)
+	SessionIntegrity SessionIntegrity `json:"session_integrity"`
+
+	// Actividad de las CLIs de nube por proveedor
+	CloudSummary []CloudProviderSummary `json:"cloud_summary,omitempty"`
+
+	// Indicadores de compromiso (IPs, dominios, URLs, correos, hashes y rutas)
+	IOCs []IOC `json:"iocs,omitempty"`
+
+	// Grafo de movimiento lateral (ssh, scp, rsync, sftp y cadenas -J)
+	LateralMovement *HostGraph `json:"lateral_movement,omitempty"`
+
+	// Cambios de usuario y equipo de la sesión (sudo -i, su, doas -s, machinectl shell, ssh)
+	Contexts []ContextSession `json:"contexts,omitempty"`
+
+	// Puntuación de riesgo de la sesión con la aportación de cada detección
+	Risk RiskAssessment `json:"risk"`
+
+	// Detecciones apartadas por supresiones vigentes; no cuentan en el resumen ni en el riesgo. Solo
+	// se suprimen amenazas y hallazgos del linter: Patterns y Anomalies se reportan siempre
+	Suppressed []SuppressedThreat `json:"suppressed"`
+
+	// Línea base del usuario con la que se compararon los comandos (solo con user)
+	Baseline *BaselineStatus `json:"baseline,omitempty"`
+
+	// Perfil de actividad: distribución de intenciones, transiciones y resumen
+	SessionProfile *SessionProfile `json:"session_profile,omitempty"`
+
+	// Shell, opciones y hallazgos del linter (solo con mode=script)
+	Script *ScriptInfo `json:"script,omitempty"`
+
+	// Dialecto con el que se analizó el contenido y cómo se determinó
+	Dialect *DialectInfo `json:"dialect,omitempty"`
+}
+
+// SessionProfile resume la actividad de la sesión según la intención de los comandos
+type SessionProfile struct {
+	Type         string             `json:"type"` // development, administration, reconnaissance, mixed o empty
+	Summary      string             `json:"summary"`
+	Classified   int                `json:"classified"`   // Comandos con categoría
+	Unclassified int                `json:"unclassified"` // Comandos sin regla en la taxonomía
+	Categories   []IntentShare      `json:"categories"`   // Ordenadas de mayor a menor
+	Transitions  []IntentTransition `json:"transitions"`  // Cambios de categoría entre comandos consecutivos
+	Tools        []ToolUsage        `json:"tools"`
+}
+
+// IntentShare es la proporción de comandos de una categoría
+type IntentShare struct {
+	Category   string  `json:"category"`
+	Label      string  `json:"label"`
+	Count      int     `json:"count"`
+	Percentage float64 `json:"percentage"`
+}
+
+// IntentTransition cuenta los cambios de una categoría a otra
+type IntentTransition struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Count int    `json:"count"`
+}
+
+// ToolUsage cuenta los comandos de una herramienta (Go, Docker, Git, ...)
+type ToolUsage struct {
+	Tool     string `json:"tool"`
+	Category string `json:"category"`
+	Count    int    `json:"count"`
+}
+
+// SuppressedThreat es una detección suprimida con la supresión que la aparta
+type SuppressedThreat struct {
+	Threat        ThreatDetection `json:"threat"`
+	SuppressionID string          `json:"suppression_id"`
+	Reason        string          `json:"reason"` // Justificación de la supresión
+	ExpiresAt     time.Time       `json:"expires_at"`
+}
+
+// RiskAssessment es la puntuación de riesgo de la sesión
+type RiskAssessment struct {
+	Score         int                `json:"score"` // 0-100
+	Level         ThreatLevel        `json:"level"`
+	RawPoints     float64            `json:"raw_points"`    // Suma de los puntos de las detecciones
+	Contributions []RiskContribution `json:"contributions"` // Ordenadas de mayor a menor aportación
+}
+
+// RiskContribution es la aportación de una detección a la puntuación: Points es el producto de los factores
+type RiskContribution struct {
+	ThreatIndex int         `json:"threat_index"` // Posición en semantic_analysis.threats
+	Line        int         `json:"line"`
+	Type        string      `json:"type"`
+	RuleID      string      `json:"rule_id,omitempty"`
+	Level       ThreatLevel `json:"level"`
+	Points      float64     `json:"points"`
+	Share       float64     `json:"share"` // Porcentaje del total de puntos
+
+	Severity    float64 `json:"severity"`   // Puntos base del nivel
+	Confidence  float64 `json:"confidence"` // Factor de confianza
+	Rarity      float64 `json:"rarity"`     // Factor por repetición de la misma regla
+	Chaining    float64 `json:"chaining"`   // Factor por formar parte de una cadena
+	ChainReason string  `json:"chain_reason,omitempty"`
+	Recency     float64 `json:"recency"` // Factor por la posición en la sesión
+}
+
+// SessionIntegrity indica si el historial analizado puede estar incompleto
+type SessionIntegrity struct {
+	PossiblyIncomplete bool     `json:"possibly_incomplete"`
+	FirstTamperLine    int      `json:"first_tamper_line,omitempty"` // Primera línea con actividad anti-forense
+	Reasons            []string `json:"reasons,omitempty"`
+}
+
+// CloudProviderSummary resume el uso de la CLI de un proveedor de nube en la sesión
+type CloudProviderSummary struct {
+	Provider     string         `json:"provider"` // aws, gcp, azure, terraform
+	Invocations  int            `json:"invocations"`
+	Services     []string       `json:"services,omitempty"`      // s3, iam, compute, destroy, ...
+	Scopes       []string       `json:"scopes,omitempty"`        // Perfiles, proyectos o suscripciones indicados
+	Findings     map[string]int `json:"findings,omitempty"`      // Detecciones por tipo (cloud_destructive, ...)
+	HighestLevel ThreatLevel    `json:"highest_level,omitempty"` // Nivel de la detección más grave
+}
+
+// HostGraph es el grafo de movimiento lateral: hosts y saltos ssh/scp/rsync/sftp entre ellos
+type HostGraph struct {
+	Nodes    []HostNode      `json:"nodes"`
+	Edges    []HostEdge      `json:"edges"`
+	Sessions []RemoteSession `json:"sessions,omitempty"`
+}
+
+// HostNode es un host del grafo; el nodo "local" es el equipo en el que se registró el historial
+type HostNode struct {
+	ID        string   `json:"id"`
+	Class     string   `json:"class,omitempty"` // Clase de la dirección (rfc1918, public, hostname, ...)
+	Local     bool     `json:"local,omitempty"`
+	Users     []string `json:"users,omitempty"`    // Usuarios con los que se accedió
+	Commands  int      `json:"commands,omitempty"` // Comandos ejecutados en sesiones interactivas en el host
+	FirstLine int      `json:"first_line"`
+}
+
+// HostEdge es un salto entre dos hosts con un protocolo
+type HostEdge struct {
+	From            string   `json:"from"`
+	To              string   `json:"to"`
+	Protocol        string   `json:"protocol"` // ssh, scp, rsync, sftp
+	Users           []string `json:"users,omitempty"`
+	Ports           []string `json:"ports,omitempty"`
+	Lines           []int    `json:"lines"`
+	Count           int      `json:"count"`
+	Jump            bool     `json:"jump,omitempty"`             // Parte de una cadena ssh -J / ProxyJump
+	Direction       string   `json:"direction,omitempty"`        // scp/rsync: upload, download o both
+	AgentForwarding bool     `json:"agent_forwarding,omitempty"` // ssh -A o ForwardAgent=yes
+	Tunnels         []string `json:"tunnels,omitempty"`          // -L/-R/-D con su especificación
+}
+
+// RemoteSession es una sesión ssh interactiva: los comandos hasta exit se ejecutan en el host remoto
+type RemoteSession struct {
+	Host      string `json:"host"`
+	User      string `json:"user,omitempty"`
+	From      string `json:"from"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line,omitempty"` // 0 si la sesión no se cerró en el historial
+	Commands  int    `json:"commands"`
+}
+
+// ExecutionContext es el usuario y el equipo con los que se ejecuta un comando. Cambia con sudo -s/-i,
+// su, doas -s, machinectl shell y ssh interactivo y se recupera con exit o logout
+type ExecutionContext struct {
+	User     string `json:"user,omitempty"`     // Vacío si no se conoce
+	Host     string `json:"host"`               // "local" para el equipo del historial
+	Elevated bool   `json:"elevated,omitempty"` // El usuario efectivo es root
+	Via      string `json:"via,omitempty"`      // Comando que abrió el contexto (sudo -i, su - postgres)
+	Since    int    `json:"since,omitempty"`    // Línea en la que se abrió
+}
+
+// ContextSession es un tramo de la sesión con otro usuario o en otro equipo, hasta su exit
+type ContextSession struct {
+	ExecutionContext
+	EndLine  int `json:"end_line,omitempty"` // 0 si no se cerró en el historial
+	Commands int `json:"commands"`
+}
+
+// IOC es un indicador de compromiso extraído del historial
+type IOC struct {
+	Type      string   `json:"type"` // ipv4, ipv6, domain, url, email, md5, sha1, sha256, sha512, file_path
+	Value     string   `json:"value"`
+	FirstLine int      `json:"first_line"`
+	LastLine  int      `json:"last_line"`
+	Count     int      `json:"count"`    // Apariciones en el historial
+	Commands  []string `json:"commands"` // Comandos en los que aparece, sin repetir
 }
 
 // CommandFrequency representa la frecuencia de uso de comandos
@@ -116,24 +394,55 @@ type LexicalError struct {
 
 // PatternMatch representa un patrón detectado
 type PatternMatch struct {
-	Pattern     string   `json:"pattern"`
-	Description string   `json:"description"`
-	Occurrences int      `json:"occurrences"`
-	Examples    []string `json:"examples"`
+	Pattern     string           `json:"pattern"`
+	Description string           `json:"description"`
+	Occurrences int              `json:"occurrences"`
+	Examples    []string         `json:"examples"`
+	Attack      *AttackTechnique `json:"attack,omitempty"`
 }
 
 // Anomaly representa una anomalía detectada
 type Anomaly struct {
-	Type        string `json:"type"`
-	Description string `json:"description"`
-	Command     string `json:"command"`
-	Line        int    `json:"line"`
+	Type        string            `json:"type"`
+	Description string            `json:"description"`
+	Command     string            `json:"command"`
+	Line        int               `json:"line"`
+	Attack      *AttackTechnique  `json:"attack,omitempty"`
+	Chain       []ChainStep       `json:"chain,omitempty"`
+	Rarity      float64           `json:"rarity,omitempty"`   // Rareza respecto a la línea base del usuario (0 a 1)
+	Baseline    *BaselineEvidence `json:"baseline,omitempty"` // Frecuencia del rasgo en la línea base
+}
+
+// BaselineEvidence es la frecuencia de un rasgo de la sesión en la línea base del usuario
+type BaselineEvidence struct {
+	Kind  string `json:"kind"` // command, flag, host o sequence
+	Value string `json:"value"`
+	Seen  int    `json:"seen"`  // Veces que aparece en la línea base
+	Total int    `json:"total"` // Observaciones del mismo tipo en la línea base
+}
+
+// BaselineStatus indica con qué línea base se comparó la sesión y si se aprendió
+type BaselineStatus struct {
+	Key      string `json:"key"`      // usuario@equipo
+	Analyses int    `json:"analyses"` // Sesiones aprendidas, incluida esta si Learned
+	Commands int    `json:"commands"`
+	Compared bool   `json:"compared"` // La línea base tenía historial suficiente para comparar
+	Learned  bool   `json:"learned"`
+	Skipped  string `json:"skipped,omitempty"` // Motivo por el que no se aprendió: disabled, threats o duplicate
 }
 
 // UploadRequest representa una petición de análisis
 type UploadRequest struct {
-	Content  string `json:"content"`
-	Filename string `json:"filename,omitempty"`
+	Content          string   `json:"content"`
+	Filename         string   `json:"filename,omitempty"`
+	AutoFix          bool     `json:"autofix,omitempty" form:"autofix"`
+	AutoFixThreshold *float64 `json:"autofix_threshold,omitempty" form:"autofix_threshold" binding:"omitempty,gte=0,lte=1"`
+	RevealSecrets    bool     `json:"reveal_secrets,omitempty" form:"reveal_secrets"` // Desactiva el enmascarado de credenciales
+	User             string   `json:"user,omitempty" form:"user"`                     // Usuario dueño del historial (ámbito user de las supresiones)
+	Host             string   `json:"host,omitempty" form:"host"`                     // Equipo del que proviene el historial (ámbito host)
+	Mode             string   `json:"mode,omitempty" form:"mode"`                     // history (por defecto) o script
+	Dialect          string   `json:"dialect,omitempty" form:"dialect"`               // bash, zsh, fish, sh, powershell o auto (por defecto)
+	Learn            *bool    `json:"learn,omitempty" form:"learn"`                   // false: se compara con la línea base sin sumarse a ella
 }
 
 // SpellingSuggestion representa una sugerencia de corrección ortográfica
@@ -181,10 +490,28 @@ type SyntaxError struct {
 	Line       int              `json:"line"`
 	Command    string           `json:"command"`
 	Position   int              `json:"position,omitempty"`
-	Type       string           `json:"type"` // "unknown_command", "malformed_syntax", "missing_argument"
+	Type       string           `json:"type"`               // "unknown_command", "malformed_syntax", "missing_argument", "lint"
+	Code       string           `json:"code,omitempty"`     // Regla del linter de scripts (SH001, ...)
+	Severity   string           `json:"severity,omitempty"` // Severidad de la regla: error, warning, style
 	Validation SyntaxValidation `json:"validation"`
 }
 
+// ScriptInfo resume cómo se ejecutaría un script analizado con mode=script
+type ScriptInfo struct {
+	Shebang  string         `json:"shebang,omitempty"`
+	Shell    string         `json:"shell"`    // Intérprete del shebang (sh, bash, ...); bash si no hay shebang
+	POSIX    bool           `json:"posix"`    // El intérprete solo garantiza sh POSIX
+	Errexit  bool           `json:"errexit"`  // set -e (o -e en el shebang) activo en algún punto
+	Nounset  bool           `json:"nounset"`  // set -u (o -u en el shebang) activo en algún punto
+	Findings map[string]int `json:"findings"` // Hallazgos por regla
+}
+
+// DialectInfo indica el dialecto de shell del contenido analizado
+type DialectInfo struct {
+	Name   string `json:"name"`   // bash, zsh, fish, sh o powershell
+	Source string `json:"source"` // request, filename, shebang, content o default
+}
+
 // CommandValidationResult representa el resultado de validar un comando
 type CommandValidationResult struct {
 	IsValid          bool                 `json:"is_valid"`
@@ -228,6 +555,18 @@ type FileSystemAnalysis struct {
 	State        FileSystemStateInfo `json:"state"`
 	Dependencies []DependencyChain   `json:"dependencies"`
 	Summary      FileSystemSummary   `json:"summary"`
+	Variables    []ShellVariable     `json:"variables,omitempty"` // Variables definidas en la sesión
+}
+
+// ShellVariable es el último estado conocido de una variable de la sesión
+type ShellVariable struct {
+	Name     string `json:"name"`
+	Value    string `json:"value,omitempty"`
+	Known    bool   `json:"known"` // false si el valor viene de read, source o una sustitución
+	Exported bool   `json:"exported,omitempty"`
+	Unset    bool   `json:"unset,omitempty"`
+	Line     int    `json:"line"`   // Línea de la última asignación
+	Source   string `json:"source"` // assignment, export, declare, read, unset, ...
 }
 
 // DependencyChain representa una cadena de dependencias
@@ -246,3 +585,30 @@ type FileSystemSummary struct {
 	DirectoriesCreated  int `json:"directories_created"`
 	FilesCreated        int `json:"files_created"`
 }
+
+// AutoFixResult representa la versión corregida automáticamente del historial
+type AutoFixResult struct {
+	Threshold float64   `json:"threshold"`         // Confianza mínima para aplicar un cambio
+	Corrected string    `json:"corrected"`         // Historial con las correcciones aplicadas
+	Applied   []FixHunk `json:"applied"`           // Cambios aplicados
+	Skipped   []FixHunk `json:"skipped,omitempty"` // Cambios por debajo del umbral
+}
+
+// FixHunk representa un cambio individual sobre el historial original
+type FixHunk struct {
+	Type        string     `json:"type"` // "command", "flag", "subcommand"
+	Original    string     `json:"original"`
+	Replacement string     `json:"replacement"`
+	Confidence  float64    `json:"confidence"`
+	Reason      string     `json:"reason"`
+	Span        SourceSpan `json:"span"`
+	Diff        string     `json:"diff"` // Hunk en formato unified diff de la línea afectada
+}
+
+// SourceSpan representa la ubicación de un fragmento dentro del texto original
+type SourceSpan struct {
+	Line   int `json:"line"`   // Línea (base 1)
+	Column int `json:"column"` // Columna dentro de la línea (base 1)
+	Start  int `json:"start"`  // Offset inicial en bytes dentro del contenido
+	End    int `json:"end"`    // Offset final (exclusivo) en bytes
+}
@@ -162,7 +162,8 @@ func (m *Monitor) printReport() {
 		fmt.Printf("🐌 Fase más lenta: %s (%v)\n", slowestPhase, slowestDuration)
 	}
 
-	fmt.Println("====================" + "\n")
+	fmt.Println("====================")
+	fmt.Println()
 }
 
 // getCPUUsage obtiene el porcentaje de uso de CPU (simplificado)
@@ -0,0 +1,268 @@
+// Package netintel interpreta destinos de red (usuario@host:puerto, URLs, host:ruta) y clasifica
+// sus direcciones: redes privadas, CGNAT, loopback, link-local, ULA, de documentación, públicas y
+// de la organización
+package netintel
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Clases de dirección
+const (
+	ClassRFC1918       = "rfc1918"       // 10/8, 172.16/12, 192.168/16
+	ClassCGNAT         = "cgnat"         // 100.64/10 (RFC 6598)
+	ClassLoopback      = "loopback"      // 127/8, ::1 y localhost
+	ClassLinkLocal     = "link_local"    // 169.254/16, fe80::/10
+	ClassULA           = "ipv6_ula"      // fc00::/7
+	ClassReserved      = "reserved"      // Sin especificar, multicast, benchmarking y reservadas (0/8, 198.18/15, 224/4, 240/4, ff00::/8)
+	ClassDocumentation = "documentation" // Ejemplos de documentación (192.0.2/24, 198.51.100/24, 203.0.113/24, 2001:db8::/32)
+	ClassPublic        = "public"        // Resto de direcciones enrutables
+	ClassOrganization  = "organization"  // Redes propias configuradas (ORG_CIDRS); tiene prioridad sobre el resto
+	ClassHostname      = "hostname"      // Nombre sin resolver
+)
+
+// classes son todas las clases válidas, para validar las reglas
+var classes = map[string]bool{
+	ClassRFC1918: true, ClassCGNAT: true, ClassLoopback: true, ClassLinkLocal: true, ClassULA: true,
+	ClassReserved: true, ClassDocumentation: true, ClassPublic: true, ClassOrganization: true, ClassHostname: true,
+}
+
+// classNetwork asocia una red con su clase
+type classNetwork struct {
+	class   string
+	network *net.IPNet
+}
+
+// specialNetworks son los rangos de propósito especial, comprobados en orden
+var specialNetworks = []classNetwork{
+	mustNetwork(ClassLoopback, "127.0.0.0/8"),
+	mustNetwork(ClassLoopback, "::1/128"),
+	mustNetwork(ClassRFC1918, "10.0.0.0/8"),
+	mustNetwork(ClassRFC1918, "172.16.0.0/12"),
+	mustNetwork(ClassRFC1918, "192.168.0.0/16"),
+	mustNetwork(ClassCGNAT, "100.64.0.0/10"),
+	mustNetwork(ClassLinkLocal, "169.254.0.0/16"),
+	mustNetwork(ClassLinkLocal, "fe80::/10"),
+	mustNetwork(ClassULA, "fc00::/7"),
+	mustNetwork(ClassDocumentation, "192.0.2.0/24"),
+	mustNetwork(ClassDocumentation, "198.51.100.0/24"),
+	mustNetwork(ClassDocumentation, "203.0.113.0/24"),
+	mustNetwork(ClassDocumentation, "2001:db8::/32"),
+	mustNetwork(ClassReserved, "0.0.0.0/8"),
+	mustNetwork(ClassReserved, "198.18.0.0/15"),
+	mustNetwork(ClassReserved, "224.0.0.0/4"),
+	mustNetwork(ClassReserved, "240.0.0.0/4"),
+	mustNetwork(ClassReserved, "::/128"),
+	mustNetwork(ClassReserved, "ff00::/8"),
+}
+
+var (
+	organizationNetworks   []*net.IPNet
+	organizationNetworksMu sync.RWMutex
+)
+
+func mustNetwork(class, cidr string) classNetwork {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic("red inválida: " + cidr)
+	}
+	return classNetwork{class: class, network: network}
+}
+
+// Host es un destino de red extraído de un argumento
+type Host struct {
+	Value string `json:"value"` // Argumento original
+	Host  string `json:"host"`
+	User  string `json:"user,omitempty"`
+	Port  string `json:"port,omitempty"`
+	Class string `json:"class"`
+}
+
+// IsKnownClass indica si la clase de dirección existe
+func IsKnownClass(class string) bool {
+	return classes[class]
+}
+
+// IsIPClass indica si la clase corresponde a una dirección IP (no a un nombre)
+func IsIPClass(class string) bool {
+	return classes[class] && class != ClassHostname
+}
+
+// SetOrganizationNetworks configura las redes propias de la organización (CIDR o IPs sueltas)
+func SetOrganizationNetworks(cidrs []string) error {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("red de la organización inválida %q: %w", cidr, err)
+		}
+		networks = append(networks, network)
+	}
+
+	organizationNetworksMu.Lock()
+	organizationNetworks = networks
+	organizationNetworksMu.Unlock()
+	return nil
+}
+
+// OrganizationNetworks devuelve las redes propias configuradas
+func OrganizationNetworks() []string {
+	organizationNetworksMu.RLock()
+	defer organizationNetworksMu.RUnlock()
+
+	result := make([]string, 0, len(organizationNetworks))
+	for _, network := range organizationNetworks {
+		result = append(result, network.String())
+	}
+	return result
+}
+
+// Classify devuelve la clase de un host: una IP (con o sin zona %eth0) o un nombre
+func Classify(host string) string {
+	host = strings.Trim(host, "[]")
+	if zone := strings.Index(host, "%"); zone >= 0 {
+		host = host[:zone]
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		name := strings.TrimSuffix(strings.ToLower(host), ".")
+		if name == "localhost" || strings.HasSuffix(name, ".localhost") {
+			return ClassLoopback
+		}
+		return ClassHostname
+	}
+	return ClassifyIP(ip)
+}
+
+// ClassifyIP devuelve la clase de una dirección IP
+func ClassifyIP(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	organizationNetworksMu.RLock()
+	for _, network := range organizationNetworks {
+		if network.Contains(ip) {
+			organizationNetworksMu.RUnlock()
+			return ClassOrganization
+		}
+	}
+	organizationNetworksMu.RUnlock()
+
+	for _, special := range specialNetworks {
+		if special.network.Contains(ip) {
+			return special.class
+		}
+	}
+	return ClassPublic
+}
+
+// ParseHost interpreta un destino [usuario@]host[:puerto], [usuario@][ipv6]:puerto o una URL
+func ParseHost(value string) (Host, bool) {
+	text := strings.Trim(value, `"'`)
+	if strings.Contains(text, "://") {
+		return parseURL(value, text)
+	}
+
+	host := Host{Value: value}
+	if at := strings.LastIndex(text, "@"); at >= 0 {
+		host.User, text = text[:at], text[at+1:]
+	}
+
+	switch {
+	case strings.HasPrefix(text, "["):
+		end := strings.Index(text, "]")
+		if end < 0 {
+			return Host{}, false
+		}
+		host.Host = text[1:end]
+		host.Port = strings.TrimPrefix(text[end+1:], ":")
+	case strings.Count(text, ":") == 1:
+		host.Host, host.Port = text[:strings.Index(text, ":")], text[strings.Index(text, ":")+1:]
+	default:
+		host.Host = text
+	}
+
+	return finish(host)
+}
+
+// ParseRemotePath interpreta un destino de scp/rsync: [usuario@]host:ruta, [usuario@][ipv6]:ruta,
+// host::módulo o una URL (scp://, rsync://). Devuelve false si el argumento es una ruta local.
+func ParseRemotePath(value string) (Host, bool) {
+	text := strings.Trim(value, `"'`)
+	if strings.Contains(text, "://") {
+		return parseURL(value, text)
+	}
+
+	host := Host{Value: value}
+	if at := strings.Index(text, "@"); at >= 0 && at < strings.IndexAny(text+":", ":/") {
+		host.User, text = text[:at], text[at+1:]
+	}
+
+	if strings.HasPrefix(text, "[") {
+		end := strings.Index(text, "]")
+		if end < 0 || !strings.HasPrefix(text[end+1:], ":") {
+			return Host{}, false
+		}
+		host.Host = text[1:end]
+		return finish(host)
+	}
+
+	colon := strings.Index(text, ":")
+	if colon <= 0 || strings.Contains(text[:colon], "/") {
+		return Host{}, false
+	}
+	host.Host = text[:colon]
+	return finish(host)
+}
+
+// parseURL extrae el host de una URL
+func parseURL(value, text string) (Host, bool) {
+	parsed, err := url.Parse(text)
+	if err != nil || parsed.Hostname() == "" {
+		return Host{}, false
+	}
+	host := Host{Value: value, Host: parsed.Hostname(), Port: parsed.Port()}
+	if parsed.User != nil {
+		host.User = parsed.User.Username()
+	}
+	return finish(host)
+}
+
+// finish valida el host y calcula su clase
+func finish(host Host) (Host, bool) {
+	if host.Host == "" || strings.HasPrefix(host.Host, "-") || strings.HasPrefix(host.Host, "$") {
+		return Host{}, false
+	}
+	for _, r := range host.Host {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune(".-_:%", r)) {
+			return Host{}, false
+		}
+	}
+	if host.Port != "" && strings.Trim(host.Port, "0123456789") != "" {
+		return Host{}, false
+	}
+
+	host.Class = Classify(host.Host)
+	// Un número suelto no es un host (puertos de nc, contadores)
+	if host.Class == ClassHostname && strings.Trim(host.Host, "0123456789") == "" {
+		return Host{}, false
+	}
+	return host, true
+}
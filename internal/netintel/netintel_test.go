@@ -0,0 +1,47 @@
+package netintel
+
+import "testing"
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		host  string
+		class string
+	}{
+		{"192.0.2.10", ClassDocumentation},
+		{"198.51.100.7", ClassDocumentation},
+		{"203.0.113.5", ClassDocumentation},
+		{"2001:db8::1", ClassDocumentation},
+		{"[2001:db8:1::2]", ClassDocumentation},
+		{"198.18.0.1", ClassReserved},
+		{"198.19.255.254", ClassReserved},
+		{"224.0.0.1", ClassReserved},
+		{"10.0.0.5", ClassRFC1918},
+		{"100.64.1.1", ClassCGNAT},
+		{"fe80::1%eth0", ClassLinkLocal},
+		{"localhost", ClassLoopback},
+		{"198.20.0.1", ClassPublic},
+		{"203.0.114.1", ClassPublic},
+		{"2001:db9::1", ClassPublic},
+		{"8.8.8.8", ClassPublic},
+		{"example.com", ClassHostname},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := Classify(tt.host); got != tt.class {
+				t.Errorf("Classify(%q) = %s, se esperaba %s", tt.host, got, tt.class)
+			}
+		})
+	}
+}
+
+func TestOrganizationNetworksTakePrecedence(t *testing.T) {
+	if err := SetOrganizationNetworks([]string{"203.0.113.0/24"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetOrganizationNetworks(nil) })
+
+	if got := Classify("203.0.113.5"); got != ClassOrganization {
+		t.Errorf("Classify = %s, se esperaba %s", got, ClassOrganization)
+	}
+}
@@ -0,0 +1,161 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"terminal-history-analyzer/internal/models"
+)
+
+// assignmentPattern reconoce el inicio de una asignación: NOMBRE=
+var assignmentPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// blockOpeners son las palabras reservadas y agrupaciones que preceden a un comando en una
+// estructura de control (if grep ..., then rm ..., do echo ..., { cd ...)
+var blockOpeners = map[string]bool{
+	"if": true, "then": true, "else": true, "elif": true, "while": true, "until": true, "do": true,
+	"!": true, "{": true, "(": true,
+}
+
+// blockClosers cierran una estructura de control; no son comandos
+var blockClosers = map[string]bool{
+	"fi": true, "done": true, "esac": true, "}": true, ")": true,
+}
+
+// splitAssignments separa las asignaciones al inicio del comando (DIR=/opt/app, X=1 comando).
+// Devuelve las asignaciones, los tokens de sus valores (para analizar sus sustituciones) y los
+// tokens restantes, que empiezan por el comando si lo hay.
+func splitAssignments(tokens []models.Token) ([]models.Assignment, []models.Token, []models.Token) {
+	var assignments []models.Assignment
+	var values []models.Token
+
+	i := 0
+	for ; i < len(tokens); i++ {
+		token := tokens[i]
+		if token.Type == models.REDIRECT || token.Type == models.PIPE || !assignmentPattern.MatchString(token.Value) {
+			break
+		}
+
+		equals := strings.Index(token.Value, "=")
+		assignment := models.Assignment{Name: token.Value[:equals]}
+		value := token
+		value.Value = token.Value[equals+1:]
+
+		// X=$(date) y X="$(date)" llegan como "X=" seguido del token del valor
+		if value.Value == "" && i+1 < len(tokens) && adjacent(token, tokens[i+1]) {
+			i++
+			value = tokens[i]
+		}
+		assignment.Value = unquoteValue(value.Value)
+
+		assignments = append(assignments, assignment)
+		values = append(values, value)
+	}
+
+	return assignments, values, tokens[i:]
+}
+
+// stripReservedWords quita las palabras reservadas, las cabeceras de función (deploy() {) y los
+// patrones de case (start|restart)) que preceden al comando; devuelve nil si la línea solo abre o
+// cierra un bloque (fi, done, done < archivo)
+func (p *Parser) stripReservedWords(tokens []models.Token) []models.Token {
+	start := 0
+	for start < len(tokens) {
+		rest := tokens[start:]
+		if rest[0].Type != models.STRING && (blockOpeners[rest[0].Value] || containsString(p.options.Keywords, rest[0].Value)) {
+			start++
+		} else if name, n := functionHeader(rest); n > 0 {
+			p.functions[name] = true
+			start += n
+		} else if n := p.casePattern(rest); n > 0 {
+			start += n
+		} else {
+			break
+		}
+	}
+	if start == len(tokens) || blockClosers[tokens[start].Value] || containsString(p.options.Closers, tokens[start].Value) {
+		return nil
+	}
+	if start == 0 {
+		return tokens
+	}
+
+	tokens = append([]models.Token(nil), tokens[start:]...)
+	if tokens[0].Type != models.REDIRECT {
+		tokens[0].Type = models.COMMAND // [ en if [ -f x ] llega como operador
+	}
+	return tokens
+}
+
+// functionHeader devuelve el nombre y el número de tokens de la cabecera de una función: deploy(),
+// function deploy, function deploy() o, en fish, function deploy --description "..." (la línea
+// entera es la cabecera)
+func functionHeader(tokens []models.Token) (string, int) {
+	n := 0
+	if tokens[0].Type != models.STRING && tokens[0].Value == "function" && len(tokens) > 1 {
+		n = 1
+	}
+	if len(tokens) >= n+3 && isOperator(tokens[n+1], "(") && isOperator(tokens[n+2], ")") {
+		return tokens[n].Value, n + 3
+	}
+	if n == 1 && len(tokens) > 2 && tokens[2].Type == models.FLAG {
+		return tokens[1].Value, len(tokens)
+	}
+	if n == 1 {
+		return tokens[1].Value, 2
+	}
+	return "", 0
+}
+
+// casePattern devuelve el número de tokens del patrón de una rama de case (start|restart), *),
+// "x")); 0 si la línea no empieza por un patrón
+func (p *Parser) casePattern(tokens []models.Token) int {
+	for i := 1; i < len(tokens); i++ {
+		if !adjacent(tokens[i-1], tokens[i]) || isOperator(tokens[i], "(") {
+			return 0
+		}
+		if !isOperator(tokens[i], ")") {
+			continue
+		}
+
+		// Un comando conocido seguido solo de ) cierra un subshell: (cd build && make)
+		if i == 1 && len(tokens) == 2 && tokens[0].Type != models.STRING && p.spellChecker.knownCommands[tokens[0].Value] {
+			return 0
+		}
+		return i + 1
+	}
+	return 0
+}
+
+// isOperator indica si el token es el operador indicado
+func isOperator(token models.Token, value string) bool {
+	return token.Type == models.OPERATOR && token.Value == value
+}
+
+// adjacent indica si el siguiente token empieza justo donde termina el anterior, sin espacios
+func adjacent(token, next models.Token) bool {
+	return next.Position == token.Position+len(token.Value)
+}
+
+// joinTokens reconstruye el texto del comando: espacio entre tokens salvo si estaban pegados (X=$(date))
+func joinTokens(tokens []models.Token) string {
+	var raw strings.Builder
+	for i, token := range tokens {
+		if i > 0 && !adjacent(tokens[i-1], token) {
+			raw.WriteString(" ")
+		}
+		raw.WriteString(token.Value)
+	}
+	return raw.String()
+}
+
+// unquoteValue quita las comillas que envuelven el valor completo ("hola mundo" -> hola mundo)
+func unquoteValue(value string) string {
+	if len(value) >= 2 {
+		first, last := value[0], value[len(value)-1]
+		if (first == '"' || first == '\'') && first == last {
+			return value[1 : len(value)-1]
+		}
+	}
+	return value
+}
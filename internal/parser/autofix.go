@@ -0,0 +1,199 @@
+package parser
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"terminal-history-analyzer/internal/models"
+)
+
+// DefaultAutoFixThreshold es la confianza mínima por defecto para aplicar una corrección
+const DefaultAutoFixThreshold = 0.8
+
+// AutoFixer genera una versión corregida del historial a partir de las sugerencias del SpellChecker
+type AutoFixer struct {
+	spellChecker *SpellChecker
+	threshold    float64
+}
+
+// NewAutoFixer crea un corrector automático con el umbral de confianza indicado, entre 0 (aplica
+// todas las correcciones) y 1
+func NewAutoFixer(threshold float64) *AutoFixer {
+	return &AutoFixer{
+		spellChecker: NewSpellChecker(),
+		threshold:    threshold,
+	}
+}
+
+// Fix recorre los tokens del contenido y corrige comandos, subcomandos y flags mal escritos.
+// Los tokens deben provenir del lexer sobre el mismo contenido para que las posiciones coincidan.
+func (af *AutoFixer) Fix(content string, tokens []models.Token) *models.AutoFixResult {
+	lineStarts := computeLineStarts(content)
+	var candidates []models.FixHunk
+
+	command := ""      // Comando efectivo del segmento actual (ya corregido si aplica)
+	wrapped := false   // El comando actual es un wrapper (sudo) que aún no recibió su comando
+	expectSub := false // El siguiente argumento es un subcomando
+	skipValue := false // El siguiente token es el valor de una flag
+
+	for _, token := range tokens {
+		switch token.Type {
+		case models.WHITESPACE, models.COMMENT:
+			continue
+
+		case models.NEWLINE, models.PIPE, models.EOF:
+			command, wrapped, expectSub, skipValue = "", false, false, false
+			continue
+
+		case models.OPERATOR:
+			if isCommandSeparator(token.Value) {
+				command, wrapped, expectSub, skipValue = "", false, false, false
+			}
+			continue
+
+		case models.COMMAND:
+			command = af.checkCommand(token, content, lineStarts, &candidates)
+			wrapped = IsWrapperCommand(command)
+			expectSub = HasSubcommands(command)
+			skipValue = false
+			continue
+
+		case models.FLAG:
+			if command == "" {
+				continue
+			}
+			if suggestion := af.spellChecker.CheckFlag(command, token.Value); suggestion != nil {
+				if hunk, ok := newFixHunk("flag", token, suggestion, content, lineStarts); ok {
+					candidates = append(candidates, hunk)
+				}
+			}
+			skipValue = FlagTakesValue(command, token.Value)
+			continue
+		}
+
+		if skipValue {
+			skipValue = false
+			continue
+		}
+
+		if token.Type != models.ARGUMENT {
+			expectSub = false
+			continue
+		}
+
+		if wrapped {
+			// sudo apt-get ...: el primer argumento es el comando real
+			command = af.checkCommand(token, content, lineStarts, &candidates)
+			wrapped = false
+			expectSub = HasSubcommands(command)
+			continue
+		}
+
+		if expectSub {
+			if suggestion := af.spellChecker.CheckSubcommand(command, token.Value); suggestion != nil {
+				if hunk, ok := newFixHunk("subcommand", token, suggestion, content, lineStarts); ok {
+					candidates = append(candidates, hunk)
+				}
+			}
+			expectSub = false
+		}
+	}
+
+	return af.buildResult(content, candidates)
+}
+
+// checkCommand verifica la ortografía de un comando y devuelve el comando efectivo
+func (af *AutoFixer) checkCommand(token models.Token, content string, lineStarts []int, candidates *[]models.FixHunk) string {
+	suggestion := af.spellChecker.CheckSpelling(token.Value)
+	if suggestion == nil || strings.ContainsAny(token.Value, "/=") {
+		return filepath.Base(token.Value)
+	}
+
+	hunk, ok := newFixHunk("command", token, suggestion, content, lineStarts)
+	if !ok {
+		return token.Value
+	}
+	*candidates = append(*candidates, hunk)
+	if suggestion.Confidence >= af.threshold {
+		return suggestion.Suggested
+	}
+	return token.Value
+}
+
+// buildResult separa los cambios aplicables y genera el contenido corregido
+func (af *AutoFixer) buildResult(content string, candidates []models.FixHunk) *models.AutoFixResult {
+	result := &models.AutoFixResult{
+		Threshold: af.threshold,
+		Applied:   make([]models.FixHunk, 0),
+	}
+
+	for _, hunk := range candidates {
+		if hunk.Confidence >= af.threshold {
+			result.Applied = append(result.Applied, hunk)
+		} else {
+			result.Skipped = append(result.Skipped, hunk)
+		}
+	}
+
+	// Aplicar de atrás hacia adelante para no invalidar los offsets
+	corrected := content
+	for i := len(result.Applied) - 1; i >= 0; i-- {
+		hunk := result.Applied[i]
+		corrected = corrected[:hunk.Span.Start] + hunk.Replacement + corrected[hunk.Span.End:]
+	}
+	result.Corrected = corrected
+
+	return result
+}
+
+// newFixHunk construye un cambio con su ubicación y el diff de la línea afectada. La línea se calcula
+// a partir de la posición del token (token.Line no cuenta los saltos dentro de strings entre comillas);
+// devuelve false si el token no cae dentro del contenido.
+func newFixHunk(fixType string, token models.Token, suggestion *models.SpellingSuggestion, content string, lineStarts []int) (models.FixHunk, bool) {
+	start := token.Position
+	end := start + len(token.Value)
+	line := sort.Search(len(lineStarts), func(i int) bool { return lineStarts[i] > start })
+	if line == 0 {
+		return models.FixHunk{}, false
+	}
+
+	lineStart := lineStarts[line-1]
+	lineEnd := len(content)
+	if line < len(lineStarts) {
+		lineEnd = lineStarts[line] - 1
+	}
+	if !(lineStart <= start && start <= end && end <= lineEnd && lineEnd <= len(content)) {
+		return models.FixHunk{}, false
+	}
+
+	originalLine := content[lineStart:lineEnd]
+	fixedLine := content[lineStart:start] + suggestion.Suggested + content[end:lineEnd]
+
+	return models.FixHunk{
+		Type:        fixType,
+		Original:    token.Value,
+		Replacement: suggestion.Suggested,
+		Confidence:  suggestion.Confidence,
+		Reason:      suggestion.Reason,
+		Span: models.SourceSpan{
+			Line:   line,
+			Column: start - lineStart + 1,
+			Start:  start,
+			End:    end,
+		},
+		Diff: fmt.Sprintf("@@ -%d +%d @@\n-%s\n+%s\n", line, line, originalLine, fixedLine),
+	}, true
+}
+
+// computeLineStarts calcula el offset de inicio de cada línea del contenido
+func computeLineStarts(content string) []int {
+	starts := []int{0}
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"testing"
+
+	"terminal-history-analyzer/internal/lexer"
+)
+
+func TestAutoFixerFix(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		corrected string
+		line      int // Línea del primer cambio aplicado (0 si no hay cambios)
+	}{
+		{
+			name:      "comando mal escrito",
+			content:   "gti status",
+			corrected: "git status",
+			line:      1,
+		},
+		{
+			name:      "salto de línea dentro de comillas",
+			content:   "git commit -m 'first\nsecond'\ngti status",
+			corrected: "git commit -m 'first\nsecond'\ngit status",
+			line:      3,
+		},
+		{
+			name:      "sin cambios",
+			content:   "ls -la\ncd /tmp",
+			corrected: "ls -la\ncd /tmp",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, _ := lexer.NewLexer(tt.content).Tokenize()
+			result := NewAutoFixer(DefaultAutoFixThreshold).Fix(tt.content, tokens)
+
+			if result.Corrected != tt.corrected {
+				t.Errorf("Corrected = %q, se esperaba %q", result.Corrected, tt.corrected)
+			}
+			if tt.line == 0 {
+				if len(result.Applied) != 0 {
+					t.Errorf("Applied = %+v, no se esperaban cambios", result.Applied)
+				}
+				return
+			}
+			if len(result.Applied) == 0 {
+				t.Fatalf("no se aplicó ningún cambio")
+			}
+			if got := result.Applied[0].Span.Line; got != tt.line {
+				t.Errorf("Span.Line = %d, se esperaba %d", got, tt.line)
+			}
+		})
+	}
+}
@@ -0,0 +1,485 @@
+package parser
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// commandSpec describe las flags y subcomandos conocidos de un comando
+type commandSpec struct {
+	shortFlags     string   // Flags cortas que no reciben valor, ej. "lahrt"
+	shortValue     string   // Flags cortas que reciben un valor, ej. "o" en curl -o archivo
+	longFlags      []string // Flags largas sin valor (sin los guiones)
+	longValue      []string // Flags largas que reciben un valor
+	subcommands    []string // Subcomandos válidos (git commit, docker run, ...)
+	singleDashLong bool     // El comando usa flags largas con un solo guion (find -name, terraform -auto-approve)
+}
+
+// wrapperCommands son comandos que ejecutan a otro comando pasado como argumento
+var wrapperCommands = map[string]bool{
+	"sudo": true, "doas": true, "nohup": true, "time": true, "nice": true, "env": true,
+	"busybox": true,
+}
+
+// commandSpecs contiene la especificación de flags de los comandos más comunes
+var commandSpecs = map[string]commandSpec{
+	"ls": {
+		shortFlags: "lahrtRSdF1iGn",
+		longFlags:  []string{"all", "almost-all", "human-readable", "reverse", "recursive", "color", "directory", "inode"},
+		longValue:  []string{"sort", "format", "time-style", "ignore"},
+	},
+	"rm": {
+		shortFlags: "rfiRdv",
+		longFlags:  []string{"recursive", "force", "interactive", "dir", "verbose", "preserve-root", "no-preserve-root"},
+	},
+	"cp": {
+		shortFlags: "rfiRavpnulsT",
+		shortValue: "t",
+		longFlags:  []string{"recursive", "force", "interactive", "archive", "verbose", "preserve", "no-clobber", "update"},
+		longValue:  []string{"target-directory", "backup", "suffix"},
+	},
+	"mv": {
+		shortFlags: "fivnuT",
+		shortValue: "t",
+		longFlags:  []string{"force", "interactive", "verbose", "no-clobber", "update"},
+		longValue:  []string{"target-directory", "backup", "suffix"},
+	},
+	"mkdir": {
+		shortFlags: "pv",
+		shortValue: "m",
+		longFlags:  []string{"parents", "verbose"},
+		longValue:  []string{"mode"},
+	},
+	"chmod": {
+		shortFlags: "Rvcf",
+		longFlags:  []string{"recursive", "verbose", "changes", "silent", "quiet"},
+		longValue:  []string{"reference"},
+	},
+	"chown": {
+		shortFlags: "RvchfH",
+		longFlags:  []string{"recursive", "verbose", "changes", "silent", "quiet", "dereference"},
+		longValue:  []string{"reference", "from"},
+	},
+	"grep": {
+		shortFlags: "rRinvlcwxEFoqHhsLz",
+		shortValue: "eABCfm",
+		longFlags:  []string{"recursive", "ignore-case", "invert-match", "files-with-matches", "count", "word-regexp", "line-regexp", "extended-regexp", "only-matching", "quiet", "color"},
+		longValue:  []string{"include", "exclude", "exclude-dir", "regexp", "file", "max-count", "after-context", "before-context", "context"},
+	},
+	"find": {
+		singleDashLong: true,
+		longFlags:      []string{"print", "print0", "delete", "ls", "empty", "follow", "xdev", "depth"},
+		longValue:      []string{"name", "iname", "type", "path", "ipath", "size", "mtime", "mmin", "atime", "ctime", "user", "group", "perm", "exec", "execdir", "maxdepth", "mindepth", "newer", "regex"},
+	},
+	"tar": {
+		shortFlags: "cxtzjJvpPkruW",
+		shortValue: "fCT",
+		longFlags:  []string{"create", "extract", "list", "gzip", "bzip2", "xz", "verbose", "overwrite"},
+		longValue:  []string{"file", "directory", "exclude", "files-from"},
+	},
+	"curl": {
+		shortFlags: "sSLkIfOJivgNnZ#",
+		shortValue: "oHdXuAebxTFmwrKEc",
+		longFlags:  []string{"silent", "show-error", "location", "insecure", "head", "fail", "remote-name", "remote-header-name", "include", "verbose", "compressed", "globoff", "http1.1", "http2", "ssl", "tlsv1.2", "create-dirs", "progress-bar"},
+		longValue:  []string{"output", "header", "data", "data-raw", "data-binary", "data-urlencode", "request", "user", "user-agent", "referer", "cookie", "proxy", "upload-file", "form", "max-time", "connect-timeout", "retry", "write-out", "config", "cert", "key", "cacert", "resolve", "output-dir"},
+	},
+	"wget": {
+		shortFlags: "qcrbNnvkmpSx",
+		shortValue: "OoPUtTeil",
+		longFlags:  []string{"quiet", "continue", "recursive", "background", "no-check-certificate", "mirror", "page-requisites", "no-parent", "verbose", "spider", "timestamping", "no-verbose", "convert-links"},
+		longValue:  []string{"output-document", "output-file", "directory-prefix", "user-agent", "tries", "timeout", "header", "user", "password", "http-user", "http-password", "post-data", "input-file", "level", "limit-rate"},
+	},
+	"ssh": {
+		shortFlags: "46AaCfGgKkMNnqsTtVvXxYy",
+		shortValue: "BbcDEeFIiJLlmOoPpQRSWw",
+	},
+	"scp": {
+		shortFlags: "346ABCpqrTv",
+		shortValue: "cFiJloPS",
+	},
+	"sftp": {
+		shortFlags: "46aCfNpqrv",
+		shortValue: "BbcDFiJloPRSs",
+	},
+	"rsync": {
+		shortFlags: "avzrlptgoDPhnqcuRSHWxEAXiIbd",
+		shortValue: "eTfB",
+		longFlags:  []string{"archive", "verbose", "compress", "recursive", "delete", "progress", "partial", "dry-run", "quiet", "checksum", "update", "human-readable", "stats", "remove-source-files"},
+		longValue:  []string{"rsh", "exclude", "include", "exclude-from", "include-from", "files-from", "bwlimit", "port", "password-file", "rsync-path", "chmod", "chown", "backup-dir", "temp-dir"},
+	},
+	"nc": {
+		shortFlags: "46DdhklnrStUuvzNC",
+		shortValue: "eciIOpqsTVwXx",
+	},
+	"netcat": {
+		shortFlags: "46DdhklnrStUuvzNC",
+		shortValue: "eciIOpqsTVwXx",
+	},
+	"ncat": {
+		shortFlags: "46CklnuUvzh",
+		shortValue: "ecpsgGimdw",
+		longFlags:  []string{"listen", "keep-open", "udp", "ssl", "nodns", "verbose", "broker", "chat", "no-shutdown"},
+		longValue:  []string{"exec", "sh-exec", "lua-exec", "source-port", "source", "proxy", "proxy-type", "proxy-auth", "allow", "deny", "output", "wait", "idle-timeout"},
+	},
+	"ping": {
+		shortFlags: "46aAbBdDfhLnOqrRUv",
+		shortValue: "cFiIlmMpQsStTwW",
+	},
+	"ping6": {
+		shortFlags: "46aAbBdDfhLnOqrRUv",
+		shortValue: "cFiIlmMpQsStTwW",
+	},
+	"socat": {
+		shortFlags: "dDhuUvVxgL",
+		shortValue: "lT",
+	},
+	"openssl": {
+		singleDashLong: true,
+		longFlags:      []string{"quiet", "brief", "showcerts", "tls1_2", "tls1_3", "ign_eof", "nbio", "crlf", "base64", "a", "d", "e", "salt", "nosalt", "pbkdf2"},
+		longValue:      []string{"connect", "accept", "port", "host", "servername", "cert", "key", "CAfile", "in", "out", "pass", "passin", "passout", "k", "iter", "md", "cipher"},
+		subcommands:    []string{"s_client", "s_server", "enc", "req", "x509", "genrsa", "genpkey", "rsa", "rand", "dgst", "base64", "passwd", "pkcs12", "verify", "version"},
+	},
+	"git": {
+		longFlags:  []string{"version", "help", "bare", "no-pager", "paginate"},
+		longValue:  []string{"git-dir", "work-tree", "namespace", "exec-path"},
+		shortValue: "Cc",
+		subcommands: []string{
+			"add", "am", "archive", "bisect", "blame", "branch", "bundle", "checkout", "cherry-pick",
+			"clean", "clone", "commit", "config", "describe", "diff", "fetch", "format-patch", "gc",
+			"grep", "init", "log", "merge", "mv", "notes", "pull", "push", "rebase", "reflog", "remote",
+			"reset", "restore", "revert", "rm", "shortlog", "show", "stash", "status", "submodule",
+			"switch", "tag", "worktree",
+		},
+	},
+	"docker": {
+		shortFlags: "DlvdittP",
+		shortValue: "HcevpuwL",
+		longFlags:  []string{"debug", "tls", "tlsverify", "rm", "detach", "interactive", "tty", "privileged", "read-only", "init", "all", "force", "quiet", "no-cache", "pull", "version"},
+		longValue:  []string{"host", "context", "config", "log-level", "name", "volume", "publish", "env", "env-file", "user", "workdir", "network", "net", "pid", "ipc", "uts", "userns", "cap-add", "cap-drop", "security-opt", "device", "mount", "entrypoint", "label", "memory", "cpus", "restart", "tag", "file", "platform", "build-arg", "target", "format", "filter"},
+		subcommands: []string{
+			"attach", "build", "commit", "compose", "container", "cp", "create", "exec", "image", "images",
+			"info", "inspect", "kill", "load", "login", "logout", "logs", "network", "ps", "pull", "push",
+			"restart", "rm", "rmi", "run", "save", "start", "stats", "stop", "system", "tag", "top",
+			"volume", "version",
+		},
+	},
+	"kubectl": {
+		shortFlags: "itwA",
+		shortValue: "nofclL",
+		longFlags:  []string{"all-namespaces", "stdin", "tty", "watch", "dry-run", "force", "recursive", "show-labels"},
+		longValue:  []string{"namespace", "output", "filename", "selector", "context", "cluster", "kubeconfig", "container", "clusterrole", "role", "serviceaccount", "user", "group", "image", "overrides", "as", "as-group", "token", "server", "field-selector", "sort-by", "template"},
+		subcommands: []string{
+			"annotate", "api-resources", "apply", "attach", "auth", "autoscale", "certificate", "cluster-info",
+			"config", "cordon", "cp", "create", "debug", "delete", "describe", "diff", "drain", "edit",
+			"exec", "explain", "expose", "get", "label", "logs", "patch", "port-forward", "proxy",
+			"replace", "rollout", "run", "scale", "set", "taint", "top", "uncordon", "version", "wait",
+		},
+	},
+	"systemctl": {
+		shortFlags: "alqf",
+		shortValue: "tpHMn",
+		longFlags:  []string{"all", "user", "system", "now", "quiet", "force", "no-pager", "failed", "runtime"},
+		longValue:  []string{"type", "state", "property", "host", "machine", "lines", "output"},
+		subcommands: []string{
+			"daemon-reload", "disable", "edit", "enable", "is-active", "is-enabled", "kill", "list-timers",
+			"list-unit-files", "list-units", "mask", "reboot", "reload", "restart", "show", "start",
+			"status", "stop", "unmask", "poweroff",
+		},
+	},
+	"apt": {
+		shortFlags: "yqsf",
+		longFlags:  []string{"yes", "quiet", "simulate", "fix-broken", "no-install-recommends", "purge", "only-upgrade"},
+		longValue:  []string{"target-release", "option"},
+		subcommands: []string{
+			"autoremove", "full-upgrade", "install", "list", "purge", "reinstall", "remove", "search",
+			"show", "update", "upgrade", "edit-sources",
+		},
+	},
+	"apt-get": {
+		shortFlags: "yqsfdb",
+		longFlags:  []string{"yes", "quiet", "simulate", "fix-broken", "no-install-recommends", "purge", "only-upgrade", "download-only"},
+		longValue:  []string{"target-release", "option"},
+		subcommands: []string{
+			"autoclean", "autoremove", "build-dep", "clean", "dist-upgrade", "download", "install",
+			"purge", "remove", "source", "update", "upgrade",
+		},
+	},
+	"npm": {
+		shortFlags: "gDSEy",
+		longFlags:  []string{"global", "save-dev", "save", "save-exact", "production", "force", "legacy-peer-deps", "yes"},
+		longValue:  []string{"registry", "prefix", "tag", "workspace"},
+		subcommands: []string{
+			"audit", "cache", "ci", "config", "exec", "init", "install", "link", "login", "ls", "outdated",
+			"pack", "publish", "rebuild", "run", "start", "test", "uninstall", "update", "version", "view",
+		},
+	},
+	"pip": {
+		shortFlags:  "UqveI",
+		shortValue:  "rct",
+		longFlags:   []string{"upgrade", "user", "quiet", "verbose", "editable", "no-cache-dir", "force-reinstall", "break-system-packages"},
+		longValue:   []string{"requirement", "constraint", "target", "index-url", "extra-index-url", "trusted-host", "prefix"},
+		subcommands: []string{"download", "freeze", "install", "list", "show", "uninstall", "wheel", "check", "config", "cache"},
+	},
+	"go": {
+		shortFlags: "vxnr",
+		shortValue: "o",
+		longValue:  []string{"tags", "ldflags", "gcflags", "mod", "run", "bench", "count", "timeout", "coverprofile"},
+		subcommands: []string{
+			"build", "clean", "doc", "env", "fmt", "generate", "get", "install", "list", "mod", "run",
+			"test", "tool", "version", "vet", "work",
+		},
+		singleDashLong: true,
+	},
+	"sudo": {
+		shortFlags: "sibEHknlSAPv",
+		shortValue: "ugCchprDT",
+		longFlags:  []string{"shell", "login", "background", "preserve-env", "set-home", "non-interactive", "list", "stdin", "validate", "reset-timestamp"},
+		longValue:  []string{"user", "group", "close-from", "host", "prompt", "chdir"},
+	},
+	"bash": {
+		shortFlags: "ilsrxevnu",
+		shortValue: "cO",
+		longFlags:  []string{"login", "noprofile", "norc", "posix", "restricted", "verbose", "version"},
+		longValue:  []string{"rcfile", "init-file"},
+	},
+	"sh": {
+		shortFlags: "ilsxevnu",
+		shortValue: "c",
+	},
+	"zsh": {
+		shortFlags: "ilsxevnfd",
+		shortValue: "co",
+	},
+	"python": {
+		shortFlags: "BdEhiIOqsSuvVxb",
+		shortValue: "cmWX",
+	},
+	"python3": {
+		shortFlags: "BdEhiIOqsSuvVxb",
+		shortValue: "cmWX",
+	},
+	"perl": {
+		shortFlags: "nplawcstTUvW",
+		shortValue: "eEIMmx",
+	},
+	"php": {
+		shortFlags: "ahilmsvw",
+		shortValue: "rBRFEfcdz",
+	},
+	"ruby": {
+		shortFlags: "acdlnpsvwyW",
+		shortValue: "eIrCEFx",
+	},
+	"node": {
+		shortFlags: "ivhpc",
+		shortValue: "er",
+		longValue:  []string{"eval", "print", "require", "import"},
+	},
+	"ps": {
+		shortFlags: "aeflxuwAHjT",
+		shortValue: "opUuCtG",
+	},
+	"kill": {
+		shortFlags: "l",
+		shortValue: "sn",
+	},
+	"head": {
+		shortFlags: "qvz",
+		shortValue: "nc",
+		longValue:  []string{"lines", "bytes"},
+	},
+	"tail": {
+		shortFlags: "fFqvz",
+		shortValue: "ncs",
+		longFlags:  []string{"follow", "retry", "quiet", "verbose"},
+		longValue:  []string{"lines", "bytes", "pid", "sleep-interval"},
+	},
+	"sed": {
+		shortFlags: "nrEsuzi",
+		shortValue: "efl",
+		longFlags:  []string{"quiet", "silent", "regexp-extended", "separate", "in-place", "null-data"},
+		longValue:  []string{"expression", "file", "line-length"},
+	},
+	"awk": {
+		shortValue: "Fvf",
+	},
+	"tee": {
+		shortFlags: "aip",
+		longFlags:  []string{"append", "ignore-interrupts"},
+	},
+	"touch": {
+		shortFlags: "acmfh",
+		shortValue: "drt",
+		longFlags:  []string{"no-create", "no-dereference"},
+		longValue:  []string{"date", "reference", "time"},
+	},
+	"ln": {
+		shortFlags: "sfnvbiLPrT",
+		shortValue: "St",
+		longFlags:  []string{"symbolic", "force", "no-dereference", "verbose", "interactive", "relative"},
+		longValue:  []string{"suffix", "target-directory"},
+	},
+	"history": {
+		shortFlags: "canrwps",
+		shortValue: "d",
+	},
+	"crontab": {
+		shortFlags: "elri",
+		shortValue: "u",
+	},
+	"useradd": {
+		shortFlags: "mMNorDlU",
+		shortValue: "cdegGkKpsuRPbf",
+		longFlags:  []string{"create-home", "no-create-home", "non-unique", "system", "no-user-group", "user-group"},
+		longValue:  []string{"comment", "home-dir", "expiredate", "gid", "groups", "skel", "password", "shell", "uid", "root", "prefix", "base-dir", "inactive"},
+	},
+	"usermod": {
+		shortFlags: "aLUmor",
+		shortValue: "cdegGlpsuRPf",
+		longFlags:  []string{"append", "lock", "unlock", "move-home", "non-unique"},
+		longValue:  []string{"comment", "home", "expiredate", "gid", "groups", "login", "password", "shell", "uid", "root", "prefix", "inactive"},
+	},
+	"journalctl": {
+		shortFlags: "fraxkemq",
+		shortValue: "unpbSUo",
+		longFlags:  []string{"follow", "reverse", "catalog", "dmesg", "pager-end", "no-pager", "rotate", "flush", "sync", "quiet", "list-boots"},
+		longValue:  []string{"unit", "lines", "priority", "boot", "since", "until", "output", "vacuum-size", "vacuum-time", "vacuum-files", "identifier"},
+	},
+	"shred": {
+		shortFlags: "fvuxz",
+		shortValue: "ns",
+		longFlags:  []string{"force", "verbose", "remove", "exact", "zero"},
+		longValue:  []string{"iterations", "size", "random-source"},
+	},
+	"base64": {
+		shortFlags: "diD",
+		shortValue: "w",
+		longFlags:  []string{"decode", "ignore-garbage"},
+		longValue:  []string{"wrap"},
+	},
+	"mysql": {
+		shortFlags: "ABeEfNnqrsStvVX",
+		shortValue: "uhPDS",
+		longFlags:  []string{"batch", "force", "silent", "verbose", "skip-column-names"},
+		longValue:  []string{"user", "host", "port", "database", "socket", "execute", "password"},
+	},
+	"sshpass": {
+		shortFlags: "ev",
+		shortValue: "pfdP",
+	},
+	"su": {
+		shortFlags: "lmpfP",
+		shortValue: "csgG",
+		longFlags:  []string{"login", "preserve-environment", "pty"},
+		longValue:  []string{"command", "shell", "group", "supp-group", "session-command"},
+	},
+}
+
+// lookupSpec devuelve la especificación de flags para un comando
+func lookupSpec(command string) (commandSpec, bool) {
+	spec, exists := commandSpecs[filepath.Base(command)]
+	return spec, exists
+}
+
+// IsWrapperCommand indica si el comando ejecuta a otro comando (sudo, doas, nohup, ...)
+func IsWrapperCommand(command string) bool {
+	return wrapperCommands[filepath.Base(command)]
+}
+
+// FlagTakesValue indica si la flag (con sus guiones) consume el siguiente token como valor.
+// Sigue la convención de getopt: en flags cortas agrupadas (-qO) solo la última letra puede
+// recibir un valor separado; los comandos sin especificación no consumen valores.
+func FlagTakesValue(command, flag string) bool {
+	spec, exists := lookupSpec(command)
+	if !exists || strings.Contains(flag, "=") {
+		return false
+	}
+
+	name := strings.TrimLeft(flag, "-")
+	if name == "" {
+		return false
+	}
+
+	if strings.HasPrefix(flag, "--") || spec.singleDashLong && len(name) > 1 {
+		return containsString(spec.longValue, name)
+	}
+
+	for i, letter := range name {
+		if strings.ContainsRune(spec.shortValue, letter) {
+			// La letra que recibe valor consume el resto del token; solo toma el siguiente token si es la última
+			return i == len(name)-1
+		}
+		if !strings.ContainsRune(spec.shortFlags, letter) {
+			return false
+		}
+	}
+
+	return false
+}
+
+// IsKnownFlag indica si la flag (con sus guiones) está en la especificación del comando
+func IsKnownFlag(command, flag string) bool {
+	spec, exists := lookupSpec(command)
+	if !exists {
+		return false
+	}
+
+	name := strings.TrimLeft(flag, "-")
+	if idx := strings.Index(name, "="); idx >= 0 {
+		name = name[:idx]
+	}
+
+	if strings.HasPrefix(flag, "--") || spec.singleDashLong && len(name) > 1 {
+		return containsString(spec.longFlags, name) || containsString(spec.longValue, name)
+	}
+
+	for _, letter := range name {
+		if strings.ContainsRune(spec.shortValue, letter) {
+			return true
+		}
+		if !strings.ContainsRune(spec.shortFlags, letter) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ShortFlagLetters devuelve las letras de una flag corta agrupada (-rf -> "rf").
+// Se detiene en la primera letra que recibe valor, ya que el resto del token es su valor (-pS3cret -> "p").
+func ShortFlagLetters(command, flag string) string {
+	if strings.HasPrefix(flag, "--") || !strings.HasPrefix(flag, "-") {
+		return ""
+	}
+
+	name := strings.TrimPrefix(flag, "-")
+	spec, exists := lookupSpec(command)
+	if !exists {
+		return name
+	}
+	if spec.singleDashLong && len(name) > 1 {
+		return ""
+	}
+
+	for i, letter := range name {
+		if strings.ContainsRune(spec.shortValue, letter) {
+			return name[:i+1]
+		}
+	}
+	return name
+}
+
+// HasSubcommands indica si el comando se invoca con subcomandos (git, docker, kubectl, ...)
+func HasSubcommands(command string) bool {
+	spec, exists := lookupSpec(command)
+	return exists && len(spec.subcommands) > 0
+}
+
+func containsString(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
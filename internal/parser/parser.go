@@ -2,6 +2,7 @@ package parser
 
 import (
 	"strings"
+	"terminal-history-analyzer/internal/lexer"
 	"terminal-history-analyzer/internal/models"
 )
 
@@ -12,9 +13,25 @@ type Parser struct {
 	errors       []models.SyntaxError
 	warnings     []string
 	spellChecker *SpellChecker
+	depth        int             // Nivel de anidamiento al analizar sustituciones
+	functions    map[string]bool // Funciones definidas (deploy() {): no son errores de ortografía
+	options      Options
+	powershell   bool // Gramática de PowerShell (NewPowerShellParser)
+}
+
+// Options ajusta el parser a un dialecto de la familia de sh; el valor cero es bash
+type Options struct {
+	Lexer    lexer.Options // Opciones del lexer para las sustituciones
+	Keywords []string      // Palabras reservadas adicionales que preceden al comando (fish: and, or, not, begin)
+	Closers  []string      // Palabras que cierran bloques o abren ramas sin comando (fish: end, switch, case)
 }
 
 func NewParser(tokens []models.Token) *Parser {
+	return NewParserWithOptions(tokens, Options{})
+}
+
+// NewParserWithOptions crea un parser para un dialecto de la familia de sh (zsh, fish)
+func NewParserWithOptions(tokens []models.Token, options Options) *Parser {
 	return &Parser{
 		tokens:       filterTokens(tokens), // Filtrar whitespace y comentarios
 		position:     0,
@@ -22,6 +39,8 @@ func NewParser(tokens []models.Token) *Parser {
 		errors:       make([]models.SyntaxError, 0),
 		warnings:     make([]string, 0),
 		spellChecker: NewSpellChecker(),
+		functions:    make(map[string]bool),
+		options:      options,
 	}
 }
 
@@ -32,7 +51,11 @@ func (p *Parser) Parse() ([]models.CommandAST, []models.SyntaxError, []string) {
 			continue
 		}
 
-		cmd := p.parseCommand()
+		parse := p.parseCommand
+		if p.powershell {
+			parse = p.parsePowerShellCommand
+		}
+		cmd := parse()
 		if cmd != nil {
 			p.commands = append(p.commands, *cmd)
 		}
@@ -47,7 +70,9 @@ func (p *Parser) parseCommand() *models.CommandAST {
 	}
 
 	startLine := p.current().Line
+	leadingSpace := p.startsWithSpace()
 	var tokens []models.Token
+	inTest := false
 
 	// Recopilar tokens hasta el final de la línea o comando
 	for p.position < len(p.tokens) {
@@ -57,9 +82,18 @@ func (p *Parser) parseCommand() *models.CommandAST {
 			break
 		}
 
-		// Si encontramos un punto y coma, es el final del comando actual
-		if token.Type == models.OPERATOR && token.Value == ";" {
-			p.position++ // Consumir el punto y coma
+		// && y || dentro de [[ ]] forman parte de la condición
+		if last := len(tokens) - 1; last >= 0 && adjacent(tokens[last], token) {
+			if isOperator(tokens[last], "[") && isOperator(token, "[") {
+				inTest = true
+			} else if isOperator(tokens[last], "]") && isOperator(token, "]") {
+				inTest = false
+			}
+		}
+
+		// Un separador (;, &&, || o & en segundo plano) termina el comando actual
+		if token.Type == models.OPERATOR && isCommandSeparator(token.Value) && !(inTest && (token.Value == "&&" || token.Value == "||")) {
+			p.position++ // Consumir el separador
 			break
 		}
 
@@ -67,16 +101,33 @@ func (p *Parser) parseCommand() *models.CommandAST {
 		p.position++
 	}
 
+	// Palabras reservadas: en "then rm -rf $DIR" el comando es rm; fi, done, esac y } solo cierran
+	// bloques. También se quitan las cabeceras de función y los patrones de case.
+	tokens = p.stripReservedWords(tokens)
 	if len(tokens) == 0 {
 		return nil
 	}
 
 	// Construir el comando raw
-	var rawParts []string
-	for _, token := range tokens {
-		rawParts = append(rawParts, token.Value)
+	raw := joinTokens(tokens)
+
+	// Una línea con solo redirecciones (> /var/log/auth.log) equivale a ": > archivo"
+	if tokens[0].Type == models.REDIRECT {
+		if cmd := p.parseRedirectOnly(tokens, startLine, raw); cmd != nil {
+			cmd.LeadingSpace = leadingSpace
+			return cmd
+		}
+	}
+
+	// Asignaciones iniciales: DIR=/opt/app o X=1 comando
+	assignments, values, rest := splitAssignments(tokens)
+	if len(assignments) > 0 {
+		if len(rest) == 0 || rest[0].Type == models.REDIRECT {
+			return p.parseAssignmentOnly(assignments, values, rest, startLine, raw, leadingSpace)
+		}
+		tokens = append([]models.Token(nil), rest...)
+		tokens[0].Type = models.COMMAND // env en X=1 env llega como argumento
 	}
-	raw := strings.Join(rawParts, " ")
 
 	// NUEVA VALIDACIÓN: Verificar que el primer token sea un comando válido
 	if len(tokens) > 0 {
@@ -90,7 +141,7 @@ func (p *Parser) parseCommand() *models.CommandAST {
 
 		// NUEVA FUNCIONALIDAD: Verificar ortografía del comando
 		suggestion := p.spellChecker.CheckSpelling(firstToken.Value)
-		if suggestion != nil {
+		if suggestion != nil && !p.functions[firstToken.Value] {
 			p.addSpellingError(firstToken.Value, suggestion, startLine, raw)
 		}
 	}
@@ -113,8 +164,15 @@ func (p *Parser) parseCommand() *models.CommandAST {
 
 	// Verificar si hay pipes en el comando
 	if p.hasPipes(tokens) {
-		return p.parsePipedCommand(tokens, startLine, raw)
+		piped := p.parsePipedCommand(tokens, startLine, raw)
+		if piped != nil {
+			piped.LeadingSpace = leadingSpace
+			p.addAssignments(piped, assignments, values)
+		}
+		return piped
 	}
+	cmd.LeadingSpace = leadingSpace
+	p.addAssignments(cmd, assignments, values)
 
 	// Parsear argumentos, flags y redirecciones
 	for i := 1; i < len(tokens); i++ {
@@ -125,8 +183,9 @@ func (p *Parser) parseCommand() *models.CommandAST {
 			p.parseFlag(cmd, tokens, &i)
 		case models.REDIRECT:
 			p.parseRedirect(cmd, tokens, &i)
-		case models.ARGUMENT, models.PATH, models.URL, models.STRING, models.NUMBER:
+		case models.ARGUMENT, models.PATH, models.URL, models.STRING, models.NUMBER, models.SUBSTITUTION:
 			cmd.Arguments = append(cmd.Arguments, token.Value)
+			p.parseSubstitutions(cmd, token)
 		case models.VARIABLE:
 			cmd.Arguments = append(cmd.Arguments, token.Value)
 			p.addWarning("Variable detectada: " + token.Value)
@@ -138,6 +197,67 @@ func (p *Parser) parseCommand() *models.CommandAST {
 	return cmd
 }
 
+// startsWithSpace indica si el comando actual es el primero de su línea y está precedido por espacios
+// (con HISTCONTROL=ignorespace esos comandos no se guardan en el historial)
+func (p *Parser) startsWithSpace() bool {
+	token := p.current()
+	if p.position == 0 {
+		return token.Position > 0
+	}
+	previous := p.tokens[p.position-1]
+	return previous.Type == models.NEWLINE && token.Position > previous.Position+1
+}
+
+// parseRedirectOnly construye el comando nulo ":" de una línea que solo contiene redirecciones
+func (p *Parser) parseRedirectOnly(tokens []models.Token, line int, raw string) *models.CommandAST {
+	cmd := &models.CommandAST{
+		Command:   ":",
+		Arguments: make([]string, 0),
+		Flags:     make(map[string]string),
+		Redirects: make([]models.Redirect, 0),
+		Line:      line,
+		Raw:       raw,
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].Type != models.REDIRECT {
+			return nil
+		}
+		p.parseRedirect(cmd, tokens, &i)
+	}
+
+	return cmd
+}
+
+// parseAssignmentOnly construye el comando de una línea que solo asigna variables (DIR=/opt/app);
+// el comando es la primera asignación, como la escribió el usuario
+func (p *Parser) parseAssignmentOnly(assignments []models.Assignment, values, redirects []models.Token, line int, raw string, leadingSpace bool) *models.CommandAST {
+	cmd := &models.CommandAST{
+		Command:      assignments[0].Name + "=" + values[0].Value,
+		Arguments:    make([]string, 0),
+		Flags:        make(map[string]string),
+		Redirects:    make([]models.Redirect, 0),
+		Line:         line,
+		Raw:          raw,
+		LeadingSpace: leadingSpace,
+	}
+	for i := 0; i < len(redirects); i++ {
+		if redirects[i].Type == models.REDIRECT {
+			p.parseRedirect(cmd, redirects, &i)
+		}
+	}
+	p.addAssignments(cmd, assignments, values)
+	return cmd
+}
+
+// addAssignments añade las asignaciones al comando y analiza las sustituciones de sus valores
+func (p *Parser) addAssignments(cmd *models.CommandAST, assignments []models.Assignment, values []models.Token) {
+	cmd.Assignments = assignments
+	for _, value := range values {
+		p.parseSubstitutions(cmd, value)
+	}
+}
+
 func (p *Parser) parsePipedCommand(tokens []models.Token, startLine int, raw string) *models.CommandAST {
 	// Dividir por pipes
 	var commandGroups [][]models.Token
@@ -192,6 +312,7 @@ func (p *Parser) parseSimpleCommand(tokens []models.Token, line int) *models.Com
 		Flags:     make(map[string]string),
 		Redirects: make([]models.Redirect, 0),
 		Line:      line,
+		Raw:       joinTokens(tokens),
 	}
 
 	for i := 1; i < len(tokens); i++ {
@@ -202,8 +323,9 @@ func (p *Parser) parseSimpleCommand(tokens []models.Token, line int) *models.Com
 			p.parseFlag(cmd, tokens, &i)
 		case models.REDIRECT:
 			p.parseRedirect(cmd, tokens, &i)
-		case models.ARGUMENT, models.PATH, models.URL, models.STRING, models.NUMBER, models.VARIABLE:
+		case models.ARGUMENT, models.PATH, models.URL, models.STRING, models.NUMBER, models.VARIABLE, models.SUBSTITUTION:
 			cmd.Arguments = append(cmd.Arguments, token.Value)
+			p.parseSubstitutions(cmd, token)
 		}
 	}
 
@@ -214,19 +336,35 @@ func (p *Parser) parseFlag(cmd *models.CommandAST, tokens []models.Token, index
 	flag := tokens[*index]
 	flagName := strings.TrimLeft(flag.Value, "-")
 
-	// Verificar si el flag tiene un valor
-	if *index+1 < len(tokens) {
+	// Flags con valor embebido: --output=archivo
+	if idx := strings.Index(flagName, "="); idx > 0 {
+		cmd.Flags[flagName[:idx]] = flagName[idx+1:]
+		return
+	}
+
+	// Verificar si el flag recibe un valor según la especificación del comando
+	if *index+1 < len(tokens) && FlagTakesValue(specCommand(cmd), flag.Value) {
 		nextToken := tokens[*index+1]
 		if nextToken.Type == models.ARGUMENT || nextToken.Type == models.STRING ||
-			nextToken.Type == models.NUMBER || nextToken.Type == models.PATH {
+			nextToken.Type == models.NUMBER || nextToken.Type == models.PATH ||
+			nextToken.Type == models.URL || nextToken.Type == models.VARIABLE ||
+			nextToken.Type == models.SUBSTITUTION {
 			cmd.Flags[flagName] = nextToken.Value
+			p.parseSubstitutions(cmd, nextToken)
 			*index++ // Consumir el valor del flag
-		} else {
-			cmd.Flags[flagName] = "true"
+			return
 		}
-	} else {
-		cmd.Flags[flagName] = "true"
 	}
+
+	cmd.Flags[flagName] = "true"
+}
+
+// specCommand devuelve el comando cuya especificación de flags aplica (sudo rm -> rm)
+func specCommand(cmd *models.CommandAST) string {
+	if IsWrapperCommand(cmd.Command) && len(cmd.Arguments) > 0 {
+		return cmd.Arguments[0]
+	}
+	return cmd.Command
 }
 
 func (p *Parser) parseRedirect(cmd *models.CommandAST, tokens []models.Token, index *int) {
@@ -235,16 +373,132 @@ func (p *Parser) parseRedirect(cmd *models.CommandAST, tokens []models.Token, in
 	// Buscar el target de la redirección
 	if *index+1 < len(tokens) {
 		target := tokens[*index+1]
+		operator := strings.TrimLeft(redirect.Value, "0123456789")
 		cmd.Redirects = append(cmd.Redirects, models.Redirect{
-			Type:   redirect.Value,
+			Type:   operator,
+			FD:     strings.TrimSuffix(redirect.Value, operator),
 			Target: target.Value,
 		})
-		*index++ // Consumir el target
+		p.parseSubstitutions(cmd, target) // bash < <(curl ...)
+		*index++                          // Consumir el target
 	} else {
 		p.addError("Redirección sin target", cmd.Line, cmd.Raw)
 	}
 }
 
+// isCommandSeparator indica si el operador separa comandos independientes
+func isCommandSeparator(operator string) bool {
+	return operator == ";" || operator == "&&" || operator == "||" || operator == "&"
+}
+
+// maxSubstitutionDepth limita el anidamiento de sustituciones que se analizan
+const maxSubstitutionDepth = 3
+
+// parseSubstitutions analiza los comandos internos de $(...), <(...) y `...`,
+// incluidas las sustituciones dentro de strings con comillas dobles
+func (p *Parser) parseSubstitutions(cmd *models.CommandAST, token models.Token) {
+	if p.depth >= maxSubstitutionDepth {
+		return
+	}
+
+	for _, body := range p.extractSubstitutions(token) {
+		inner := p.innerParser(body)
+		inner.depth = p.depth + 1
+		commands, _, _ := inner.Parse()
+
+		for i := range commands {
+			commands[i].Line = cmd.Line
+			cmd.Substitutions = append(cmd.Substitutions, &commands[i])
+		}
+	}
+}
+
+// innerParser crea el parser del contenido de una sustitución con la gramática del dialecto
+func (p *Parser) innerParser(body string) *Parser {
+	if p.powershell {
+		tokens, _ := lexer.NewPowerShellLexer(body).Tokenize()
+		return NewPowerShellParser(tokens)
+	}
+	tokens, _ := lexer.NewLexerWithOptions(body, p.options.Lexer).Tokenize()
+	return NewParserWithOptions(tokens, p.options)
+}
+
+// extractSubstitutions devuelve el contenido de las sustituciones de un token
+func (p *Parser) extractSubstitutions(token models.Token) []string {
+	value := token.Value
+	switch {
+	case token.Type == models.SUBSTITUTION:
+		if strings.HasPrefix(value, "`") {
+			return []string{strings.Trim(value, "`")}
+		}
+		if strings.HasPrefix(value, "@{") {
+			return nil // Tabla hash de PowerShell
+		}
+		return []string{substitutionBody(value)}
+	case token.Type == models.STRING && strings.HasPrefix(value, "\""):
+		return findQuotedSubstitutions(value[1:len(value)-1], !p.powershell)
+	}
+	return nil
+}
+
+// substitutionBody devuelve el contenido del primer grupo de la sustitución: $(cmd), <(cmd), =(cmd)
+// de zsh, (cmd) de fish y, en PowerShell, (cmd).Metodo(...), @(cmd) o {bloque}
+func substitutionBody(value string) string {
+	open := strings.IndexAny(value, "({")
+	if open < 0 {
+		return ""
+	}
+	depth := 0
+	for i := open; i < len(value); i++ {
+		switch value[i] {
+		case '(', '{':
+			depth++
+		case ')', '}':
+			depth--
+			if depth == 0 {
+				return value[open+1 : i]
+			}
+		case '\'', '"':
+			if end := strings.IndexByte(value[i+1:], value[i]); end >= 0 {
+				i += end + 1
+			}
+		}
+	}
+	return value[open+1:]
+}
+
+// findQuotedSubstitutions busca $(...) y, si backticks lo indica, `...` dentro del contenido de un
+// string con comillas dobles (en PowerShell el acento grave es el carácter de escape)
+func findQuotedSubstitutions(content string, backticks bool) []string {
+	var bodies []string
+
+	for i := 0; i < len(content); i++ {
+		switch {
+		case strings.HasPrefix(content[i:], "$("):
+			depth := 0
+			for j := i + 1; j < len(content); j++ {
+				if content[j] == '(' {
+					depth++
+				} else if content[j] == ')' {
+					depth--
+					if depth == 0 {
+						bodies = append(bodies, content[i+2:j])
+						i = j
+						break
+					}
+				}
+			}
+		case content[i] == '`' && backticks:
+			if end := strings.IndexByte(content[i+1:], '`'); end >= 0 {
+				bodies = append(bodies, content[i+1:i+1+end])
+				i += end + 1
+			}
+		}
+	}
+
+	return bodies
+}
+
 func (p *Parser) hasPipes(tokens []models.Token) bool {
 	for _, token := range tokens {
 		if token.Type == models.PIPE {
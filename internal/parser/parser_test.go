@@ -0,0 +1,69 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+
+	"terminal-history-analyzer/internal/lexer"
+)
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		content   string
+		flags     map[string]string
+		arguments []string
+	}{
+		{
+			content:   "rm -rf build",
+			flags:     map[string]string{"rf": "true"},
+			arguments: []string{"build"},
+		},
+		{
+			content:   "ls -la /tmp",
+			flags:     map[string]string{"la": "true"},
+			arguments: []string{"/tmp"},
+		},
+		{
+			content:   "curl -o out.html http://example.com",
+			flags:     map[string]string{"o": "out.html"},
+			arguments: []string{"http://example.com"},
+		},
+		{
+			content:   "curl --output=/tmp/x http://example.com/a",
+			flags:     map[string]string{"output": "/tmp/x"},
+			arguments: []string{"http://example.com/a"},
+		},
+		{
+			content:   "ssh -p 2222 host",
+			flags:     map[string]string{"p": "2222"},
+			arguments: []string{"host"},
+		},
+		{
+			content:   "sudo grep -v foo file.txt",
+			flags:     map[string]string{"v": "true"},
+			arguments: []string{"grep", "foo", "file.txt"},
+		},
+		{
+			content:   "apt install build-essential",
+			flags:     map[string]string{},
+			arguments: []string{"install", "build-essential"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.content, func(t *testing.T) {
+			tokens, _ := lexer.NewLexer(tt.content).Tokenize()
+			commands, _, _ := NewParser(tokens).Parse()
+			if len(commands) != 1 {
+				t.Fatalf("comandos = %d, se esperaba 1", len(commands))
+			}
+			cmd := commands[0]
+			if !reflect.DeepEqual(cmd.Flags, tt.flags) {
+				t.Errorf("flags = %v, se esperaba %v", cmd.Flags, tt.flags)
+			}
+			if !reflect.DeepEqual(cmd.Arguments, tt.arguments) {
+				t.Errorf("argumentos = %q, se esperaba %q", cmd.Arguments, tt.arguments)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package parser
+
+import (
+	"strings"
+
+	"terminal-history-analyzer/internal/models"
+)
+
+// powerShellSwitches son los parámetros de PowerShell que no reciben valor; el resto toma la
+// palabra siguiente (-OutFile archivo, -WindowStyle Hidden)
+var powerShellSwitches = map[string]bool{
+	"noprofile": true, "nop": true, "noninteractive": true, "noni": true, "nologo": true, "noexit": true,
+	"sta": true, "mta": true, "force": true, "recurse": true, "usebasicparsing": true, "verbose": true,
+	"whatif": true, "confirm": true, "passthru": true, "wait": true, "asjob": true, "raw": true,
+	"directory": true,
+}
+
+// NewPowerShellParser crea un parser para los tokens de NewPowerShellLexer; PowerShell no distingue
+// mayúsculas ni usa la lista de comandos de Unix, así que no se revisa la ortografía
+func NewPowerShellParser(tokens []models.Token) *Parser {
+	p := NewParser(tokens)
+	p.powershell = true
+	return p
+}
+
+// parsePowerShellCommand analiza una sentencia de PowerShell hasta el fin de línea, ; , && o ||:
+// una asignación ($url = 'http://...'), un comando con sus tuberías o una expresión
+// ((New-Object Net.WebClient).DownloadString(...))
+func (p *Parser) parsePowerShellCommand() *models.CommandAST {
+	startLine := p.current().Line
+	leadingSpace := p.startsWithSpace()
+	var tokens []models.Token
+
+	for p.position < len(p.tokens) {
+		token := p.current()
+		if token.Type == models.NEWLINE || token.Type == models.EOF {
+			break
+		}
+		p.position++
+		if token.Type == models.OPERATOR && (token.Value == ";" || token.Value == "&&" || token.Value == "||") {
+			break
+		}
+		tokens = append(tokens, token)
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+	raw := joinTokens(tokens)
+
+	// Asignaciones: $url = 'http://...', $env:PATH = ..., $r = Invoke-WebRequest ...
+	if len(tokens) > 1 && tokens[0].Type == models.VARIABLE && isOperator(tokens[1], "=") {
+		cmd := p.parsePowerShellAssignment(tokens, startLine, raw)
+		if cmd != nil {
+			cmd.LeadingSpace = leadingSpace
+		}
+		return cmd
+	}
+
+	cmd := p.parsePowerShellPipeline(tokens, startLine, raw)
+	if cmd == nil {
+		p.addError("Se esperaba un comando", startLine, raw)
+		return nil
+	}
+	cmd.LeadingSpace = leadingSpace
+	return cmd
+}
+
+// parsePowerShellAssignment construye el comando de una asignación. Si el valor es un comando
+// ($r = iwr http://x), el comando es ese y lleva la asignación; si no, el comando es la asignación
+// como en bash (url='http://x')
+func (p *Parser) parsePowerShellAssignment(tokens []models.Token, line int, raw string) *models.CommandAST {
+	name := strings.Trim(strings.TrimPrefix(tokens[0].Value, "$"), "{}")
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "env:"), "global:")
+	values := tokens[2:]
+	value := joinTokens(values)
+	assignment := models.Assignment{Name: name, Value: unquoteValue(value)}
+
+	if len(values) > 0 && values[0].Type == models.COMMAND {
+		cmd := p.parsePowerShellPipeline(values, line, raw)
+		if cmd != nil {
+			cmd.Assignments = []models.Assignment{assignment}
+		}
+		return cmd
+	}
+
+	cmd := &models.CommandAST{
+		Command:     name + "=" + value,
+		Arguments:   make([]string, 0),
+		Flags:       make(map[string]string),
+		Redirects:   make([]models.Redirect, 0),
+		Line:        line,
+		Raw:         raw,
+		Assignments: []models.Assignment{assignment},
+	}
+	for _, token := range values {
+		p.parseSubstitutions(cmd, token)
+	}
+	return cmd
+}
+
+// parsePowerShellPipeline divide la sentencia por | y analiza cada segmento
+func (p *Parser) parsePowerShellPipeline(tokens []models.Token, line int, raw string) *models.CommandAST {
+	var segments [][]models.Token
+	start := 0
+	for i, token := range tokens {
+		if token.Type == models.PIPE {
+			segments = append(segments, tokens[start:i])
+			start = i + 1
+		}
+	}
+	segments = append(segments, tokens[start:])
+
+	main := p.parsePowerShellSimple(segments[0], line)
+	if main == nil {
+		return nil
+	}
+	main.Raw = raw
+	for _, segment := range segments[1:] {
+		if piped := p.parsePowerShellSimple(segment, line); piped != nil {
+			main.Pipes = append(main.Pipes, piped)
+		}
+	}
+	return main
+}
+
+// parsePowerShellSimple analiza un comando sin tuberías; los operadores de invocación (& y .) no
+// forman parte del comando
+func (p *Parser) parsePowerShellSimple(tokens []models.Token, line int) *models.CommandAST {
+	for len(tokens) > 0 && (isOperator(tokens[0], "&") || isOperator(tokens[0], ".")) {
+		tokens = tokens[1:]
+	}
+	if len(tokens) == 0 || tokens[0].Type == models.REDIRECT || tokens[0].Type == models.OPERATOR {
+		return nil
+	}
+
+	cmd := &models.CommandAST{
+		Command:   unquoteValue(tokens[0].Value),
+		Arguments: make([]string, 0),
+		Flags:     make(map[string]string),
+		Redirects: make([]models.Redirect, 0),
+		Line:      line,
+		Raw:       joinTokens(tokens),
+	}
+	p.parseSubstitutions(cmd, tokens[0]) // (New-Object Net.WebClient).DownloadString(...)
+
+	for i := 1; i < len(tokens); i++ {
+		token := tokens[i]
+		switch token.Type {
+		case models.FLAG:
+			p.parsePowerShellParameter(cmd, tokens, &i)
+		case models.REDIRECT:
+			p.parseRedirect(cmd, tokens, &i)
+		case models.OPERATOR:
+			continue
+		default:
+			cmd.Arguments = append(cmd.Arguments, token.Value)
+			p.parseSubstitutions(cmd, token)
+		}
+	}
+	return cmd
+}
+
+// parsePowerShellParameter registra -Nombre:valor, -Nombre valor o el switch -Nombre
+func (p *Parser) parsePowerShellParameter(cmd *models.CommandAST, tokens []models.Token, index *int) {
+	name := strings.TrimPrefix(tokens[*index].Value, "-")
+	if colon := strings.Index(name, ":"); colon > 0 {
+		cmd.Flags[name[:colon]] = name[colon+1:]
+		return
+	}
+
+	if *index+1 < len(tokens) && !powerShellSwitches[strings.ToLower(name)] {
+		next := tokens[*index+1]
+		if next.Type != models.FLAG && next.Type != models.REDIRECT && next.Type != models.OPERATOR {
+			cmd.Flags[name] = next.Value
+			p.parseSubstitutions(cmd, next)
+			*index++
+			return
+		}
+	}
+	cmd.Flags[name] = "true"
+}
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"strings"
+
 	"terminal-history-analyzer/internal/models"
 )
 
@@ -56,6 +58,35 @@ func getKnownCommands() map[string]bool {
 		// Comandos de monitoreo
 		"lsof", "strace", "ltrace", "tcpdump", "wireshark", "iotop",
 		"vmstat", "iostat", "free", "uptime", "uname",
+
+		// Shells e intérpretes
+		"bash", "sh", "zsh", "fish", "dash", "perl", "ruby", "php", "source",
+		"exec", "eval", "exit", "logout", "set", "unset", "read", "printf",
+
+		// Palabras reservadas y builtins de scripts
+		"if", "then", "else", "elif", "fi", "for", "while", "until", "do", "done", "case", "esac",
+		"select", "function", "test", "[", "[[", "local", "declare", "typeset", "readonly", "return",
+		"shift", "getopts", "trap", "wait", "true", "false", "break", "continue", ":", "command",
+		"type", "pushd", "popd", "umask", "ulimit", "mapfile", "readarray",
+
+		// Builtins de zsh y fish
+		"setopt", "unsetopt", "autoload", "zstyle", "bindkey", "compinit", "compdef", "zmodload",
+		"zle", "print", "noglob", "funcsave", "funced", "abbr",
+		"fish_add_path", "fish_config", "set_color", "string", "math", "contains", "psub", "status",
+		"functions", "emit",
+
+		// Discos y administración
+		"dd", "mkfs", "fdisk", "parted", "lsblk", "blkid", "usermod", "groupadd",
+		"chgrp", "install", "truncate", "shred", "journalctl", "at", "env",
+		"hostname", "ip", "ifconfig", "date", "sleep", "stat", "last", "who", "w",
+
+		// Gestores de paquetes y herramientas de desarrollo
+		"apt", "apt-get", "yum", "dnf", "brew", "go", "cargo", "rustc", "yarn",
+		"pip3", "terraform", "aws", "gcloud", "az",
+
+		// Utilidades de codificación y red adicionales
+		"base64", "xxd", "rev", "mkfifo", "ncat", "ssh-keygen", "ssh-copy-id",
+		"sshpass", "mysql", "psql", "nsenter",
 	}
 
 	result := make(map[string]bool)
@@ -165,6 +196,44 @@ func getCommonTypos() map[string]string {
 	}
 }
 
+// commonSubcommandTypos contiene errores típicos al escribir subcomandos, por comando
+var commonSubcommandTypos = map[string]map[string]string{
+	"git": {
+		"comit": "commit", "commti": "commit", "commmit": "commit", "ocmmit": "commit",
+		"psuh": "push", "puhs": "push", "pussh": "push",
+		"pul": "pull", "plul": "pull", "pulll": "pull",
+		"stauts": "status", "satus": "status", "statsu": "status", "stats": "status",
+		"chekcout": "checkout", "checkotu": "checkout", "chekout": "checkout",
+		"brnach": "branch", "branc": "branch",
+		"mrege": "merge", "merg": "merge",
+		"clnoe": "clone", "clne": "clone",
+		"dif": "diff", "lgo": "log", "rebsae": "rebase",
+	},
+	"docker": {
+		"biuld": "build", "buidl": "build", "rnu": "run", "urn": "run",
+		"imgaes": "images", "imaegs": "images", "pul": "pull", "psuh": "push",
+	},
+	"kubectl": {
+		"gte": "get", "teg": "get", "aplly": "apply", "appyl": "apply",
+		"delte": "delete", "descibe": "describe", "desribe": "describe", "exce": "exec",
+	},
+	"npm": {
+		"isntall": "install", "intall": "install", "instal": "install", "rn": "run",
+	},
+	"apt": {
+		"instal": "install", "isntall": "install", "intall": "install",
+		"udpate": "update", "upadte": "update", "upgarde": "upgrade",
+	},
+	"apt-get": {
+		"instal": "install", "isntall": "install", "intall": "install",
+		"udpate": "update", "upadte": "update", "upgarde": "upgrade",
+	},
+	"systemctl": {
+		"restat": "restart", "retsart": "restart", "strat": "start", "stauts": "status",
+		"enabel": "enable", "stpo": "stop",
+	},
+}
+
 // CheckSpelling verifica si un comando está mal escrito
 func (sc *SpellChecker) CheckSpelling(command string) *models.SpellingSuggestion {
 	// Si el comando es válido, no hay problema
@@ -172,6 +241,11 @@ func (sc *SpellChecker) CheckSpelling(command string) *models.SpellingSuggestion
 		return nil
 	}
 
+	// Los ejecutables invocados por ruta (./script.sh, /bin/sh) no son errores de tipeo
+	if strings.Contains(command, "/") {
+		return nil
+	}
+
 	// Verificar errores conocidos primero
 	if correction, exists := sc.commonTypos[command]; exists {
 		return &models.SpellingSuggestion{
@@ -208,6 +282,91 @@ func (sc *SpellChecker) CheckSpelling(command string) *models.SpellingSuggestion
 	return nil
 }
 
+// CheckSubcommand verifica si el subcomando de un comando (git comit) está mal escrito
+func (sc *SpellChecker) CheckSubcommand(command, subcommand string) *models.SpellingSuggestion {
+	spec, exists := lookupSpec(command)
+	if !exists || len(spec.subcommands) == 0 || containsString(spec.subcommands, subcommand) {
+		return nil
+	}
+
+	if correction, exists := commonSubcommandTypos[command][subcommand]; exists {
+		return &models.SpellingSuggestion{
+			Original:   subcommand,
+			Suggested:  correction,
+			Confidence: 0.95,
+			Reason:     "Error de tipeo común en subcomando de " + command,
+		}
+	}
+
+	best := closestWord(subcommand, spec.subcommands, 2)
+	if best == nil {
+		return nil
+	}
+
+	return &models.SpellingSuggestion{
+		Original:   subcommand,
+		Suggested:  best.Command,
+		Confidence: best.Similarity,
+		Reason:     "Subcomando similar de " + command + " encontrado",
+	}
+}
+
+// CheckFlag verifica si una flag larga (--recusive) está mal escrita para el comando dado
+func (sc *SpellChecker) CheckFlag(command, flag string) *models.SpellingSuggestion {
+	spec, exists := lookupSpec(command)
+	if !exists || IsKnownFlag(command, flag) {
+		return nil
+	}
+
+	// Solo se corrigen flags largas: las cortas agrupadas son ambiguas
+	name := strings.TrimLeft(flag, "-")
+	prefix := flag[:len(flag)-len(name)]
+	if prefix != "--" && !(spec.singleDashLong && len(name) > 1) {
+		return nil
+	}
+
+	value := ""
+	if idx := strings.Index(name, "="); idx >= 0 {
+		name, value = name[:idx], name[idx:]
+	}
+
+	candidates := append(append([]string{}, spec.longFlags...), spec.longValue...)
+	best := closestWord(name, candidates, 2)
+	if best == nil {
+		return nil
+	}
+
+	return &models.SpellingSuggestion{
+		Original:   flag,
+		Suggested:  prefix + best.Command + value,
+		Confidence: best.Similarity,
+		Reason:     "Flag similar de " + command + " encontrada",
+	}
+}
+
+// closestWord devuelve la palabra más parecida dentro de la distancia máxima
+func closestWord(word string, candidates []string, maxDistance int) *internalCommandSuggestion {
+	var best *internalCommandSuggestion
+
+	for _, candidate := range candidates {
+		distance := levenshteinDistance(word, candidate)
+		if distance == 0 || distance > maxDistance {
+			continue
+		}
+
+		similarity := 1.0 - (float64(distance) / float64(max(len(word), len(candidate))))
+		if best == nil || similarity > best.Similarity {
+			best = &internalCommandSuggestion{
+				Command:    candidate,
+				Distance:   distance,
+				Similarity: similarity,
+			}
+		}
+	}
+
+	return best
+}
+
 // internalCommandSuggestion representa una sugerencia interna
 type internalCommandSuggestion struct {
 	Command    string
@@ -0,0 +1,150 @@
+package risk
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"terminal-history-analyzer/internal/models"
+)
+
+// Assess puntúa las detecciones de una sesión de sessionLines líneas con los pesos activos
+func Assess(threats []models.ThreatDetection, sessionLines int) models.RiskAssessment {
+	return AssessWithWeights(threats, sessionLines, ActiveWeights())
+}
+
+// AssessWithWeights puntúa las detecciones con los pesos indicados
+func AssessWithWeights(threats []models.ThreatDetection, sessionLines int, w Weights) models.RiskAssessment {
+	if sessionLines < 1 {
+		sessionLines = 1
+	}
+	for _, threat := range threats {
+		if threat.Line > sessionLines {
+			sessionLines = threat.Line
+		}
+	}
+
+	assessment := models.RiskAssessment{
+		Level:         models.SAFE,
+		Contributions: make([]models.RiskContribution, 0, len(threats)),
+	}
+
+	byLine := make(map[int][]int)
+	for i, threat := range threats {
+		if w.ignores(threat.Type) {
+			continue
+		}
+		byLine[threat.Line] = append(byLine[threat.Line], i)
+	}
+
+	seen := make(map[string]int)
+	for i, threat := range threats {
+		if w.ignores(threat.Type) {
+			continue
+		}
+		key := threat.Type + "|" + threat.RuleID
+		seen[key]++
+
+		contribution := models.RiskContribution{
+			ThreatIndex: i,
+			Line:        threat.Line,
+			Type:        threat.Type,
+			RuleID:      threat.RuleID,
+			Level:       threat.Level,
+			Severity:    w.Severity[threat.Level],
+			Confidence:  round(1 - w.Confidence + w.Confidence*confidence(threat, w)),
+			Rarity:      math.Pow(w.Rarity, float64(seen[key]-1)),
+			Chaining:    1,
+			Recency:     round(1 - w.Recency + w.Recency*float64(threat.Line)/float64(sessionLines)),
+		}
+		if reason := chainReason(threats, byLine, i, w.ChainWindow); reason != "" {
+			contribution.Chaining = 1 + w.Chaining
+			contribution.ChainReason = reason
+		}
+
+		contribution.Points = round(contribution.Severity * contribution.Confidence * contribution.Rarity *
+			contribution.Chaining * contribution.Recency)
+		assessment.RawPoints += contribution.Points
+		assessment.Contributions = append(assessment.Contributions, contribution)
+	}
+
+	assessment.RawPoints = round(assessment.RawPoints)
+	assessment.Score = int(math.Round(100 * (1 - math.Exp(-assessment.RawPoints/w.Saturation))))
+	assessment.Level = levelForScore(assessment.Score)
+
+	for i := range assessment.Contributions {
+		if assessment.RawPoints > 0 {
+			assessment.Contributions[i].Share = round(100 * assessment.Contributions[i].Points / assessment.RawPoints)
+		}
+	}
+	sort.SliceStable(assessment.Contributions, func(i, j int) bool {
+		return assessment.Contributions[i].Points > assessment.Contributions[j].Points
+	})
+
+	return assessment
+}
+
+// confidence devuelve la confianza (0-1) de una detección
+func confidence(threat models.ThreatDetection, w Weights) float64 {
+	if threat.ThreatIntel != nil {
+		return float64(threat.ThreatIntel.Confidence) / 100
+	}
+	if value, ok := w.TypeConfidence[threat.Type]; ok {
+		return value
+	}
+	return 1
+}
+
+// chainReason indica por qué una detección forma parte de una cadena de ataque, o "" si no
+func chainReason(threats []models.ThreatDetection, byLine map[int][]int, index, window int) string {
+	threat := threats[index]
+	switch {
+	case len(threat.Chain) > 0:
+		return "cadena de " + strconv.Itoa(len(threat.Chain)) + " pasos"
+	case threat.Deobfuscation != nil:
+		return "comando ofuscado"
+	case threat.Host != "":
+		return "ejecutado en un host remoto (" + threat.Host + ")"
+	}
+
+	// Detecciones cercanas de otra táctica ATT&CK (descarga, ejecución, persistencia, ...)
+	if !significant(threat) {
+		return ""
+	}
+	for line := threat.Line - window; line <= threat.Line+window; line++ {
+		for _, i := range byLine[line] {
+			other := threats[i]
+			if i != index && significant(other) && other.Attack.TacticID != threat.Attack.TacticID {
+				return "junto a " + other.Attack.Tactic + " en la línea " + strconv.Itoa(other.Line)
+			}
+		}
+	}
+	return ""
+}
+
+// significant indica si la detección cuenta para el encadenamiento (tiene táctica y no es leve);
+// los tipos ignorados no están en byLine
+func significant(threat models.ThreatDetection) bool {
+	return threat.Attack != nil && threat.Level != models.LOW && threat.Level != models.SAFE
+}
+
+// levelForScore convierte la puntuación en nivel
+func levelForScore(score int) models.ThreatLevel {
+	switch {
+	case score >= 75:
+		return models.CRITICAL
+	case score >= 50:
+		return models.HIGH
+	case score >= 25:
+		return models.MEDIUM
+	case score > 0:
+		return models.LOW
+	default:
+		return models.SAFE
+	}
+}
+
+// round redondea a dos decimales para que el desglose sea legible
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,147 @@
+// Package risk calcula la puntuación de riesgo de una sesión (0-100) a partir de sus detecciones,
+// con el desglose de lo que aporta cada una
+package risk
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"terminal-history-analyzer/internal/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Weights son los parámetros del modelo de puntuación. Cada detección aporta
+//
+//	puntos = severidad × confianza × rareza × encadenamiento × recencia
+//
+// y la sesión obtiene 100 × (1 − e^(−Σpuntos / saturación)): pocas detecciones graves pesan más
+// que muchas leves y la puntuación nunca supera 100.
+type Weights struct {
+	// Puntos base por nivel de la detección
+	Severity map[models.ThreatLevel]float64 `yaml:"severity" json:"severity"`
+
+	// Peso de la confianza (0-1): factor = 1 − peso + peso × confianza. Las detecciones de reglas
+	// tienen confianza 1; las de fuentes de inteligencia usan la confianza de la fuente.
+	Confidence float64 `yaml:"confidence" json:"confidence"`
+
+	// Confianza por tipo de detección cuando el tipo es ruidoso (errores de simulación, entropía)
+	TypeConfidence map[string]float64 `yaml:"type_confidence" json:"type_confidence"`
+
+	// Tipos de detección que no cuentan para el riesgo (p. ej. errores de la simulación del sistema de archivos)
+	IgnoreTypes []string `yaml:"ignore_types" json:"ignore_types"`
+
+	// Rareza (0-1): la n-ésima repetición de la misma regla aporta rareza^(n−1) de sus puntos
+	Rarity float64 `yaml:"rarity" json:"rarity"`
+
+	// Encadenamiento: factor 1 + peso si la detección forma parte de una cadena (descarga → ejecución,
+	// comando ofuscado, sesión remota o detecciones de otras tácticas a menos de ChainWindow líneas)
+	Chaining    float64 `yaml:"chaining" json:"chaining"`
+	ChainWindow int     `yaml:"chain_window" json:"chain_window"`
+
+	// Peso de la recencia (0-1): factor = 1 − peso + peso × línea / líneas de la sesión
+	Recency float64 `yaml:"recency" json:"recency"`
+
+	// Puntos con los que la puntuación alcanza el 63% (1 − 1/e)
+	Saturation float64 `yaml:"saturation" json:"saturation"`
+}
+
+// DefaultWeights devuelve los pesos por defecto
+func DefaultWeights() Weights {
+	return Weights{
+		Severity: map[models.ThreatLevel]float64{
+			models.CRITICAL: 60,
+			models.HIGH:     25,
+			models.MEDIUM:   8,
+			models.LOW:      2,
+			models.SAFE:     0,
+		},
+		Confidence: 0.8,
+		TypeConfidence: map[string]float64{
+			"credential_exposure": 0.9,
+		},
+		IgnoreTypes: []string{"filesystem_error"},
+		Rarity:      0.5,
+		Chaining:    0.5,
+		ChainWindow: 5,
+		Recency:     0.3,
+		Saturation:  50,
+	}
+}
+
+var (
+	activeWeights   = DefaultWeights()
+	activeWeightsMu sync.RWMutex
+)
+
+// ActiveWeights devuelve los pesos activos
+func ActiveWeights() Weights {
+	activeWeightsMu.RLock()
+	defer activeWeightsMu.RUnlock()
+	return activeWeights
+}
+
+// Configure carga los pesos de un archivo YAML sobre los valores por defecto y los activa;
+// sin archivo (o si no existe) se usan los valores por defecto
+func Configure(path string) (Weights, error) {
+	weights := DefaultWeights()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return Weights{}, fmt.Errorf("no se pudo leer el archivo de pesos %s: %w", path, err)
+		}
+		if err == nil {
+			if err := yaml.Unmarshal(data, &weights); err != nil {
+				return Weights{}, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	}
+
+	if err := weights.validate(); err != nil {
+		return Weights{}, err
+	}
+
+	activeWeightsMu.Lock()
+	activeWeights = weights
+	activeWeightsMu.Unlock()
+
+	return weights, nil
+}
+
+// ignores indica si el tipo de detección no cuenta para el riesgo
+func (w Weights) ignores(threatType string) bool {
+	for _, ignored := range w.IgnoreTypes {
+		if ignored == threatType {
+			return true
+		}
+	}
+	return false
+}
+
+// validate comprueba los rangos de los pesos
+func (w Weights) validate() error {
+	for level, points := range w.Severity {
+		if points < 0 {
+			return fmt.Errorf("pesos de riesgo: puntos negativos para %s", level)
+		}
+	}
+	for name, value := range map[string]float64{"confidence": w.Confidence, "rarity": w.Rarity, "recency": w.Recency} {
+		if value < 0 || value > 1 {
+			return fmt.Errorf("pesos de riesgo: %s debe estar entre 0 y 1", name)
+		}
+	}
+	for threatType, value := range w.TypeConfidence {
+		if value < 0 || value > 1 {
+			return fmt.Errorf("pesos de riesgo: la confianza de %s debe estar entre 0 y 1", threatType)
+		}
+	}
+	if w.Chaining < 0 || w.ChainWindow < 0 {
+		return fmt.Errorf("pesos de riesgo: chaining y chain_window no pueden ser negativos")
+	}
+	if w.Saturation <= 0 {
+		return fmt.Errorf("pesos de riesgo: saturation debe ser mayor que 0")
+	}
+	return nil
+}
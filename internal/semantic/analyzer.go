@@ -1,85 +1,211 @@
 package semantic
 
 import (
-	"regexp"
+	"sort"
 	"strings"
+	"time"
 
+	"terminal-history-analyzer/internal/attack"
+	"terminal-history-analyzer/internal/baseline"
+	"terminal-history-analyzer/internal/dialect"
+	"terminal-history-analyzer/internal/intel"
 	"terminal-history-analyzer/internal/models"
+	"terminal-history-analyzer/internal/redact"
+	"terminal-history-analyzer/internal/suppress"
 )
 
 type Analyzer struct {
+	rules           *RuleSet
 	threats         []models.ThreatDetection
 	patterns        []models.PatternMatch
 	anomalies       []models.Anomaly
 	filesystemState *FileSystemState
+	symbols         *SymbolTable    // Variables de la sesión
+	typed           *typedCommand   // Comando actual tal como se escribió (ver expandSession)
+	unsafeOperands  map[string]bool // Operandos del comando actual reportados como unsafe_expansion
 	fsErrors        []models.FileSystemError
+	dataflow        *dataFlowTracker
+	secrets         []redact.Secret // Credenciales detectadas, para la redacción
+	historyControl  historyControlState
+	systemdUnits    map[string]writtenUnit // Unidades systemd escritas en la sesión, por nombre
+	cloudActivity   map[string]*models.CloudProviderSummary
+	sourceLines     []string               // Texto original de la sesión, para la normalización de comandos ofuscados
+	obfuscated      map[int]*deobfuscation // Líneas ofuscadas de la sesión y su forma decodificada
+	intel           *intel.Matcher
+	intelSeen       map[string]bool // Valores ya comparados con las fuentes, por línea
+	lateral         *lateralTracker
+	privilege       *privilegeTracker // Pila de contextos de usuario y equipo (sudo -i, su, ssh)
+	sequences       *sequenceTracker
+	suppressions    *suppress.Set
+	suppressed      []models.SuppressedThreat
+	sessionUser     string             // Dueño del historial (SetSession)
+	sessionHost     string             // Equipo del historial (SetSession)
+	baseline        *baseline.Baseline // Línea base del dueño del historial (nil si no tiene)
+	observed        *baseline.Session  // Rasgos de la sesión para la línea base (solo con usuario)
+	script          bool               // El contenido es un script, no un historial (SetScript)
+	dialect         string             // Dialecto de shell del contenido (SetDialect)
 }
 
-// Patrones de amenazas existentes...
-var (
-	// Comandos extremadamente peligrosos
-	criticalPatterns = map[string]string{
-		`rm\s+-rf\s+/`:         "Eliminación recursiva del sistema de archivos raíz",
-		`dd\s+if=.*of=/dev/sd`: "Sobrescritura directa de disco",
-		`mkfs`:                 "Formateo de sistema de archivos",
-		`fdisk.*-l`:            "Manipulación de particiones",
-		`chmod\s+777\s+/`:      "Permisos peligrosos en directorio raíz",
-	}
-
-	// Comandos con escalación de privilegios
-	privilegePatterns = map[string]string{
-		`sudo\s+su\s*-`: "Cambio a usuario root",
-		`sudo\s+-s`:     "Shell con privilegios elevados",
-		`sudo\s+passwd`: "Cambio de contraseña con sudo",
-		`su\s+root`:     "Cambio directo a root",
-	}
-
-	// Patrones de red sospechosos
-	networkPatterns = map[string]string{
-		`wget.*http://[^/]*[0-9]+\.[0-9]+\.[0-9]+\.[0-9]+`: "Descarga desde IP directa",
-		`curl.*http://[^/]*[0-9]+\.[0-9]+\.[0-9]+\.[0-9]+`: "Descarga con curl desde IP",
-		`ssh.*[0-9]+\.[0-9]+\.[0-9]+\.[0-9]+`:              "Conexión SSH a IP directa",
-		`nc\s+.*[0-9]+\.[0-9]+\.[0-9]+\.[0-9]+`:            "Netcat a IP directa",
-	}
-
-	// Dominios sospechosos conocidos
-	suspiciousDomains = []string{
-		"pastebin.com", "hastebin.com", "ix.io", "0x0.st",
-		"temp.sh", "transfer.sh", "file.io",
-	}
-
-	// Extensiones de archivos peligrosas
-	dangerousExtensions = []string{
-		".sh", ".py", ".pl", ".exe", ".bat", ".cmd", ".scr",
-	}
-)
-
 func NewAnalyzer() *Analyzer {
-	return &Analyzer{
+	filesystemState := NewFileSystemState()
+	a := &Analyzer{
+		rules:           ActiveRules(),
 		threats:         make([]models.ThreatDetection, 0),
 		patterns:        make([]models.PatternMatch, 0),
 		anomalies:       make([]models.Anomaly, 0),
-		filesystemState: NewFileSystemState(),
+		filesystemState: filesystemState,
+		symbols:         NewSymbolTable(),
 		fsErrors:        make([]models.FileSystemError, 0),
-	}
+		dataflow:        newDataFlowTracker(filesystemState),
+		systemdUnits:    make(map[string]writtenUnit),
+		cloudActivity:   make(map[string]*models.CloudProviderSummary),
+		intel:           intel.Active().Matcher,
+		intelSeen:       make(map[string]bool),
+		lateral:         newLateralTracker(),
+		privilege:       newPrivilegeTracker(),
+		sequences:       newSequenceTracker(),
+		suppressions:    suppress.Active().Snapshot(time.Now()),
+		suppressed:      make([]models.SuppressedThreat, 0),
+		dialect:         dialect.Bash,
+	}
+	filesystemState.homeOf = a.homeFor
+	return a
 }
 
 // Analyze realiza el análisis semántico completo incluyendo el sistema de archivos
 func (a *Analyzer) Analyze(commands []models.CommandAST) ([]models.ThreatDetection, []models.PatternMatch, []models.Anomaly) {
-	// Análisis tradicional de amenazas
+	// Las líneas se recorren en orden; las ofuscadas se analizan también decodificadas, incluso
+	// si el parser no produjo comandos para ellas
+	obfuscated := a.obfuscatedLines(commands)
+	a.obfuscated = obfuscated
+	byLine := make(map[int][]models.CommandAST)
 	for _, cmd := range commands {
-		a.analyzeCommand(cmd)
+		byLine[cmd.Line] = append(byLine[cmd.Line], cmd)
+	}
+
+	for _, line := range sessionLines(byLine, obfuscated) {
+		for _, cmd := range byLine[line] {
+			a.analyzeSessionCommand(cmd)
+		}
+		if result, ok := obfuscated[line]; ok {
+			a.analyzeObfuscated(line, result)
+		}
 	}
 
 	a.detectPatterns(commands)
 	a.detectAnomalies(commands)
-
-	// NUEVO: Análisis del sistema de archivos
-	a.analyzeFileSystem(commands)
+	a.detectBaselineAnomalies()
 
 	return a.threats, a.patterns, a.anomalies
 }
 
+// analyzeSessionCommand pasa un comando por todos los analizadores que dependen del orden de la sesión
+func (a *Analyzer) analyzeSessionCommand(cmd models.CommandAST) {
+	from := len(a.threats)
+	fsFrom, observedFrom := len(a.fsErrors), a.observedCount()
+	context := a.executionContext()
+	user := context.User
+	a.recordContext(cmd, context)
+
+	// Variables de la sesión: los analizadores ven el comando con los valores conocidos sustituidos
+	// y el reporte muestra el original; las asignaciones se aplican después del comando
+	original := cmd
+	cmd = a.expandSession(cmd)
+	defer a.symbols.Process(original)
+	defer func() { a.restoreRaw(from, fsFrom, observedFrom, original.Raw, cmd.Raw) }()
+
+	// Saltos a otros hosts; dentro de una sesión ssh interactiva el comando se ejecuta en el remoto y
+	// tras sudo -i, su o doas -s con otro usuario
+	sessions := len(a.lateral.open)
+	host := a.trackLateral(cmd)
+	a.observeBaseline(cmd)
+	if host != localHost || context.Since > 0 {
+		defer func() {
+			for i := from; i < len(a.threats); i++ {
+				if host != localHost {
+					a.threats[i].Host = host
+				}
+				if context.Since > 0 {
+					a.threats[i].User = user
+				}
+			}
+		}()
+	}
+
+	// Análisis tradicional de amenazas
+	a.analyzeCommand(cmd)
+
+	// Comandos ocultos del historial con HISTCONTROL=ignorespace (en un script el espacio es sangría)
+	if !a.script {
+		a.trackHiddenCommand(cmd)
+	}
+
+	// Unidades systemd creadas y habilitadas en la sesión
+	a.trackSystemdUnits(cmd)
+
+	// Flujo de archivos descargados (usa el directorio actual previo al comando)
+	a.analyzeDataFlow(cmd)
+
+	// Como root las detecciones de mayor alcance suben un nivel
+	a.raiseElevated(from, context)
+
+	// Supresiones vigentes (antes de las secuencias para que no usen detecciones suprimidas)
+	scope := a.suppressionScope(cmd, host, user)
+	a.suppressThreats(from, scope)
+	next := len(a.threats)
+
+	// Secuencias de ataque de varios pasos (usan las detecciones del comando y el directorio previo)
+	a.trackSequences(cmd, host, a.threats[from:])
+
+	// Análisis del sistema de archivos (el modelo es el de Unix; no se aplica a PowerShell)
+	if a.dialect != dialect.PowerShell {
+		a.analyzeFileSystem(cmd)
+	}
+	a.suppressThreats(next, scope)
+
+	// Contextos que abre o cierra el comando (sudo -i, su, ssh, exit); rigen desde el siguiente
+	a.trackContext(cmd, original.Raw, len(a.lateral.open) > sessions)
+}
+
+// sessionLines devuelve los números de línea con comandos o con texto ofuscado, en orden
+func sessionLines(byLine map[int][]models.CommandAST, obfuscated map[int]*deobfuscation) []int {
+	lines := make([]int, 0, len(byLine)+len(obfuscated))
+	for line := range byLine {
+		lines = append(lines, line)
+	}
+	for line := range obfuscated {
+		if _, ok := byLine[line]; !ok {
+			lines = append(lines, line)
+		}
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// dropDuplicateThreats elimina las amenazas añadidas desde from que repiten una detección
+// anterior de la misma línea (el texto decodificado suele conservar partes del original).
+// Las coincidencias con fuentes de inteligencia ya se registran una sola vez por valor y línea.
+func (a *Analyzer) dropDuplicateThreats(from int) {
+	kept := a.threats[:from]
+	for _, threat := range a.threats[from:] {
+		duplicate := false
+		if threat.ThreatIntel != nil {
+			kept = append(kept, threat)
+			continue
+		}
+		for _, previous := range kept {
+			if previous.Line == threat.Line && previous.Type == threat.Type && previous.RuleID == threat.RuleID {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			kept = append(kept, threat)
+		}
+	}
+	a.threats = kept
+}
+
 // AnalyzeWithFileSystem realiza análisis completo y retorna también errores del sistema de archivos
 func (a *Analyzer) AnalyzeWithFileSystem(commands []models.CommandAST) ([]models.ThreatDetection, []models.PatternMatch, []models.Anomaly, models.FileSystemAnalysis) {
 	// Análisis estándar
@@ -91,23 +217,47 @@ func (a *Analyzer) AnalyzeWithFileSystem(commands []models.CommandAST) ([]models
 		State:        a.filesystemState.GetCurrentState(),
 		Dependencies: a.buildDependencyChains(commands),
 		Summary:      a.buildFileSystemSummary(),
+		Variables:    a.symbols.Variables(),
 	}
 
 	return threats, patterns, anomalies, fsAnalysis
 }
 
-// analyzeFileSystem analiza cada comando en el contexto del sistema de archivos
-func (a *Analyzer) analyzeFileSystem(commands []models.CommandAST) {
-	for _, cmd := range commands {
-		// Procesar el comando y detectar errores del sistema de archivos
-		errors := a.filesystemState.ProcessCommand(cmd)
-		a.fsErrors = append(a.fsErrors, errors...)
-
-		// Convertir errores críticos del sistema de archivos en amenazas
-		for _, fsError := range errors {
-			if a.isFileSystemErrorCritical(fsError) {
-				a.addThreat(models.HIGH, "filesystem_error", fsError.Description, cmd)
+// analyzeFileSystem analiza un comando en el contexto del sistema de archivos
+func (a *Analyzer) analyzeFileSystem(cmd models.CommandAST) {
+	// Procesar el comando y detectar errores del sistema de archivos
+	errors := a.filesystemState.ProcessCommand(cmd)
+	a.fsErrors = append(a.fsErrors, errors...)
+
+	// Convertir errores críticos del sistema de archivos en amenazas; un script se ejecutará sobre un
+	// sistema de archivos que no se conoce, así que sus errores quedan solo en el análisis
+	for _, fsError := range errors {
+		if a.isFileSystemErrorCritical(fsError) && !a.script {
+			a.addThreat(models.HIGH, "filesystem_error", fsError.Description, cmd)
+		}
+	}
+}
+
+// analyzeDataFlow sigue el contenido descargado hasta su ejecución (pipes a intérpretes,
+// archivos descargados que se copian, reciben permisos y se ejecutan)
+func (a *Analyzer) analyzeDataFlow(cmd models.CommandAST) {
+	for _, finding := range a.dataflow.Process(cmd) {
+		if finding.anomaly {
+			commands := make([]string, 0, len(finding.chain))
+			for _, step := range finding.chain {
+				commands = append(commands, step.Command)
 			}
+			a.addAnomaly(finding.threatType, finding.description, strings.Join(commands, " ; "), finding.chain[0].Line)
+			a.anomalies[len(a.anomalies)-1].Chain = finding.chain
+			continue
+		}
+
+		a.addThreat(finding.level, finding.threatType, finding.description, cmd)
+		threat := &a.threats[len(a.threats)-1]
+		threat.Attack = attack.Lookup(finding.technique)
+		threat.Chain = finding.chain
+		if finding.rewrite != nil {
+			threat.Suggestions = append([]models.Suggestion{*finding.rewrite}, threat.Suggestions...)
 		}
 	}
 }
@@ -216,172 +366,79 @@ func (a *Analyzer) buildFileSystemSummary() models.FileSystemSummary {
 // Funciones existentes del analizador semántico...
 
 func (a *Analyzer) analyzeCommand(cmd models.CommandAST) {
-	// Análisis de comandos críticos
-	a.checkCriticalCommands(cmd)
-
-	// Análisis de escalación de privilegios
-	a.checkPrivilegeEscalation(cmd)
-
-	// Análisis de actividad de red
-	a.checkNetworkActivity(cmd)
-
-	// Análisis de manipulación de archivos
-	a.checkFileManipulation(cmd)
-
-	// Análisis de comandos encadenados peligrosos
-	a.checkCommandChaining(cmd)
-
-	// Análisis de descargas sospechosas
-	a.checkSuspiciousDownloads(cmd)
-}
-
-func (a *Analyzer) checkCriticalCommands(cmd models.CommandAST) {
-	commandLine := cmd.Raw
-
-	for pattern, description := range criticalPatterns {
-		if matched, _ := regexp.MatchString(pattern, commandLine); matched {
-			a.addThreat(models.CRITICAL, "critical_command", description, cmd)
-			return
+	// Reglas declarativas: comandos críticos, escalación de privilegios, red,
+	// archivos sensibles, cadenas y descargas sospechosas
+	for _, hit := range a.rules.Evaluate(cmd, a.filesystemState, a.dialect) {
+		// Los operandos con variables los revisa detectUnsafeExpansions, que ya describe el borrado
+		// con la ruta resultante; el texto sin expandir ($f/) no es un directorio del sistema
+		if hit.rule.Type == "dangerous_deletion" && (a.unsafeOperands[removeQuotes(hit.argument)] || len(references(hit.argument)) > 0) {
+			continue
 		}
+		a.addRuleThreat(hit, cmd)
 	}
 
-	// Verificaciones específicas adicionales
-	if cmd.Command == "rm" {
-		if hasFlag(cmd, "rf") || hasFlag(cmd, "r") && hasFlag(cmd, "f") {
-			for _, arg := range cmd.Arguments {
-				if strings.Contains(arg, "/") && !strings.HasPrefix(arg, "./") {
-					a.addThreat(models.HIGH, "dangerous_deletion",
-						"Eliminación recursiva forzada en directorio del sistema", cmd)
-				}
-			}
-		}
+	// Reverse y bind shells (redirecciones /dev/tcp, netcat, socat, pipelines y código en línea)
+	if finding := detectReverseShell(cmd); finding != nil {
+		a.addThreat(models.CRITICAL, finding.kind, finding.description(), cmd)
+		threat := &a.threats[len(a.threats)-1]
+		threat.Attack = attack.Lookup(finding.technique)
+		threat.Remote = finding.remote
 	}
 
-	if cmd.Command == "dd" {
-		for _, arg := range cmd.Arguments {
-			if strings.Contains(arg, "/dev/") {
-				a.addThreat(models.CRITICAL, "disk_manipulation",
-					"Manipulación directa de dispositivo de disco", cmd)
-			}
+	// Credenciales expuestas (formatos conocidos, flags de contraseña, variables y entropía)
+	for _, finding := range detectSecrets(cmd) {
+		if finding.kind == "high_entropy_string" && a.decodedPayload(cmd.Line, finding.value) {
+			continue
 		}
-	}
-}
-
-func (a *Analyzer) checkPrivilegeEscalation(cmd models.CommandAST) {
-	commandLine := cmd.Raw
-
-	for pattern, description := range privilegePatterns {
-		if matched, _ := regexp.MatchString(pattern, commandLine); matched {
-			a.addThreat(models.HIGH, "privilege_escalation", description, cmd)
-			return
-		}
-	}
-
-	if cmd.Command == "sudo" {
-		if len(cmd.Arguments) > 0 {
-			sudoCmd := cmd.Arguments[0]
-			if contains([]string{"rm", "chmod", "chown", "mount", "umount"}, sudoCmd) {
-				a.addThreat(models.MEDIUM, "sudo_dangerous",
-					"Uso de sudo con comando potencialmente peligroso", cmd)
-			}
-		}
-	}
-}
-
-func (a *Analyzer) checkNetworkActivity(cmd models.CommandAST) {
-	commandLine := cmd.Raw
-
-	for pattern, description := range networkPatterns {
-		if matched, _ := regexp.MatchString(pattern, commandLine); matched {
-			a.addThreat(models.MEDIUM, "suspicious_network", description, cmd)
+		a.addThreat(finding.level, "credential_exposure", finding.description(), cmd)
+		a.threats[len(a.threats)-1].Secret = &models.SecretFinding{
+			Type:     finding.kind,
+			Location: finding.location,
+			Column:   strings.Index(cmd.Raw, finding.value) + 1,
+			Length:   len(finding.value),
+			Entropy:  finding.entropy,
 		}
+		a.secrets = append(a.secrets, redact.Secret{
+			Value:   finding.value,
+			Line:    cmd.Line,
+			Field:   secretField(cmd.Raw, finding.value, finding.location),
+			Command: cmd.Raw,
+			Entropy: shannonEntropy(finding.value),
+		})
 	}
 
-	if contains([]string{"wget", "curl"}, cmd.Command) {
-		for _, arg := range cmd.Arguments {
-			if strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://") {
-				// Verificar dominios sospechosos
-				for _, domain := range suspiciousDomains {
-					if strings.Contains(arg, domain) {
-						a.addThreat(models.MEDIUM, "suspicious_download",
-							"Descarga desde dominio sospechoso: "+domain, cmd)
-					}
-				}
-
-				// Verificar extensiones peligrosas
-				for _, ext := range dangerousExtensions {
-					if strings.HasSuffix(arg, ext) {
-						a.addThreat(models.MEDIUM, "dangerous_file_download",
-							"Descarga de archivo ejecutable", cmd)
-					}
-				}
-			}
-		}
-	}
+	// Operaciones destructivas o de exposición en CLIs de nube (aws, gcloud, az, terraform)
+	a.analyzeCloud(cmd)
 
-	if cmd.Command == "ssh" {
-		// Verificar conexiones SSH sospechosas
-		for _, arg := range cmd.Arguments {
-			if strings.Contains(arg, "root@") {
-				a.addThreat(models.MEDIUM, "root_ssh",
-					"Conexión SSH como usuario root", cmd)
-			}
-			// IPs privadas sospechosas
-			if matched, _ := regexp.MatchString(`192\.168\.|10\.|172\.`, arg); matched {
-				a.addThreat(models.LOW, "private_network_ssh",
-					"Conexión SSH a red privada", cmd)
-			}
-		}
-	}
+	// URLs, hosts, IPs y hashes presentes en las fuentes de inteligencia de amenazas
+	a.analyzeThreatIntel(cmd)
 }
 
-func (a *Analyzer) checkFileManipulation(cmd models.CommandAST) {
-	sensitiveFiles := []string{
-		"/etc/passwd", "/etc/shadow", "/etc/hosts", "/etc/fstab",
-		"/boot/", "/sys/", "/proc/", "~/.ssh/", "~/.bashrc",
-	}
-
-	if contains([]string{"cat", "less", "more", "head", "tail", "grep", "sed", "awk"}, cmd.Command) {
-		for _, arg := range cmd.Arguments {
-			for _, sensitive := range sensitiveFiles {
-				if strings.Contains(arg, sensitive) {
-					a.addThreat(models.MEDIUM, "sensitive_file_access",
-						"Acceso a archivo sensible del sistema: "+arg, cmd)
-				}
-			}
+// analyzeCloud detecta las operaciones de riesgo de las CLIs de nube y acumula su actividad
+func (a *Analyzer) analyzeCloud(cmd models.CommandAST) {
+	segments := append([]*models.CommandAST{&cmd}, cmd.Pipes...)
+	for _, segment := range segments {
+		inv := parseCloudInvocation(newCommandView(*segment))
+		if inv == nil {
+			continue
 		}
-	}
-}
-
-func (a *Analyzer) checkCommandChaining(cmd models.CommandAST) {
-	// Esta función necesitaría acceso a comandos anteriores para detectar patrones
-	// Por ahora, detectamos algunos patrones básicos
 
-	if strings.Contains(cmd.Raw, "&&") || strings.Contains(cmd.Raw, ";") {
-		if strings.Contains(cmd.Raw, "wget") && strings.Contains(cmd.Raw, "chmod") {
-			a.addThreat(models.HIGH, "download_execute_chain",
-				"Cadena de descarga y ejecución detectada", cmd)
+		findings := detectCloudRisks(inv, segment.Raw)
+		for _, finding := range findings {
+			a.addThreat(finding.operation.level, finding.operation.kind, finding.description(), cmd)
+			threat := &a.threats[len(a.threats)-1]
+			if finding.operation.technique != "" {
+				threat.Attack = attack.Lookup(finding.operation.technique)
+			}
+			threat.Details = finding.details(inv)
 		}
+		a.recordCloudActivity(inv, findings)
 	}
 }
 
-func (a *Analyzer) checkSuspiciousDownloads(cmd models.CommandAST) {
-	if contains([]string{"wget", "curl"}, cmd.Command) {
-		for _, arg := range cmd.Arguments {
-			// Verificar patrones sospechosos en URLs
-			suspiciousPatterns := []string{
-				"malware", "payload", "exploit", "backdoor", "shell",
-				"reverse", "bind", "nc", "netcat",
-			}
-
-			for _, pattern := range suspiciousPatterns {
-				if strings.Contains(strings.ToLower(arg), pattern) {
-					a.addThreat(models.HIGH, "suspicious_filename",
-						"Descarga con nombre sospechoso: "+pattern, cmd)
-				}
-			}
-		}
-	}
+// Secrets devuelve las credenciales detectadas para enmascararlas en la salida
+func (a *Analyzer) Secrets() []redact.Secret {
+	return a.secrets
 }
 
 func (a *Analyzer) detectPatterns(commands []models.CommandAST) {
@@ -414,18 +471,12 @@ func (a *Analyzer) detectPatterns(commands []models.CommandAST) {
 }
 
 func (a *Analyzer) detectAnomalies(commands []models.CommandAST) {
-	// Detectar anomalías en secuencias de comandos
+	// Detectar anomalías en pares de comandos consecutivos (descarga y chmod +x se sigue en
+	// analyzeDataFlow; las cadenas de varios pasos se declaran en rules/sequences.yaml)
 	for i := 0; i < len(commands)-1; i++ {
 		current := commands[i]
 		next := commands[i+1]
 
-		// wget/curl seguido de chmod +x
-		if contains([]string{"wget", "curl"}, current.Command) && next.Command == "chmod" && hasFlag(next, "x") {
-			a.addAnomaly("download_execute_sequence",
-				"Secuencia de descarga y dar permisos de ejecución",
-				current.Raw+" ; "+next.Raw, current.Line)
-		}
-
 		// sudo seguido de rm
 		if current.Command == "sudo" && next.Command == "rm" {
 			a.addAnomaly("sudo_delete_sequence",
@@ -436,7 +487,7 @@ func (a *Analyzer) detectAnomalies(commands []models.CommandAST) {
 }
 
 func (a *Analyzer) addThreat(level models.ThreatLevel, threatType, description string, cmd models.CommandAST) {
-	suggestions := generateSuggestions(threatType, cmd)
+	suggestions := a.generateSuggestions(threatType, cmd, nil)
 
 	threat := models.ThreatDetection{
 		Type:        threatType,
@@ -445,17 +496,36 @@ func (a *Analyzer) addThreat(level models.ThreatLevel, threatType, description s
 		Command:     cmd.Raw,
 		Line:        cmd.Line,
 		Suggestions: suggestions,
+		Attack:      attack.ForType(threatType),
 	}
 
 	a.threats = append(a.threats, threat)
 }
 
+// addRuleThreat registra una amenaza producida por una regla declarativa
+func (a *Analyzer) addRuleThreat(hit ruleHit, cmd models.CommandAST) {
+	suggestions := a.generateSuggestions(hit.rule.Type, cmd, hit.rule.Remediation)
+
+	a.threats = append(a.threats, models.ThreatDetection{
+		RuleID:      hit.rule.ID,
+		Type:        hit.rule.Type,
+		Level:       hit.rule.Severity,
+		Description: hit.message(cmd),
+		Command:     cmd.Raw,
+		Line:        cmd.Line,
+		Suggestions: suggestions,
+		Attack:      hit.rule.attackTechnique(),
+		Details:     hit.details(cmd),
+	})
+}
+
 func (a *Analyzer) addPattern(patternType, description string, occurrences int, examples []string) {
 	pattern := models.PatternMatch{
 		Pattern:     patternType,
 		Description: description,
 		Occurrences: occurrences,
 		Examples:    examples,
+		Attack:      attack.ForType(patternType),
 	}
 
 	a.patterns = append(a.patterns, pattern)
@@ -467,6 +537,7 @@ func (a *Analyzer) addAnomaly(anomalyType, description, command string, line int
 		Description: description,
 		Command:     command,
 		Line:        line,
+		Attack:      attack.ForType(anomalyType),
 	}
 
 	a.anomalies = append(a.anomalies, anomaly)
@@ -487,7 +558,8 @@ func contains(slice []string, item string) bool {
 	return false
 }
 
-func generateSuggestions(threatType string, cmd models.CommandAST) []string {
+// typeAdvice devuelve los consejos generales de un tipo de detección
+func typeAdvice(threatType string) []string {
 	switch threatType {
 	case "critical_command":
 		return []string{
@@ -507,6 +579,123 @@ func generateSuggestions(threatType string, cmd models.CommandAST) []string {
 			"Use dominios oficiales y repositorios confiables",
 			"Escanee archivos descargados antes de ejecutarlos",
 		}
+	case "reverse_shell", "bind_shell":
+		return []string{
+			"Aísle el equipo y revise las conexiones activas (ss -tnp) hacia el extremo remoto",
+			"Bloquee la dirección y el puerto en el firewall perimetral",
+			"Investigue cómo se obtuvo acceso para ejecutar este comando y revise el proceso padre",
+		}
+	case "pipe_to_interpreter":
+		return []string{
+			"Descargue el script a un archivo y revise su contenido antes de ejecutarlo",
+			"Verifique la integridad con un checksum o firma publicada por el proveedor",
+			"Prefiera paquetes del gestor de la distribución en lugar de scripts remotos",
+		}
+	case "download_execute":
+		return []string{
+			"Revise el origen del archivo descargado y su hash antes de ejecutarlo",
+			"Analice el binario en un entorno aislado",
+			"Elimine el archivo y sus copias si no se reconoce el origen",
+		}
+	case "credential_exposure":
+		return []string{
+			"Rote la credencial: debe considerarse comprometida",
+			"Elimine la entrada del historial (history -d N) y evite guardar comandos con secretos (HISTCONTROL=ignorespace)",
+			"Use variables de entorno desde un archivo protegido, un gestor de secretos o la solicitud interactiva de contraseña",
+		}
+	case "anti_forensics":
+		return []string{
+			"Considere la sesión incompleta: contraste con registros remotos (syslog centralizado, auditd, EDR)",
+			"Preserve los registros y el historial restantes antes de continuar la investigación",
+			"Fije HISTFILE, HISTSIZE y HISTCONTROL como variables de solo lectura en /etc/profile",
+		}
+	case "persistence":
+		return []string{
+			"Revise el contenido del mecanismo de persistencia y elimínelo si no está autorizado",
+			"Compare con una línea base del sistema (crontabs, unidades systemd, authorized_keys, archivos de inicio)",
+			"Monitorice estas rutas con auditd o un verificador de integridad (AIDE, osquery)",
+		}
+	case "container_escape":
+		return []string{
+			"Evite --privileged, los montajes del host y los espacios de nombres del host; añada solo las capacidades necesarias",
+			"Aplique políticas de admisión (Pod Security Admission, OPA/Kyverno) o autorización en el daemon de Docker",
+			"Revise el host en busca de cambios realizados desde el contenedor",
+		}
+	case "kubernetes_misuse":
+		return []string{
+			"Revise los permisos RBAC del usuario o service account que ejecutó el comando",
+			"Restrinja exec y la lectura de secrets en kube-system y evite asignar cluster-admin",
+			"Consulte los registros de auditoría del API server y rote los secretos expuestos",
+		}
+	case "cloud_destructive":
+		return []string{
+			"Verifique que la operación estaba planificada y que existen copias de seguridad o snapshots",
+			"Exija confirmación y revisión (terraform plan, MFA delete, protección contra borrado)",
+			"Restrinja los permisos de borrado con políticas IAM y bloqueos de recursos",
+		}
+	case "cloud_exposure":
+		return []string{
+			"Revierta el acceso público y active el bloqueo de acceso público de la cuenta",
+			"Revise los registros de acceso del recurso para detectar descargas no autorizadas",
+			"Comparta los datos con cuentas concretas o URLs firmadas con caducidad",
+		}
+	case "cloud_credentials":
+		return []string{
+			"Verifique quién solicitó las credenciales o permisos y revóquelos si no están autorizados",
+			"Prefiera credenciales temporales (roles, identidad federada) a claves de larga duración",
+			"Revise la actividad de las nuevas credenciales en los registros de auditoría",
+		}
+	case "cloud_logging_disabled":
+		return []string{
+			"Reactive el registro de auditoría inmediatamente y revise el periodo sin registros",
+			"Proteja la configuración de auditoría con políticas de organización (SCP, Azure Policy)",
+			"Configure alertas ante cambios en la auditoría",
+		}
+	case "cloud_insecure_tls":
+		return []string{
+			"Mantenga la verificación de certificados activada",
+			"Si usa un proxy corporativo, configure su CA (AWS_CA_BUNDLE, core/custom_ca_certs_file, REQUESTS_CA_BUNDLE)",
+		}
+	case "ssh_agent_forwarding":
+		return []string{
+			"Use ProxyJump (-J) en lugar de -A para atravesar bastiones sin exponer el agente",
+			"Confirme cada uso de las claves con ssh-add -c o claves en hardware",
+			"Desactive AllowAgentForwarding en los servidores que no lo necesiten",
+		}
+	case "ssh_tunnel":
+		return []string{
+			"Verifique que el túnel está autorizado y ciérrelo al terminar",
+			"Desactive AllowTcpForwarding/GatewayPorts en los servidores que no lo necesiten",
+			"Revise las conexiones que pasaron por el túnel en los registros de red",
+		}
+	case "threat_intel_match":
+		return []string{
+			"Bloquee el indicador en el proxy, el DNS o el firewall y busque otras conexiones hacia él",
+			"Revise qué se descargó o envió y analice los archivos implicados",
+			"Consulte la fuente de inteligencia para conocer la campaña o el malware asociado",
+		}
+	case "attack_sequence":
+		return []string{
+			"Trate la sesión como una intrusión: los pasos de la cadena se refuerzan entre sí",
+			"Revise cada comando de la cadena y los archivos y hosts implicados",
+			"Contraste la sesión con registros remotos (auditd, EDR, syslog centralizado)",
+		}
+	case "obfuscated_command":
+		return []string{
+			"Revise el comando decodificado: la ofuscación suele ocultar acciones maliciosas",
+			"Investigue el origen del comando (script descargado, pegado desde una web, sesión comprometida)",
+			"Registre la ejecución de procesos con auditd o un EDR para ver los comandos reales",
+		}
+	case "insecure_permissions":
+		return []string{
+			"Conceda escritura solo al propietario; para compartir use un grupo (chgrp y 775/664) o ACLs (setfacl)",
+			"Revise si el archivo o directorio se modificó mientras tuvo permisos de escritura para todos",
+		}
+	case "unsafe_expansion":
+		return []string{
+			"Proteja las variables de las rutas con \"${VAR:?}\" para que el comando falle si están vacías",
+			"Active set -u en los scripts para detenerse ante variables no definidas",
+		}
 	case "filesystem_error":
 		return []string{
 			"Verifique que los directorios y archivos existan antes de usarlos",
@@ -0,0 +1,51 @@
+package semantic
+
+import (
+	"fmt"
+	"regexp"
+
+	"terminal-history-analyzer/internal/attack"
+	"terminal-history-analyzer/internal/models"
+)
+
+// historyControlPattern reconoce la configuración que omite del historial los comandos con espacio inicial
+var historyControlPattern = regexp.MustCompile(`(?:^|\s)HISTCONTROL=["']?(ignorespace|ignoreboth)`)
+
+// historyControlState recuerda si la sesión activó HISTCONTROL=ignorespace y dónde
+type historyControlState struct {
+	active bool
+	line   int
+}
+
+// trackHiddenCommand reporta los comandos con espacio inicial posteriores a HISTCONTROL=ignorespace:
+// la intención es que no queden en el historial. El ajuste en sí lo reporta la regla history-ignorespace.
+func (a *Analyzer) trackHiddenCommand(cmd models.CommandAST) {
+	if a.historyControl.active && cmd.LeadingSpace {
+		a.addThreat(models.HIGH, "anti_forensics",
+			fmt.Sprintf("Comando con espacio inicial tras HISTCONTROL=ignorespace (línea %d): se intentó ocultar del historial", a.historyControl.line),
+			cmd)
+		a.threats[len(a.threats)-1].Attack = attack.Lookup("T1562.003")
+	}
+
+	if historyControlPattern.MatchString(cmd.Raw) {
+		a.historyControl = historyControlState{active: true, line: cmd.Line}
+	}
+}
+
+// SessionIntegrity indica si el historial pudo haberse manipulado a partir de las detecciones anti-forense
+func (a *Analyzer) SessionIntegrity() models.SessionIntegrity {
+	integrity := models.SessionIntegrity{}
+
+	for _, threat := range a.threats {
+		if threat.Type != "anti_forensics" {
+			continue
+		}
+		integrity.PossiblyIncomplete = true
+		if integrity.FirstTamperLine == 0 || threat.Line < integrity.FirstTamperLine {
+			integrity.FirstTamperLine = threat.Line
+		}
+		integrity.Reasons = append(integrity.Reasons, fmt.Sprintf("línea %d: %s", threat.Line, threat.Description))
+	}
+
+	return integrity
+}
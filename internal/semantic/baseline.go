@@ -0,0 +1,228 @@
+package semantic
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"terminal-history-analyzer/internal/baseline"
+	"terminal-history-analyzer/internal/models"
+	"terminal-history-analyzer/internal/parser"
+)
+
+// baselineAnomalyTypes son los tipos de anomalía por tipo de rasgo
+var baselineAnomalyTypes = map[string]string{
+	baseline.KindCommand:  "rare_command",
+	baseline.KindFlag:     "rare_flag",
+	baseline.KindHost:     "rare_host",
+	baseline.KindSequence: "rare_sequence",
+}
+
+// loadBaseline toma la línea base del dueño del historial; sin usuario o en un script no se
+// observa la sesión
+func (a *Analyzer) loadBaseline() {
+	a.baseline, a.observed = nil, nil
+	if a.sessionUser == "" || a.script {
+		return
+	}
+	a.baseline, _ = baseline.Active().Get(a.sessionUser, a.sessionHost)
+	a.observed = &baseline.Session{}
+}
+
+// observeBaseline registra el comando, sus pipelines y sus sustituciones con los rasgos de la
+// línea base: comando efectivo, flags y hosts de destino
+func (a *Analyzer) observeBaseline(cmd models.CommandAST) {
+	if a.observed == nil {
+		return
+	}
+
+	for _, segment := range baselineSegments(&cmd) {
+		view := newCommandView(*segment)
+		if view.name == "" {
+			continue
+		}
+
+		observation := baseline.Observation{
+			Line:    cmd.Line,
+			Command: view.name,
+			Raw:     cmd.Raw,
+			Flags:   observedFlags(view),
+		}
+		for _, host := range remoteHosts(view) {
+			observation.Hosts = append(observation.Hosts, strings.ToLower(host.Host))
+		}
+		a.observed.Observe(observation)
+	}
+}
+
+// observedCount devuelve el número de observaciones de la sesión registradas hasta ahora
+func (a *Analyzer) observedCount() int {
+	if a.observed == nil {
+		return 0
+	}
+	return len(a.observed.Observations)
+}
+
+// detectBaselineAnomalies compara los rasgos de la sesión con la línea base del usuario
+func (a *Analyzer) detectBaselineAnomalies() {
+	if a.observed == nil {
+		return
+	}
+
+	for _, finding := range a.baseline.Compare(a.observed) {
+		a.addAnomaly(baselineAnomalyTypes[finding.Kind], a.baselineDescription(finding), finding.Raw, finding.Line)
+		anomaly := &a.anomalies[len(a.anomalies)-1]
+		anomaly.Rarity = finding.Rarity
+		anomaly.Baseline = &models.BaselineEvidence{
+			Kind:  finding.Kind,
+			Value: finding.Value,
+			Seen:  finding.Seen,
+			Total: finding.Total,
+		}
+	}
+}
+
+// Motivos por los que una sesión no se suma a la línea base
+const (
+	baselineSkipDisabled  = "disabled"  // learn=false
+	baselineSkipThreats   = "threats"   // Detecciones HIGH o CRITICAL: la actividad no es habitual
+	baselineSkipDuplicate = "duplicate" // El mismo contenido ya se aprendió
+)
+
+// LearnBaseline suma la sesión a la línea base del usuario y devuelve con qué línea base se comparó.
+// No se aprende con learn=false, si la sesión tiene detecciones HIGH o CRITICAL no suprimidas (un
+// ataque no debe volverse habitual) ni si el mismo contenido ya se aprendió. Sin usuario (SetSession)
+// devuelve nil.
+func (a *Analyzer) LearnBaseline(learn bool) (*models.BaselineStatus, error) {
+	if a.observed == nil {
+		return nil, nil
+	}
+
+	status := &models.BaselineStatus{
+		Key:      baseline.Key(a.sessionUser, a.sessionHost),
+		Compared: a.baseline.Ready(),
+	}
+	if a.baseline != nil {
+		status.Analyses = a.baseline.Analyses
+		status.Commands = a.baseline.Commands
+	}
+
+	switch {
+	case !learn:
+		status.Skipped = baselineSkipDisabled
+		return status, nil
+	case a.hasSevereThreats():
+		status.Skipped = baselineSkipThreats
+		return status, nil
+	}
+
+	learned, err := baseline.Active().Learn(a.sessionUser, a.sessionHost, a.sessionDigest(), a.observed)
+	if errors.Is(err, baseline.ErrAlreadyLearned) {
+		status.Skipped = baselineSkipDuplicate
+		return status, nil
+	}
+	if err != nil {
+		return status, err
+	}
+	status.Analyses = learned.Analyses
+	status.Commands = learned.Commands
+	status.Learned = true
+	return status, nil
+}
+
+// hasSevereThreats indica si la sesión tiene detecciones HIGH o CRITICAL. Los errores del sistema de
+// archivos virtual no cuentan: reflejan archivos anteriores al historial, no actividad inusual.
+func (a *Analyzer) hasSevereThreats() bool {
+	for _, threat := range a.threats {
+		if threat.Type == "filesystem_error" {
+			continue
+		}
+		if threat.Level == models.HIGH || threat.Level == models.CRITICAL {
+			return true
+		}
+	}
+	return false
+}
+
+// sessionDigest devuelve la huella del contenido de la sesión (SetSource) o, sin él, de sus comandos
+func (a *Analyzer) sessionDigest() string {
+	content := strings.Join(a.sourceLines, "\n")
+	if content == "" {
+		var raws []string
+		for _, observation := range a.observed.Observations {
+			raws = append(raws, fmt.Sprintf("%d:%s", observation.Line, observation.Raw))
+		}
+		content = strings.Join(raws, "\n")
+	}
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// baselineDescription describe la anomalía: primer uso o uso poco habitual con su frecuencia
+func (a *Analyzer) baselineDescription(finding baseline.Finding) string {
+	who := baseline.Key(a.sessionUser, a.sessionHost)
+
+	if finding.Seen == 0 {
+		switch finding.Kind {
+		case baseline.KindFlag:
+			return fmt.Sprintf("Primer uso de %s por %s", finding.Value, who)
+		case baseline.KindHost:
+			return fmt.Sprintf("Primera conexión de %s a %s", who, finding.Value)
+		case baseline.KindSequence:
+			return fmt.Sprintf("Secuencia de comandos nunca vista para %s: %s", who, finding.Value)
+		default:
+			return fmt.Sprintf("Primer uso de %s por %s", finding.Value, who)
+		}
+	}
+
+	return fmt.Sprintf("Uso poco habitual de %s por %s (%d de %d observaciones, rareza %.2f)",
+		finding.Value, who, finding.Seen, finding.Total, finding.Rarity)
+}
+
+// baselineSegments devuelve el comando, los segmentos de sus pipelines y los comandos de sus
+// sustituciones, en orden de aparición
+func baselineSegments(cmd *models.CommandAST) []*models.CommandAST {
+	var segments []*models.CommandAST
+	for _, segment := range append([]*models.CommandAST{cmd}, cmd.Pipes...) {
+		for _, inner := range segment.Substitutions {
+			segments = append(segments, baselineSegments(inner)...)
+		}
+		segments = append(segments, segment)
+	}
+	return segments
+}
+
+// observedFlags devuelve las flags del comando como "comando -x" y "comando --largo", sin repetir
+func observedFlags(view *commandView) []string {
+	seen := make(map[string]bool)
+	var flags []string
+
+	for _, field := range view.fields {
+		if !strings.HasPrefix(field, "-") || len(field) < 2 || field == "--" {
+			continue
+		}
+
+		var names []string
+		if strings.HasPrefix(field, "--") {
+			names = append(names, "--"+flagName(field))
+		} else {
+			for _, letter := range parser.ShortFlagLetters(view.name, field) {
+				names = append(names, "-"+string(letter))
+			}
+		}
+
+		for _, name := range names {
+			flag := view.name + " " + name
+			if !seen[flag] {
+				seen[flag] = true
+				flags = append(flags, flag)
+			}
+		}
+	}
+
+	sort.Strings(flags)
+	return flags
+}
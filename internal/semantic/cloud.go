@@ -0,0 +1,366 @@
+package semantic
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"terminal-history-analyzer/internal/models"
+)
+
+// cloudCLIs asocia cada ejecutable con su proveedor
+var cloudCLIs = map[string]string{
+	"aws":       "aws",
+	"gcloud":    "gcp",
+	"gsutil":    "gcp",
+	"az":        "azure",
+	"terraform": "terraform",
+	"tofu":      "terraform",
+}
+
+// cloudBooleanFlags son las flags de las CLIs de nube que no reciben valor.
+// El resto de flags de un guion o dos consume la palabra siguiente (--bucket nombre).
+var cloudBooleanFlags = map[string]bool{
+	"recursive": true, "r": true, "R": true, "force": true, "f": true, "quiet": true, "q": true,
+	"yes": true, "y": true, "no-wait": true, "debug": true, "dryrun": true, "dry-run": true,
+	"no-verify-ssl": true, "no-paginate": true, "no-sign-request": true, "m": true,
+	"auto-approve": true, "destroy": true, "refresh-only": true, "input": true,
+	"skip-final-snapshot": true, "delete-automated-backups": true, "all": true,
+	"force-delete-without-recovery": true, "no-enable-logging": true, "verbose": true,
+}
+
+// cloudScopeFlags identifican la cuenta, proyecto o suscripción de cada proveedor
+var cloudScopeFlags = map[string][]string{
+	"aws":   {"profile"},
+	"gcp":   {"project"},
+	"azure": {"subscription"},
+}
+
+// cloudResourceFlags nombran el recurso afectado cuando no es un argumento posicional
+var cloudResourceFlags = []string{
+	"bucket", "name", "n", "user-name", "trail-name", "detector-id", "iam-account", "resource-group", "g",
+	"instance-ids", "db-instance-identifier", "snapshot-id", "image-id", "group-id", "secret-id", "target",
+}
+
+// cloudOperation describe una operación de riesgo de una CLI de nube
+type cloudOperation struct {
+	provider    string
+	command     string         // Grupos y operación separados por espacios (s3 rm, compute instances delete)
+	flags       []string       // Flags de las que al menos una debe estar presente (sin guiones)
+	value       *regexp.Regexp // Valor exigido a alguna de esas flags (nil: basta con la presencia)
+	raw         *regexp.Regexp // Patrón adicional sobre el comando completo
+	kind        string
+	level       models.ThreatLevel
+	technique   string
+	description string
+}
+
+var (
+	publicACLPattern    = regexp.MustCompile(`(?i)^public-read(-write)?$`)
+	allUsersPattern     = regexp.MustCompile(`(?i)(AllUsers|allAuthenticatedUsers)`)
+	openCIDRPattern     = regexp.MustCompile(`^(0\.0\.0\.0/0|::/0)$`)
+	publicPolicyPattern = regexp.MustCompile(`"Principal"\s*:\s*(\{\s*"AWS"\s*:\s*)?"\*"`)
+	shareAllPattern     = regexp.MustCompile(`(?i)(Group=all|"Group"\s*:\s*"all"|^all$)`)
+	adminPolicyPattern  = regexp.MustCompile(`(?i)(AdministratorAccess|IAMFullAccess|roles/(owner|editor|iam\.securityAdmin)|^Owner$|^Contributor$)`)
+	trueValuePattern    = regexp.MustCompile(`(?i)^(true|1|yes)$`)
+	containerPublic     = regexp.MustCompile(`(?i)^(blob|container)$`)
+	// Algún ajuste Block*/Restrict* desactivado, en sintaxis abreviada (BlockPublicAcls=false) o JSON
+	publicAccessUnblock = regexp.MustCompile(`(?i)"?(Block|Restrict)[A-Za-z]*"?\s*[=:]\s*false\b`)
+	gcloudSSLPattern    = regexp.MustCompile(`(?i)auth/disable_ssl_validation\s+(true|1)`)
+)
+
+// cloudOperations contiene las operaciones destructivas o que facilitan la exfiltración
+var cloudOperations = []cloudOperation{
+	// AWS: destrucción
+	{provider: "aws", command: "s3 rm", flags: []string{"recursive"}, kind: "cloud_destructive", level: models.CRITICAL, technique: "T1485", description: "Borrado recursivo de objetos en S3"},
+	{provider: "aws", command: "s3 rb", flags: []string{"force"}, kind: "cloud_destructive", level: models.CRITICAL, technique: "T1485", description: "Eliminación forzada de un bucket S3 con todo su contenido"},
+	{provider: "aws", command: "s3api delete-bucket", kind: "cloud_destructive", level: models.HIGH, technique: "T1485", description: "Eliminación de un bucket S3"},
+	{provider: "aws", command: "ec2 terminate-instances", kind: "cloud_destructive", level: models.HIGH, technique: "T1578.003", description: "Terminación de instancias EC2"},
+	{provider: "aws", command: "rds delete-db-instance", flags: []string{"skip-final-snapshot"}, kind: "cloud_destructive", level: models.CRITICAL, technique: "T1485", description: "Eliminación de una base de datos RDS sin snapshot final"},
+	{provider: "aws", command: "kms schedule-key-deletion", kind: "cloud_destructive", level: models.HIGH, technique: "T1485", description: "Programación del borrado de una clave KMS (los datos cifrados quedan irrecuperables)"},
+
+	// AWS: exposición pública y compartición de datos
+	{provider: "aws", command: "s3api put-bucket-acl", flags: []string{"acl"}, value: publicACLPattern, kind: "cloud_exposure", level: models.CRITICAL, technique: "T1530", description: "Bucket S3 hecho público mediante ACL"},
+	{provider: "aws", command: "s3api put-object-acl", flags: []string{"acl"}, value: publicACLPattern, kind: "cloud_exposure", level: models.HIGH, technique: "T1530", description: "Objeto S3 hecho público mediante ACL"},
+	{provider: "aws", command: "s3api put-bucket-acl", flags: []string{"grant-read", "grant-full-control"}, value: allUsersPattern, kind: "cloud_exposure", level: models.CRITICAL, technique: "T1530", description: "Permisos concedidos a todos los usuarios sobre un bucket S3"},
+	{provider: "aws", command: "s3 cp", flags: []string{"acl"}, value: publicACLPattern, kind: "cloud_exposure", level: models.HIGH, technique: "T1530", description: "Objetos subidos a S3 con ACL pública"},
+	{provider: "aws", command: "s3 sync", flags: []string{"acl"}, value: publicACLPattern, kind: "cloud_exposure", level: models.HIGH, technique: "T1530", description: "Sincronización a S3 con ACL pública"},
+	{provider: "aws", command: "s3api put-bucket-policy", raw: publicPolicyPattern, kind: "cloud_exposure", level: models.CRITICAL, technique: "T1530", description: "Política de bucket S3 con Principal \"*\" (acceso público)"},
+	{provider: "aws", command: "s3api delete-public-access-block", kind: "cloud_exposure", level: models.HIGH, technique: "T1530", description: "Eliminación del bloqueo de acceso público de S3"},
+	{provider: "aws", command: "s3api put-public-access-block", flags: []string{"public-access-block-configuration"}, value: publicAccessUnblock, kind: "cloud_exposure", level: models.HIGH, technique: "T1530", description: "Bloqueo de acceso público de S3 desactivado en el bucket"},
+	{provider: "aws", command: "s3control put-public-access-block", flags: []string{"public-access-block-configuration"}, value: publicAccessUnblock, kind: "cloud_exposure", level: models.CRITICAL, technique: "T1530", description: "Bloqueo de acceso público de S3 desactivado en toda la cuenta"},
+	{provider: "aws", command: "ec2 authorize-security-group-ingress", flags: []string{"cidr"}, value: openCIDRPattern, kind: "cloud_exposure", level: models.HIGH, technique: "T1562.007", description: "Grupo de seguridad abierto a todo Internet"},
+	{provider: "aws", command: "ec2 modify-snapshot-attribute", raw: shareAllPattern, kind: "cloud_exposure", level: models.CRITICAL, technique: "T1537", description: "Snapshot EBS compartido públicamente"},
+	{provider: "aws", command: "ec2 modify-snapshot-attribute", flags: []string{"user-ids"}, kind: "cloud_exposure", level: models.HIGH, technique: "T1537", description: "Snapshot EBS compartido con otra cuenta"},
+	{provider: "aws", command: "rds modify-db-snapshot-attribute", flags: []string{"values-to-add"}, kind: "cloud_exposure", level: models.HIGH, technique: "T1537", description: "Snapshot RDS compartido con otras cuentas"},
+
+	// AWS: credenciales y permisos
+	{provider: "aws", command: "iam create-access-key", kind: "cloud_credentials", level: models.HIGH, technique: "T1098.001", description: "Creación de claves de acceso IAM"},
+	{provider: "aws", command: "iam create-login-profile", kind: "cloud_credentials", level: models.HIGH, technique: "T1098.001", description: "Creación de contraseña de consola para un usuario IAM"},
+	{provider: "aws", command: "iam attach-user-policy", flags: []string{"policy-arn"}, value: adminPolicyPattern, kind: "cloud_credentials", level: models.CRITICAL, technique: "T1098.003", description: "Política de administrador asignada a un usuario IAM"},
+	{provider: "aws", command: "iam attach-role-policy", flags: []string{"policy-arn"}, value: adminPolicyPattern, kind: "cloud_credentials", level: models.CRITICAL, technique: "T1098.003", description: "Política de administrador asignada a un rol IAM"},
+	{provider: "aws", command: "secretsmanager get-secret-value", kind: "cloud_credentials", level: models.MEDIUM, technique: "T1555.006", description: "Lectura de un secreto de Secrets Manager"},
+
+	// AWS: registro y monitorización
+	{provider: "aws", command: "cloudtrail stop-logging", kind: "cloud_logging_disabled", level: models.CRITICAL, technique: "T1562.008", description: "CloudTrail detenido"},
+	{provider: "aws", command: "cloudtrail delete-trail", kind: "cloud_logging_disabled", level: models.CRITICAL, technique: "T1562.008", description: "Eliminación de un trail de CloudTrail"},
+	{provider: "aws", command: "cloudtrail update-trail", flags: []string{"no-include-global-service-events", "no-is-multi-region-trail"}, kind: "cloud_logging_disabled", level: models.HIGH, technique: "T1562.008", description: "Reducción del alcance de CloudTrail"},
+	{provider: "aws", command: "cloudtrail put-event-selectors", kind: "cloud_logging_disabled", level: models.MEDIUM, technique: "T1562.008", description: "Modificación de los eventos registrados por CloudTrail"},
+	{provider: "aws", command: "guardduty delete-detector", kind: "cloud_logging_disabled", level: models.CRITICAL, technique: "T1562.008", description: "Eliminación del detector de GuardDuty"},
+	{provider: "aws", command: "config stop-configuration-recorder", kind: "cloud_logging_disabled", level: models.HIGH, technique: "T1562.008", description: "AWS Config detenido"},
+
+	// AWS: transporte inseguro (flag global, válida en cualquier operación)
+	{provider: "aws", flags: []string{"no-verify-ssl"}, kind: "cloud_insecure_tls", level: models.MEDIUM, description: "Verificación de certificados TLS desactivada (--no-verify-ssl)"},
+
+	// Google Cloud
+	{provider: "gcp", command: "projects delete", kind: "cloud_destructive", level: models.CRITICAL, technique: "T1485", description: "Eliminación de un proyecto de Google Cloud"},
+	{provider: "gcp", command: "compute instances delete", kind: "cloud_destructive", level: models.HIGH, technique: "T1578.003", description: "Eliminación de instancias de Compute Engine"},
+	{provider: "gcp", command: "storage rm", flags: []string{"recursive", "r"}, kind: "cloud_destructive", level: models.CRITICAL, technique: "T1485", description: "Borrado recursivo en Cloud Storage"},
+	{provider: "gcp", command: "storage buckets delete", kind: "cloud_destructive", level: models.HIGH, technique: "T1485", description: "Eliminación de un bucket de Cloud Storage"},
+	{provider: "gcp", command: "sql instances delete", kind: "cloud_destructive", level: models.HIGH, technique: "T1485", description: "Eliminación de una instancia de Cloud SQL"},
+	{provider: "gcp", command: "gsutil rm", flags: []string{"r", "R"}, kind: "cloud_destructive", level: models.CRITICAL, technique: "T1485", description: "Borrado recursivo en Cloud Storage (gsutil)"},
+	{provider: "gcp", command: "gsutil rb", kind: "cloud_destructive", level: models.HIGH, technique: "T1485", description: "Eliminación de un bucket de Cloud Storage (gsutil)"},
+	{provider: "gcp", command: "storage buckets add-iam-policy-binding", flags: []string{"member"}, value: allUsersPattern, kind: "cloud_exposure", level: models.CRITICAL, technique: "T1530", description: "Bucket de Cloud Storage accesible para todos los usuarios"},
+	{provider: "gcp", command: "gsutil iam ch", raw: allUsersPattern, kind: "cloud_exposure", level: models.CRITICAL, technique: "T1530", description: "Bucket de Cloud Storage accesible para todos los usuarios (gsutil)"},
+	{provider: "gcp", command: "gsutil acl ch", raw: allUsersPattern, kind: "cloud_exposure", level: models.CRITICAL, technique: "T1530", description: "ACL de Cloud Storage concedida a todos los usuarios (gsutil)"},
+	{provider: "gcp", command: "compute firewall-rules create", flags: []string{"source-ranges"}, value: openCIDRPattern, kind: "cloud_exposure", level: models.HIGH, technique: "T1562.007", description: "Regla de firewall abierta a todo Internet"},
+	{provider: "gcp", command: "iam service-accounts keys create", kind: "cloud_credentials", level: models.HIGH, technique: "T1098.001", description: "Creación de una clave de cuenta de servicio"},
+	{provider: "gcp", command: "projects add-iam-policy-binding", flags: []string{"role"}, value: adminPolicyPattern, kind: "cloud_credentials", level: models.CRITICAL, technique: "T1098.003", description: "Rol privilegiado asignado en el proyecto"},
+	{provider: "gcp", command: "secrets versions access", kind: "cloud_credentials", level: models.MEDIUM, technique: "T1555.006", description: "Lectura de un secreto de Secret Manager"},
+	{provider: "gcp", command: "logging sinks delete", kind: "cloud_logging_disabled", level: models.HIGH, technique: "T1562.008", description: "Eliminación de un sink de Cloud Logging"},
+	{provider: "gcp", command: "logging sinks update", flags: []string{"disabled"}, kind: "cloud_logging_disabled", level: models.HIGH, technique: "T1562.008", description: "Sink de Cloud Logging desactivado"},
+	{provider: "gcp", command: "config set", raw: gcloudSSLPattern, kind: "cloud_insecure_tls", level: models.MEDIUM, description: "Validación de certificados TLS desactivada en gcloud"},
+
+	// Azure
+	{provider: "azure", command: "group delete", kind: "cloud_destructive", level: models.CRITICAL, technique: "T1485", description: "Eliminación de un grupo de recursos de Azure"},
+	{provider: "azure", command: "vm delete", kind: "cloud_destructive", level: models.HIGH, technique: "T1578.003", description: "Eliminación de una máquina virtual de Azure"},
+	{provider: "azure", command: "storage account delete", kind: "cloud_destructive", level: models.CRITICAL, technique: "T1485", description: "Eliminación de una cuenta de almacenamiento de Azure"},
+	{provider: "azure", command: "keyvault purge", kind: "cloud_destructive", level: models.CRITICAL, technique: "T1485", description: "Purga definitiva de un Key Vault"},
+	{provider: "azure", command: "storage account update", flags: []string{"allow-blob-public-access"}, value: trueValuePattern, kind: "cloud_exposure", level: models.HIGH, technique: "T1530", description: "Acceso público a blobs habilitado en la cuenta de almacenamiento"},
+	{provider: "azure", command: "storage container set-permission", flags: []string{"public-access"}, value: containerPublic, kind: "cloud_exposure", level: models.CRITICAL, technique: "T1530", description: "Contenedor de Azure Storage hecho público"},
+	{provider: "azure", command: "storage container create", flags: []string{"public-access"}, value: containerPublic, kind: "cloud_exposure", level: models.HIGH, technique: "T1530", description: "Contenedor de Azure Storage creado con acceso público"},
+	{provider: "azure", command: "network nsg rule create", flags: []string{"source-address-prefixes"}, value: regexp.MustCompile(`^(\*|0\.0\.0\.0/0|Internet)$`), kind: "cloud_exposure", level: models.HIGH, technique: "T1562.007", description: "Regla de NSG abierta a todo Internet"},
+	{provider: "azure", command: "ad sp credential reset", kind: "cloud_credentials", level: models.HIGH, technique: "T1098.001", description: "Nuevas credenciales para un service principal"},
+	{provider: "azure", command: "ad app credential reset", kind: "cloud_credentials", level: models.HIGH, technique: "T1098.001", description: "Nuevas credenciales para una aplicación de Entra ID"},
+	{provider: "azure", command: "role assignment create", flags: []string{"role"}, value: adminPolicyPattern, kind: "cloud_credentials", level: models.CRITICAL, technique: "T1098.003", description: "Rol privilegiado asignado en Azure"},
+	{provider: "azure", command: "keyvault secret show", kind: "cloud_credentials", level: models.MEDIUM, technique: "T1555.006", description: "Lectura de un secreto de Key Vault"},
+	{provider: "azure", command: "monitor diagnostic-settings delete", kind: "cloud_logging_disabled", level: models.HIGH, technique: "T1562.008", description: "Eliminación de la configuración de diagnóstico de Azure Monitor"},
+	{provider: "azure", command: "monitor log-profiles delete", kind: "cloud_logging_disabled", level: models.HIGH, technique: "T1562.008", description: "Eliminación de un perfil de registro de actividad"},
+
+	// Terraform
+	{provider: "terraform", command: "destroy", flags: []string{"auto-approve"}, kind: "cloud_destructive", level: models.CRITICAL, technique: "T1485", description: "terraform destroy sin confirmación (-auto-approve)"},
+	{provider: "terraform", command: "apply", flags: []string{"destroy"}, kind: "cloud_destructive", level: models.CRITICAL, technique: "T1485", description: "terraform apply -destroy: destrucción de toda la infraestructura"},
+	{provider: "terraform", command: "apply", flags: []string{"auto-approve"}, kind: "cloud_destructive", level: models.MEDIUM, technique: "T1578", description: "terraform apply sin revisar el plan (-auto-approve)"},
+	{provider: "terraform", command: "state rm", kind: "cloud_destructive", level: models.MEDIUM, technique: "T1578", description: "Recursos eliminados del estado de Terraform (dejan de gestionarse)"},
+	{provider: "terraform", command: "force-unlock", kind: "cloud_destructive", level: models.MEDIUM, technique: "T1578", description: "Desbloqueo forzado del estado de Terraform"},
+}
+
+// cloudInvocation es una invocación de una CLI de nube separada en grupos, argumentos y flags
+type cloudInvocation struct {
+	provider string
+	words    []string            // Palabras posicionales: grupos, operación y recursos (s3 rm s3://bucket)
+	flags    map[string][]string // Flags sin guiones con sus valores ("" si no reciben valor)
+}
+
+// cloudFinding es una operación de riesgo encontrada en una invocación
+type cloudFinding struct {
+	operation cloudOperation
+	resource  string
+}
+
+// parseCloudInvocation separa una invocación de aws, gcloud, az o terraform; nil si no lo es
+func parseCloudInvocation(view *commandView) *cloudInvocation {
+	provider, ok := cloudCLIs[view.name]
+	if !ok {
+		return nil
+	}
+
+	inv := &cloudInvocation{provider: provider, flags: make(map[string][]string)}
+	if view.name == "gsutil" {
+		inv.words = append(inv.words, "gsutil")
+	}
+
+	// Palabras a partir del ejecutable (se omiten los wrappers como sudo)
+	fields := view.fields
+	for i, field := range fields {
+		if filepath.Base(unquote(field)) == view.name {
+			fields = fields[i+1:]
+			break
+		}
+	}
+
+	for i := 0; i < len(fields); i++ {
+		field := unquote(fields[i])
+		if !strings.HasPrefix(field, "-") || len(field) < 2 {
+			inv.words = append(inv.words, field)
+			continue
+		}
+
+		name := strings.TrimLeft(field, "-")
+		value := ""
+		if idx := strings.Index(name, "="); idx >= 0 {
+			name, value = name[:idx], name[idx+1:]
+		} else if !cloudBooleanFlags[name] && i+1 < len(fields) && !strings.HasPrefix(fields[i+1], "-") {
+			value = unquote(fields[i+1])
+			i++
+		}
+		inv.flags[name] = append(inv.flags[name], value)
+	}
+
+	return inv
+}
+
+// service devuelve el servicio o subcomando principal (s3, compute, storage, destroy)
+func (inv *cloudInvocation) service() string {
+	if len(inv.words) == 0 {
+		return ""
+	}
+	if inv.words[0] == "gsutil" {
+		return "storage"
+	}
+	return inv.words[0]
+}
+
+// resource devuelve el recurso afectado: una flag de nombre o, si no la hay, el argumento tras la
+// operación (se prefieren las URLs de almacenamiento: gsutil iam ch allUsers:objectViewer gs://b -> gs://b)
+func (inv *cloudInvocation) resource(op cloudOperation) string {
+	for _, flag := range cloudResourceFlags {
+		if values := inv.flags[flag]; len(values) > 0 && values[0] != "" {
+			return values[0]
+		}
+	}
+
+	n := len(strings.Fields(op.command))
+	if op.command == "" || len(inv.words) <= n {
+		return ""
+	}
+	for _, word := range inv.words[n:] {
+		if strings.Contains(word, "://") {
+			return word
+		}
+	}
+	return inv.words[n]
+}
+
+// matches indica si la invocación realiza la operación
+func (op cloudOperation) matches(inv *cloudInvocation, raw string) bool {
+	if op.provider != inv.provider {
+		return false
+	}
+
+	for i, word := range strings.Fields(op.command) {
+		if i >= len(inv.words) || !strings.EqualFold(inv.words[i], word) {
+			return false
+		}
+	}
+
+	if len(op.flags) > 0 {
+		found := false
+		for _, flag := range op.flags {
+			for _, value := range inv.flags[flag] {
+				if op.value == nil || op.value.MatchString(value) {
+					found = true
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return op.raw == nil || op.raw.MatchString(raw)
+}
+
+// detectCloudRisks devuelve las operaciones de riesgo de una invocación de CLI de nube
+func detectCloudRisks(inv *cloudInvocation, raw string) []cloudFinding {
+	var findings []cloudFinding
+	seen := make(map[string]bool)
+
+	for _, op := range cloudOperations {
+		// Una detección por tipo: put-bucket-acl con --acl y --grant-read no se reporta dos veces
+		if seen[op.kind] || !op.matches(inv, raw) {
+			continue
+		}
+		seen[op.kind] = true
+		findings = append(findings, cloudFinding{operation: op, resource: inv.resource(op)})
+	}
+
+	return findings
+}
+
+// description devuelve el mensaje de la detección con la operación y el recurso
+func (f cloudFinding) description() string {
+	description := f.operation.description
+	if f.resource != "" {
+		description += fmt.Sprintf(": %s", f.resource)
+	}
+	return description
+}
+
+// details devuelve los datos estructurados de la detección
+func (f cloudFinding) details(inv *cloudInvocation) map[string]string {
+	details := map[string]string{"provider": inv.provider}
+	if service := inv.service(); service != "" {
+		details["service"] = service
+	}
+	if f.operation.command != "" {
+		details["operation"] = f.operation.command
+	}
+	if f.resource != "" {
+		details["resource"] = f.resource
+	}
+	return details
+}
+
+// recordCloudActivity acumula la invocación en el resumen de su proveedor
+func (a *Analyzer) recordCloudActivity(inv *cloudInvocation, findings []cloudFinding) {
+	summary, ok := a.cloudActivity[inv.provider]
+	if !ok {
+		summary = &models.CloudProviderSummary{Provider: inv.provider, Findings: make(map[string]int)}
+		a.cloudActivity[inv.provider] = summary
+	}
+
+	summary.Invocations++
+	if service := inv.service(); service != "" && !contains(summary.Services, service) {
+		summary.Services = append(summary.Services, service)
+	}
+	for _, flag := range cloudScopeFlags[inv.provider] {
+		for _, value := range inv.flags[flag] {
+			if value != "" && !contains(summary.Scopes, value) {
+				summary.Scopes = append(summary.Scopes, value)
+			}
+		}
+	}
+
+	for _, finding := range findings {
+		summary.Findings[finding.operation.kind]++
+		if threatLevelRank[finding.operation.level] > threatLevelRank[summary.HighestLevel] {
+			summary.HighestLevel = finding.operation.level
+		}
+	}
+}
+
+// threatLevelRank ordena los niveles de amenaza de menor a mayor
+var threatLevelRank = map[models.ThreatLevel]int{
+	models.SAFE: 1, models.LOW: 2, models.MEDIUM: 3, models.HIGH: 4, models.CRITICAL: 5,
+}
+
+// CloudSummary devuelve la actividad de cada CLI de nube ordenada por proveedor
+func (a *Analyzer) CloudSummary() []models.CloudProviderSummary {
+	providers := make([]string, 0, len(a.cloudActivity))
+	for provider := range a.cloudActivity {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+
+	summaries := make([]models.CloudProviderSummary, 0, len(providers))
+	for _, provider := range providers {
+		summary := *a.cloudActivity[provider]
+		sort.Strings(summary.Services)
+		sort.Strings(summary.Scopes)
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
@@ -0,0 +1,41 @@
+package semantic
+
+import "testing"
+
+func TestPublicAccessBlock(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		exposed bool
+	}{
+		{
+			name:    "todos los ajustes desactivados",
+			content: "aws s3api put-public-access-block --bucket data --public-access-block-configuration BlockPublicAcls=false,IgnorePublicAcls=false,BlockPublicPolicy=false,RestrictPublicBuckets=false",
+			exposed: true,
+		},
+		{
+			name:    "un ajuste desactivado en JSON",
+			content: `aws s3api put-public-access-block --bucket data --public-access-block-configuration '{"BlockPublicAcls": true, "IgnorePublicAcls": true, "BlockPublicPolicy": false, "RestrictPublicBuckets": true}'`,
+			exposed: true,
+		},
+		{
+			name:    "bloqueo de la cuenta desactivado",
+			content: "aws s3control put-public-access-block --account-id 123456789012 --public-access-block-configuration RestrictPublicBuckets=false",
+			exposed: true,
+		},
+		{
+			name:    "bloqueo completo",
+			content: "aws s3api put-public-access-block --bucket data --public-access-block-configuration BlockPublicAcls=true,IgnorePublicAcls=true,BlockPublicPolicy=true,RestrictPublicBuckets=true",
+			exposed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			types := threatTypes(analyzeSession(t, NewAnalyzer(), tt.content))
+			if got := types["cloud_exposure"] == 1; got != tt.exposed {
+				t.Errorf("cloud_exposure = %d, se esperaba exposición %v (%v)", types["cloud_exposure"], tt.exposed, types)
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package semantic
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"terminal-history-analyzer/internal/models"
+	"terminal-history-analyzer/internal/netintel"
+	"terminal-history-analyzer/internal/parser"
+)
+
+// elevationCommands son los wrappers que ejecutan el comando con privilegios elevados
+var elevationCommands = map[string]bool{
+	"sudo": true, "doas": true, "pkexec": true,
+}
+
+// commandView es una vista normalizada de un comando usada por las reglas y detectores.
+// Desenvuelve wrappers (sudo rm -> rm) y separa flags cortas, largas y sus valores.
+type commandView struct {
+	cmd        models.CommandAST
+	name       string          // Comando efectivo sin ruta (sudo /bin/rm -> rm)
+	wrapper    string          // Wrapper que lo ejecuta (sudo, doas, nohup, ...)
+	elevated   bool            // Se ejecuta mediante sudo/doas
+	runAs      string          // Usuario efectivo de sudo, doas o su (root si no se indica); vacío si no cambia
+	arguments  []string        // Argumentos posicionales del comando efectivo
+	values     []string        // Argumentos posicionales más valores de flags
+	subcommand string          // Primer argumento posicional (git commit -> commit)
+	shortFlags map[rune]bool   // Letras de flags cortas (-rf -> r, f)
+	longFlags  map[string]bool // Flags largas sin guiones (--force -> force)
+	fields     []string        // Palabras del segmento del comando (sin pipes)
+	writes     []string        // Rutas absolutas que escribe el comando (ver writtenPaths)
+	hosts      []netintel.Host // Destinos de red clasificados (ver remoteHosts)
+}
+
+// newCommandView construye la vista normalizada de un comando
+func newCommandView(cmd models.CommandAST) *commandView {
+	view := &commandView{
+		cmd:        cmd,
+		name:       filepath.Base(cmd.Command),
+		arguments:  cmd.Arguments,
+		shortFlags: make(map[rune]bool),
+		longFlags:  make(map[string]bool),
+		fields:     segmentFields(cmd),
+	}
+	view.runAs = targetUser(view.fields)
+
+	if parser.IsWrapperCommand(cmd.Command) && len(cmd.Arguments) > 0 {
+		view.wrapper = view.name
+		view.elevated = elevationCommands[view.wrapper]
+		view.name = filepath.Base(cmd.Arguments[0])
+		view.arguments = cmd.Arguments[1:]
+	}
+
+	if len(view.arguments) > 0 {
+		view.subcommand = view.arguments[0]
+	}
+
+	for _, field := range view.fields {
+		if !strings.HasPrefix(field, "-") || len(field) < 2 {
+			continue
+		}
+		if strings.HasPrefix(field, "--") {
+			view.longFlags[flagName(field)] = true
+			continue
+		}
+		// Flags de un guion: se registran como letras y como nombre (terraform -auto-approve)
+		for _, letter := range parser.ShortFlagLetters(view.name, field) {
+			view.shortFlags[letter] = true
+		}
+		view.longFlags[flagName(field)] = true
+	}
+
+	view.values = append(view.values, view.arguments...)
+	keys := make([]string, 0, len(cmd.Flags))
+	for key := range cmd.Flags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if value := cmd.Flags[key]; value != "true" {
+			view.values = append(view.values, value)
+		}
+	}
+
+	return view
+}
+
+// hasFlag verifica una flag escrita como en la terminal: "-rf" exige todas las letras, "--force" la flag larga
+func (v *commandView) hasFlag(flag string) bool {
+	if strings.HasPrefix(flag, "--") {
+		return v.longFlags[strings.TrimPrefix(flag, "--")]
+	}
+
+	letters := strings.TrimPrefix(flag, "-")
+	if letters == "" {
+		return false
+	}
+	for _, letter := range letters {
+		if !v.shortFlags[letter] {
+			return false
+		}
+	}
+	return true
+}
+
+// flagValues devuelve todos los valores de una flag (-v a -v b, --volume=a, -ofile)
+func (v *commandView) flagValues(flag string) []string {
+	var values []string
+	long := strings.HasPrefix(flag, "--")
+	name := strings.TrimLeft(flag, "-")
+
+	for i, field := range v.fields {
+		if long || len(name) > 1 {
+			if field == flag || field == "-"+name || field == "--"+name {
+				if i+1 < len(v.fields) {
+					values = append(values, unquote(v.fields[i+1]))
+				}
+			} else if strings.HasPrefix(field, "--"+name+"=") || strings.HasPrefix(field, "-"+name+"=") {
+				values = append(values, unquote(field[strings.Index(field, "=")+1:]))
+			}
+			continue
+		}
+
+		if !strings.HasPrefix(field, "-") || strings.HasPrefix(field, "--") {
+			continue
+		}
+		letters := parser.ShortFlagLetters(v.name, field)
+		if !strings.HasSuffix(letters, name) {
+			continue
+		}
+		rest := strings.TrimPrefix(field, "-"+letters)
+		if rest != "" {
+			values = append(values, unquote(strings.TrimPrefix(rest, "=")))
+		} else if i+1 < len(v.fields) {
+			values = append(values, unquote(v.fields[i+1]))
+		}
+	}
+
+	return values
+}
+
+// segmentFields devuelve las palabras del propio comando, sin los comandos encadenados por pipes
+func segmentFields(cmd models.CommandAST) []string {
+	raw := cmd.Raw
+	if idx := strings.Index(raw, " | "); idx >= 0 {
+		raw = raw[:idx]
+	}
+	return splitFields(raw)
+}
+
+// splitFields separa por espacios respetando las comillas ('import socket; s=...' es un solo campo)
+func splitFields(raw string) []string {
+	var fields []string
+	var current strings.Builder
+	var quote byte
+
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ' ' || c == '\t':
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		current.WriteByte(c)
+	}
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+
+	return fields
+}
+
+// flagName devuelve el nombre de una flag sin guiones ni valor embebido
+func flagName(flag string) string {
+	name := strings.TrimLeft(flag, "-")
+	if idx := strings.Index(name, "="); idx >= 0 {
+		name = name[:idx]
+	}
+	return name
+}
+
+// unquote elimina las comillas externas de un valor
+func unquote(value string) string {
+	if len(value) >= 2 {
+		first, last := value[0], value[len(value)-1]
+		if (first == '"' || first == '\'') && first == last {
+			return value[1 : len(value)-1]
+		}
+	}
+	return value
+}
+
+// fileEditors son editores cuyos argumentos son archivos que se modifican
+var fileEditors = map[string]bool{
+	"vi": true, "vim": true, "nvim": true, "nano": true, "emacs": true, "pico": true, "ed": true,
+}
+
+// writtenPaths devuelve las rutas absolutas que escribe un comando y los segmentos de su pipeline:
+// redirecciones, tee, cp/mv/install/ln, dd of=, sed -i, editores y descargas. Las rutas relativas
+// se resuelven con el directorio actual de la sesión (echo key >> authorized_keys dentro de ~/.ssh).
+func writtenPaths(cmd models.CommandAST, fs *FileSystemState) []string {
+	if fs == nil {
+		return nil
+	}
+
+	var paths []string
+	segments := append([]*models.CommandAST{&cmd}, cmd.Pipes...)
+	for _, segment := range segments {
+		view := newCommandView(*segment)
+		var targets []string
+
+		for _, redirect := range segment.Redirects {
+			switch redirect.Type {
+			case ">", ">>", ">|", "&>", "&>>", "<>":
+				targets = append(targets, unquote(redirect.Target))
+			}
+		}
+
+		files := positionalPaths(view)
+		switch {
+		case view.name == "tee" || fileEditors[view.name]:
+			targets = append(targets, files...)
+
+		case view.name == "cp" || view.name == "mv" || view.name == "install" || view.name == "ln":
+			if dirs := view.flagValues("-t"); len(dirs) > 0 {
+				for _, source := range files {
+					targets = append(targets, path.Join(dirs[0], path.Base(source)))
+				}
+				break
+			}
+			if len(files) == 1 && view.name == "ln" {
+				targets = append(targets, path.Base(files[0]))
+				break
+			}
+			if len(files) < 2 {
+				break
+			}
+			dest := files[len(files)-1]
+			if strings.HasSuffix(dest, "/") || fs.directories[fs.resolvePath(dest)] {
+				for _, source := range files[:len(files)-1] {
+					targets = append(targets, path.Join(dest, path.Base(source)))
+				}
+			} else {
+				targets = append(targets, dest)
+			}
+
+		case view.name == "dd":
+			for _, arg := range files {
+				if strings.HasPrefix(arg, "of=") {
+					targets = append(targets, strings.TrimPrefix(arg, "of="))
+				}
+			}
+
+		case view.name == "sed" && (view.hasFlag("-i") || view.hasFlag("--in-place")):
+			if len(view.flagValues("-e")) == 0 && len(files) > 0 {
+				files = files[1:] // El primer argumento es el script
+			}
+			targets = append(targets, files...)
+
+		case networkFetchers[view.name]:
+			downloads, _ := fetchTargets(view)
+			targets = append(targets, downloads...)
+		}
+
+		for _, target := range targets {
+			if target == "" || strings.HasPrefix(target, "&") {
+				continue
+			}
+			if absolute := fs.resolvePath(target); !contains(paths, absolute) {
+				paths = append(paths, absolute)
+			}
+		}
+	}
+
+	return paths
+}
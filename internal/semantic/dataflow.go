@@ -0,0 +1,515 @@
+package semantic
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"terminal-history-analyzer/internal/models"
+)
+
+// networkFetchers descargan contenido de la red
+var networkFetchers = map[string]bool{
+	"curl": true, "wget": true, "fetch": true, "aria2c": true,
+}
+
+// scriptInterpreters ejecutan código recibido por stdin o como archivo, con su técnica ATT&CK
+var scriptInterpreters = map[string]string{
+	"sh": "T1059.004", "bash": "T1059.004", "dash": "T1059.004", "zsh": "T1059.004", "ksh": "T1059.004",
+	"fish": "T1059.004", "source": "T1059.004", ".": "T1059.004", "eval": "T1059.004",
+	"python": "T1059.006", "python2": "T1059.006", "python3": "T1059.006",
+	"perl": "T1059", "ruby": "T1059", "php": "T1059", "lua": "T1059",
+	"node": "T1059.007",
+}
+
+// streamFilters transforman el contenido de un pipe sin interrumpir el flujo (curl | base64 -d | sh)
+var streamFilters = map[string]bool{
+	"base64": true, "gunzip": true, "gzip": true, "zcat": true, "bzip2": true, "xz": true,
+	"xxd": true, "tr": true, "sed": true, "cat": true, "tee": true, "openssl": true, "rev": true,
+	"head": true, "tail": true,
+}
+
+// standardPath son los directorios del PATH por defecto, en orden de búsqueda, para los comandos
+// invocados por su nombre (helper tras mv /tmp/h /usr/local/bin/helper)
+var standardPath = []string{
+	"~/.local/bin", "~/bin", "/usr/local/sbin", "/usr/local/bin", "/usr/sbin", "/usr/bin", "/sbin", "/bin",
+}
+
+// taintedArtifact es un archivo cuyo contenido proviene de la red
+type taintedArtifact struct {
+	source     string // URL de origen
+	chain      []models.ChainStep
+	executable bool
+	executed   bool
+}
+
+// dataFlowFinding es una detección del análisis de flujo de datos
+type dataFlowFinding struct {
+	threatType  string // pipe_to_interpreter, download_execute o download_execute_sequence (anomalía)
+	level       models.ThreatLevel
+	description string
+	technique   string
+	chain       []models.ChainStep
+	anomaly     bool
+	rewrite     *models.Suggestion // Alternativa segura del comando (descargar, verificar y ejecutar)
+}
+
+// dataFlowTracker sigue los archivos descargados a través de copias, cambios de permisos y ejecuciones
+type dataFlowTracker struct {
+	fs        *FileSystemState
+	artifacts map[string]*taintedArtifact // Ruta absoluta -> artefacto
+}
+
+func newDataFlowTracker(fs *FileSystemState) *dataFlowTracker {
+	return &dataFlowTracker{
+		fs:        fs,
+		artifacts: make(map[string]*taintedArtifact),
+	}
+}
+
+// Process analiza un comando antes de que se aplique al sistema de archivos (las rutas relativas
+// se resuelven con el directorio actual previo al comando)
+func (t *dataFlowTracker) Process(cmd models.CommandAST) []dataFlowFinding {
+	var findings []dataFlowFinding
+
+	segments := append([]*models.CommandAST{&cmd}, cmd.Pipes...)
+	views := make([]*commandView, len(segments))
+	for i, segment := range segments {
+		views[i] = newCommandView(*segment)
+	}
+
+	// Ejecución de artefactos descargados previamente
+	for _, view := range views {
+		if finding := t.checkExecution(view); finding != nil {
+			findings = append(findings, *finding)
+		}
+	}
+
+	// Contenido de red enviado directamente a un intérprete
+	if finding := detectPipeToInterpreter(cmd, views); finding != nil {
+		findings = append(findings, *finding)
+	}
+
+	// Nuevas descargas y propagación de artefactos
+	t.trackDownloads(cmd, views)
+	for _, view := range views {
+		if finding := t.propagate(view); finding != nil {
+			findings = append(findings, *finding)
+		}
+	}
+
+	return findings
+}
+
+// trackDownloads registra los archivos escritos por curl/wget (-o, -O, redirecciones y tee)
+func (t *dataFlowTracker) trackDownloads(cmd models.CommandAST, views []*commandView) {
+	for i, view := range views {
+		if !networkFetchers[view.name] {
+			continue
+		}
+		source := fetchSource(view)
+		targets, stdout := fetchTargets(view)
+
+		if stdout {
+			// La salida continúa por el pipe: se guarda si algún segmento la escribe en un archivo
+			for _, next := range views[i+1:] {
+				if next.name == "tee" {
+					targets = append(targets, next.arguments...)
+				}
+				if !streamFilters[next.name] {
+					break
+				}
+			}
+			last := views[len(views)-1]
+			if last == view || streamFilters[last.name] {
+				targets = append(targets, outputRedirects(last.cmd)...)
+			}
+		}
+
+		for _, target := range targets {
+			absolute := t.fs.resolvePath(target)
+			t.fs.files[absolute] = true
+			t.artifacts[absolute] = &taintedArtifact{
+				source: source,
+				chain: []models.ChainStep{{
+					Line:    cmd.Line,
+					Action:  "download",
+					Command: cmd.Raw,
+					Path:    absolute,
+					Source:  source,
+				}},
+			}
+		}
+	}
+}
+
+// propagate sigue los artefactos en cp, mv, install, cat > y chmod
+func (t *dataFlowTracker) propagate(view *commandView) *dataFlowFinding {
+	switch view.name {
+	case "cp", "mv", "install":
+		files := positionalPaths(view)
+		if len(files) < 2 {
+			return nil
+		}
+		dest := files[len(files)-1]
+		for _, source := range files[:len(files)-1] {
+			sourceAbsolute := t.fs.resolvePath(source)
+			artifact, tainted := t.artifacts[sourceAbsolute]
+			if !tainted {
+				continue
+			}
+			destAbsolute := t.fs.resolvePath(dest)
+			if t.fs.directories[destAbsolute] || strings.HasSuffix(dest, "/") {
+				destAbsolute = path.Join(destAbsolute, path.Base(sourceAbsolute))
+			}
+
+			action := "copy"
+			if view.name == "mv" {
+				action = "move"
+				delete(t.artifacts, sourceAbsolute)
+			}
+			t.artifacts[destAbsolute] = artifact.derive(models.ChainStep{
+				Line:    view.cmd.Line,
+				Action:  action,
+				Command: view.cmd.Raw,
+				Path:    destAbsolute,
+				Source:  sourceAbsolute,
+			}, view.name == "install" || artifact.executable)
+		}
+
+	case "cat":
+		for _, target := range outputRedirects(view.cmd) {
+			for _, source := range view.arguments {
+				if artifact, tainted := t.artifacts[t.fs.resolvePath(source)]; tainted {
+					destAbsolute := t.fs.resolvePath(target)
+					t.artifacts[destAbsolute] = artifact.derive(models.ChainStep{
+						Line:    view.cmd.Line,
+						Action:  "copy",
+						Command: view.cmd.Raw,
+						Path:    destAbsolute,
+						Source:  t.fs.resolvePath(source),
+					}, false)
+				}
+			}
+		}
+
+	case "chmod":
+		if len(view.arguments) < 2 || !grantsExecution(view.arguments[0]) {
+			return nil
+		}
+		for _, file := range view.arguments[1:] {
+			absolute := t.fs.resolvePath(file)
+			artifact, tainted := t.artifacts[absolute]
+			if !tainted || artifact.executable {
+				continue
+			}
+			artifact.executable = true
+			artifact.chain = append(artifact.chain, models.ChainStep{
+				Line:    view.cmd.Line,
+				Action:  "chmod",
+				Command: view.cmd.Raw,
+				Path:    absolute,
+			})
+			return &dataFlowFinding{
+				threatType:  "download_execute_sequence",
+				description: fmt.Sprintf("Archivo descargado de %s recibe permisos de ejecución (%s)", artifact.source, chainLines(artifact.chain)),
+				technique:   "T1204.002",
+				chain:       append([]models.ChainStep{}, artifact.chain...),
+				anomaly:     true,
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkExecution detecta la ejecución de un artefacto descargado, directa o mediante un intérprete
+func (t *dataFlowTracker) checkExecution(view *commandView) *dataFlowFinding {
+	var candidates []string
+	for _, candidate := range executedPaths(view) {
+		candidates = append(candidates, t.fs.resolvePath(candidate))
+	}
+	if found := t.lookPath(invokedExecutable(view)); found != "" {
+		candidates = append(candidates, found)
+	}
+
+	for _, absolute := range candidates {
+		artifact, tainted := t.artifacts[absolute]
+		if !tainted || artifact.executed {
+			continue
+		}
+		artifact.executed = true
+
+		chain := append(append([]models.ChainStep{}, artifact.chain...), models.ChainStep{
+			Line:    view.cmd.Line,
+			Action:  "execute",
+			Command: view.cmd.Raw,
+			Path:    absolute,
+		})
+
+		how := "se ejecuta"
+		if view.elevated {
+			how = "se ejecuta con privilegios elevados"
+		}
+		return &dataFlowFinding{
+			threatType:  "download_execute",
+			level:       models.CRITICAL,
+			description: fmt.Sprintf("Archivo descargado de %s %s como %s (%s)", artifact.source, how, absolute, chainLines(chain)),
+			technique:   "T1204.002",
+			chain:       chain,
+		}
+	}
+
+	return nil
+}
+
+// lookPath busca un comando invocado por su nombre en los directorios del PATH por defecto y
+// devuelve el primer archivo conocido de la sesión, como haría la shell
+func (t *dataFlowTracker) lookPath(name string) string {
+	if name == "" || strings.Contains(name, "/") {
+		return ""
+	}
+	for _, dir := range standardPath {
+		candidate := path.Join(t.fs.resolvePath(dir), name)
+		if _, tainted := t.artifacts[candidate]; tainted || t.fs.files[candidate] {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// invokedExecutable devuelve el programa que ejecuta el comando, también tras un wrapper (sudo x)
+func invokedExecutable(view *commandView) string {
+	if view.wrapper != "" && len(view.cmd.Arguments) > 0 {
+		return view.cmd.Arguments[0]
+	}
+	return view.cmd.Command
+}
+
+// executedPaths devuelve los archivos que ejecuta un comando, sin resolver: el propio comando
+// si se invoca por ruta (./x, sudo /tmp/x) y el script de un intérprete (bash x.sh, python3 < x.py)
+func executedPaths(view *commandView) []string {
+	var candidates []string
+
+	if executable := invokedExecutable(view); strings.Contains(executable, "/") {
+		candidates = append(candidates, executable)
+	}
+
+	if _, interpreter := scriptInterpreters[view.name]; interpreter {
+		if files := positionalPaths(view); len(files) > 0 && !view.hasFlag("-c") {
+			candidates = append(candidates, files[0])
+		}
+		for _, redirect := range view.cmd.Redirects {
+			if redirect.Type == "<" {
+				candidates = append(candidates, redirect.Target)
+			}
+		}
+	}
+
+	return candidates
+}
+
+// detectPipeToInterpreter detecta curl | sh, wget -qO- | sudo bash, bash <(curl ...) y sh -c "$(curl ...)"
+func detectPipeToInterpreter(cmd models.CommandAST, views []*commandView) *dataFlowFinding {
+	// Pipeline: descarga a stdout, filtros opcionales e intérprete
+	for i, view := range views {
+		if !networkFetchers[view.name] {
+			continue
+		}
+		if _, stdout := fetchTargets(view); !stdout {
+			continue
+		}
+		for _, next := range views[i+1:] {
+			if technique, ok := scriptInterpreters[next.name]; ok {
+				return newPipeFinding(cmd, view, next, technique)
+			}
+			if !streamFilters[next.name] {
+				break
+			}
+		}
+	}
+
+	// Sustitución de procesos o comandos como código del intérprete
+	for _, view := range views {
+		technique, ok := scriptInterpreters[view.name]
+		if !ok {
+			continue
+		}
+		for _, inner := range view.cmd.Substitutions {
+			innerView := newCommandView(*inner)
+			if !networkFetchers[innerView.name] {
+				continue
+			}
+			if _, stdout := fetchTargets(innerView); stdout {
+				return newPipeFinding(cmd, innerView, view, technique)
+			}
+		}
+	}
+
+	return nil
+}
+
+// newPipeFinding construye la detección de contenido de red ejecutado sin guardarse en disco
+func newPipeFinding(cmd models.CommandAST, fetcher, interpreter *commandView, technique string) *dataFlowFinding {
+	source := fetchSource(fetcher)
+	name := interpreter.name
+	if interpreter.elevated {
+		name = interpreter.wrapper + " " + name
+	}
+
+	return &dataFlowFinding{
+		threatType:  "pipe_to_interpreter",
+		level:       models.CRITICAL,
+		description: fmt.Sprintf("Contenido descargado de %s se ejecuta directamente con %s", source, name),
+		technique:   technique,
+		chain: []models.ChainStep{
+			{Line: cmd.Line, Action: "download", Command: fetcher.cmd.Raw, Source: source},
+			{Line: cmd.Line, Action: "execute", Command: interpreter.cmd.Raw},
+		},
+		rewrite: pipeRewrite(fetcher, interpreter),
+	}
+}
+
+// derive crea el artefacto resultante de copiar o mover otro artefacto
+func (a *taintedArtifact) derive(step models.ChainStep, executable bool) *taintedArtifact {
+	return &taintedArtifact{
+		source:     a.source,
+		chain:      append(append([]models.ChainStep{}, a.chain...), step),
+		executable: executable,
+	}
+}
+
+// fetchSource devuelve la URL descargada por curl/wget
+func fetchSource(view *commandView) string {
+	for _, value := range view.values {
+		if strings.Contains(value, "://") {
+			return unquote(value)
+		}
+	}
+	if len(view.arguments) > 0 {
+		return unquote(view.arguments[0])
+	}
+	return ""
+}
+
+// fetchTargets devuelve los archivos que escribe la descarga y si el contenido va a stdout
+func fetchTargets(view *commandView) ([]string, bool) {
+	var targets []string
+	source := fetchSource(view)
+
+	switch view.name {
+	case "curl":
+		for _, flag := range []string{"-o", "--output"} {
+			for _, value := range view.flagValues(flag) {
+				if value != "-" {
+					targets = append(targets, value)
+				}
+			}
+		}
+		if view.hasFlag("-O") || view.hasFlag("--remote-name") {
+			targets = append(targets, urlFileName(source))
+		}
+
+	case "wget":
+		dir := ""
+		for _, flag := range []string{"-P", "--directory-prefix"} {
+			if values := view.flagValues(flag); len(values) > 0 {
+				dir = values[0]
+			}
+		}
+		named := false
+		for _, flag := range []string{"-O", "--output-document"} {
+			for _, value := range view.flagValues(flag) {
+				named = true
+				if value != "-" {
+					targets = append(targets, value)
+				}
+			}
+		}
+		if !named {
+			targets = append(targets, path.Join(dir, urlFileName(source)))
+		}
+		if named && len(targets) == 0 {
+			return outputRedirects(view.cmd), true
+		}
+		return append(targets, outputRedirects(view.cmd)...), false
+
+	default:
+		if len(view.flagValues("-o")) > 0 {
+			return view.flagValues("-o"), false
+		}
+	}
+
+	redirects := outputRedirects(view.cmd)
+	if len(targets) == 0 && len(redirects) == 0 {
+		return nil, true
+	}
+	return append(targets, redirects...), false
+}
+
+// outputRedirects devuelve los archivos a los que se redirige la salida estándar
+func outputRedirects(cmd models.CommandAST) []string {
+	var targets []string
+	for _, redirect := range cmd.Redirects {
+		switch redirect.Type {
+		case ">", ">>", ">|", "&>", "&>>":
+			if redirect.FD == "" || redirect.FD == "1" {
+				targets = append(targets, unquote(redirect.Target))
+			}
+		}
+	}
+	return targets
+}
+
+// urlFileName devuelve el nombre de archivo que usan curl -O y wget para una URL
+func urlFileName(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Path == "" || strings.HasSuffix(parsed.Path, "/") {
+		return "index.html"
+	}
+	return path.Base(parsed.Path)
+}
+
+// positionalPaths devuelve los argumentos posicionales que no son flags
+func positionalPaths(view *commandView) []string {
+	var paths []string
+	for _, arg := range view.arguments {
+		if !strings.HasPrefix(arg, "-") {
+			paths = append(paths, unquote(arg))
+		}
+	}
+	return paths
+}
+
+// grantsExecution indica si un modo de chmod concede ejecución (+x, u+x, 755, a=rx)
+func grantsExecution(mode string) bool {
+	if strings.Contains(mode, "x") && !strings.Contains(mode, "-x") {
+		return true
+	}
+	if _, err := strconv.ParseUint(mode, 8, 32); err != nil {
+		return false
+	}
+	for _, digit := range mode[max(0, len(mode)-3):] {
+		if (digit-'0')&1 == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// chainLines resume las líneas de una cadena (línea 3, líneas 3 → 5 → 12)
+func chainLines(chain []models.ChainStep) string {
+	lines := make([]string, 0, len(chain))
+	for _, step := range chain {
+		label := strconv.Itoa(step.Line)
+		if len(lines) == 0 || lines[len(lines)-1] != label {
+			lines = append(lines, label)
+		}
+	}
+	if len(lines) == 1 {
+		return "línea " + lines[0]
+	}
+	return "líneas " + strings.Join(lines, " → ")
+}
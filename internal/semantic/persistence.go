@@ -0,0 +1,59 @@
+package semantic
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"terminal-history-analyzer/internal/attack"
+	"terminal-history-analyzer/internal/models"
+)
+
+// systemdUnitPattern reconoce las rutas de unidades systemd del sistema y del usuario
+var systemdUnitPattern = regexp.MustCompile(`(?:^(?:/etc|/usr/lib|/lib|/run)/systemd/(?:system|user)/|/\.config/systemd/user/)[^/]+\.(?:service|timer|socket|path)$`)
+
+// writtenUnit es una unidad systemd escrita durante la sesión
+type writtenUnit struct {
+	path string
+	step models.ChainStep
+}
+
+// trackSystemdUnits relaciona las unidades systemd escritas en la sesión con su habilitación posterior
+// (cat > /etc/systemd/system/x.service ... ; systemctl enable --now x)
+func (a *Analyzer) trackSystemdUnits(cmd models.CommandAST) {
+	for _, written := range writtenPaths(cmd, a.filesystemState) {
+		if systemdUnitPattern.MatchString(written) {
+			a.systemdUnits[path.Base(written)] = writtenUnit{
+				path: written,
+				step: models.ChainStep{Line: cmd.Line, Action: "write", Command: cmd.Raw, Path: written},
+			}
+		}
+	}
+
+	view := newCommandView(cmd)
+	if view.name != "systemctl" || view.subcommand != "enable" && view.subcommand != "reenable" {
+		return
+	}
+
+	for _, name := range view.arguments[1:] {
+		unit := unquote(name)
+		if !strings.Contains(unit, ".") {
+			unit += ".service"
+		}
+		written, exists := a.systemdUnits[unit]
+		if !exists {
+			continue
+		}
+
+		a.addThreat(models.HIGH, "persistence",
+			fmt.Sprintf("Servicio systemd creado en la línea %d y habilitado: %s", written.step.Line, written.path),
+			cmd)
+		threat := &a.threats[len(a.threats)-1]
+		threat.Attack = attack.Lookup("T1543.002")
+		threat.Chain = []models.ChainStep{
+			written.step,
+			{Line: cmd.Line, Action: "enable", Command: cmd.Raw, Path: written.path},
+		}
+	}
+}
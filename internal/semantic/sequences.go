@@ -0,0 +1,556 @@
+package semantic
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"terminal-history-analyzer/internal/attack"
+	"terminal-history-analyzer/internal/models"
+)
+
+// maxSequenceProgress limita las coincidencias parciales abiertas por secuencia
+const maxSequenceProgress = 32
+
+// Sequence define una cadena de ataque de varios pasos: los pasos deben aparecer en orden en la
+// sesión, con otros comandos intercalados, sin superar las distancias máximas entre ellos
+type Sequence struct {
+	ID          string             `yaml:"id" json:"id"`
+	Type        string             `yaml:"type,omitempty" json:"type,omitempty"` // Por defecto attack_sequence
+	Severity    models.ThreatLevel `yaml:"severity" json:"severity"`
+	Technique   string             `yaml:"technique,omitempty" json:"technique,omitempty"`
+	Tactic      string             `yaml:"tactic,omitempty" json:"tactic,omitempty"`
+	Message     string             `yaml:"message" json:"message"` // Admite {{steps}} y las variables capturadas ({{file}})
+	Remediation []string           `yaml:"remediation,omitempty" json:"remediation,omitempty"`
+	Window      int                `yaml:"window,omitempty" json:"window,omitempty"` // Líneas máximas entre el primer y el último paso (0: sin límite)
+	Disabled    bool               `yaml:"disabled,omitempty" json:"disabled,omitempty"`
+	Steps       []SequenceStep     `yaml:"steps" json:"steps"`
+}
+
+// SequenceStep es un paso de una secuencia. El comando debe cumplir match (condiciones de las
+// reglas), haber producido alguna de las detecciones de threats y las restricciones de where.
+type SequenceStep struct {
+	ID          string     `yaml:"id" json:"id"`
+	Description string     `yaml:"description,omitempty" json:"description,omitempty"`
+	Match       *RuleMatch `yaml:"match,omitempty" json:"match,omitempty"`
+	Threats     []string   `yaml:"threats,omitempty" json:"threats,omitempty"`   // Tipos de detección o IDs de regla del mismo comando
+	Optional    bool       `yaml:"optional,omitempty" json:"optional,omitempty"` // Puede omitirse
+	Repeat      bool       `yaml:"repeat,omitempty" json:"repeat,omitempty"`     // Las repeticiones consecutivas se agregan al paso
+	MaxGap      int        `yaml:"max_gap,omitempty" json:"max_gap,omitempty"`   // Líneas máximas desde el paso anterior (0: sin límite)
+
+	// Variables: capture guarda el primer valor de la fuente y where exige que el valor ya capturado
+	// esté entre los de la fuente. Fuentes: match, argument, command, hosts, writes, executes, paths.
+	Capture map[string]string `yaml:"capture,omitempty" json:"capture,omitempty"`
+	Where   map[string]string `yaml:"where,omitempty" json:"where,omitempty"`
+}
+
+// sequenceSources son las fuentes de valores para capture y where
+var sequenceSources = map[string]bool{
+	"match": true, "argument": true, "command": true, "hosts": true, "writes": true, "executes": true, "paths": true,
+}
+
+// compiledSequence es una secuencia con las condiciones de sus pasos precompiladas
+type compiledSequence struct {
+	Sequence
+	rules []*compiledRule // Condiciones match de cada paso (nil si el paso no tiene)
+}
+
+// attackTechnique devuelve la técnica ATT&CK de la secuencia
+func (s *compiledSequence) attackTechnique() *models.AttackTechnique {
+	if s.Technique == "" {
+		return attack.ForType(s.Type)
+	}
+	return attack.LookupWithTactic(s.Technique, s.Tactic)
+}
+
+// compileSequence valida una secuencia y compila las condiciones de sus pasos como reglas
+func compileSequence(sequence Sequence) (*compiledSequence, error) {
+	if sequence.ID == "" {
+		return nil, fmt.Errorf("secuencia sin id")
+	}
+	if sequence.Type == "" {
+		sequence.Type = "attack_sequence"
+	}
+
+	switch sequence.Severity {
+	case models.SAFE, models.LOW, models.MEDIUM, models.HIGH, models.CRITICAL:
+	default:
+		return nil, fmt.Errorf("secuencia %s: severidad inválida %q", sequence.ID, sequence.Severity)
+	}
+	if sequence.Technique != "" && !techniqueIDPattern.MatchString(sequence.Technique) {
+		return nil, fmt.Errorf("secuencia %s: técnica ATT&CK inválida %q", sequence.ID, sequence.Technique)
+	}
+	if sequence.Tactic != "" && !attack.IsKnownTactic(sequence.Tactic) {
+		return nil, fmt.Errorf("secuencia %s: táctica ATT&CK desconocida %q", sequence.ID, sequence.Tactic)
+	}
+
+	compiled := &compiledSequence{Sequence: sequence}
+	if sequence.Disabled {
+		return compiled, nil
+	}
+
+	required := 0
+	captured := make(map[string]bool)
+	stepIDs := make(map[string]bool)
+	for i, step := range sequence.Steps {
+		if step.ID == "" {
+			return nil, fmt.Errorf("secuencia %s: el paso %d no tiene id", sequence.ID, i+1)
+		}
+		if stepIDs[step.ID] {
+			return nil, fmt.Errorf("secuencia %s: paso duplicado %s", sequence.ID, step.ID)
+		}
+		stepIDs[step.ID] = true
+		if step.Match == nil && len(step.Threats) == 0 && len(step.Where) == 0 {
+			return nil, fmt.Errorf("secuencia %s, paso %s: requiere match, threats o where", sequence.ID, step.ID)
+		}
+		if !step.Optional {
+			required++
+		}
+
+		for variable, source := range step.Where {
+			if !sequenceSources[source] {
+				return nil, fmt.Errorf("secuencia %s, paso %s: fuente desconocida %q", sequence.ID, step.ID, source)
+			}
+			if !captured[variable] {
+				return nil, fmt.Errorf("secuencia %s, paso %s: la variable %s no se captura en un paso obligatorio anterior", sequence.ID, step.ID, variable)
+			}
+		}
+		for variable, source := range step.Capture {
+			if !sequenceSources[source] {
+				return nil, fmt.Errorf("secuencia %s, paso %s: fuente desconocida %q", sequence.ID, step.ID, source)
+			}
+			if !step.Optional {
+				captured[variable] = true
+			}
+		}
+
+		var rule *compiledRule
+		if step.Match != nil {
+			var err error
+			rule, err = compileRule(Rule{
+				ID:       sequence.ID + "/" + step.ID,
+				Type:     sequence.Type,
+				Severity: sequence.Severity,
+				Match:    *step.Match,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		compiled.rules = append(compiled.rules, rule)
+	}
+
+	if required < 2 {
+		return nil, fmt.Errorf("secuencia %s: requiere al menos dos pasos obligatorios", sequence.ID)
+	}
+	if sequence.Steps[len(sequence.Steps)-1].Optional {
+		return nil, fmt.Errorf("secuencia %s: el último paso no puede ser opcional", sequence.ID)
+	}
+
+	return compiled, nil
+}
+
+// sequenceProgress es una coincidencia parcial de una secuencia
+type sequenceProgress struct {
+	next     int               // Índice del siguiente paso
+	last     int               // Índice del último paso que coincidió
+	host     string            // Host en el que se ejecutan los pasos (ver lateral.go)
+	first    int               // Línea del primer paso
+	line     int               // Línea del último paso
+	bindings map[string]string // Variables capturadas
+	chain    []models.ChainStep
+}
+
+// key identifica coincidencias parciales equivalentes (mismo punto, host y variables)
+func (p *sequenceProgress) key() string {
+	names := make([]string, 0, len(p.bindings))
+	for name := range p.bindings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var key strings.Builder
+	key.WriteString(strconv.Itoa(p.next) + "|" + p.host)
+	for _, name := range names {
+		key.WriteString("|" + name + "=" + p.bindings[name])
+	}
+	return key.String()
+}
+
+// sequenceEvent son los datos de un comando disponibles para los pasos
+type sequenceEvent struct {
+	cmd      models.CommandAST
+	view     *commandView
+	host     string
+	threats  []models.ThreatDetection // Detecciones producidas por el comando
+	executes []string                 // Archivos ejecutados, resueltos
+	paths    []string                 // Archivos que nombra el comando, resueltos
+}
+
+// sequenceTracker avanza las coincidencias parciales de las secuencias con cada comando
+type sequenceTracker struct {
+	progress map[string][]*sequenceProgress // Por ID de secuencia
+}
+
+func newSequenceTracker() *sequenceTracker {
+	return &sequenceTracker{progress: make(map[string][]*sequenceProgress)}
+}
+
+// sequenceMatch es una secuencia completa
+type sequenceMatch struct {
+	sequence *compiledSequence
+	progress *sequenceProgress
+}
+
+// trackSequences avanza las secuencias con un comando ya analizado (threats son sus detecciones)
+// y registra las que se completan. Debe llamarse antes de actualizar el directorio actual.
+func (a *Analyzer) trackSequences(cmd models.CommandAST, host string, threats []models.ThreatDetection) {
+	sequences := a.rules.sequences
+	if len(sequences) == 0 {
+		return
+	}
+
+	view := newCommandView(cmd)
+	view.writes = writtenPaths(cmd, a.filesystemState)
+	view.hosts = remoteHosts(view)
+	event := &sequenceEvent{
+		cmd:      cmd,
+		view:     view,
+		host:     host,
+		threats:  threats,
+		executes: a.resolvePaths(executedPaths(view)),
+		paths:    a.resolvePaths(namedPaths(view)),
+	}
+
+	for _, sequence := range sequences {
+		if sequence.Disabled {
+			continue
+		}
+		for _, match := range a.sequences.advance(sequence, event) {
+			a.addSequenceThreat(match, cmd)
+		}
+	}
+}
+
+// advance aplica un comando a las coincidencias parciales de una secuencia y devuelve las completas
+func (t *sequenceTracker) advance(sequence *compiledSequence, event *sequenceEvent) []sequenceMatch {
+	line := event.cmd.Line
+	var open []*sequenceProgress
+	var matches []sequenceMatch
+
+	for _, progress := range t.progress[sequence.ID] {
+		if sequence.Window > 0 && line-progress.first > sequence.Window {
+			continue
+		}
+		if progress.host != event.host {
+			open = append(open, progress)
+			continue
+		}
+
+		// Repetición del último paso: se agrega a la cadena y reinicia la distancia
+		if step := sequence.Steps[progress.last]; step.Repeat {
+			if _, ok := sequence.stepMatches(progress.last, event, progress.bindings); ok {
+				progress.record(step, event, nil)
+				open = append(open, progress)
+				continue
+			}
+		}
+
+		next, captured := sequence.nextStep(progress, event)
+		if next != nil && next.next == len(sequence.Steps) {
+			matches = append(matches, sequenceMatch{sequence: sequence, progress: next})
+		} else if next != nil {
+			open = append(open, next)
+		}
+
+		// La coincidencia original sigue abierta si no avanzó o si el paso capturó variables
+		// (otro comando posterior puede completar la secuencia con otros valores)
+		if (next == nil || captured) && !progress.expired(sequence, line) {
+			open = append(open, progress)
+		}
+	}
+
+	// Nueva coincidencia parcial desde el primer paso obligatorio (o un opcional anterior)
+	start := &sequenceProgress{next: 0, host: event.host, first: line, line: line}
+	if next, _ := sequence.nextStep(start, event); next != nil {
+		open = append(open, next)
+	}
+
+	t.progress[sequence.ID] = compactProgress(open)
+	return matches
+}
+
+// expired indica si ya no es posible alcanzar el siguiente paso obligatorio
+func (p *sequenceProgress) expired(sequence *compiledSequence, line int) bool {
+	for i := p.next; i < len(sequence.Steps); i++ {
+		step := sequence.Steps[i]
+		if step.MaxGap == 0 || line-p.line <= step.MaxGap {
+			return false
+		}
+		if !step.Optional {
+			return true
+		}
+	}
+	return true
+}
+
+// nextStep intenta avanzar desde progress con el comando, saltando pasos opcionales; devuelve
+// una nueva coincidencia parcial (o nil) y si el paso capturó variables
+func (s *compiledSequence) nextStep(progress *sequenceProgress, event *sequenceEvent) (*sequenceProgress, bool) {
+	for i := progress.next; i < len(s.Steps); i++ {
+		step := s.Steps[i]
+		inGap := progress.chain == nil || step.MaxGap == 0 || event.cmd.Line-progress.line <= step.MaxGap
+		if inGap {
+			if captures, ok := s.stepMatches(i, event, progress.bindings); ok {
+				next := progress.clone()
+				next.next, next.last = i+1, i
+				if next.chain == nil {
+					next.first = event.cmd.Line
+				}
+				next.record(step, event, captures)
+				return next, len(captures) > 0
+			}
+		}
+		if !step.Optional {
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// stepMatches evalúa un paso sobre el comando y devuelve las variables que captura
+func (s *compiledSequence) stepMatches(index int, event *sequenceEvent, bindings map[string]string) (map[string]string, bool) {
+	step := s.Steps[index]
+
+	var hit *ruleHit
+	if rule := s.rules[index]; rule != nil {
+		hits := rule.evaluate(event.view)
+		if len(hits) == 0 {
+			return nil, false
+		}
+		hit = &hits[0]
+	}
+
+	if len(step.Threats) > 0 {
+		found := false
+		for _, threat := range event.threats {
+			if contains(step.Threats, threat.Type) || threat.RuleID != "" && contains(step.Threats, threat.RuleID) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+
+	for variable, source := range step.Where {
+		if !contains(event.values(source, hit), bindings[variable]) {
+			return nil, false
+		}
+	}
+
+	var captures map[string]string
+	for variable, source := range step.Capture {
+		values := event.values(source, hit)
+		if len(values) == 0 {
+			return nil, false
+		}
+		if captures == nil {
+			captures = make(map[string]string, len(step.Capture))
+		}
+		captures[variable] = values[0]
+	}
+
+	return captures, true
+}
+
+// values devuelve los valores de una fuente de variables para el comando
+func (e *sequenceEvent) values(source string, hit *ruleHit) []string {
+	switch source {
+	case "match", "argument":
+		if hit == nil {
+			return nil
+		}
+		value := hit.match
+		if source == "argument" {
+			value = unquote(hit.argument)
+		}
+		if value == "" {
+			return nil
+		}
+		return []string{value}
+	case "command":
+		return []string{e.view.name}
+	case "hosts":
+		var hosts []string
+		for _, host := range e.view.hosts {
+			hosts = append(hosts, host.Host)
+		}
+		return hosts
+	case "writes":
+		return e.view.writes
+	case "executes":
+		return e.executes
+	case "paths":
+		return e.paths
+	}
+	return nil
+}
+
+// record agrega el comando a la cadena; Path es la variable capturada o exigida en el paso
+func (p *sequenceProgress) record(step SequenceStep, event *sequenceEvent, captures map[string]string) {
+	chainStep := models.ChainStep{
+		Line:    event.cmd.Line,
+		Action:  step.ID,
+		Command: event.cmd.Raw,
+	}
+
+	names := make([]string, 0, len(captures))
+	for name := range captures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if p.bindings == nil {
+			p.bindings = make(map[string]string)
+		}
+		p.bindings[name] = captures[name]
+		if chainStep.Path == "" {
+			chainStep.Path = captures[name]
+		}
+	}
+
+	for variable := range step.Where {
+		if chainStep.Path == "" {
+			chainStep.Path = p.bindings[variable]
+		}
+	}
+
+	p.line = event.cmd.Line
+	p.chain = append(p.chain, chainStep)
+}
+
+func (p *sequenceProgress) clone() *sequenceProgress {
+	cloned := *p
+	cloned.chain = append([]models.ChainStep{}, p.chain...)
+	cloned.bindings = make(map[string]string, len(p.bindings))
+	for name, value := range p.bindings {
+		cloned.bindings[name] = value
+	}
+	return &cloned
+}
+
+// compactProgress descarta coincidencias parciales equivalentes (conserva la más reciente y, a
+// igualdad, la de cadena más larga) y limita su número
+func compactProgress(open []*sequenceProgress) []*sequenceProgress {
+	best := make(map[string]*sequenceProgress, len(open))
+	var keys []string
+	for _, progress := range open {
+		key := progress.key()
+		current, exists := best[key]
+		if !exists {
+			keys = append(keys, key)
+		}
+		if !exists || progress.line > current.line || progress.line == current.line && len(progress.chain) > len(current.chain) {
+			best[key] = progress
+		}
+	}
+
+	compacted := make([]*sequenceProgress, 0, len(keys))
+	for _, key := range keys {
+		compacted = append(compacted, best[key])
+	}
+	if len(compacted) > maxSequenceProgress {
+		sort.SliceStable(compacted, func(i, j int) bool { return compacted[i].line > compacted[j].line })
+		compacted = compacted[:maxSequenceProgress]
+	}
+	return compacted
+}
+
+// resolvePaths resuelve rutas con el directorio actual de la sesión
+func (a *Analyzer) resolvePaths(paths []string) []string {
+	resolved := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if path = unquote(path); path != "" {
+			resolved = append(resolved, a.filesystemState.resolvePath(path))
+		}
+	}
+	return resolved
+}
+
+// namedPaths devuelve los archivos que nombra un comando: argumentos, redirecciones y el propio
+// ejecutable si se invoca por ruta
+func namedPaths(view *commandView) []string {
+	paths := positionalPaths(view)
+	for _, redirect := range view.cmd.Redirects {
+		if !strings.HasPrefix(redirect.Target, "&") {
+			paths = append(paths, redirect.Target)
+		}
+	}
+	return append(paths, executedPaths(view)...)
+}
+
+// addSequenceThreat registra una secuencia completa con la cadena de pasos
+func (a *Analyzer) addSequenceThreat(match sequenceMatch, cmd models.CommandAST) {
+	sequence, progress := match.sequence, match.progress
+
+	suggestions := a.generateSuggestions(sequence.Type, cmd, sequence.Remediation)
+
+	details := map[string]string{
+		"sequence":   sequence.ID,
+		"first_line": strconv.Itoa(progress.first),
+	}
+	for name, value := range progress.bindings {
+		details[name] = value
+	}
+
+	a.threats = append(a.threats, models.ThreatDetection{
+		RuleID:      sequence.ID,
+		Type:        sequence.Type,
+		Level:       sequence.Severity,
+		Description: sequence.message(progress),
+		Command:     cmd.Raw,
+		Line:        cmd.Line,
+		Suggestions: suggestions,
+		Attack:      sequence.attackTechnique(),
+		Chain:       progress.chain,
+		Details:     details,
+	})
+}
+
+// message construye la descripción: {{steps}} es la cadena de pasos y {{variable}} su valor capturado
+func (s *compiledSequence) message(progress *sequenceProgress) string {
+	var steps []string
+	for _, chainStep := range progress.chain {
+		name := chainStep.Action
+		for _, step := range s.Steps {
+			if step.ID == chainStep.Action && step.Description != "" {
+				name = step.Description
+			}
+		}
+		label := name + " (línea " + strconv.Itoa(chainStep.Line) + ")"
+		if len(steps) > 0 && strings.HasPrefix(steps[len(steps)-1], name+" (") {
+			continue // Las repeticiones se muestran una vez
+		}
+		steps = append(steps, label)
+	}
+
+	replacements := []string{"{{steps}}", strings.Join(steps, " → ")}
+	for name, value := range progress.bindings {
+		replacements = append(replacements, "{{"+name+"}}", value)
+	}
+	return strings.NewReplacer(replacements...).Replace(s.Message)
+}
+
+// Sequences devuelve las definiciones de las secuencias activas
+func (rs *RuleSet) Sequences() []Sequence {
+	sequences := make([]Sequence, 0, len(rs.sequences))
+	for _, sequence := range rs.sequences {
+		if !sequence.Disabled {
+			sequences = append(sequences, sequence.Sequence)
+		}
+	}
+	return sequences
+}
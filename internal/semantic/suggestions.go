@@ -0,0 +1,360 @@
+package semantic
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"terminal-history-analyzer/internal/models"
+)
+
+var (
+	// shellVariable reconoce $NOMBRE y ${NOMBRE} sin modificadores (${DIR:-x} y ${DIR:?} se respetan)
+	shellVariable = regexp.MustCompile(`\$(\{[A-Za-z_][A-Za-z0-9_]*\}|[A-Za-z_][A-Za-z0-9_]*)`)
+
+	// numericMode reconoce un modo octal de chmod con bits especiales opcionales (777, 0777, 4777)
+	numericMode = regexp.MustCompile(`^(0?[0-7]?)([0-7])([0-7])([0-7])$`)
+
+	// symbolicClause reconoce una cláusula de modo simbólico (a+w, go=rwx, +x)
+	symbolicClause = regexp.MustCompile(`^([ugoa]*)([-+=])([rwxXst]*)$`)
+
+	// safeShellWord son las palabras que no necesitan comillas
+	safeShellWord = regexp.MustCompile(`^[A-Za-z0-9_./:@%+=,~-]+$`)
+)
+
+// interpreterExtensions es la extensión del script descargado según el intérprete
+var interpreterExtensions = map[string]string{
+	"python": ".py", "python2": ".py", "python3": ".py",
+	"perl": ".pl", "ruby": ".rb", "php": ".php", "lua": ".lua", "node": ".js",
+}
+
+// remoteLoginCommands son los comandos de acceso remoto con usuario (root@host, -l root)
+var remoteLoginCommands = map[string]bool{
+	"ssh": true, "scp": true, "sftp": true, "rsync": true, "mosh": true, "autossh": true,
+}
+
+// generateSuggestions combina la alternativa concreta del comando, si la hay, con la remediación de
+// la regla o los consejos generales del tipo de detección
+func (a *Analyzer) generateSuggestions(threatType string, cmd models.CommandAST, remediation []string) []models.Suggestion {
+	var suggestions []models.Suggestion
+	if rewrite := a.rewriteCommand(threatType, a.unexpanded(cmd)); rewrite != nil {
+		suggestions = append(suggestions, *rewrite)
+	}
+
+	if len(remediation) == 0 {
+		remediation = typeAdvice(threatType)
+	}
+	for _, advice := range remediation {
+		suggestions = append(suggestions, models.Suggestion{Explanation: advice})
+	}
+	return suggestions
+}
+
+// rewriteCommand reescribe el comando de forma más segura a partir de su AST
+func (a *Analyzer) rewriteCommand(threatType string, cmd models.CommandAST) *models.Suggestion {
+	view := newCommandView(cmd)
+
+	switch threatType {
+	case "critical_command", "dangerous_deletion", "sudo_dangerous", "insecure_permissions", "unsafe_expansion":
+		switch view.name {
+		case "rm":
+			return rmRewrite(view)
+		case "chmod":
+			return chmodRewrite(view)
+		}
+	case "root_ssh":
+		if remoteLoginCommands[view.name] {
+			return a.rootLoginRewrite(view)
+		}
+	}
+	return nil
+}
+
+// rmRewrite protege las variables de las rutas con ${VAR:?} y comillas y añade -- antes de los
+// operandos; sin variables cambia -f por -I para pedir confirmación
+func rmRewrite(view *commandView) *models.Suggestion {
+	prefix, rest := commandFields(view)
+
+	var flags, operands []string
+	guarded, endOfOptions := false, false
+	for _, field := range rest {
+		switch {
+		case !endOfOptions && field == "--":
+			endOfOptions = true
+		case !endOfOptions && strings.HasPrefix(field, "-") && len(field) > 1:
+			flags = append(flags, field)
+		default:
+			operand, changed := guardExpansions(field)
+			guarded = guarded || changed
+			operands = append(operands, operand)
+		}
+	}
+	if len(operands) == 0 {
+		return nil
+	}
+
+	if guarded {
+		return &models.Suggestion{
+			Rewrite: joinFields(prefix, flags, []string{"--"}, operands),
+			Explanation: "${VAR:?} detiene el comando si la variable está vacía o no definida, en lugar de borrar " +
+				"desde / o desde el directorio actual; las comillas evitan que la ruta se separe en varias palabras " +
+				"y -- que un nombre que empiece por - se tome como opción",
+		}
+	}
+
+	if !view.hasFlag("-f") && !view.longFlags["force"] {
+		return nil
+	}
+	safeFlags := []string{"-I"}
+	if view.hasFlag("-r") || view.hasFlag("-R") || view.longFlags["recursive"] {
+		safeFlags = []string{"-rI"}
+	}
+	return &models.Suggestion{
+		Rewrite: joinFields(prefix, safeFlags, []string{"--"}, operands),
+		Explanation: "Sin -f, -I pide una confirmación antes de un borrado recursivo o de más de tres archivos y " +
+			"avisa de los archivos protegidos; -- impide que un nombre que empiece por - se tome como opción",
+	}
+}
+
+// guardExpansions cambia $VAR y ${VAR} por "${VAR:?}" y entrecomilla la ruta sin los comodines finales
+// ($DIR/* -> "${DIR:?}/"*). Las palabras con comillas simples o comillas intermedias no se tocan.
+func guardExpansions(field string) (string, bool) {
+	text := field
+	if len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"' {
+		text = text[1 : len(text)-1]
+	}
+	if strings.ContainsAny(text, `"'`) || !shellVariable.MatchString(text) {
+		return field, false
+	}
+
+	start := globStart(text)
+	prefix, glob := text[:start], text[start:]
+	prefix = shellVariable.ReplaceAllStringFunc(prefix, func(variable string) string {
+		name := strings.Trim(strings.TrimPrefix(variable, "$"), "{}")
+		return "${" + name + ":?}"
+	})
+	if prefix == "" {
+		return glob, true
+	}
+	return `"` + prefix + `"` + glob, true
+}
+
+// globStart devuelve la posición del primer comodín (*, ? o [) fuera de las expansiones ${...}
+func globStart(text string) int {
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		switch {
+		case strings.HasPrefix(text[i:], "${"):
+			depth++
+			i++
+		case text[i] == '}' && depth > 0:
+			depth--
+		case depth == 0 && strings.IndexByte("*?[", text[i]) >= 0:
+			return i
+		}
+	}
+	return len(text)
+}
+
+// chmodRewrite quita la escritura al grupo y al resto de usuarios del modo (777 -> 755, a+w -> u+w)
+func chmodRewrite(view *commandView) *models.Suggestion {
+	prefix, rest := commandFields(view)
+
+	for i, field := range rest {
+		if strings.HasPrefix(field, "-") && !symbolicClause.MatchString(field) {
+			continue
+		}
+		mode, ok := restrictMode(unquote(field))
+		if !ok {
+			return nil
+		}
+
+		rewritten := append(append(append([]string{}, rest[:i]...), mode), rest[i+1:]...)
+		return &models.Suggestion{
+			Rewrite: joinFields(prefix, rewritten),
+			Explanation: fmt.Sprintf("%s mantiene la lectura (y la ejecución) para el resto pero solo el propietario puede "+
+				"escribir; con %s cualquier usuario del sistema puede modificar o reemplazar el contenido. Si otro "+
+				"usuario necesita escribir, use un grupo compartido o ACLs (setfacl)", mode, unquote(field)),
+		}
+	}
+	return nil
+}
+
+// restrictMode devuelve el modo sin escritura para el grupo ni el resto, o false si ya no la tenía
+func restrictMode(mode string) (string, bool) {
+	if parts := numericMode.FindStringSubmatch(mode); parts != nil {
+		group, _ := strconv.Atoi(parts[3])
+		other, _ := strconv.Atoi(parts[4])
+		if group&2 == 0 && other&2 == 0 {
+			return "", false
+		}
+		return fmt.Sprintf("%s%s%d%d", parts[1], parts[2], group&^2, other&^2), true
+	}
+
+	var clauses []string
+	changed := false
+	for _, clause := range strings.Split(mode, ",") {
+		parts := symbolicClause.FindStringSubmatch(clause)
+		if parts == nil {
+			return "", false
+		}
+		who, op, perms := parts[1], parts[2], parts[3]
+		if who == "" || strings.Contains(who, "a") {
+			who = "ugo"
+		}
+		others := strings.ReplaceAll(who, "u", "")
+		if op == "-" || others == "" || !strings.Contains(perms, "w") {
+			clauses = append(clauses, clause)
+			continue
+		}
+
+		changed = true
+		if strings.Contains(who, "u") {
+			clauses = append(clauses, "u"+op+perms)
+		}
+		if reduced := strings.ReplaceAll(perms, "w", ""); reduced != "" || op == "=" {
+			clauses = append(clauses, others+op+reduced)
+		}
+	}
+	if !changed {
+		return "", false
+	}
+	return strings.Join(clauses, ","), true
+}
+
+// rootLoginRewrite cambia root por una cuenta personal (la del dueño del historial si se conoce) y
+// ejecuta con sudo el comando remoto
+func (a *Analyzer) rootLoginRewrite(view *commandView) *models.Suggestion {
+	user := a.sessionUser
+	if user == "" || user == "root" {
+		user = "usuario"
+	}
+
+	prefix, rest := commandFields(view)
+	rewritten := make([]string, 0, len(rest)+1)
+	remote := -1
+	for i := 0; i < len(rest); i++ {
+		field := rest[i]
+		switch {
+		case field == "-l" && i+1 < len(rest) && unquote(rest[i+1]) == "root":
+			rewritten = append(rewritten, field, user)
+			i++
+			continue
+		case field == "-lroot":
+			field = "-l" + user
+		case strings.Contains(field, "root@"):
+			field = strings.Replace(field, "root@", user+"@", 1)
+			if view.name == "ssh" && remote < 0 {
+				remote = len(rewritten) + 1
+			}
+		}
+		rewritten = append(rewritten, field)
+	}
+
+	explanation := "Entre con una cuenta personal y eleve con sudo solo los comandos que lo necesiten: queda " +
+		"registrado quién hizo cada cambio y el servidor puede desactivar PermitRootLogin"
+	if view.name != "ssh" && view.name != "mosh" && view.name != "autossh" {
+		explanation = "Copie con una cuenta personal a un directorio propio y mueva los archivos con sudo en el " +
+			"servidor: queda registrado quién los cambió y el servidor puede desactivar PermitRootLogin"
+	}
+
+	// Comando remoto de ssh: se antepone sudo ('sudo systemctl restart app')
+	if remote >= 0 && remote < len(rewritten) {
+		command := rewritten[remote]
+		switch {
+		case len(command) >= 2 && (command[0] == '\'' || command[0] == '"'):
+			rewritten[remote] = command[:1] + "sudo " + command[1:]
+		case command != "sudo":
+			rewritten = append(append(append([]string{}, rewritten[:remote]...), "sudo"), rewritten[remote:]...)
+		}
+	}
+
+	return &models.Suggestion{
+		Rewrite:     joinFields(prefix, rewritten),
+		Explanation: explanation,
+	}
+}
+
+// pipeRewrite reemplaza la descarga enviada al intérprete por descargar a un archivo, verificar su hash,
+// revisarlo y ejecutarlo
+func pipeRewrite(fetcher, interpreter *commandView) *models.Suggestion {
+	source := fetchSource(fetcher)
+	if source == "" {
+		return nil
+	}
+
+	file := "script"
+	if parsed, err := url.Parse(source); err == nil && path.Base(parsed.Path) != "" {
+		if base := path.Base(parsed.Path); base != "/" && base != "." {
+			file = base
+		}
+	}
+	if path.Ext(file) == "" {
+		extension, ok := interpreterExtensions[interpreter.name]
+		if !ok {
+			extension = ".sh"
+		}
+		file += extension
+	}
+
+	download := "curl -fsSL -o " + file + " " + shellQuote(source)
+	if fetcher.name == "wget" {
+		download = "wget -O " + file + " " + shellQuote(source)
+	}
+
+	run := interpreter.name
+	if run == "source" || run == "." || run == "eval" {
+		run = "bash"
+	}
+	if interpreter.elevated {
+		run = interpreter.wrapper + " " + run
+	}
+
+	return &models.Suggestion{
+		Rewrite: strings.Join([]string{
+			download,
+			"sha256sum " + file + "  # compare con el hash publicado por el proveedor",
+			"less " + file,
+			run + " " + file,
+		}, "\n"),
+		Explanation: "Descargue el script a un archivo, compare su hash con el publicado por el proveedor y revise " +
+			"su contenido antes de ejecutarlo; con -f curl falla ante un error HTTP en lugar de pasar la página de " +
+			"error (o un script manipulado) directamente al intérprete",
+	}
+}
+
+// commandFields separa las palabras del comando en el prefijo (wrapper y comando: sudo rm) y el resto
+func commandFields(view *commandView) ([]string, []string) {
+	fields := view.fields
+	start := 0
+	if view.wrapper != "" {
+		start = 1
+	}
+	for i := start; i < len(fields); i++ {
+		if filepath.Base(unquote(fields[i])) == view.name {
+			return fields[:i+1], fields[i+1:]
+		}
+	}
+	return fields[:0], fields
+}
+
+// joinFields une grupos de palabras en un comando
+func joinFields(groups ...[]string) string {
+	var words []string
+	for _, group := range groups {
+		words = append(words, group...)
+	}
+	return strings.Join(words, " ")
+}
+
+// shellQuote entrecomilla con comillas simples un valor con caracteres especiales para la shell
+func shellQuote(value string) string {
+	if safeShellWord.MatchString(value) {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
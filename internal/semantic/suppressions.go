@@ -0,0 +1,190 @@
+package semantic
+
+import (
+	"sort"
+	"strings"
+
+	"terminal-history-analyzer/internal/dialect"
+	"terminal-history-analyzer/internal/models"
+	"terminal-history-analyzer/internal/suppress"
+)
+
+// suppressionScope es el contexto de un comando con el que se evalúan las supresiones
+type suppressionScope struct {
+	host  string // Host en el que se ejecutó el comando (localHost para el equipo del historial)
+	hosts []string
+	paths []string
+	users []string
+}
+
+// SetSession indica el dueño y el equipo del historial para las supresiones con ámbito user y host
+// y para comparar la sesión con su línea base
+func (a *Analyzer) SetSession(user, host string) {
+	a.sessionUser = user
+	a.sessionHost = host
+	if user != "" {
+		a.symbols.SetEnvironment("USER", user)
+		a.symbols.SetEnvironment("LOGNAME", user)
+	}
+	a.loadBaseline()
+}
+
+// SetScript indica que el contenido es un script: la sangría no oculta comandos, los errores del
+// sistema de archivos virtual no son amenazas, el script no forma parte de la línea base del usuario
+// y las opciones del shebang (-u) rigen desde la primera línea
+func (a *Analyzer) SetScript(info models.ScriptInfo) {
+	a.script = true
+	a.symbols.nounset = info.Nounset
+	a.loadBaseline()
+}
+
+// SetDialect indica el dialecto de shell del contenido: selecciona las reglas que aplican, la forma de
+// asignar variables de fish (set -x NOMBRE valor) y desactiva el modelo de sistema de archivos de
+// Unix y las expansiones sin comillas en PowerShell
+func (a *Analyzer) SetDialect(name string) {
+	a.dialect = name
+	a.symbols.fish = name == dialect.Fish
+}
+
+// Suppressed devuelve las detecciones apartadas por supresiones con su justificación
+func (a *Analyzer) Suppressed() []models.SuppressedThreat {
+	return a.suppressed
+}
+
+// currentUser devuelve el usuario con el que se ejecuta el siguiente comando: el del contexto abierto
+// (sudo -i, su, sesión ssh) si se conoce o el dueño del historial
+func (a *Analyzer) currentUser() string {
+	return a.executionContext().User
+}
+
+// suppressionScope calcula los hosts, rutas y usuarios del comando; debe llamarse antes de
+// actualizar el directorio actual. Sin supresiones vigentes devuelve nil.
+func (a *Analyzer) suppressionScope(cmd models.CommandAST, host, user string) *suppressionScope {
+	if a.suppressions.Len() == 0 {
+		return nil
+	}
+
+	view := newCommandView(cmd)
+	scope := &suppressionScope{host: host}
+
+	switch {
+	case host != localHost:
+		scope.hosts = append(scope.hosts, host)
+	case a.sessionHost != "":
+		scope.hosts = append(scope.hosts, a.sessionHost)
+	}
+	if user != "" {
+		scope.users = append(scope.users, user)
+	}
+	for _, target := range remoteHosts(view) {
+		scope.hosts = append(scope.hosts, target.Host)
+		if target.User != "" {
+			scope.users = append(scope.users, target.User)
+		}
+	}
+
+	scope.paths = a.resolvePaths(append(namedPaths(view), flagPaths(cmd)...))
+	for _, written := range writtenPaths(cmd, a.filesystemState) {
+		if !contains(scope.paths, written) {
+			scope.paths = append(scope.paths, written)
+		}
+	}
+
+	return scope
+}
+
+// suppressThreats aparta las detecciones añadidas desde from que cumplen alguna supresión vigente
+func (a *Analyzer) suppressThreats(from int, scope *suppressionScope) {
+	if scope == nil || from >= len(a.threats) {
+		return
+	}
+
+	kept := a.threats[:from]
+	for _, threat := range a.threats[from:] {
+		if !a.suppressThreat(threat, scope) {
+			kept = append(kept, threat)
+		}
+	}
+	a.threats = kept
+}
+
+// suppressThreat aparta la detección si cumple alguna supresión vigente y devuelve si lo hizo
+func (a *Analyzer) suppressThreat(threat models.ThreatDetection, scope *suppressionScope) bool {
+	rule, ok := a.suppressions.Match(suppress.Finding{
+		Threat: threat,
+		Hosts:  scope.hosts,
+		Paths:  scope.paths,
+		Users:  scope.users,
+	})
+	if !ok {
+		return false
+	}
+
+	if scope.host != localHost {
+		threat.Host = scope.host
+	}
+	a.suppressed = append(a.suppressed, models.SuppressedThreat{
+		Threat:        threat,
+		SuppressionID: rule.ID,
+		Reason:        rule.Justification,
+		ExpiresAt:     rule.ExpiresAt,
+	})
+	return true
+}
+
+// SuppressFindings aplica las supresiones vigentes a detecciones generadas fuera del analizador
+// (los hallazgos del linter en mode=script) y devuelve las que no se apartan; las apartadas se
+// suman a Suppressed. El ámbito es el del comando de la línea de cada detección con el usuario, el
+// equipo y el directorio con los que se ejecutó, por lo que debe llamarse después de Analyze.
+func (a *Analyzer) SuppressFindings(commands []models.CommandAST, threats []models.ThreatDetection) []models.ThreatDetection {
+	if a.suppressions.Len() == 0 {
+		return threats
+	}
+
+	byLine := make(map[int]models.CommandAST)
+	for _, cmd := range commands {
+		if _, exists := byLine[cmd.Line]; !exists {
+			byLine[cmd.Line] = cmd
+		}
+	}
+
+	// Las rutas relativas se resuelven con el directorio de cada línea, no con el del final
+	fs := a.filesystemState
+	defer func(directory, home string) {
+		fs.currentDirectory, fs.home = directory, home
+	}(fs.currentDirectory, fs.home)
+
+	kept := make([]models.ThreatDetection, 0, len(threats))
+	for _, threat := range threats {
+		cmd, ok := byLine[threat.Line]
+		if !ok {
+			cmd = models.CommandAST{Raw: threat.Command, Line: threat.Line}
+		}
+		context, ok := a.privilege.lines[threat.Line]
+		if !ok {
+			context = models.ExecutionContext{User: a.sessionUser, Host: localHost}
+		}
+		if place, ok := a.privilege.places[threat.Line]; ok {
+			fs.currentDirectory, fs.home = place.directory, place.home
+		}
+		if !a.suppressThreat(threat, a.suppressionScope(cmd, context.Host, context.User)) {
+			kept = append(kept, threat)
+		}
+	}
+	return kept
+}
+
+// flagPaths devuelve las rutas de los valores de flags, incluidas las de pares origen:destino
+// (docker run -v /opt/ci:/ci, --volume=/srv:/data)
+func flagPaths(cmd models.CommandAST) []string {
+	var paths []string
+	for _, value := range cmd.Flags {
+		for _, part := range strings.Split(unquote(value), ":") {
+			if strings.HasPrefix(part, "/") || strings.HasPrefix(part, "~/") || strings.HasPrefix(part, "./") {
+				paths = append(paths, part)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,108 @@
+package semantic
+
+import (
+	"testing"
+	"time"
+
+	"terminal-history-analyzer/internal/lexer"
+	"terminal-history-analyzer/internal/lint"
+	"terminal-history-analyzer/internal/parser"
+	"terminal-history-analyzer/internal/suppress"
+)
+
+func TestSuppressFindings(t *testing.T) {
+	store, err := suppress.Configure("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { suppress.Configure("") })
+	if _, err := store.Create(suppress.Rule{
+		RuleID:        "SH006",
+		User:          "deploy",
+		Justification: "El script de despliegue valida la entrada antes del eval",
+		ExpiresAt:     time.Now().Add(time.Hour),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	content := "#!/bin/bash\nread name\neval \"$name\"\n"
+	tests := []struct {
+		name       string
+		user       string
+		suppressed bool
+	}{
+		{name: "usuario de la supresión", user: "deploy", suppressed: true},
+		{name: "otro usuario", user: "alice", suppressed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, _ := lexer.NewLexer(content).Tokenize()
+			commands, _, _ := parser.NewParser(tokens).Parse()
+
+			analyzer := NewAnalyzer()
+			analyzer.SetSession(tt.user, "ci")
+			analyzer.SetScript(lint.Shebang(content))
+			analyzer.SetSource(content)
+			analyzer.Analyze(commands)
+
+			report := lint.Lint(content)
+			if report.Script.Findings["SH006"] != 1 {
+				t.Fatalf("el linter no reportó SH006: %v", report.Script.Findings)
+			}
+			kept := analyzer.SuppressFindings(commands, report.Threats)
+
+			var inSuppressed bool
+			for _, threat := range analyzer.Suppressed() {
+				if threat.Threat.RuleID == "SH006" {
+					inSuppressed = true
+				}
+			}
+			if inSuppressed != tt.suppressed {
+				t.Errorf("SH006 en Suppressed = %v, se esperaba %v", inSuppressed, tt.suppressed)
+			}
+			if got := len(kept) == len(report.Threats); got == tt.suppressed {
+				t.Errorf("kept = %d de %d detecciones", len(kept), len(report.Threats))
+			}
+		})
+	}
+}
+
+func TestSuppressFindingsLineDirectory(t *testing.T) {
+	store, err := suppress.Configure("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { suppress.Configure("") })
+	if _, err := store.Create(suppress.Rule{
+		RuleID:        "SH006",
+		Path:          "/opt/ci/*",
+		Justification: "El job de CI solo evalúa comandos de su propio directorio",
+		ExpiresAt:     time.Now().Add(time.Hour),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// El eval escribe ./job.log en /opt/ci; al terminar el script el directorio es /tmp
+	content := "#!/bin/bash\nmkdir -p /opt/ci\ncd /opt/ci\nread name\neval \"$name\" > job.log\ncd /tmp\n"
+	tokens, _ := lexer.NewLexer(content).Tokenize()
+	commands, _, _ := parser.NewParser(tokens).Parse()
+
+	analyzer := NewAnalyzer()
+	analyzer.SetScript(lint.Shebang(content))
+	analyzer.SetSource(content)
+	analyzer.Analyze(commands)
+
+	report := lint.Lint(content)
+	if report.Script.Findings["SH006"] != 1 {
+		t.Fatalf("el linter no reportó SH006: %v", report.Script.Findings)
+	}
+	for _, threat := range analyzer.SuppressFindings(commands, report.Threats) {
+		if threat.RuleID == "SH006" {
+			t.Errorf("SH006 no se suprimió con el directorio de su línea: %+v", threat)
+		}
+	}
+	if got := analyzer.filesystemState.currentDirectory; got != "/tmp" {
+		t.Errorf("directorio tras SuppressFindings = %s, se esperaba /tmp", got)
+	}
+}
@@ -0,0 +1,515 @@
+package semantic
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"terminal-history-analyzer/internal/models"
+)
+
+var (
+	// bracedReference reconoce ${NOMBRE} y ${NOMBRE<operador>palabra} (:-, -, :=, =, :?, ?, :+, +)
+	bracedReference = regexp.MustCompile(`^\$\{([A-Za-z_][A-Za-z0-9_]*)(?:(:?[-=?+])([^}]*))?\}`)
+
+	// plainReference reconoce $NOMBRE
+	plainReference = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+	// assignmentWord reconoce una palabra NOMBRE=valor
+	assignmentWord = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)=`)
+)
+
+// declarationCommands son los builtins que asignan variables con NOMBRE=valor en sus argumentos
+var declarationCommands = map[string]bool{
+	"export": true, "declare": true, "typeset": true, "local": true, "readonly": true,
+}
+
+// readValueFlags son las flags de read que reciben un valor (-p prompt, -d delim, ...)
+const readValueFlags = "adinNptu"
+
+// environmentVariables son variables que la shell define siempre aunque el historial no las asigne;
+// su valor se desconoce salvo las que se fijan con SetEnvironment (HOME, USER, PWD)
+var environmentVariables = []string{
+	"PATH", "SHELL", "TERM", "LANG", "LOGNAME", "HOSTNAME", "OLDPWD", "UID", "EUID", "PPID",
+	"RANDOM", "SECONDS", "LINENO", "BASH", "BASH_VERSION", "HISTFILE", "HISTSIZE", "IFS", "PS1",
+}
+
+// SymbolTable mantiene las variables de la sesión: asignaciones, export, unset, read y source
+type SymbolTable struct {
+	symbols     map[string]*models.ShellVariable
+	environment map[string]string // Variables de entorno con valor conocido
+	sourced     bool              // Un source o . pudo definir cualquier variable
+	nounset     bool              // set -u: las variables no definidas detienen el comando
+	fish        bool              // set asigna variables (set -gx NOMBRE valor) en lugar de opciones
+	loops       map[string]bool   // Variables de bucles for cuya lista no produce palabras vacías
+}
+
+// reference es una referencia a una variable dentro de una palabra
+type reference struct {
+	start, end int    // Posición en el texto
+	name       string // Vacío si la expansión no es de las reconocidas (${#X}, ${X%.*})
+	operator   string // "", ":-", "-", ":=", "=", ":?", "?", ":+" o "+"
+	word       string // Palabra del operador (${X:-palabra})
+}
+
+// NewSymbolTable crea la tabla de símbolos con el entorno inicial de la sesión
+func NewSymbolTable() *SymbolTable {
+	t := &SymbolTable{
+		symbols:     make(map[string]*models.ShellVariable),
+		environment: make(map[string]string),
+		loops:       make(map[string]bool),
+	}
+	for _, name := range environmentVariables {
+		t.environment[name] = ""
+	}
+	t.environment["HOME"] = defaultHome // El mismo home que FileSystemState
+	return t
+}
+
+// SetEnvironment fija el valor de una variable de entorno (USER del dueño del historial, PWD actual)
+func (t *SymbolTable) SetEnvironment(name, value string) {
+	t.environment[name] = value
+}
+
+// Process actualiza la tabla con las asignaciones del comando. Las asignaciones que preceden a un
+// comando (X=1 make) solo afectan a ese comando y no se registran.
+func (t *SymbolTable) Process(cmd models.CommandAST) {
+	view := newCommandView(cmd)
+	_, rest := commandFields(view)
+
+	switch {
+	case len(cmd.Assignments) > 0 && strings.HasPrefix(cmd.Command, cmd.Assignments[0].Name+"="):
+		for _, assignment := range cmd.Assignments {
+			t.assign(assignment.Name, assignment.Value, cmd.Line, "assignment", false)
+		}
+	case declarationCommands[view.name]:
+		t.declare(view.name, rest, cmd.Line)
+	case view.name == "unset":
+		t.unset(rest, cmd.Line)
+	case view.name == "read":
+		t.read(rest, cmd.Line)
+	case view.name == "for" || view.name == "select":
+		t.loop(view.name, rest, cmd.Line)
+	case view.name == "source" || view.name == ".":
+		t.sourced = true
+	case view.name == "set" && t.fish:
+		t.fishSet(rest, cmd.Line)
+	case view.name == "set":
+		t.setOptions(rest)
+	}
+}
+
+// fishSet procesa set de fish: set [-gluxU] NOMBRE valores... asigna, set -x exporta y set -e borra
+func (t *SymbolTable) fishSet(fields []string, line int) {
+	exported, erase := false, false
+	for i, field := range fields {
+		if strings.HasPrefix(field, "--") {
+			exported = exported || field == "--export"
+			erase = erase || field == "--erase"
+			continue
+		}
+		if strings.HasPrefix(field, "-") {
+			exported = exported || strings.Contains(field, "x")
+			erase = erase || strings.Contains(field, "e")
+			continue
+		}
+		if erase {
+			t.unset(fields[i:], line)
+		} else {
+			t.assign(field, strings.Join(fields[i+1:], " "), line, "set", exported)
+		}
+		return
+	}
+}
+
+// declare procesa export, declare, typeset, local y readonly
+func (t *SymbolTable) declare(builtin string, fields []string, line int) {
+	exported := builtin == "export"
+	for _, field := range fields {
+		if strings.HasPrefix(field, "-") {
+			switch {
+			case strings.ContainsAny(field, "fFp"): // Funciones o listado
+				return
+			case builtin == "export" && strings.Contains(field, "n"):
+				exported = false
+			case strings.Contains(field, "x"):
+				exported = true
+			}
+			continue
+		}
+
+		if assignmentWord.MatchString(field) {
+			t.assignWord(field, line, builtin, exported)
+			continue
+		}
+		if symbol, ok := t.symbols[field]; ok && exported {
+			symbol.Exported = true
+		}
+	}
+}
+
+// assignWord registra una palabra NOMBRE=valor de export, declare, ...
+func (t *SymbolTable) assignWord(word string, line int, source string, exported bool) {
+	if match := assignmentWord.FindStringSubmatch(word); match != nil {
+		t.assign(match[1], word[len(match[0]):], line, source, exported)
+	}
+}
+
+// assign registra la variable con su valor expandido y sin comillas
+func (t *SymbolTable) assign(name, value string, line int, source string, exported bool) {
+	expanded, resolved := t.expand(value)
+	if previous, ok := t.symbols[name]; ok && previous.Exported {
+		exported = true
+	}
+	t.symbols[name] = &models.ShellVariable{
+		Name:     name,
+		Value:    removeQuotes(expanded),
+		Known:    resolved,
+		Exported: exported,
+		Line:     line,
+		Source:   source,
+	}
+}
+
+// unset elimina variables (unset -f elimina funciones)
+func (t *SymbolTable) unset(fields []string, line int) {
+	for _, field := range fields {
+		if field == "-f" {
+			return
+		}
+		if strings.HasPrefix(field, "-") {
+			continue
+		}
+		t.symbols[field] = &models.ShellVariable{Name: field, Known: true, Unset: true, Line: line, Source: "unset"}
+	}
+}
+
+// read marca como desconocidas las variables que lee de la entrada (REPLY si no se indica ninguna)
+func (t *SymbolTable) read(fields []string, line int) {
+	var names []string
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		if !strings.HasPrefix(field, "-") || len(field) < 2 {
+			names = append(names, field)
+			continue
+		}
+		// La última letra puede recibir el valor en la palabra siguiente (-p "Nombre: ", -a lista)
+		last := field[len(field)-1]
+		if strings.IndexByte(readValueFlags, last) >= 0 && i+1 < len(fields) {
+			if last == 'a' {
+				names = append(names, fields[i+1])
+			}
+			i++
+		}
+	}
+	if len(names) == 0 {
+		names = []string{"REPLY"}
+	}
+
+	for _, name := range names {
+		if !assignmentWord.MatchString(name + "=") {
+			continue
+		}
+		exported := false
+		if previous, ok := t.symbols[name]; ok {
+			exported = previous.Exported
+		}
+		t.symbols[name] = &models.ShellVariable{Name: name, Exported: exported, Line: line, Source: "read"}
+	}
+}
+
+// loop marca como desconocida la variable de for NOMBRE in palabras... (y de select). Las palabras sin
+// comillas que quedan vacías tras la expansión se descartan, así que la variable solo puede quedar
+// vacía con una palabra entre comillas vacía o con variables ("", "$X") o sin lista (recorre "$@").
+func (t *SymbolTable) loop(builtin string, fields []string, line int) {
+	if len(fields) == 0 || !assignmentWord.MatchString(fields[0]+"=") {
+		return // for ((i=0; ...)) aritmético
+	}
+	name := fields[0]
+	nonEmpty := len(fields) > 1 && fields[1] == "in"
+	if nonEmpty {
+		for _, word := range fields[2:] {
+			quoted := strings.ContainsAny(word, `"'`)
+			if removeQuotes(word) == "" || quoted && len(references(word)) > 0 {
+				nonEmpty = false
+			}
+		}
+	}
+
+	exported := false
+	if previous, ok := t.symbols[name]; ok {
+		exported = previous.Exported
+	}
+	t.symbols[name] = &models.ShellVariable{Name: name, Exported: exported, Line: line, Source: builtin}
+	t.loops[name] = nonEmpty
+}
+
+// setOptions sigue set -u / set -o nounset (y su desactivación con +u / +o nounset)
+func (t *SymbolTable) setOptions(fields []string) {
+	for i, field := range fields {
+		switch {
+		case (field == "-o" || field == "+o") && i+1 < len(fields) && fields[i+1] == "nounset":
+			t.nounset = field == "-o"
+		case strings.HasPrefix(field, "-") && !strings.HasPrefix(field, "--") && strings.Contains(field, "u"):
+			t.nounset = true
+		case strings.HasPrefix(field, "+") && strings.Contains(field, "u"):
+			t.nounset = false
+		}
+	}
+}
+
+// Variables devuelve el último estado de las variables definidas en la sesión, por línea
+func (t *SymbolTable) Variables() []models.ShellVariable {
+	variables := make([]models.ShellVariable, 0, len(t.symbols))
+	for _, symbol := range t.symbols {
+		variables = append(variables, *symbol)
+	}
+	sort.Slice(variables, func(i, j int) bool {
+		if variables[i].Line != variables[j].Line {
+			return variables[i].Line < variables[j].Line
+		}
+		return variables[i].Name < variables[j].Name
+	})
+	return variables
+}
+
+// Expand devuelve una copia del comando con las referencias de valor conocido sustituidas en el
+// comando, los argumentos, las flags, las redirecciones, los pipelines y las sustituciones
+func (t *SymbolTable) Expand(cmd models.CommandAST) models.CommandAST {
+	expanded := cmd
+	expanded.Command = t.expandWord(cmd.Command)
+	expanded.Raw = t.expandWord(cmd.Raw)
+
+	expanded.Arguments = make([]string, len(cmd.Arguments))
+	for i, argument := range cmd.Arguments {
+		expanded.Arguments[i] = t.expandWord(argument)
+	}
+
+	expanded.Flags = make(map[string]string, len(cmd.Flags))
+	for flag, value := range cmd.Flags {
+		expanded.Flags[flag] = t.expandWord(value)
+	}
+
+	expanded.Redirects = make([]models.Redirect, len(cmd.Redirects))
+	for i, redirect := range cmd.Redirects {
+		redirect.Target = t.expandWord(redirect.Target)
+		expanded.Redirects[i] = redirect
+	}
+
+	expanded.Pipes = t.expandAll(cmd.Pipes)
+	expanded.Substitutions = t.expandAll(cmd.Substitutions)
+	return expanded
+}
+
+func (t *SymbolTable) expandAll(commands []*models.CommandAST) []*models.CommandAST {
+	if commands == nil {
+		return nil
+	}
+	expanded := make([]*models.CommandAST, len(commands))
+	for i, cmd := range commands {
+		copied := t.Expand(*cmd)
+		expanded[i] = &copied
+	}
+	return expanded
+}
+
+func (t *SymbolTable) expandWord(word string) string {
+	expanded, _ := t.expand(word)
+	return expanded
+}
+
+// expand sustituye las referencias de valor conocido y conserva las demás; indica si el texto quedó
+// completamente resuelto (sin referencias desconocidas ni sustituciones de comandos)
+func (t *SymbolTable) expand(text string) (string, bool) {
+	if !strings.Contains(text, "$") && !strings.Contains(text, "`") {
+		return text, true
+	}
+
+	var out strings.Builder
+	resolved := !strings.Contains(text, "$(") && !strings.Contains(text, "`")
+	last := 0
+	for _, ref := range references(text) {
+		value, ok := t.resolve(ref)
+		if !ok {
+			resolved = false
+			continue
+		}
+		out.WriteString(text[last:ref.start])
+		out.WriteString(value)
+		last = ref.end
+	}
+	out.WriteString(text[last:])
+	return out.String(), resolved
+}
+
+// resolve devuelve el valor de una referencia si se conoce. Las variables vacías o eliminadas se
+// dejan sin sustituir salvo que un operador dé el valor (${X:-/tmp}).
+func (t *SymbolTable) resolve(ref reference) (string, bool) {
+	if ref.name == "" {
+		return "", false
+	}
+	value, set, known := t.lookup(ref.name)
+	if !known {
+		return "", false
+	}
+
+	empty := !set || value == ""
+	switch ref.operator {
+	case ":-", ":=":
+		if empty {
+			return t.expand(ref.word)
+		}
+	case "-", "=":
+		if !set {
+			return t.expand(ref.word)
+		}
+	case ":+":
+		if empty {
+			return "", true
+		}
+		return t.expand(ref.word)
+	case "+":
+		if !set {
+			return "", true
+		}
+		return t.expand(ref.word)
+	}
+
+	if empty {
+		return "", false
+	}
+	return value, true
+}
+
+// emptyRisk indica si una referencia sin protección (${X:?}, ${X:-valor}) puede expandirse a vacío,
+// el motivo y si es seguro que estará vacía (asignada vacía o eliminada con unset; las no definidas
+// pueden venir del entorno del usuario)
+func (t *SymbolTable) emptyRisk(ref reference) (string, bool, bool) {
+	if ref.name == "" {
+		return "", false, false
+	}
+	switch ref.operator {
+	case "":
+	case ":-", "-", ":=", "=":
+		if ref.word != "" {
+			return "", false, false
+		}
+	default:
+		return "", false, false
+	}
+
+	symbol, defined := t.symbols[ref.name]
+	switch {
+	case defined && symbol.Unset:
+		if t.nounset && ref.operator == "" {
+			return "", false, false // set -u detiene el comando
+		}
+		return fmt.Sprintf("se eliminó con unset en la línea %d", symbol.Line), true, true
+	case defined && !symbol.Known && (symbol.Source == "for" || symbol.Source == "select") && t.loops[ref.name]:
+		return "", false, false
+	case defined && !symbol.Known:
+		return fmt.Sprintf("tiene un valor desconocido (%s en la línea %d)", symbol.Source, symbol.Line), false, true
+	case defined && symbol.Value == "":
+		return fmt.Sprintf("está vacía (línea %d)", symbol.Line), true, true
+	case defined:
+		return "", false, false
+	}
+
+	if _, ok := t.environment[ref.name]; ok {
+		return "", false, false
+	}
+	if t.nounset && ref.operator == "" {
+		return "", false, false
+	}
+	if t.sourced {
+		return "no está definida en la sesión (podría venir de un source)", false, true
+	}
+	return "no está definida en la sesión", false, true
+}
+
+// lookup devuelve el valor de una variable, si está definida y si se conoce su estado
+func (t *SymbolTable) lookup(name string) (value string, set bool, known bool) {
+	if symbol, ok := t.symbols[name]; ok {
+		if symbol.Unset {
+			return "", false, true
+		}
+		return symbol.Value, true, symbol.Known
+	}
+	if value, ok := t.environment[name]; ok {
+		return value, true, value != ""
+	}
+	return "", false, false
+}
+
+// references devuelve las referencias a variables fuera de comillas simples
+func references(text string) []reference {
+	var refs []reference
+	var quote byte
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			}
+		case c == '\\':
+			i++
+		case c == '\'' && quote == 0:
+			quote = c
+		case c == '"':
+			if quote == '"' {
+				quote = 0
+			} else {
+				quote = c
+			}
+		case c == '$':
+			if m := bracedReference.FindStringSubmatch(text[i:]); m != nil {
+				refs = append(refs, reference{start: i, end: i + len(m[0]), name: m[1], operator: m[2], word: m[3]})
+				i += len(m[0]) - 1
+			} else if m := plainReference.FindStringSubmatch(text[i:]); m != nil {
+				refs = append(refs, reference{start: i, end: i + len(m[0]), name: m[1]})
+				i += len(m[0]) - 1
+			} else if strings.HasPrefix(text[i:], "${") {
+				refs = append(refs, reference{start: i, end: i + 2})
+			} else if i+1 < len(text) && strings.IndexByte("0123456789@*#?$!-", text[i+1]) >= 0 {
+				// Parámetros posicionales y especiales ($1, $@, $?): su valor no se conoce
+				refs = append(refs, reference{start: i, end: i + 2})
+				i++
+			}
+		}
+	}
+	return refs
+}
+
+// removeQuotes quita las comillas de una palabra como hace la shell ("$DIR"/build -> /opt/app/build)
+func removeQuotes(word string) string {
+	if !strings.ContainsAny(word, `"'\`) {
+		return word
+	}
+
+	var out strings.Builder
+	var quote byte
+	for i := 0; i < len(word); i++ {
+		c := word[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+				continue
+			}
+		case c == '\\' && i+1 < len(word):
+			i++
+			c = word[i]
+		case c == '\'' && quote == 0, c == '"' && quote == 0:
+			quote = c
+			continue
+		case c == '"' && quote == '"':
+			quote = 0
+			continue
+		}
+		out.WriteByte(c)
+	}
+	return out.String()
+}
@@ -0,0 +1,96 @@
+package semantic
+
+import (
+	"fmt"
+
+	"terminal-history-analyzer/internal/intel"
+	"terminal-history-analyzer/internal/ioc"
+	"terminal-history-analyzer/internal/models"
+)
+
+// intelKinds indica con qué tabla de las fuentes se compara cada tipo de indicador extraído
+var intelKinds = map[string]string{
+	ioc.TypeIPv4:   intel.KindIP,
+	ioc.TypeIPv6:   intel.KindIP,
+	ioc.TypeDomain: intel.KindDomain,
+	ioc.TypeURL:    intel.KindURL,
+	ioc.TypeMD5:    intel.KindHash,
+	ioc.TypeSHA1:   intel.KindHash,
+	ioc.TypeSHA256: intel.KindHash,
+	ioc.TypeSHA512: intel.KindHash,
+}
+
+// analyzeThreatIntel compara las URLs, hosts, IPs y hashes del comando con las fuentes de
+// inteligencia; cada valor genera una detección por línea con la fuente de mayor confianza
+func (a *Analyzer) analyzeThreatIntel(cmd models.CommandAST) {
+	if a.intel == nil || a.intel.Len() == 0 {
+		return
+	}
+
+	for _, indicator := range ioc.ExtractCommand(cmd) {
+		kind, ok := intelKinds[indicator.Type]
+		if !ok {
+			continue
+		}
+		key := fmt.Sprintf("%d|%s", cmd.Line, indicator.Value)
+		if a.intelSeen[key] {
+			continue
+		}
+
+		var entries []intel.Entry
+		switch kind {
+		case intel.KindIP:
+			entries = a.intel.MatchIP(indicator.Value)
+		case intel.KindDomain:
+			entries = a.intel.MatchDomain(indicator.Value)
+		case intel.KindURL:
+			entries = a.intel.MatchURL(indicator.Value)
+		case intel.KindHash:
+			entries = a.intel.MatchHash(indicator.Value)
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		a.intelSeen[key] = true
+
+		best := entries[0]
+		feeds := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			if entry.Confidence > best.Confidence {
+				best = entry
+			}
+			if !contains(feeds, entry.Feed) {
+				feeds = append(feeds, entry.Feed)
+			}
+		}
+
+		description := fmt.Sprintf("Indicador de la fuente %s (confianza %d): %s", best.Feed, best.Confidence, indicator.Value)
+		if best.Indicator != indicator.Value {
+			description += " (coincide con " + best.Indicator + ")"
+		}
+		a.addThreat(intelLevel(best.Confidence), "threat_intel_match", description, cmd)
+		threat := &a.threats[len(a.threats)-1]
+		threat.ThreatIntel = &models.ThreatIntelMatch{
+			Feed:       best.Feed,
+			Confidence: best.Confidence,
+			Indicator:  best.Indicator,
+			Value:      indicator.Value,
+			Kind:       kind,
+			Feeds:      feeds,
+		}
+	}
+}
+
+// intelLevel convierte la confianza de la fuente en nivel de amenaza
+func intelLevel(confidence int) models.ThreatLevel {
+	switch {
+	case confidence >= 90:
+		return models.CRITICAL
+	case confidence >= 70:
+		return models.HIGH
+	case confidence >= 40:
+		return models.MEDIUM
+	default:
+		return models.LOW
+	}
+}
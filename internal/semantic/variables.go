@@ -0,0 +1,153 @@
+package semantic
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"terminal-history-analyzer/internal/dialect"
+	"terminal-history-analyzer/internal/models"
+)
+
+// expansionTargets son los comandos cuyos operandos se revisan en busca de variables que, vacías,
+// convierten la ruta en / o en un directorio del sistema
+var expansionTargets = map[string]bool{
+	"rm": true, "shred": true, "chmod": true, "chown": true, "chgrp": true,
+}
+
+// typedCommand es el comando tal como se escribió mientras los analizadores ven su versión expandida
+type typedCommand struct {
+	expanded string // Raw del comando expandido
+	original models.CommandAST
+}
+
+// expandSession devuelve el comando con las variables de la sesión sustituidas, tras revisar las
+// expansiones peligrosas con los valores previos al comando
+func (a *Analyzer) expandSession(cmd models.CommandAST) models.CommandAST {
+	a.symbols.SetEnvironment("PWD", a.filesystemState.currentDirectory)
+	a.unsafeOperands = nil
+	if a.dialect != dialect.PowerShell { // Sin división en palabras: una variable vacía no se descarta
+		a.detectUnsafeExpansions(cmd)
+	}
+
+	expanded := a.symbols.Expand(cmd)
+	a.typed = nil
+	if expanded.Raw != cmd.Raw {
+		a.typed = &typedCommand{expanded: expanded.Raw, original: cmd}
+	}
+	return expanded
+}
+
+// unexpanded devuelve el comando tal como se escribió si cmd es la versión expandida del actual;
+// las alternativas seguras conservan las variables del usuario
+func (a *Analyzer) unexpanded(cmd models.CommandAST) models.CommandAST {
+	if a.typed != nil && cmd.Raw == a.typed.expanded {
+		return a.typed.original
+	}
+	return cmd
+}
+
+// restoreRaw devuelve el texto original a las detecciones, errores y observaciones registrados
+// desde from con el comando expandido, para que el reporte muestre lo que escribió el usuario
+func (a *Analyzer) restoreRaw(from, fsFrom, observedFrom int, original, expanded string) {
+	a.typed = nil
+	if original == expanded {
+		return
+	}
+	for i := from; i < len(a.threats); i++ {
+		if a.threats[i].Command == expanded {
+			a.threats[i].Command = original
+		}
+	}
+	for i := fsFrom; i < len(a.fsErrors); i++ {
+		if a.fsErrors[i].Command == expanded {
+			a.fsErrors[i].Command = original
+		}
+	}
+	if a.observed != nil {
+		for i := observedFrom; i < len(a.observed.Observations); i++ {
+			a.observed.Observations[i].Raw = original
+		}
+	}
+}
+
+// detectUnsafeExpansions detecta operandos de rm, chmod -R, chown -R, ... con variables vacías, no
+// definidas o de valor desconocido que sin valor dejan una ruta absoluta (rm -rf $BUILD/ -> rm -rf /)
+func (a *Analyzer) detectUnsafeExpansions(cmd models.CommandAST) {
+	for _, segment := range append([]*models.CommandAST{&cmd}, cmd.Pipes...) {
+		view := newCommandView(*segment)
+		if !expansionTargets[view.name] {
+			continue
+		}
+		if view.name != "rm" && view.name != "shred" && !view.hasFlag("-R") && !view.longFlags["recursive"] {
+			continue
+		}
+
+		_, rest := commandFields(view)
+		endOfOptions := false
+		for _, field := range rest {
+			if !endOfOptions && field == "--" {
+				endOfOptions = true
+				continue
+			}
+			if !endOfOptions && strings.HasPrefix(field, "-") {
+				continue
+			}
+			a.checkExpansion(view, field, cmd)
+		}
+	}
+}
+
+// checkExpansion registra la amenaza si el operando, con sus variables de riesgo vacías, queda
+// como una ruta absoluta
+func (a *Analyzer) checkExpansion(view *commandView, field string, cmd models.CommandAST) {
+	var risky []reference
+	var reasons []string
+	certain := false
+
+	for _, ref := range references(field) {
+		reason, sure, ok := a.symbols.emptyRisk(ref)
+		if !ok {
+			continue
+		}
+		risky = append(risky, ref)
+		reasons = append(reasons, "$"+ref.name+" "+reason)
+		certain = certain || sure
+	}
+	if len(risky) == 0 {
+		return
+	}
+
+	// Ruta resultante con las variables de riesgo vacías y el resto con su valor conocido
+	var emptied strings.Builder
+	last := 0
+	for _, ref := range risky {
+		emptied.WriteString(field[last:ref.start])
+		last = ref.end
+	}
+	emptied.WriteString(field[last:])
+	result := removeQuotes(a.symbols.expandWord(emptied.String()))
+	if !strings.HasPrefix(result, "/") {
+		return
+	}
+	result = path.Clean(result)
+
+	root := result == "/" || result == "/*"
+	level := models.MEDIUM
+	switch {
+	case certain && root:
+		level = models.CRITICAL
+	case certain || root:
+		level = models.HIGH
+	}
+
+	if a.unsafeOperands == nil {
+		a.unsafeOperands = make(map[string]bool)
+	}
+	// Tal como se escribió y tal como lo verán las reglas, con los valores conocidos sustituidos
+	a.unsafeOperands[removeQuotes(field)] = true
+	a.unsafeOperands[removeQuotes(a.symbols.expandWord(field))] = true
+
+	a.addThreat(level, "unsafe_expansion", fmt.Sprintf("Expansión peligrosa en %s: %s; el operando %s quedaría como %s",
+		view.name, strings.Join(reasons, ", "), field, result), cmd)
+}
@@ -0,0 +1,53 @@
+package semantic
+
+import "testing"
+
+func TestLoopVariableExpansion(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]int // Detecciones esperadas por tipo
+	}{
+		{
+			name:    "for con lista literal",
+			content: "for f in a b; do rm -rf $f/; done",
+			want:    map[string]int{"unsafe_expansion": 0, "dangerous_deletion": 0, "filesystem_error": 0},
+		},
+		{
+			name:    "for con sustitución de comandos",
+			content: "for f in $(ls); do rm -rf $f/; done",
+			want:    map[string]int{"unsafe_expansion": 0, "dangerous_deletion": 0},
+		},
+		{
+			name:    "for con palabra entre comillas que puede quedar vacía",
+			content: "for f in \"$DIR\"; do rm -rf $f/; done",
+			want:    map[string]int{"unsafe_expansion": 1, "dangerous_deletion": 0},
+		},
+		{
+			name:    "while read",
+			content: "while read f; do rm -rf $f/; done",
+			want:    map[string]int{"unsafe_expansion": 1, "dangerous_deletion": 0, "filesystem_error": 0},
+		},
+		{
+			name:    "variable vacía",
+			content: "X=\"\"\nrm -rf $X/",
+			want:    map[string]int{"unsafe_expansion": 1, "dangerous_deletion": 0},
+		},
+		{
+			name:    "directorio del sistema literal",
+			content: "rm -rf /etc/",
+			want:    map[string]int{"unsafe_expansion": 0, "dangerous_deletion": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			types := threatTypes(analyzeSession(t, NewAnalyzer(), tt.content))
+			for threatType, count := range tt.want {
+				if types[threatType] != count {
+					t.Errorf("%s = %d, se esperaba %d (%v)", threatType, types[threatType], count, types)
+				}
+			}
+		})
+	}
+}
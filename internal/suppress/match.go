@@ -0,0 +1,137 @@
+package suppress
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"regexp"
+	"strings"
+
+	"terminal-history-analyzer/internal/models"
+)
+
+// Finding es una detección con el contexto del comando que la produjo
+type Finding struct {
+	Threat models.ThreatDetection
+	Hosts  []string // Hosts de destino y host en el que se ejecutó el comando
+	Paths  []string // Rutas absolutas que nombra o escribe el comando
+	Users  []string // Usuario de la sesión y usuarios remotos
+}
+
+// Set son las supresiones vigentes compiladas
+type Set struct {
+	rules []*compiledRule
+}
+
+// compiledRule es una supresión con su expresión y su red precompiladas
+type compiledRule struct {
+	Rule
+	command *regexp.Regexp
+	network *net.IPNet
+}
+
+// compile valida los patrones de la supresión
+func compile(rule Rule) (*compiledRule, error) {
+	compiled := &compiledRule{Rule: rule}
+
+	if rule.Command != "" {
+		re, err := regexp.Compile(rule.Command)
+		if err != nil {
+			return nil, fmt.Errorf("expresión de command inválida: %w", err)
+		}
+		compiled.command = re
+	}
+	if strings.Contains(rule.Host, "/") {
+		_, network, err := net.ParseCIDR(rule.Host)
+		if err != nil {
+			return nil, fmt.Errorf("CIDR de host inválido: %w", err)
+		}
+		compiled.network = network
+	}
+	patterns := map[string]string{"rule_id": rule.RuleID, "path": rule.Path, "user": rule.User}
+	if compiled.network == nil {
+		patterns["host"] = rule.Host
+	}
+	for name, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("comodín de %s inválido: %q", name, pattern)
+		}
+	}
+
+	return compiled, nil
+}
+
+// Len devuelve el número de supresiones vigentes
+func (s *Set) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.rules)
+}
+
+// Match devuelve la primera supresión cuyos ámbitos cumple la detección
+func (s *Set) Match(finding Finding) (Rule, bool) {
+	if s == nil {
+		return Rule{}, false
+	}
+	for _, rule := range s.rules {
+		if rule.matches(finding) {
+			return rule.Rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// matches verifica todos los ámbitos presentes
+func (r *compiledRule) matches(finding Finding) bool {
+	threat := finding.Threat
+
+	if r.RuleID != "" && !glob(r.RuleID, threat.RuleID) && !glob(r.RuleID, threat.Type) {
+		return false
+	}
+	if r.command != nil && !r.command.MatchString(threat.Command) {
+		return false
+	}
+	if r.Host != "" && !anyMatch(finding.Hosts, r.matchesHost) {
+		return false
+	}
+	if r.Path != "" && !anyMatch(finding.Paths, func(value string) bool { return glob(r.Path, value) }) {
+		return false
+	}
+	if r.User != "" && !anyMatch(finding.Users, func(value string) bool { return glob(r.User, value) }) {
+		return false
+	}
+	return true
+}
+
+// matchesHost compara un host con la red CIDR o con el comodín (sin distinguir mayúsculas)
+func (r *compiledRule) matchesHost(host string) bool {
+	if r.network != nil {
+		ip := net.ParseIP(strings.Trim(host, "[]"))
+		return ip != nil && r.network.Contains(ip)
+	}
+	return glob(strings.ToLower(r.Host), strings.ToLower(host))
+}
+
+// glob compara un valor con un comodín de path.Match; "/*" al final abarca el directorio y todo su contenido
+func glob(pattern, value string) bool {
+	if value == "" {
+		return false
+	}
+	if matched, _ := path.Match(pattern, value); matched {
+		return true
+	}
+	if dir := strings.TrimSuffix(pattern, "/*"); dir != pattern {
+		return value == dir || strings.HasPrefix(value, dir+"/")
+	}
+	return false
+}
+
+func anyMatch(values []string, match func(string) bool) bool {
+	for _, value := range values {
+		if match(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,273 @@
+package suppress
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound indica que no existe una supresión con el ID indicado
+	ErrNotFound = errors.New("supresión no encontrada")
+
+	// ErrInvalid indica que la supresión no tiene ámbito, justificación o expiración válidos
+	ErrInvalid = errors.New("supresión inválida")
+)
+
+// Rule es una supresión: las detecciones que cumplen todos sus ámbitos se apartan a la lista de
+// suprimidas hasta la fecha de expiración. Se aplican a las amenazas y a los hallazgos del linter,
+// que proceden de un comando; los patrones y las anomalías agregan varios comandos y no se suprimen.
+type Rule struct {
+	ID            string    `json:"id"`
+	RuleID        string    `json:"rule_id,omitempty"` // ID de regla o tipo de detección; admite comodines (ssh-*)
+	Command       string    `json:"command,omitempty"` // Regex sobre el comando
+	Host          string    `json:"host,omitempty"`    // Comodín o CIDR sobre el host de destino o de ejecución
+	Path          string    `json:"path,omitempty"`    // Comodín sobre las rutas del comando (/opt/ci/*)
+	User          string    `json:"user,omitempty"`    // Usuario de la sesión o usuario remoto
+	Justification string    `json:"justification"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Expired indica si la supresión ya no se aplica
+func (r Rule) Expired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
+
+// Store guarda las supresiones en memoria y, si tiene archivo, las persiste en JSON
+type Store struct {
+	mu    sync.RWMutex
+	path  string
+	rules map[string]Rule
+}
+
+// storeFile es el formato del archivo de supresiones
+type storeFile struct {
+	Suppressions []Rule `json:"suppressions"`
+}
+
+var (
+	activeStore   = &Store{rules: make(map[string]Rule)}
+	activeStoreMu sync.RWMutex
+)
+
+// Active devuelve el almacén de supresiones activo
+func Active() *Store {
+	activeStoreMu.RLock()
+	defer activeStoreMu.RUnlock()
+	return activeStore
+}
+
+// Configure carga las supresiones del archivo indicado y lo activa; si no existe se crea al guardar
+// la primera supresión. Sin ruta las supresiones solo se guardan en memoria.
+func Configure(path string) (*Store, error) {
+	store := &Store{path: path, rules: make(map[string]Rule)}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("no se pudo leer el archivo de supresiones %s: %w", path, err)
+		}
+		if err == nil {
+			var file storeFile
+			if err := json.Unmarshal(data, &file); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			for _, rule := range file.Suppressions {
+				if _, err := compile(rule); err != nil {
+					return nil, fmt.Errorf("%s: supresión %s: %w", path, rule.ID, err)
+				}
+				store.rules[rule.ID] = rule
+			}
+		}
+	}
+
+	activeStoreMu.Lock()
+	activeStore = store
+	activeStoreMu.Unlock()
+
+	return store, nil
+}
+
+// List devuelve las supresiones ordenadas por fecha de creación
+func (s *Store) List() []Rule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rules := make([]Rule, 0, len(s.rules))
+	for _, rule := range s.rules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].CreatedAt.Equal(rules[j].CreatedAt) {
+			return rules[i].ID < rules[j].ID
+		}
+		return rules[i].CreatedAt.Before(rules[j].CreatedAt)
+	})
+	return rules
+}
+
+// Get devuelve una supresión por ID
+func (s *Store) Get(id string) (Rule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rule, ok := s.rules[id]
+	if !ok {
+		return Rule{}, ErrNotFound
+	}
+	return rule, nil
+}
+
+// Create valida y guarda una nueva supresión, asignando ID y fechas
+func (s *Store) Create(rule Rule) (Rule, error) {
+	now := time.Now().UTC()
+	rule.ID = newID()
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+	if err := validate(rule, now); err != nil {
+		return Rule{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rules[rule.ID] = rule
+	if err := s.save(); err != nil {
+		delete(s.rules, rule.ID)
+		return Rule{}, err
+	}
+	return rule, nil
+}
+
+// Update reemplaza los ámbitos, la justificación y la expiración de una supresión
+func (s *Store) Update(id string, rule Rule) (Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, ok := s.rules[id]
+	if !ok {
+		return Rule{}, ErrNotFound
+	}
+
+	now := time.Now().UTC()
+	rule.ID = id
+	rule.CreatedAt = previous.CreatedAt
+	rule.UpdatedAt = now
+	if err := validate(rule, now); err != nil {
+		return Rule{}, err
+	}
+
+	s.rules[id] = rule
+	if err := s.save(); err != nil {
+		s.rules[id] = previous
+		return Rule{}, err
+	}
+	return rule, nil
+}
+
+// Delete elimina una supresión
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, ok := s.rules[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	delete(s.rules, id)
+	if err := s.save(); err != nil {
+		s.rules[id] = previous
+		return err
+	}
+	return nil
+}
+
+// Snapshot devuelve las supresiones vigentes compiladas para aplicarlas en un análisis
+func (s *Store) Snapshot(now time.Time) *Set {
+	set := &Set{}
+	for _, rule := range s.List() {
+		if rule.Expired(now) {
+			continue
+		}
+		if compiled, err := compile(rule); err == nil {
+			set.rules = append(set.rules, compiled)
+		}
+	}
+	return set
+}
+
+// save escribe el archivo de supresiones de forma atómica; requiere el bloqueo de escritura
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	rules := make([]Rule, 0, len(s.rules))
+	for _, rule := range s.rules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+
+	data, err := json.MarshalIndent(storeFile{Suppressions: rules}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".suppressions-*.json")
+	if err != nil {
+		return fmt.Errorf("no se pudo guardar el archivo de supresiones: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("no se pudo guardar el archivo de supresiones: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("no se pudo guardar el archivo de supresiones: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("no se pudo guardar el archivo de supresiones: %w", err)
+	}
+	return nil
+}
+
+// validate comprueba que la supresión tenga ámbito, justificación y una expiración futura
+func validate(rule Rule, now time.Time) error {
+	if rule.RuleID == "" && rule.Command == "" && rule.Host == "" && rule.Path == "" && rule.User == "" {
+		return fmt.Errorf("%w: indique al menos un ámbito (rule_id, command, host, path o user)", ErrInvalid)
+	}
+	if strings.TrimSpace(rule.Justification) == "" {
+		return fmt.Errorf("%w: falta la justificación", ErrInvalid)
+	}
+	if rule.ExpiresAt.IsZero() {
+		return fmt.Errorf("%w: falta la fecha de expiración (expires_at)", ErrInvalid)
+	}
+	if rule.Expired(now) {
+		return fmt.Errorf("%w: la fecha de expiración debe ser futura", ErrInvalid)
+	}
+	if _, err := compile(rule); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return nil
+}
+
+// newID genera un identificador aleatorio de 12 caracteres hexadecimales
+func newID() string {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return hex.EncodeToString([]byte(time.Now().Format("150405")))
+	}
+	return hex.EncodeToString(buf)
+}
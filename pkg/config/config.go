@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strings"
 )
 
 type Config struct {
@@ -9,6 +10,13 @@ type Config struct {
 	Port           string
 	MaxFileSize    int64
 	AllowedOrigins []string
+	RulesDir       string   // Directorio con reglas de amenazas propias de la organización
+	IntelDir       string   // Directorio con fuentes de inteligencia de amenazas sin conexión
+	OrgCIDRs       []string // Redes propias de la organización (clase "organization" de netintel)
+	RiskWeights    string   // Archivo YAML con los pesos de la puntuación de riesgo
+	Suppressions   string   // Archivo JSON donde se guardan las supresiones
+	IntentTaxonomy string   // Taxonomía YAML de intenciones de los comandos (por defecto la embebida)
+	Baselines      string   // Archivo JSON con las líneas base por usuario@equipo
 }
 
 func Load() *Config {
@@ -19,6 +27,13 @@ func Load() *Config {
 		AllowedOrigins: []string{
 			getEnv("FRONTEND_URL", "http://localhost:3000"),
 		},
+		RulesDir:       getEnv("RULES_DIR", "rules"),
+		IntelDir:       getEnv("INTEL_DIR", "intel"),
+		OrgCIDRs:       splitList(getEnv("ORG_CIDRS", "")),
+		RiskWeights:    getEnv("RISK_WEIGHTS", "risk-weights.yaml"),
+		Suppressions:   getEnv("SUPPRESSIONS_FILE", "suppressions.json"),
+		IntentTaxonomy: getEnv("INTENT_TAXONOMY", "taxonomy.yaml"),
+		Baselines:      getEnv("BASELINES_FILE", "baselines.json"),
 	}
 }
 
@@ -28,3 +43,14 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// splitList separa una lista separada por comas, descartando los elementos vacíos
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
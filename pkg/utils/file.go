@@ -120,6 +120,7 @@ func IsHistoryFile(filename string) bool {
 		"zsh_history", ".zsh_history",
 		"history", ".history",
 		"fish_history", ".fish_history",
+		"ConsoleHost_history", // PSReadLine (PowerShell)
 	}
 
 	for _, name := range historyNames {